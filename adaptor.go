@@ -1,5 +1,7 @@
 package gobot
 
+import "context"
+
 // Adaptor is the interface that describes an adaptor in gobot
 type Adaptor interface {
 	// Name returns the label for the Adaptor
@@ -14,3 +16,20 @@ type Adaptor interface {
 type Porter interface {
 	Port() string
 }
+
+// ContextAdaptor is the interface that describes an adaptor which supports
+// cancellation and deadlines while connecting and finalizing. Connections
+// which implement it are preferred over the plain Adaptor methods.
+type ContextAdaptor interface {
+	// ConnectContext initiates the Adaptor, giving up when ctx is done
+	ConnectContext(ctx context.Context) []error
+	// FinalizeContext terminates the Adaptor, giving up when ctx is done
+	FinalizeContext(ctx context.Context) []error
+}
+
+// Pinger is the interface that describes an adaptor which can check whether
+// its connection is still alive. Supervised robots call Ping periodically
+// and reconnect the adaptor when it returns an error.
+type Pinger interface {
+	Ping() error
+}
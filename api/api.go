@@ -2,28 +2,35 @@ package api
 
 import (
 	"encoding/json"
-	"errors"
 	"fmt"
-	"log"
+	"io"
 	"net/http"
-	"net/http/httptest"
 	"strings"
+	"sync"
 
 	"github.com/bmizerany/pat"
 	"github.com/hybridgroup/gobot"
 	"github.com/hybridgroup/gobot/api/robeaux"
+	"golang.org/x/net/websocket"
 )
 
 // API represents an API server
 type API struct {
-	gobot    *gobot.Gobot
-	router   *pat.PatternServeMux
-	Host     string
-	Port     string
-	Cert     string
-	Key      string
-	handlers []func(http.ResponseWriter, *http.Request)
-	start    func(*API)
+	gobot  *gobot.Gobot
+	router *pat.PatternServeMux
+	Host   string
+	Port   string
+	Cert   string
+	Key    string
+	// SocketOrigins are the origins, besides the api's own, of the pages
+	// which may connect to /api/socket, with wildcards as accepted by
+	// CORSMiddleware
+	SocketOrigins []string
+
+	mtx        sync.RWMutex
+	middleware []Middleware
+	routes     []route
+	start      func(*API)
 }
 
 // NewAPI returns a new api instance
@@ -33,14 +40,14 @@ func NewAPI(g *gobot.Gobot) *API {
 		router: pat.New(),
 		Port:   "3000",
 		start: func(a *API) {
-			log.Println("Initializing API on " + a.Host + ":" + a.Port + "...")
+			a.logger().Info("Initializing API", "host", a.Host, "port", a.Port)
 			http.Handle("/", a)
 
 			go func() {
 				if a.Cert != "" && a.Key != "" {
 					http.ListenAndServeTLS(a.Host+":"+a.Port, a.Cert, a.Key, nil)
 				} else {
-					log.Println("WARNING: API using insecure connection. " +
+					a.logger().Warn("API using insecure connection. " +
 						"We recommend using an SSL certificate with Gobot.")
 					http.ListenAndServe(a.Host+":"+a.Port, nil)
 				}
@@ -49,55 +56,54 @@ func NewAPI(g *gobot.Gobot) *API {
 	}
 }
 
-// ServeHTTP calls api handlers and then serves request using api router
+// ServeHTTP serves request using api router, wrapped in the api middleware
 func (a *API) ServeHTTP(res http.ResponseWriter, req *http.Request) {
-	for _, handler := range a.handlers {
-		rec := httptest.NewRecorder()
-		handler(rec, req)
-		for k, v := range rec.Header() {
-			res.Header()[k] = v
-		}
-		if rec.Code == http.StatusUnauthorized {
-			http.Error(res, "Not Authorized", http.StatusUnauthorized)
-			return
-		}
-	}
-	a.router.ServeHTTP(res, req)
+	a.wrap(a.router).ServeHTTP(res, req)
 }
 
 // Post wraps api router Post call
 func (a *API) Post(path string, f func(http.ResponseWriter, *http.Request)) {
 	a.router.Post(path, http.HandlerFunc(f))
+	a.addRoute("POST", path)
 }
 
 // Put wraps api router Put call
 func (a *API) Put(path string, f func(http.ResponseWriter, *http.Request)) {
 	a.router.Put(path, http.HandlerFunc(f))
+	a.addRoute("PUT", path)
 }
 
 // Delete wraps api router Delete call
 func (a *API) Delete(path string, f func(http.ResponseWriter, *http.Request)) {
 	a.router.Del(path, http.HandlerFunc(f))
+	a.addRoute("DELETE", path)
 }
 
 // Options wraps api router Options call
 func (a *API) Options(path string, f func(http.ResponseWriter, *http.Request)) {
 	a.router.Options(path, http.HandlerFunc(f))
+	a.addRoute("OPTIONS", path)
 }
 
 // Get wraps api router Get call
 func (a *API) Get(path string, f func(http.ResponseWriter, *http.Request)) {
 	a.router.Get(path, http.HandlerFunc(f))
+	a.addRoute("GET", path)
 }
 
 // Head wraps api router Head call
 func (a *API) Head(path string, f func(http.ResponseWriter, *http.Request)) {
 	a.router.Head(path, http.HandlerFunc(f))
+	a.addRoute("HEAD", path)
 }
 
-// AddHandler appends handler to api handlers
+// AddHandler appends handler to api handlers, which are called before each
+// request is served. The headers handler sets are added to the response, and
+// if it answers with an error status, such as 401 Not Authorized, the request
+// is answered with its response instead. Use adds middleware, which can do
+// more.
 func (a *API) AddHandler(f func(http.ResponseWriter, *http.Request)) {
-	a.handlers = append(a.handlers, f)
+	a.Use(handlerMiddleware(f))
 }
 
 // Start initializes the api by setting up c3pio routes and robeaux
@@ -107,11 +113,13 @@ func (a *API) Start() {
 	robotCommandRoute := "/api/robots/:robot/commands/:command"
 
 	a.Get("/api/commands", a.mcpCommands)
+	a.Get("/api/events/:event", a.mcpEvent)
 	a.Get(mcpCommandRoute, a.executeMcpCommand)
 	a.Post(mcpCommandRoute, a.executeMcpCommand)
 	a.Get("/api/robots", a.robots)
 	a.Get("/api/robots/:robot", a.robot)
 	a.Get("/api/robots/:robot/commands", a.robotCommands)
+	a.Get("/api/robots/:robot/events/:event", a.robotEvent)
 	a.Get(robotCommandRoute, a.executeRobotCommand)
 	a.Post(robotCommandRoute, a.executeRobotCommand)
 	a.Get("/api/robots/:robot/devices", a.robotDevices)
@@ -120,8 +128,29 @@ func (a *API) Start() {
 	a.Get("/api/robots/:robot/devices/:device/commands", a.robotDeviceCommands)
 	a.Get(robotDeviceCommandRoute, a.executeRobotDeviceCommand)
 	a.Post(robotDeviceCommandRoute, a.executeRobotDeviceCommand)
+	a.Get("/api/robots/:robot/macros", a.robotMacros)
+	a.Post("/api/robots/:robot/macros", a.addRobotMacro)
+	a.Post("/api/robots/:robot/macros/run", a.runRobotMacro)
+	a.Post("/api/robots/:robot/macros/cancel", a.cancelRobotMacro)
+	a.Get("/api/robots/:robot/macros/:macro", a.robotMacro)
+	a.Get("/api/robots/:robot/store", a.store)
+	a.Get("/api/robots/:robot/store/:key", a.storeValue)
+	a.Put("/api/robots/:robot/store/:key", a.setStoreValue)
+	a.Delete("/api/robots/:robot/store/:key", a.deleteStoreValue)
+	a.Get("/api/robots/:robot/devices/:device/store", a.store)
+	a.Get("/api/robots/:robot/devices/:device/store/:key", a.storeValue)
+	a.Put("/api/robots/:robot/devices/:device/store/:key", a.setStoreValue)
+	a.Delete("/api/robots/:robot/devices/:device/store/:key", a.deleteStoreValue)
 	a.Get("/api/robots/:robot/connections", a.robotConnections)
 	a.Get("/api/robots/:robot/connections/:connection", a.robotConnection)
+	a.Get("/api/rules", a.rules)
+	a.Get("/api/rules/:rule", a.rule)
+	a.Post("/api/rules/:rule/enable", a.enableRule)
+	a.Post("/api/rules/:rule/disable", a.disableRule)
+	a.Get("/api/metrics", a.jsonMetrics)
+	a.Get("/metrics", a.metrics)
+	a.Get("/api/socket", websocket.Server{Handler: a.socket, Handshake: a.socketHandshake}.ServeHTTP)
+	a.Get("/api/openapi.json", a.openAPI)
 	a.Get("/api/", a.mcp)
 
 	a.Get("/", func(res http.ResponseWriter, req *http.Request) {
@@ -137,6 +166,10 @@ func (a *API) Start() {
 	a.Get("/css/:a/:b", a.robeaux)
 	a.Get("/partials/:a", a.robeaux)
 
+	a.mtx.Lock()
+	a.middleware = append([]Middleware{RequestID(), Recover(a.logger())}, a.middleware...)
+	a.mtx.Unlock()
+
 	a.start(a)
 }
 
@@ -172,6 +205,66 @@ func (a *API) mcpCommands(res http.ResponseWriter, req *http.Request) {
 	a.writeJSON(map[string]interface{}{"commands": gobot.NewJSONGobot(a.gobot).Commands}, res)
 }
 
+// rules returns rules route handler.
+// Writes JSON with the status of every rule
+func (a *API) rules(res http.ResponseWriter, req *http.Request) {
+	a.writeJSON(map[string]interface{}{"rules": a.gobot.Rules()}, res)
+}
+
+// rule returns rule route handler.
+// Writes JSON with the status of a rule
+func (a *API) rule(res http.ResponseWriter, req *http.Request) {
+	a.writeRule(req.URL.Query().Get(":rule"), res)
+}
+
+// enableRule returns enable rule route handler.
+// Enables a rule and writes JSON with its status
+func (a *API) enableRule(res http.ResponseWriter, req *http.Request) {
+	a.toggleRule(true, res, req)
+}
+
+// disableRule returns disable rule route handler.
+// Disables a rule and writes JSON with its status
+func (a *API) disableRule(res http.ResponseWriter, req *http.Request) {
+	a.toggleRule(false, res, req)
+}
+
+func (a *API) toggleRule(enabled bool, res http.ResponseWriter, req *http.Request) {
+	name := req.URL.Query().Get(":rule")
+	if a.gobot.Rule(name) == nil {
+		writeError(res, notFound("Rule", name))
+		return
+	}
+	if err := a.gobot.EnableRule(name, enabled); err != nil {
+		writeError(res, err)
+		return
+	}
+	a.writeRule(name, res)
+}
+
+func (a *API) writeRule(name string, res http.ResponseWriter) {
+	if rule := a.gobot.Rule(name); rule == nil {
+		writeError(res, notFound("Rule", name))
+	} else {
+		a.writeJSON(map[string]interface{}{"rule": rule}, res)
+	}
+}
+
+// metrics returns metrics route handler.
+// Writes every metric of the gobot's Registry in the Prometheus text format
+func (a *API) metrics(res http.ResponseWriter, req *http.Request) {
+	res.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	if err := a.gobot.Registry().WritePrometheus(res); err != nil {
+		a.logger().Warn("writing metrics failed", "error", err)
+	}
+}
+
+// jsonMetrics returns metrics route handler.
+// Writes JSON with the metrics of the gobot's Registry
+func (a *API) jsonMetrics(res http.ResponseWriter, req *http.Request) {
+	a.writeJSON(map[string]interface{}{"metrics": a.gobot.Registry().Snapshot()}, res)
+}
+
 // robots returns route handler.
 // Writes JSON with robots representation
 func (a *API) robots(res http.ResponseWriter, req *http.Request) {
@@ -186,7 +279,7 @@ func (a *API) robots(res http.ResponseWriter, req *http.Request) {
 // Writes JSON with robot representation
 func (a *API) robot(res http.ResponseWriter, req *http.Request) {
 	if robot, err := a.jsonRobotFor(req.URL.Query().Get(":robot")); err != nil {
-		a.writeJSON(map[string]interface{}{"error": err.Error()}, res)
+		writeError(res, err)
 	} else {
 		a.writeJSON(map[string]interface{}{"robot": robot}, res)
 	}
@@ -196,7 +289,7 @@ func (a *API) robot(res http.ResponseWriter, req *http.Request) {
 // Writes JSON with robot commands representation
 func (a *API) robotCommands(res http.ResponseWriter, req *http.Request) {
 	if robot, err := a.jsonRobotFor(req.URL.Query().Get(":robot")); err != nil {
-		a.writeJSON(map[string]interface{}{"error": err.Error()}, res)
+		writeError(res, err)
 	} else {
 		a.writeJSON(map[string]interface{}{"commands": robot.Commands}, res)
 	}
@@ -205,14 +298,14 @@ func (a *API) robotCommands(res http.ResponseWriter, req *http.Request) {
 // robotDevices returns devices route handler.
 // Writes JSON with robot devices representation
 func (a *API) robotDevices(res http.ResponseWriter, req *http.Request) {
-	if robot := a.gobot.Robot(req.URL.Query().Get(":robot")); robot != nil {
+	if robot, err := a.robotFor(req.URL.Query().Get(":robot")); err != nil {
+		writeError(res, err)
+	} else {
 		jsonDevices := []*gobot.JSONDevice{}
 		robot.Devices().Each(func(d gobot.Device) {
 			jsonDevices = append(jsonDevices, gobot.NewJSONDevice(d))
 		})
 		a.writeJSON(map[string]interface{}{"devices": jsonDevices}, res)
-	} else {
-		a.writeJSON(map[string]interface{}{"error": "No Robot found with the name " + req.URL.Query().Get(":robot")}, res)
 	}
 }
 
@@ -220,48 +313,81 @@ func (a *API) robotDevices(res http.ResponseWriter, req *http.Request) {
 // Writes JSON with robot device representation
 func (a *API) robotDevice(res http.ResponseWriter, req *http.Request) {
 	if device, err := a.jsonDeviceFor(req.URL.Query().Get(":robot"), req.URL.Query().Get(":device")); err != nil {
-		a.writeJSON(map[string]interface{}{"error": err.Error()}, res)
+		writeError(res, err)
 	} else {
 		a.writeJSON(map[string]interface{}{"device": device}, res)
 	}
 }
 
-func (a *API) robotDeviceEvent(res http.ResponseWriter, req *http.Request) {
-	f, _ := res.(http.Flusher)
-	c, _ := res.(http.CloseNotifier)
+// mcpEvent streams the global events named in the route, such as
+// robot-added and robot-removed
+func (a *API) mcpEvent(res http.ResponseWriter, req *http.Request) {
+	a.writeEvents(a.gobot, req.URL.Query().Get(":event"), res, req)
+}
 
+// robotEvent streams the robot events named in the route, such as
+// device-added and device-removed
+func (a *API) robotEvent(res http.ResponseWriter, req *http.Request) {
+	if robot, err := a.robotFor(req.URL.Query().Get(":robot")); err != nil {
+		writeError(res, err)
+	} else {
+		a.writeEvents(robot, req.URL.Query().Get(":event"), res, req)
+	}
+}
+
+// writeEvents writes each event called name published by e to res as a
+// server-sent event, until the client goes away
+func (a *API) writeEvents(e gobot.Eventer, name string, res http.ResponseWriter, req *http.Request) {
+	event := e.Event(name)
+	if len(event) == 0 {
+		writeError(res, notFound("Event", name))
+		return
+	}
+
+	f, _ := res.(http.Flusher)
+	closer := req.Context().Done()
 	dataChan := make(chan string)
-	closer := c.CloseNotify()
 
 	res.Header().Set("Content-Type", "text/event-stream")
 	res.Header().Set("Cache-Control", "no-cache")
 	res.Header().Set("Connection", "keep-alive")
 
-	device := a.gobot.Robot(req.URL.Query().Get(":robot")).
-		Device(req.URL.Query().Get(":device"))
-
-	if event := a.gobot.Robot(req.URL.Query().Get(":robot")).
-		Device(req.URL.Query().Get(":device")).(gobot.Eventer).
-		Event(req.URL.Query().Get(":event")); len(event) > 0 {
-		device.(gobot.Eventer).On(event, func(data interface{}) {
-			d, _ := json.Marshal(data)
-			dataChan <- string(d)
-		})
+	sub, err := e.On(event, func(data interface{}) {
+		d, _ := json.Marshal(data)
+		select {
+		case dataChan <- string(d):
+		case <-closer:
+		}
+	})
+	if err != nil {
+		writeError(res, err)
+		return
+	}
+	defer sub.Cancel()
 
-		for {
-			select {
-			case data := <-dataChan:
-				fmt.Fprintf(res, "data: %v\n\n", data)
+	for {
+		select {
+		case data := <-dataChan:
+			fmt.Fprintf(res, "data: %v\n\n", data)
+			if f != nil {
 				f.Flush()
-			case <-closer:
-				log.Println("Closing connection")
-				return
 			}
+		case <-closer:
+			a.logger().Info("Closing connection")
+			return
+		case <-sub.Done():
+			a.logger().Info("Closing connection", "reason", "event source closed")
+			return
 		}
+	}
+}
+
+// robotDeviceEvent streams the device events named in the route
+func (a *API) robotDeviceEvent(res http.ResponseWriter, req *http.Request) {
+	if e, err := a.eventerFor(req.URL.Query().Get(":robot"), req.URL.Query().Get(":device")); err != nil {
+		writeError(res, err)
 	} else {
-		a.writeJSON(map[string]interface{}{
-			"error": "No Event found with the name " + req.URL.Query().Get(":event"),
-		}, res)
+		a.writeEvents(e, req.URL.Query().Get(":event"), res, req)
 	}
 }
 
@@ -269,7 +395,7 @@ func (a *API) robotDeviceEvent(res http.ResponseWriter, req *http.Request) {
 // writes JSON with robot device commands representation
 func (a *API) robotDeviceCommands(res http.ResponseWriter, req *http.Request) {
 	if device, err := a.jsonDeviceFor(req.URL.Query().Get(":robot"), req.URL.Query().Get(":device")); err != nil {
-		a.writeJSON(map[string]interface{}{"error": err.Error()}, res)
+		writeError(res, err)
 	} else {
 		a.writeJSON(map[string]interface{}{"commands": device.Commands}, res)
 	}
@@ -278,23 +404,25 @@ func (a *API) robotDeviceCommands(res http.ResponseWriter, req *http.Request) {
 // robotConnections returns connections route handler
 // writes JSON with robot connections representation
 func (a *API) robotConnections(res http.ResponseWriter, req *http.Request) {
-	jsonConnections := []*gobot.JSONConnection{}
-	if robot := a.gobot.Robot(req.URL.Query().Get(":robot")); robot != nil {
-		robot.Connections().Each(func(c gobot.Connection) {
-			jsonConnections = append(jsonConnections, gobot.NewJSONConnection(c))
-		})
-		a.writeJSON(map[string]interface{}{"connections": jsonConnections}, res)
-	} else {
-		a.writeJSON(map[string]interface{}{"error": "No Robot found with the name " + req.URL.Query().Get(":robot")}, res)
+	robot, err := a.robotFor(req.URL.Query().Get(":robot"))
+	if err != nil {
+		writeError(res, err)
+		return
 	}
-
+	jsonConnections := []*gobot.JSONConnection{}
+	robot.Connections().Each(func(c gobot.Connection) {
+		jsonConnection := gobot.NewJSONConnection(c)
+		jsonConnection.State = robot.ConnectionState(c.Name())
+		jsonConnections = append(jsonConnections, jsonConnection)
+	})
+	a.writeJSON(map[string]interface{}{"connections": jsonConnections}, res)
 }
 
 // robotConnection returns connection route handler
 // writes JSON with robot connection representation
 func (a *API) robotConnection(res http.ResponseWriter, req *http.Request) {
 	if conn, err := a.jsonConnectionFor(req.URL.Query().Get(":robot"), req.URL.Query().Get(":connection")); err != nil {
-		a.writeJSON(map[string]interface{}{"error": err.Error()}, res)
+		writeError(res, err)
 	} else {
 		a.writeJSON(map[string]interface{}{"connection": conn}, res)
 	}
@@ -302,58 +430,218 @@ func (a *API) robotConnection(res http.ResponseWriter, req *http.Request) {
 
 // executeMcpCommand calls a global command associated to requested route
 func (a *API) executeMcpCommand(res http.ResponseWriter, req *http.Request) {
-	a.executeCommand(a.gobot.Command(req.URL.Query().Get(":command")),
-		res,
-		req,
-	)
+	a.executeCommand("", "", res, req)
 }
 
 // executeRobotDeviceCommand calls a device command associated to requested route
 func (a *API) executeRobotDeviceCommand(res http.ResponseWriter, req *http.Request) {
-	if _, err := a.jsonDeviceFor(req.URL.Query().Get(":robot"),
-		req.URL.Query().Get(":device")); err != nil {
-		a.writeJSON(map[string]interface{}{"error": err.Error()}, res)
-	} else {
-		a.executeCommand(
-			a.gobot.Robot(req.URL.Query().Get(":robot")).
-				Device(req.URL.Query().Get(":device")).(gobot.Commander).
-				Command(req.URL.Query().Get(":command")),
-			res,
-			req,
-		)
-	}
+	a.executeCommand(req.URL.Query().Get(":robot"), req.URL.Query().Get(":device"), res, req)
 }
 
 // executeRobotCommand calls a robot command associated to requested route
 func (a *API) executeRobotCommand(res http.ResponseWriter, req *http.Request) {
-	if _, err := a.jsonRobotFor(req.URL.Query().Get(":robot")); err != nil {
-		a.writeJSON(map[string]interface{}{"error": err.Error()}, res)
+	a.executeCommand(req.URL.Query().Get(":robot"), "", res, req)
+}
+
+// executeCommand calls the command named in the route, of the device or robot
+// or the gobot, with the params in the request body and writes JSON response
+// with its returned value. Commands called with params which do not match
+// their schema respond with 400 Bad Request, and commands which return an
+// error with 500 Internal Server Error.
+func (a *API) executeCommand(robot, device string, res http.ResponseWriter, req *http.Request) {
+	f, err := a.commandFor(robot, device, req.URL.Query().Get(":command"))
+	if err != nil {
+		writeError(res, err)
+		return
+	}
+
+	body := make(map[string]interface{})
+	if err = json.NewDecoder(req.Body).Decode(&body); err != nil && err != io.EOF {
+		writeError(res, badRequest(err))
+		return
+	}
+
+	result := f(body)
+	if err, ok := result.(error); ok {
+		if _, isParam := err.(*gobot.ParamError); !isParam {
+			err = &Error{Status: http.StatusInternalServerError, Code: "command_failed", Message: err.Error(), Resource: "command"}
+		}
+		writeError(res, err)
+		return
+	}
+	a.writeJSON(map[string]interface{}{"result": result}, res)
+}
+
+// robotMacros returns macros route handler.
+// Writes JSON with the macros stored by a robot
+func (a *API) robotMacros(res http.ResponseWriter, req *http.Request) {
+	if robot, err := a.robotFor(req.URL.Query().Get(":robot")); err != nil {
+		writeError(res, err)
 	} else {
-		a.executeCommand(
-			a.gobot.Robot(req.URL.Query().Get(":robot")).
-				Command(req.URL.Query().Get(":command")),
-			res,
-			req,
-		)
+		a.writeJSON(map[string]interface{}{"macros": robot.Macros()}, res)
 	}
 }
 
-// executeCommand writes JSON response with `f` returned value.
-func (a *API) executeCommand(f func(map[string]interface{}) interface{},
-	res http.ResponseWriter,
-	req *http.Request,
-) {
+// robotMacro returns macro route handler.
+// Writes JSON with a macro stored by a robot
+func (a *API) robotMacro(res http.ResponseWriter, req *http.Request) {
+	robot, err := a.robotFor(req.URL.Query().Get(":robot"))
+	if err != nil {
+		writeError(res, err)
+		return
+	}
+	name := req.URL.Query().Get(":macro")
+	if macro := robot.Macro(name); macro == nil {
+		writeError(res, notFound("Macro", name))
+	} else {
+		a.writeJSON(map[string]interface{}{"macro": macro}, res)
+	}
+}
 
-	body := make(map[string]interface{})
-	json.NewDecoder(req.Body).Decode(&body)
+// addRobotMacro returns add macro route handler.
+// Stores the macro in the request body as a robot command and writes JSON
+// with it
+func (a *API) addRobotMacro(res http.ResponseWriter, req *http.Request) {
+	robot, err := a.robotFor(req.URL.Query().Get(":robot"))
+	if err != nil {
+		writeError(res, err)
+		return
+	}
+	macro := gobot.Macro{}
+	if err = json.NewDecoder(req.Body).Decode(&macro); err == nil {
+		err = robot.AddMacro(macro)
+	}
+	if err != nil {
+		writeError(res, badRequest(err))
+		return
+	}
+	a.writeJSON(map[string]interface{}{"macro": macro}, res)
+}
+
+// runRobotMacro returns run macro route handler.
+// Runs the macro in the request body and writes JSON with the result of each
+// step. Closing the request cancels the macro
+func (a *API) runRobotMacro(res http.ResponseWriter, req *http.Request) {
+	robot, err := a.robotFor(req.URL.Query().Get(":robot"))
+	if err != nil {
+		writeError(res, err)
+		return
+	}
+	macro := gobot.Macro{}
+	var result *gobot.MacroResult
+	if err = json.NewDecoder(req.Body).Decode(&macro); err == nil {
+		result, err = robot.RunMacro(req.Context(), macro)
+	}
+	if err != nil {
+		writeError(res, badRequest(err))
+		return
+	}
+	a.writeJSON(map[string]interface{}{"result": result}, res)
+}
 
-	if f != nil {
-		a.writeJSON(map[string]interface{}{"result": f(body)}, res)
+// cancelRobotMacro returns cancel macro route handler.
+// Cancels the macro a robot is running and writes JSON saying whether there
+// was one
+func (a *API) cancelRobotMacro(res http.ResponseWriter, req *http.Request) {
+	if robot, err := a.robotFor(req.URL.Query().Get(":robot")); err != nil {
+		writeError(res, err)
 	} else {
-		a.writeJSON(map[string]interface{}{"error": "Unknown Command"}, res)
+		a.writeJSON(map[string]interface{}{"cancelled": robot.CancelMacro()}, res)
 	}
 }
 
+// store returns store route handler.
+// Writes JSON with every value kept in the store of a robot or device
+func (a *API) store(res http.ResponseWriter, req *http.Request) {
+	store, err := a.storeFor(req)
+	if err != nil {
+		writeError(res, err)
+		return
+	}
+	values := map[string]interface{}{}
+	for _, key := range store.Keys() {
+		var value interface{}
+		if _, err := store.Get(key, &value); err == nil {
+			values[key] = value
+		}
+	}
+	a.writeJSON(map[string]interface{}{"store": values}, res)
+}
+
+// storeValue returns store value route handler.
+// Writes JSON with a value kept in the store of a robot or device
+func (a *API) storeValue(res http.ResponseWriter, req *http.Request) {
+	store, err := a.storeFor(req)
+	if err != nil {
+		writeError(res, err)
+		return
+	}
+	key := req.URL.Query().Get(":key")
+	var value interface{}
+	ok, err := store.Get(key, &value)
+	if err == nil && !ok {
+		err = notFound("Key", key)
+	}
+	if err != nil {
+		writeError(res, err)
+		return
+	}
+	a.writeJSON(map[string]interface{}{"key": key, "value": value}, res)
+}
+
+// setStoreValue returns set store value route handler.
+// Keeps the JSON value in the request body in the store of a robot or device
+// and writes JSON with it
+func (a *API) setStoreValue(res http.ResponseWriter, req *http.Request) {
+	store, err := a.storeFor(req)
+	if err != nil {
+		writeError(res, err)
+		return
+	}
+	key := req.URL.Query().Get(":key")
+	var value interface{}
+	if err = json.NewDecoder(req.Body).Decode(&value); err != nil {
+		writeError(res, badRequest(err))
+		return
+	}
+	if err = store.Set(key, value); err != nil {
+		writeError(res, err)
+		return
+	}
+	a.writeJSON(map[string]interface{}{"key": key, "value": value}, res)
+}
+
+// deleteStoreValue returns delete store value route handler.
+// Removes a value from the store of a robot or device
+func (a *API) deleteStoreValue(res http.ResponseWriter, req *http.Request) {
+	store, err := a.storeFor(req)
+	if err == nil {
+		err = store.Delete(req.URL.Query().Get(":key"))
+	}
+	if err != nil {
+		writeError(res, err)
+		return
+	}
+	a.writeJSON(map[string]interface{}{"key": req.URL.Query().Get(":key")}, res)
+}
+
+// storeFor returns the store of the robot, or of the device when the route
+// names one.
+func (a *API) storeFor(req *http.Request) (gobot.Store, error) {
+	robot, err := a.robotFor(req.URL.Query().Get(":robot"))
+	if err != nil {
+		return nil, err
+	}
+	device := req.URL.Query().Get(":device")
+	if device == "" {
+		return robot.Store(), nil
+	}
+	if robot.Device(device) == nil {
+		return nil, notFound("Device", device)
+	}
+	return gobot.ScopeStore(robot.Store(), device+"/"), nil
+}
+
 // writeJSON writes `j` as JSON in response
 func (a *API) writeJSON(j interface{}, res http.ResponseWriter) {
 	data, _ := json.Marshal(j)
@@ -361,36 +649,105 @@ func (a *API) writeJSON(j interface{}, res http.ResponseWriter) {
 	res.Write(data)
 }
 
-// Debug add handler to api that prints each request
+// logger returns the Logger of the Gobot served by the api
+func (a *API) logger() gobot.Logger {
+	return a.gobot.Logger().With("component", "api")
+}
+
+// Debug adds middleware to api that logs each request
 func (a *API) Debug() {
-	a.AddHandler(func(res http.ResponseWriter, req *http.Request) {
-		log.Println(req)
-	})
+	a.Use(LogRequests(a.logger()))
+}
+
+// eventerFor returns the Eventer of the named device of the named robot, of
+// the robot when device is empty, or of the Gobot when both are.
+func (a *API) eventerFor(robot, device string) (gobot.Eventer, error) {
+	if robot == "" {
+		return a.gobot, nil
+	}
+	r, err := a.robotFor(robot)
+	if err != nil {
+		return nil, err
+	}
+	if device == "" {
+		return r, nil
+	}
+	d := r.Device(device)
+	if d == nil {
+		return nil, notFound("Device", device)
+	}
+	e, ok := d.(gobot.Eventer)
+	if !ok {
+		return nil, &Error{Status: http.StatusNotFound, Code: "not_found",
+			Message: fmt.Sprintf("Device %q has no events", device), Resource: "event"}
+	}
+	return e, nil
+}
+
+// commandFor returns the named command of the named device of the named
+// robot, of the robot when device is empty, or of the Gobot when both are.
+func (a *API) commandFor(robot, device, command string) (func(map[string]interface{}) interface{}, error) {
+	var c gobot.Commander = a.gobot
+	if robot != "" {
+		r, err := a.robotFor(robot)
+		if err != nil {
+			return nil, err
+		}
+		c = r
+		if device != "" {
+			d := r.Device(device)
+			if d == nil {
+				return nil, notFound("Device", device)
+			}
+			var ok bool
+			if c, ok = d.(gobot.Commander); !ok {
+				return nil, &Error{Status: http.StatusNotFound, Code: "not_found",
+					Message: fmt.Sprintf("Device %q has no commands", device), Resource: "command"}
+			}
+		}
+	}
+	if f := c.Command(command); f != nil {
+		return f, nil
+	}
+	return nil, &Error{Status: http.StatusNotFound, Code: "not_found", Message: "Unknown Command", Resource: "command"}
+}
+
+func (a *API) robotFor(name string) (robot *gobot.Robot, err error) {
+	if robot = a.gobot.Robot(name); robot == nil {
+		err = notFound("Robot", name)
+	}
+	return
 }
 
 func (a *API) jsonRobotFor(name string) (jrobot *gobot.JSONRobot, err error) {
 	if robot := a.gobot.Robot(name); robot != nil {
 		jrobot = gobot.NewJSONRobot(robot)
 	} else {
-		err = errors.New("No Robot found with the name " + name)
+		err = notFound("Robot", name)
 	}
 	return
 }
 
-func (a *API) jsonDeviceFor(robot string, name string) (jdevice *gobot.JSONDevice, err error) {
-	if device := a.gobot.Robot(robot).Device(name); device != nil {
-		jdevice = gobot.NewJSONDevice(device)
-	} else {
-		err = errors.New("No Device found with the name " + name)
+func (a *API) jsonDeviceFor(robot string, name string) (*gobot.JSONDevice, error) {
+	r, err := a.robotFor(robot)
+	if err != nil {
+		return nil, err
 	}
-	return
+	if device := r.Device(name); device != nil {
+		return gobot.NewJSONDevice(device), nil
+	}
+	return nil, notFound("Device", name)
 }
 
-func (a *API) jsonConnectionFor(robot string, name string) (jconnection *gobot.JSONConnection, err error) {
-	if connection := a.gobot.Robot(robot).Connection(name); connection != nil {
-		jconnection = gobot.NewJSONConnection(connection)
-	} else {
-		err = errors.New("No Connection found with the name " + name)
+func (a *API) jsonConnectionFor(robot string, name string) (*gobot.JSONConnection, error) {
+	r, err := a.robotFor(robot)
+	if err != nil {
+		return nil, err
 	}
-	return
+	if connection := r.Connection(name); connection != nil {
+		jconnection := gobot.NewJSONConnection(connection)
+		jconnection.State = r.ConnectionState(name)
+		return jconnection, nil
+	}
+	return nil, notFound("Connection", name)
 }
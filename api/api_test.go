@@ -8,6 +8,7 @@ import (
 	"log"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
@@ -118,7 +119,8 @@ func TestExecuteMcpCommand(t *testing.T) {
 	a.ServeHTTP(response, request)
 
 	json.NewDecoder(response.Body).Decode(&body)
-	gobottest.Assert(t, body.(map[string]interface{})["error"], "Unknown Command")
+	gobottest.Assert(t, errorMessage(body), "Unknown Command")
+	gobottest.Assert(t, response.Code, 404)
 }
 
 func TestRobots(t *testing.T) {
@@ -146,10 +148,12 @@ func TestRobot(t *testing.T) {
 
 	// unknown robot
 	request, _ = http.NewRequest("GET", "/api/robots/UnknownRobot1", nil)
+	response = httptest.NewRecorder()
 	a.ServeHTTP(response, request)
 
 	json.NewDecoder(response.Body).Decode(&body)
-	gobottest.Assert(t, body["error"], "No Robot found with the name UnknownRobot1")
+	gobottest.Assert(t, errorMessage(body), "No Robot found with the name UnknownRobot1")
+	gobottest.Assert(t, response.Code, 404)
 }
 
 func TestRobotDevices(t *testing.T) {
@@ -166,10 +170,12 @@ func TestRobotDevices(t *testing.T) {
 
 	// unknown robot
 	request, _ = http.NewRequest("GET", "/api/robots/UnknownRobot1/devices", nil)
+	response = httptest.NewRecorder()
 	a.ServeHTTP(response, request)
 
 	json.NewDecoder(response.Body).Decode(&body)
-	gobottest.Assert(t, body["error"], "No Robot found with the name UnknownRobot1")
+	gobottest.Assert(t, errorMessage(body), "No Robot found with the name UnknownRobot1")
+	gobottest.Assert(t, response.Code, 404)
 }
 
 func TestRobotCommands(t *testing.T) {
@@ -186,10 +192,12 @@ func TestRobotCommands(t *testing.T) {
 
 	// unknown robot
 	request, _ = http.NewRequest("GET", "/api/robots/UnknownRobot1/commands", nil)
+	response = httptest.NewRecorder()
 	a.ServeHTTP(response, request)
 
 	json.NewDecoder(response.Body).Decode(&body)
-	gobottest.Assert(t, body["error"], "No Robot found with the name UnknownRobot1")
+	gobottest.Assert(t, errorMessage(body), "No Robot found with the name UnknownRobot1")
+	gobottest.Assert(t, response.Code, 404)
 }
 
 func TestExecuteRobotCommand(t *testing.T) {
@@ -217,7 +225,8 @@ func TestExecuteRobotCommand(t *testing.T) {
 	a.ServeHTTP(response, request)
 
 	json.NewDecoder(response.Body).Decode(&body)
-	gobottest.Assert(t, body.(map[string]interface{})["error"], "Unknown Command")
+	gobottest.Assert(t, errorMessage(body), "Unknown Command")
+	gobottest.Assert(t, response.Code, 404)
 
 	// uknown robot
 	request, _ = http.NewRequest("GET",
@@ -228,7 +237,8 @@ func TestExecuteRobotCommand(t *testing.T) {
 	a.ServeHTTP(response, request)
 
 	json.NewDecoder(response.Body).Decode(&body)
-	gobottest.Assert(t, body.(map[string]interface{})["error"], "No Robot found with the name UnknownRobot1")
+	gobottest.Assert(t, errorMessage(body), "No Robot found with the name UnknownRobot1")
+	gobottest.Assert(t, response.Code, 404)
 }
 
 func TestRobotDevice(t *testing.T) {
@@ -249,10 +259,12 @@ func TestRobotDevice(t *testing.T) {
 	// unknown device
 	request, _ = http.NewRequest("GET",
 		"/api/robots/Robot1/devices/UnknownDevice1", nil)
+	response = httptest.NewRecorder()
 	a.ServeHTTP(response, request)
 
 	json.NewDecoder(response.Body).Decode(&body)
-	gobottest.Assert(t, body["error"], "No Device found with the name UnknownDevice1")
+	gobottest.Assert(t, errorMessage(body), "No Device found with the name UnknownDevice1")
+	gobottest.Assert(t, response.Code, 404)
 }
 
 func TestRobotDeviceCommands(t *testing.T) {
@@ -268,16 +280,65 @@ func TestRobotDeviceCommands(t *testing.T) {
 
 	var body map[string]interface{}
 	json.NewDecoder(response.Body).Decode(&body)
-	gobottest.Assert(t, len(body["commands"].([]interface{})), 2)
+	gobottest.Assert(t, len(body["commands"].([]interface{})), 3)
 
 	// unknown device
 	request, _ = http.NewRequest("GET",
 		"/api/robots/Robot1/devices/UnknownDevice1/commands",
 		nil,
 	)
+	response = httptest.NewRecorder()
 	a.ServeHTTP(response, request)
 	json.NewDecoder(response.Body).Decode(&body)
-	gobottest.Assert(t, body["error"], "No Device found with the name UnknownDevice1")
+	gobottest.Assert(t, errorMessage(body), "No Device found with the name UnknownDevice1")
+	gobottest.Assert(t, response.Code, 404)
+}
+
+func TestExecuteRobotDeviceCommandInvalidParams(t *testing.T) {
+	var body map[string]interface{}
+	a := initTestAPI()
+
+	request, _ := http.NewRequest("GET",
+		"/api/robots/Robot1/devices/Device1/commands/SchemaCommand",
+		bytes.NewBufferString(`{"level":5}`),
+	)
+	request.Header.Add("Content-Type", "application/json")
+	response := httptest.NewRecorder()
+	a.ServeHTTP(response, request)
+
+	gobottest.Assert(t, response.Code, 200)
+	json.NewDecoder(response.Body).Decode(&body)
+	gobottest.Assert(t, body["result"], 5.0)
+
+	request, _ = http.NewRequest("GET",
+		"/api/robots/Robot1/devices/Device1/commands/SchemaCommand",
+		bytes.NewBufferString(`{"level":11}`),
+	)
+	request.Header.Add("Content-Type", "application/json")
+	response = httptest.NewRecorder()
+	a.ServeHTTP(response, request)
+
+	gobottest.Assert(t, response.Code, 400)
+	body = nil
+	json.NewDecoder(response.Body).Decode(&body)
+	gobottest.Assert(t, errorMessage(body), `command "SchemaCommand": param "level" must be between 0 and 10, got 11`)
+}
+
+func TestRobotDeviceSchemas(t *testing.T) {
+	var body map[string]interface{}
+	a := initTestAPI()
+
+	request, _ := http.NewRequest("GET", "/api/robots/Robot1/devices/Device1", nil)
+	response := httptest.NewRecorder()
+	a.ServeHTTP(response, request)
+
+	json.NewDecoder(response.Body).Decode(&body)
+	schemas := body["device"].(map[string]interface{})["schemas"].(map[string]interface{})
+	schema := schemas["SchemaCommand"].(map[string]interface{})
+	param := schema["params"].([]interface{})[0].(map[string]interface{})
+	gobottest.Assert(t, param["name"], "level")
+	gobottest.Assert(t, param["type"], "integer")
+	gobottest.Assert(t, param["required"], true)
 }
 
 func TestExecuteRobotDeviceCommand(t *testing.T) {
@@ -306,7 +367,8 @@ func TestExecuteRobotDeviceCommand(t *testing.T) {
 	a.ServeHTTP(response, request)
 
 	json.NewDecoder(response.Body).Decode(&body)
-	gobottest.Assert(t, body.(map[string]interface{})["error"], "Unknown Command")
+	gobottest.Assert(t, errorMessage(body), "Unknown Command")
+	gobottest.Assert(t, response.Code, 404)
 
 	// unknown device
 	request, _ = http.NewRequest("GET",
@@ -317,7 +379,8 @@ func TestExecuteRobotDeviceCommand(t *testing.T) {
 	a.ServeHTTP(response, request)
 
 	json.NewDecoder(response.Body).Decode(&body)
-	gobottest.Assert(t, body.(map[string]interface{})["error"], "No Device found with the name UnknownDevice1")
+	gobottest.Assert(t, errorMessage(body), "No Device found with the name UnknownDevice1")
+	gobottest.Assert(t, response.Code, 404)
 
 }
 
@@ -335,10 +398,12 @@ func TestRobotConnections(t *testing.T) {
 
 	// unknown robot
 	request, _ = http.NewRequest("GET", "/api/robots/UnknownRobot1/connections", nil)
+	response = httptest.NewRecorder()
 	a.ServeHTTP(response, request)
 
 	json.NewDecoder(response.Body).Decode(&body)
-	gobottest.Assert(t, body["error"], "No Robot found with the name UnknownRobot1")
+	gobottest.Assert(t, errorMessage(body), "No Robot found with the name UnknownRobot1")
+	gobottest.Assert(t, response.Code, 404)
 }
 
 func TestRobotConnection(t *testing.T) {
@@ -361,9 +426,11 @@ func TestRobotConnection(t *testing.T) {
 		"/api/robots/Robot1/connections/UnknownConnection1",
 		nil,
 	)
+	response = httptest.NewRecorder()
 	a.ServeHTTP(response, request)
 	json.NewDecoder(response.Body).Decode(&body)
-	gobottest.Assert(t, body["error"], "No Connection found with the name UnknownConnection1")
+	gobottest.Assert(t, errorMessage(body), "No Connection found with the name UnknownConnection1")
+	gobottest.Assert(t, response.Code, 404)
 }
 
 func TestRobotDeviceEvent(t *testing.T) {
@@ -413,7 +480,294 @@ func TestRobotDeviceEvent(t *testing.T) {
 
 	var body map[string]interface{}
 	json.NewDecoder(response.Body).Decode(&body)
-	gobottest.Assert(t, body["error"], "No Event found with the name UnknownEvent")
+	gobottest.Assert(t, errorMessage(body), "No Event found with the name UnknownEvent")
+	gobottest.Assert(t, response.StatusCode, 404)
+}
+
+func TestRobotEvent(t *testing.T) {
+	a := initTestAPI()
+	server := httptest.NewServer(a)
+	defer server.Close()
+
+	respc := make(chan *http.Response, 1)
+	go func() {
+		resp, _ := http.Get(server.URL + "/api/robots/Robot1/events/device-added")
+		respc <- resp
+	}()
+
+	go func() {
+		time.Sleep(time.Millisecond * 5)
+		a.gobot.Robot("Robot1").AddDevice(newTestDriver(newTestAdaptor("Connection4", "/dev/null"), "Device4", "4"))
+	}()
+
+	select {
+	case resp := <-respc:
+		reader := bufio.NewReader(resp.Body)
+		data, _ := reader.ReadString('\n')
+		gobottest.Assert(t, data, "data: \"Device4\"\n")
+	case <-time.After(time.Second):
+		t.Error("Not receiving data")
+	}
+
+	server.CloseClientConnections()
+
+	var body map[string]interface{}
+	response, _ := http.Get(server.URL + "/api/robots/Robot1/events/UnknownEvent")
+	json.NewDecoder(response.Body).Decode(&body)
+	gobottest.Assert(t, errorMessage(body), "No Event found with the name UnknownEvent")
+	gobottest.Assert(t, response.StatusCode, 404)
+
+	body = nil
+	response, _ = http.Get(server.URL + "/api/robots/UnknownRobot1/events/device-added")
+	json.NewDecoder(response.Body).Decode(&body)
+	gobottest.Assert(t, errorMessage(body), "No Robot found with the name UnknownRobot1")
+	gobottest.Assert(t, response.StatusCode, 404)
+}
+
+func TestMcpEvent(t *testing.T) {
+	a := initTestAPI()
+	server := httptest.NewServer(a)
+	defer server.Close()
+
+	respc := make(chan *http.Response, 1)
+	go func() {
+		resp, _ := http.Get(server.URL + "/api/events/robot-removed")
+		respc <- resp
+	}()
+
+	go func() {
+		time.Sleep(time.Millisecond * 5)
+		a.gobot.RemoveRobot("Robot3")
+	}()
+
+	select {
+	case resp := <-respc:
+		reader := bufio.NewReader(resp.Body)
+		data, _ := reader.ReadString('\n')
+		gobottest.Assert(t, data, "data: \"Robot3\"\n")
+	case <-time.After(time.Second):
+		t.Error("Not receiving data")
+	}
+	server.CloseClientConnections()
+}
+
+func TestMetrics(t *testing.T) {
+	a := initTestAPI()
+	registry := gobot.NewRegistry()
+	a.gobot.SetRegistry(registry)
+
+	request, _ := http.NewRequest("GET",
+		"/api/robots/Robot1/devices/Device1/commands/TestDriverCommand",
+		bytes.NewBufferString(`{"name":"human"}`),
+	)
+	request.Header.Add("Content-Type", "application/json")
+	a.ServeHTTP(httptest.NewRecorder(), request)
+
+	request, _ = http.NewRequest("GET", "/metrics", nil)
+	response := httptest.NewRecorder()
+	a.ServeHTTP(response, request)
+	gobottest.Assert(t, response.Code, 200)
+	gobottest.Assert(t, response.Header().Get("Content-Type"), "text/plain; version=0.0.4; charset=utf-8")
+	gobottest.Assert(t, strings.Contains(response.Body.String(), "# TYPE gobot_commands_total counter\n"), true)
+	gobottest.Assert(t, strings.Contains(response.Body.String(),
+		`gobot_commands_total{command="TestDriverCommand",device="Device1",result="ok",robot="Robot1"}`), true)
+
+	request, _ = http.NewRequest("GET", "/api/metrics", nil)
+	response = httptest.NewRecorder()
+	a.ServeHTTP(response, request)
+
+	var body struct {
+		Metrics []gobot.MetricFamily `json:"metrics"`
+	}
+	json.NewDecoder(response.Body).Decode(&body)
+	names := []string{}
+	for _, f := range body.Metrics {
+		names = append(names, f.Name)
+	}
+	gobottest.Assert(t, strings.Contains(strings.Join(names, " "), "gobot_command_duration_seconds"), true)
+	gobottest.Assert(t, len(body.Metrics), len(registry.Snapshot()))
+}
+
+func TestRules(t *testing.T) {
+	a := initTestAPI()
+	a.gobot.AddRule(gobot.Rule{
+		Name: "relay",
+		When: gobot.RuleEvent{Robot: "Robot1", Event: "robot-event"},
+		Then: gobot.RuleThen{Robot: "Robot2", Command: "robotTestFunction"},
+	})
+
+	request, _ := http.NewRequest("GET", "/api/rules", nil)
+	response := httptest.NewRecorder()
+	a.ServeHTTP(response, request)
+
+	var body struct {
+		Rules []gobot.RuleStatus `json:"rules"`
+	}
+	json.NewDecoder(response.Body).Decode(&body)
+	gobottest.Assert(t, len(body.Rules), 1)
+	gobottest.Assert(t, body.Rules[0].Name, "relay")
+	gobottest.Assert(t, body.Rules[0].Enabled, true)
+
+	request, _ = http.NewRequest("POST", "/api/rules/relay/disable", nil)
+	response = httptest.NewRecorder()
+	a.ServeHTTP(response, request)
+
+	var rule struct {
+		Rule gobot.RuleStatus `json:"rule"`
+	}
+	json.NewDecoder(response.Body).Decode(&rule)
+	gobottest.Assert(t, rule.Rule.Enabled, false)
+	gobottest.Assert(t, a.gobot.Rule("relay").Enabled, false)
+
+	request, _ = http.NewRequest("POST", "/api/rules/relay/enable", nil)
+	a.ServeHTTP(httptest.NewRecorder(), request)
+	request, _ = http.NewRequest("GET", "/api/rules/relay", nil)
+	response = httptest.NewRecorder()
+	a.ServeHTTP(response, request)
+	json.NewDecoder(response.Body).Decode(&rule)
+	gobottest.Assert(t, rule.Rule.Enabled, true)
+	gobottest.Assert(t, rule.Rule.Then.Robot, "Robot2")
+
+	request, _ = http.NewRequest("POST", "/api/rules/UnknownRule/enable", nil)
+	response = httptest.NewRecorder()
+	a.ServeHTTP(response, request)
+	var errBody map[string]interface{}
+	json.NewDecoder(response.Body).Decode(&errBody)
+	gobottest.Assert(t, errorMessage(errBody), "No Rule found with the name UnknownRule")
+	gobottest.Assert(t, response.Code, 404)
+}
+
+func TestRobotMacros(t *testing.T) {
+	a := initTestAPI()
+
+	// run
+	request, _ := http.NewRequest("POST", "/api/robots/Robot1/macros/run", bytes.NewBufferString(
+		`{"steps": [{"name": "greeting", "device": "Device1", "command": "TestDriverCommand", "params": {"name": "human"}},
+		{"delay": "1ms"}, {"command": "robotTestFunction", "params": {"message": "hi", "robot": "r1"},
+		"if": {"step": "greeting", "op": "==", "value": "hello human"}}]}`))
+	response := httptest.NewRecorder()
+	a.ServeHTTP(response, request)
+
+	var run struct {
+		Result gobot.MacroResult `json:"result"`
+	}
+	json.NewDecoder(response.Body).Decode(&run)
+	gobottest.Assert(t, len(run.Result.Steps), 3)
+	gobottest.Assert(t, run.Result.Steps[0].Result, "hello human")
+	gobottest.Assert(t, run.Result.Steps[2].Status, gobot.StepSucceeded)
+	gobottest.Assert(t, run.Result.Steps[2].Result, "hey r1, hi")
+
+	// invalid macros run nothing
+	request, _ = http.NewRequest("POST", "/api/robots/Robot1/macros/run",
+		bytes.NewBufferString(`{"steps": [{"device": "Device9", "command": "TestDriverCommand"}]}`))
+	response = httptest.NewRecorder()
+	a.ServeHTTP(response, request)
+	var body map[string]interface{}
+	json.NewDecoder(response.Body).Decode(&body)
+	gobottest.Assert(t, errorMessage(body), "Step 0: No Device found with the name Device9")
+	gobottest.Assert(t, response.Code, 400)
+
+	// store
+	request, _ = http.NewRequest("POST", "/api/robots/Robot1/macros", bytes.NewBufferString(
+		`{"name": "greet", "steps": [{"device": "Device1", "command": "TestDriverCommand", "params": {"name": "macro"}}]}`))
+	response = httptest.NewRecorder()
+	a.ServeHTTP(response, request)
+	body = nil
+	json.NewDecoder(response.Body).Decode(&body)
+	gobottest.Assert(t, body["macro"].(map[string]interface{})["name"], "greet")
+
+	request, _ = http.NewRequest("GET", "/api/robots/Robot1/macros", nil)
+	response = httptest.NewRecorder()
+	a.ServeHTTP(response, request)
+	var macros struct {
+		Macros []gobot.Macro `json:"macros"`
+	}
+	json.NewDecoder(response.Body).Decode(&macros)
+	gobottest.Assert(t, len(macros.Macros), 1)
+	gobottest.Assert(t, macros.Macros[0].Name, "greet")
+
+	request, _ = http.NewRequest("GET", "/api/robots/Robot1/macros/unknown", nil)
+	response = httptest.NewRecorder()
+	a.ServeHTTP(response, request)
+	body = nil
+	json.NewDecoder(response.Body).Decode(&body)
+	gobottest.Assert(t, errorMessage(body), "No Macro found with the name unknown")
+	gobottest.Assert(t, response.Code, 404)
+
+	// stored macros are robot commands
+	request, _ = http.NewRequest("POST", "/api/robots/Robot1/commands/greet", bytes.NewBufferString("{}"))
+	response = httptest.NewRecorder()
+	a.ServeHTTP(response, request)
+	json.NewDecoder(response.Body).Decode(&run)
+	gobottest.Assert(t, run.Result.Macro, "greet")
+	gobottest.Assert(t, run.Result.Steps[0].Result, "hello macro")
+
+	// cancel
+	request, _ = http.NewRequest("POST", "/api/robots/Robot1/macros/cancel", nil)
+	response = httptest.NewRecorder()
+	a.ServeHTTP(response, request)
+	body = nil
+	json.NewDecoder(response.Body).Decode(&body)
+	gobottest.Assert(t, body["cancelled"], false)
+
+	request, _ = http.NewRequest("GET", "/api/robots/UnknownRobot/macros", nil)
+	response = httptest.NewRecorder()
+	a.ServeHTTP(response, request)
+	body = nil
+	json.NewDecoder(response.Body).Decode(&body)
+	gobottest.Assert(t, errorMessage(body), "No Robot found with the name UnknownRobot")
+	gobottest.Assert(t, response.Code, 404)
+}
+
+func TestStore(t *testing.T) {
+	a := initTestAPI()
+	robot := a.gobot.Robot("Robot1")
+	robot.SetStore(gobot.NewMemoryStore())
+	robot.Store().Set("count", 3)
+
+	request, _ := http.NewRequest("PUT", "/api/robots/Robot1/devices/Device1/store/calibration",
+		bytes.NewBufferString(`{"offset": 2}`))
+	response := httptest.NewRecorder()
+	a.ServeHTTP(response, request)
+	var body map[string]interface{}
+	json.NewDecoder(response.Body).Decode(&body)
+	gobottest.Assert(t, body["value"], map[string]interface{}{"offset": 2.0})
+
+	request, _ = http.NewRequest("GET", "/api/robots/Robot1/store", nil)
+	response = httptest.NewRecorder()
+	a.ServeHTTP(response, request)
+	body = nil
+	json.NewDecoder(response.Body).Decode(&body)
+	gobottest.Assert(t, body["store"], map[string]interface{}{
+		"count":               3.0,
+		"Device1/calibration": map[string]interface{}{"offset": 2.0},
+	})
+
+	request, _ = http.NewRequest("GET", "/api/robots/Robot1/devices/Device1/store/calibration", nil)
+	response = httptest.NewRecorder()
+	a.ServeHTTP(response, request)
+	body = nil
+	json.NewDecoder(response.Body).Decode(&body)
+	gobottest.Assert(t, body["key"], "calibration")
+	gobottest.Assert(t, body["value"], map[string]interface{}{"offset": 2.0})
+
+	request, _ = http.NewRequest("DELETE", "/api/robots/Robot1/store/count", nil)
+	a.ServeHTTP(httptest.NewRecorder(), request)
+	request, _ = http.NewRequest("GET", "/api/robots/Robot1/store/count", nil)
+	response = httptest.NewRecorder()
+	a.ServeHTTP(response, request)
+	body = nil
+	json.NewDecoder(response.Body).Decode(&body)
+	gobottest.Assert(t, errorMessage(body), "No Key found with the name count")
+	gobottest.Assert(t, response.Code, 404)
+
+	request, _ = http.NewRequest("GET", "/api/robots/Robot1/devices/UnknownDevice/store", nil)
+	response = httptest.NewRecorder()
+	a.ServeHTTP(response, request)
+	body = nil
+	json.NewDecoder(response.Body).Decode(&body)
+	gobottest.Assert(t, errorMessage(body), "No Device found with the name UnknownDevice")
+	gobottest.Assert(t, response.Code, 404)
 }
 
 func TestAPIRouter(t *testing.T) {
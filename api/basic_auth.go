@@ -7,17 +7,28 @@ import (
 )
 
 // BasicAuth returns basic auth handler.
+//
+// Deprecated: Use BasicAuthMiddleware, which is added with Use.
 func BasicAuth(username, password string) http.HandlerFunc {
+	return handlerFunc(BasicAuthMiddleware(username, password))
+}
+
+// BasicAuthMiddleware returns basic auth middleware.
+func BasicAuthMiddleware(username, password string) Middleware {
 	// Inspired by https://github.com/codegangsta/martini-contrib/blob/master/auth/
-	return func(res http.ResponseWriter, req *http.Request) {
-		if !secureCompare(req.Header.Get("Authorization"),
-			"Basic "+base64.StdEncoding.EncodeToString([]byte(username+":"+password)),
-		) {
-			res.Header().Set("WWW-Authenticate",
-				"Basic realm=\"Authorization Required\"",
-			)
-			http.Error(res, "Not Authorized", http.StatusUnauthorized)
-		}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+			if !secureCompare(req.Header.Get("Authorization"),
+				"Basic "+base64.StdEncoding.EncodeToString([]byte(username+":"+password)),
+			) {
+				res.Header().Set("WWW-Authenticate",
+					"Basic realm=\"Authorization Required\"",
+				)
+				writeError(res, newError(http.StatusUnauthorized, "Not Authorized"))
+				return
+			}
+			next.ServeHTTP(res, req)
+		})
 	}
 }
 
@@ -25,3 +25,22 @@ func TestBasicAuth(t *testing.T) {
 	a.ServeHTTP(response, request)
 	gobottest.Assert(t, response.Code, 401)
 }
+
+func TestBasicAuthMiddleware(t *testing.T) {
+	a := initTestAPI()
+
+	a.Use(BasicAuthMiddleware("admin", "password"))
+
+	request, _ := http.NewRequest("GET", "/api/", nil)
+	request.SetBasicAuth("admin", "password")
+	response := httptest.NewRecorder()
+	a.ServeHTTP(response, request)
+	gobottest.Assert(t, response.Code, 200)
+
+	request, _ = http.NewRequest("GET", "/api/", nil)
+	request.SetBasicAuth("admin", "wrongPassword")
+	response = httptest.NewRecorder()
+	a.ServeHTTP(response, request)
+	gobottest.Assert(t, response.Code, 401)
+	gobottest.Assert(t, response.Header().Get("WWW-Authenticate"), "Basic realm=\"Authorization Required\"")
+}
@@ -16,7 +16,15 @@ type CORS struct {
 }
 
 // AllowRequestsFrom returns handler to verify that requests come from allowedOrigins
+//
+// Deprecated: Use CORSMiddleware, which is added with Use.
 func AllowRequestsFrom(allowedOrigins ...string) http.HandlerFunc {
+	return handlerFunc(CORSMiddleware(allowedOrigins...))
+}
+
+// CORSMiddleware returns middleware to verify that requests come from
+// allowedOrigins. It answers the preflight requests of allowed origins itself.
+func CORSMiddleware(allowedOrigins ...string) Middleware {
 	c := &CORS{
 		AllowOrigins: allowedOrigins,
 		AllowMethods: []string{"GET", "POST"},
@@ -26,14 +34,21 @@ func AllowRequestsFrom(allowedOrigins ...string) http.HandlerFunc {
 
 	c.generatePatterns()
 
-	return func(w http.ResponseWriter, req *http.Request) {
-		origin := req.Header.Get("Origin")
-		if c.isOriginAllowed(origin) {
-			w.Header().Set("Access-Control-Allow-Origin", origin)
-			w.Header().Set("Access-Control-Allow-Headers", c.AllowedHeaders())
-			w.Header().Set("Access-Control-Allow-Methods", c.AllowedMethods())
-			w.Header().Set("Content-Type", c.ContentType)
-		}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			origin := req.Header.Get("Origin")
+			if c.isOriginAllowed(origin) {
+				w.Header().Set("Access-Control-Allow-Origin", origin)
+				w.Header().Set("Access-Control-Allow-Headers", c.AllowedHeaders())
+				w.Header().Set("Access-Control-Allow-Methods", c.AllowedMethods())
+				w.Header().Set("Content-Type", c.ContentType)
+				if req.Method == "OPTIONS" && req.Header.Get("Access-Control-Request-Method") != "" {
+					w.WriteHeader(http.StatusNoContent)
+					return
+				}
+			}
+			next.ServeHTTP(w, req)
+		})
 	}
 }
 
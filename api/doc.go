@@ -33,6 +33,32 @@ Example:
     	gbot.Start()
     }
 
+An OpenAPI 3 document describing every route, and every command of the
+robots and devices with the params of its schema, is served at
+/api/openapi.json, so clients can be generated with standard tooling.
+
+Requests which fail are answered with an HTTP status such as 404 Not Found or
+400 Bad Request, and a JSON body describing the error; see Error.
+
+Clients can also connect to the WebSocket endpoint at /api/socket to
+subscribe to the events of many robots and devices, and to run their
+commands, over a single connection; see SocketMessage. Pages of other
+origins than the api's own may only connect when listed in SocketOrigins.
+
+Access can be limited to bearer tokens with scopes, such as read-only
+access or running the commands of a single robot, by adding a TokenAuth:
+
+    a := api.NewAPI(gbot)
+    auth := api.NewTokenAuth([]byte("secret"))
+    auth.AddKey("key", api.ScopeRead, api.ScopeRobot+"Eve")
+    a.Use(auth.Middleware)
+    a.Start()
+
+Browsers cannot set the Authorization header of a WebSocket connection, so
+they pass the token in the access_token query parameter of /api/socket, or
+offer it as the subprotocol "bearer.<token>". Commands run over the
+connection are checked against the token's scopes.
+
 It follows Common Protocol for Programming Physical Input and Output (CPPP-IO) spec:
 https://github.com/hybridgroup/cppp-io
 */
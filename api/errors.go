@@ -0,0 +1,77 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/hybridgroup/gobot"
+)
+
+// Error is an error the api answers a request with. It is written as the
+// "error" of the JSON response body, with its Status as the HTTP status:
+//
+//	{"error": {"code": "not_found", "message": "No Robot found with the name bot", "resource": "robot"}}
+type Error struct {
+	// Status is the HTTP status code of the response
+	Status int `json:"-"`
+	// Code names the kind of error, such as "not_found" or "invalid_params"
+	Code string `json:"code"`
+	// Message describes the error
+	Message string `json:"message"`
+	// Resource is the kind of resource the error is about, such as "robot",
+	// "device" or "command", if any
+	Resource string `json:"resource,omitempty"`
+}
+
+func (e *Error) Error() string { return e.Message }
+
+// errorCodes are the codes of errors by HTTP status
+var errorCodes = map[int]string{
+	http.StatusBadRequest:          "bad_request",
+	http.StatusUnauthorized:        "unauthorized",
+	http.StatusForbidden:           "forbidden",
+	http.StatusNotFound:            "not_found",
+	http.StatusTooManyRequests:     "too_many_requests",
+	http.StatusInternalServerError: "internal_error",
+}
+
+// newError returns an Error with status and message, and the code of status
+func newError(status int, message string) *Error {
+	code, ok := errorCodes[status]
+	if !ok {
+		code = strings.ToLower(strings.Replace(http.StatusText(status), " ", "_", -1))
+	}
+	return &Error{Status: status, Code: code, Message: message}
+}
+
+// notFound returns an Error for a resource of the kind resource, such as
+// "Robot", which has no instance called name
+func notFound(resource, name string) *Error {
+	err := newError(http.StatusNotFound, "No "+resource+" found with the name "+name)
+	err.Resource = strings.ToLower(resource)
+	return err
+}
+
+// badRequest returns an Error for a request whose body is not valid
+func badRequest(err error) *Error {
+	return newError(http.StatusBadRequest, err.Error())
+}
+
+// writeError writes err as the JSON body of the response. Errors other than
+// an *Error are answered with 400 Bad Request if they are a command's
+// gobot.ParamError, and with 500 Internal Server Error otherwise.
+func writeError(res http.ResponseWriter, err error) {
+	e, ok := err.(*Error)
+	if !ok {
+		if _, isParam := err.(*gobot.ParamError); isParam {
+			e = &Error{Status: http.StatusBadRequest, Code: "invalid_params", Message: err.Error(), Resource: "command"}
+		} else {
+			e = newError(http.StatusInternalServerError, err.Error())
+		}
+	}
+	data, _ := json.Marshal(map[string]interface{}{"error": e})
+	res.Header().Set("Content-Type", "application/json; charset=utf-8")
+	res.WriteHeader(e.Status)
+	res.Write(data)
+}
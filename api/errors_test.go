@@ -0,0 +1,83 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/hybridgroup/gobot"
+	"github.com/hybridgroup/gobot/gobottest"
+)
+
+func errorFor(response *httptest.ResponseRecorder) map[string]interface{} {
+	var body map[string]map[string]interface{}
+	json.NewDecoder(response.Body).Decode(&body)
+	return body["error"]
+}
+
+func TestWriteError(t *testing.T) {
+	response := httptest.NewRecorder()
+	writeError(response, notFound("Robot", "bot"))
+	gobottest.Assert(t, response.Code, 404)
+	gobottest.Assert(t, response.Header().Get("Content-Type"), "application/json; charset=utf-8")
+	gobottest.Assert(t, errorFor(response), map[string]interface{}{
+		"code":     "not_found",
+		"message":  "No Robot found with the name bot",
+		"resource": "robot",
+	})
+
+	response = httptest.NewRecorder()
+	writeError(response, &gobot.ParamError{Command: "move", Param: "speed", Reason: "is required"})
+	gobottest.Assert(t, response.Code, 400)
+	err := errorFor(response)
+	gobottest.Assert(t, err["code"], "invalid_params")
+	gobottest.Assert(t, err["resource"], "command")
+
+	response = httptest.NewRecorder()
+	writeError(response, errors.New("disk full"))
+	gobottest.Assert(t, response.Code, 500)
+	gobottest.Assert(t, errorFor(response), map[string]interface{}{"code": "internal_error", "message": "disk full"})
+
+	gobottest.Assert(t, newError(http.StatusConflict, "busy").Code, "conflict")
+}
+
+func TestErrorStatus(t *testing.T) {
+	a := initTestAPI()
+	a.gobot.Robot("Robot1").AddCommand("failing", func(params map[string]interface{}) interface{} {
+		return errors.New("sensor unplugged")
+	})
+	a.gobot.Robot("Robot1").AddCommand("panicking", func(params map[string]interface{}) interface{} {
+		panic("sensor unplugged")
+	})
+
+	for _, test := range []struct {
+		method, path, body string
+		status             int
+		code, resource     string
+	}{
+		{"GET", "/api/robots/Unknown/devices/Device1/events/TestEvent", "", 404, "not_found", "robot"},
+		{"GET", "/api/robots/Unknown/devices/Device1", "", 404, "not_found", "robot"},
+		{"GET", "/api/robots/Unknown/connections/Connection1", "", 404, "not_found", "robot"},
+		{"POST", "/api/robots/Unknown/devices/Device1/commands/TestDriverCommand", "{}", 404, "not_found", "robot"},
+		{"POST", "/api/robots/Robot1/devices/Device1/commands/Unknown", "{}", 404, "not_found", "command"},
+		{"POST", "/api/robots/Robot1/commands/robotTestFunction", "{", 400, "bad_request", ""},
+		{"POST", "/api/robots/Robot1/commands/failing", "{}", 500, "command_failed", "command"},
+		{"POST", "/api/robots/Robot1/commands/panicking", "{}", 500, "internal_error", ""},
+		{"POST", "/api/robots/Robot1/macros", "[]", 400, "bad_request", ""},
+		{"PUT", "/api/robots/Robot1/store/count", "{", 400, "bad_request", ""},
+		{"POST", "/api/rules/Unknown/enable", "", 404, "not_found", "rule"},
+	} {
+		request, _ := http.NewRequest(test.method, test.path, bytes.NewBufferString(test.body))
+		response := httptest.NewRecorder()
+		a.ServeHTTP(response, request)
+		gobottest.Assert(t, response.Code, test.status)
+		err := errorFor(response)
+		gobottest.Assert(t, err["code"], test.code)
+		if test.resource != "" {
+			gobottest.Assert(t, err["resource"], test.resource)
+		}
+	}
+}
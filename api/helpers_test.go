@@ -55,6 +55,15 @@ func newTestDriver(adaptor *testAdaptor, name string, pin string) *testDriver {
 		return fmt.Sprintf("hello %v", name)
 	})
 
+	t.AddCommandSchema(gobot.CommandSchema{
+		Name: "SchemaCommand",
+		Params: []gobot.Param{
+			{Name: "level", Type: gobot.IntegerParam, Required: true, Range: &gobot.Range{Min: 0, Max: 10}},
+		},
+	}, func(params map[string]interface{}) interface{} {
+		return params["level"]
+	})
+
 	return t
 }
 
@@ -98,3 +107,14 @@ func newTestRobot(name string) *gobot.Robot {
 	})
 	return r
 }
+
+// errorMessage returns the message of the error in a response body, or nil
+// if it has none
+func errorMessage(body interface{}) interface{} {
+	if b, ok := body.(map[string]interface{}); ok {
+		if err, ok := b["error"].(map[string]interface{}); ok {
+			return err["message"]
+		}
+	}
+	return nil
+}
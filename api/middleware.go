@@ -0,0 +1,325 @@
+package api
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"math"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"runtime/debug"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/hybridgroup/gobot"
+)
+
+// Middleware wraps a handler, such as the api router, in one which can act on
+// each request before and after it, or answer the request itself.
+type Middleware func(http.Handler) http.Handler
+
+// Use appends middleware to the api's stack. Middleware appended first is
+// outermost, so it sees each request first and its response last. The
+// middleware added by Start, which sets request IDs and recovers from panics,
+// wraps all the others.
+func (a *API) Use(middleware ...Middleware) {
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+	a.middleware = append(a.middleware, middleware...)
+}
+
+// wrap returns h wrapped in the api's middleware stack
+func (a *API) wrap(h http.Handler) http.Handler {
+	a.mtx.RLock()
+	defer a.mtx.RUnlock()
+	for i := len(a.middleware) - 1; i >= 0; i-- {
+		h = a.middleware[i](h)
+	}
+	return h
+}
+
+// handlerMiddleware adapts a handler added with AddHandler, which is called
+// with a recorder before the request is served. The headers it sets are
+// copied to the response, and if it answers with an error status the request
+// is not served further.
+func handlerMiddleware(f func(http.ResponseWriter, *http.Request)) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+			rec := httptest.NewRecorder()
+			f(rec, req)
+			for k, v := range rec.Header() {
+				res.Header()[k] = v
+			}
+			if rec.Code >= http.StatusBadRequest {
+				res.WriteHeader(rec.Code)
+				res.Write(rec.Body.Bytes())
+				return
+			}
+			next.ServeHTTP(res, req)
+		})
+	}
+}
+
+// handlerFunc adapts middleware to a handler for AddHandler, which answers
+// requests the middleware refuses and otherwise writes only its headers.
+func handlerFunc(m Middleware) http.HandlerFunc {
+	return m(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {})).ServeHTTP
+}
+
+// responseWriter records the status and size of a response, for middleware
+// which acts after the request has been served.
+type responseWriter struct {
+	http.ResponseWriter
+	status int
+	size   int
+}
+
+func newResponseWriter(res http.ResponseWriter) *responseWriter {
+	return &responseWriter{ResponseWriter: res}
+}
+
+func (w *responseWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *responseWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	n, err := w.ResponseWriter.Write(b)
+	w.size += n
+	return n, err
+}
+
+// Flush lets event streams be flushed through the middleware.
+func (w *responseWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack lets WebSocket connections be taken over through the middleware.
+func (w *responseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("ResponseWriter cannot be hijacked")
+	}
+	if w.status == 0 {
+		w.status = http.StatusSwitchingProtocols
+	}
+	return h.Hijack()
+}
+
+// Unwrap returns the wrapped ResponseWriter, for http.ResponseController.
+func (w *responseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// written returns true once the status of the response has been sent
+func (w *responseWriter) written() bool {
+	return w.status != 0
+}
+
+type requestIDKey struct{}
+
+// RequestIDHeader is the header carrying the ID of each request.
+const RequestIDHeader = "X-Request-Id"
+
+// RequestID returns middleware which gives each request an ID, taken from
+// its X-Request-Id header or generated, and sets it on the response. The ID
+// is passed to later handlers in the request's context.
+func RequestID() Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+			id := req.Header.Get(RequestIDHeader)
+			if !validRequestID(id) {
+				id = newRequestID()
+			}
+			res.Header().Set(RequestIDHeader, id)
+			next.ServeHTTP(res, req.WithContext(context.WithValue(req.Context(), requestIDKey{}, id)))
+		})
+	}
+}
+
+// RequestIDFrom returns the ID set by RequestID on the context of a request,
+// or "" if there is none.
+func RequestIDFrom(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+func newRequestID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// validRequestID returns true if a client's request ID is short and safe to
+// log and echo
+func validRequestID(id string) bool {
+	if id == "" || len(id) > 64 {
+		return false
+	}
+	for _, c := range id {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' ||
+			c == '-' || c == '_' || c == '.') {
+			return false
+		}
+	}
+	return true
+}
+
+// LogRequests returns middleware which logs each request to l once it has
+// been served, with its status and duration.
+func LogRequests(l gobot.Logger) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+			start := time.Now()
+			w := newResponseWriter(res)
+			next.ServeHTTP(w, req)
+			fields := []interface{}{
+				"method", req.Method,
+				"url", loggedURL(req.URL),
+				"status", w.status,
+				"size", w.size,
+				"duration", time.Since(start),
+				"remote", req.RemoteAddr,
+			}
+			if id := RequestIDFrom(req.Context()); id != "" {
+				fields = append(fields, "request_id", id)
+			}
+			l.Info("Request", fields...)
+		})
+	}
+}
+
+// loggedURL returns u as a string for logging, without the access_token
+// query parameter a WebSocket client may authenticate with.
+func loggedURL(u *url.URL) string {
+	query := u.Query()
+	if _, ok := query["access_token"]; !ok {
+		return u.String()
+	}
+	query.Del("access_token")
+	logged := *u
+	logged.RawQuery = query.Encode()
+	return logged.String()
+}
+
+// Recover returns middleware which recovers from panics in later handlers,
+// such as in a command, logging them to l and answering with 500 Internal
+// Server Error, so that one request cannot bring down the api.
+func Recover(l gobot.Logger) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+			w := newResponseWriter(res)
+			defer func() {
+				r := recover()
+				if r == nil {
+					return
+				}
+				if r == http.ErrAbortHandler {
+					panic(r)
+				}
+				l.Error("Request panicked", "method", req.Method, "url", loggedURL(req.URL),
+					"request_id", RequestIDFrom(req.Context()), "panic", r, "stack", string(debug.Stack()))
+				if !w.written() {
+					writeError(w, newError(http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError)))
+				}
+			}()
+			next.ServeHTTP(w, req)
+		})
+	}
+}
+
+// RateLimit returns middleware which allows each client, by IP address, rate
+// requests a second on average and bursts of up to burst requests. Further
+// requests are answered with 429 Too Many Requests.
+func RateLimit(rate float64, burst int) Middleware {
+	l := &rateLimiter{
+		rate:    rate,
+		burst:   float64(burst),
+		buckets: make(map[string]*rateBucket),
+		now:     time.Now,
+	}
+	return l.middleware
+}
+
+type rateLimiter struct {
+	mtx       sync.Mutex
+	rate      float64
+	burst     float64
+	buckets   map[string]*rateBucket
+	lastPrune time.Time
+	now       func() time.Time
+}
+
+// rateBucket holds the requests a client may still make, as of last
+type rateBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+func (l *rateLimiter) middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		if wait := l.take(clientIP(req)); wait > 0 {
+			res.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+			writeError(res, newError(http.StatusTooManyRequests, "Too many requests"))
+			return
+		}
+		next.ServeHTTP(res, req)
+	})
+}
+
+// take takes a request from client's bucket, returning how long the client
+// must wait if it is empty.
+func (l *rateLimiter) take(client string) time.Duration {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	now := l.now()
+	l.prune(now)
+
+	b, ok := l.buckets[client]
+	if !ok {
+		b = &rateBucket{tokens: l.burst, last: now}
+		l.buckets[client] = b
+	}
+	b.tokens = math.Min(l.burst, b.tokens+now.Sub(b.last).Seconds()*l.rate)
+	b.last = now
+	if b.tokens < 1 {
+		return time.Duration((1 - b.tokens) / l.rate * float64(time.Second))
+	}
+	b.tokens--
+	return 0
+}
+
+// prune forgets, at most once a minute, the clients whose buckets have
+// refilled, so the limiter does not grow with every client ever seen.
+func (l *rateLimiter) prune(now time.Time) {
+	if now.Sub(l.lastPrune) < time.Minute {
+		return
+	}
+	l.lastPrune = now
+	for client, b := range l.buckets {
+		if b.tokens+now.Sub(b.last).Seconds()*l.rate >= l.burst {
+			delete(l.buckets, client)
+		}
+	}
+}
+
+// clientIP returns the IP address a request came from
+func clientIP(req *http.Request) string {
+	host, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		return req.RemoteAddr
+	}
+	return host
+}
@@ -0,0 +1,253 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hybridgroup/gobot"
+	"github.com/hybridgroup/gobot/gobottest"
+)
+
+// testLogger records the messages and fields logged to it.
+type testLogger struct {
+	messages *[]string
+	fields   *[]map[string]interface{}
+}
+
+func newTestLogger() testLogger {
+	return testLogger{messages: &[]string{}, fields: &[]map[string]interface{}{}}
+}
+
+func (l testLogger) log(msg string, fields []interface{}) {
+	m := map[string]interface{}{}
+	for i := 0; i+1 < len(fields); i += 2 {
+		m[fields[i].(string)] = fields[i+1]
+	}
+	*l.messages = append(*l.messages, msg)
+	*l.fields = append(*l.fields, m)
+}
+
+func (l testLogger) Debug(msg string, fields ...interface{}) { l.log(msg, fields) }
+func (l testLogger) Info(msg string, fields ...interface{})  { l.log(msg, fields) }
+func (l testLogger) Warn(msg string, fields ...interface{})  { l.log(msg, fields) }
+func (l testLogger) Error(msg string, fields ...interface{}) { l.log(msg, fields) }
+func (l testLogger) With(fields ...interface{}) gobot.Logger { return l }
+
+func TestMiddlewareOrder(t *testing.T) {
+	a := initTestAPI()
+	order := []string{}
+	trace := func(name string) Middleware {
+		return func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+				order = append(order, name)
+				next.ServeHTTP(res, req)
+				order = append(order, "/"+name)
+			})
+		}
+	}
+	a.Use(trace("outer"), trace("inner"))
+
+	request, _ := http.NewRequest("GET", "/api/", nil)
+	response := httptest.NewRecorder()
+	a.ServeHTTP(response, request)
+	gobottest.Assert(t, response.Code, 200)
+	gobottest.Assert(t, order, []string{"outer", "inner", "/inner", "/outer"})
+}
+
+func TestMiddlewareShortCircuit(t *testing.T) {
+	a := initTestAPI()
+	a.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+			if req.URL.Path == "/api/robots" {
+				http.Error(res, "Gone", http.StatusGone)
+				return
+			}
+			next.ServeHTTP(res, req)
+		})
+	})
+
+	request, _ := http.NewRequest("GET", "/api/robots", nil)
+	response := httptest.NewRecorder()
+	a.ServeHTTP(response, request)
+	gobottest.Assert(t, response.Code, http.StatusGone)
+
+	request, _ = http.NewRequest("GET", "/api/", nil)
+	response = httptest.NewRecorder()
+	a.ServeHTTP(response, request)
+	gobottest.Assert(t, response.Code, 200)
+}
+
+func TestAddHandler(t *testing.T) {
+	a := initTestAPI()
+	a.AddHandler(func(res http.ResponseWriter, req *http.Request) {
+		res.Header().Set("X-Handled", "yes")
+		if req.Header.Get("X-Block") != "" {
+			http.Error(res, "Blocked", http.StatusTeapot)
+		}
+	})
+
+	request, _ := http.NewRequest("GET", "/api/", nil)
+	response := httptest.NewRecorder()
+	a.ServeHTTP(response, request)
+	gobottest.Assert(t, response.Code, 200)
+	gobottest.Assert(t, response.Header().Get("X-Handled"), "yes")
+
+	request.Header.Set("X-Block", "1")
+	response = httptest.NewRecorder()
+	a.ServeHTTP(response, request)
+	gobottest.Assert(t, response.Code, http.StatusTeapot)
+	gobottest.Assert(t, response.Body.String(), "Blocked\n")
+}
+
+func TestRequestID(t *testing.T) {
+	a := initTestAPI()
+	var id string
+	a.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+			id = RequestIDFrom(req.Context())
+			next.ServeHTTP(res, req)
+		})
+	})
+
+	request, _ := http.NewRequest("GET", "/api/", nil)
+	response := httptest.NewRecorder()
+	a.ServeHTTP(response, request)
+	gobottest.Assert(t, len(id), 16)
+	gobottest.Assert(t, response.Header().Get(RequestIDHeader), id)
+
+	request.Header.Set(RequestIDHeader, "client-id.1")
+	response = httptest.NewRecorder()
+	a.ServeHTTP(response, request)
+	gobottest.Assert(t, id, "client-id.1")
+
+	request.Header.Set(RequestIDHeader, "bad id\n")
+	response = httptest.NewRecorder()
+	a.ServeHTTP(response, request)
+	gobottest.Refute(t, id, "bad id\n")
+	gobottest.Assert(t, len(id), 16)
+}
+
+func TestLogRequests(t *testing.T) {
+	a := initTestAPI()
+	l := newTestLogger()
+	a.Use(LogRequests(l))
+
+	request, _ := http.NewRequest("GET", "/api/robots/Robot1", nil)
+	response := httptest.NewRecorder()
+	a.ServeHTTP(response, request)
+	gobottest.Assert(t, *l.messages, []string{"Request"})
+	fields := (*l.fields)[0]
+	gobottest.Assert(t, fields["method"], "GET")
+	gobottest.Assert(t, fields["status"], 200)
+	gobottest.Assert(t, fields["size"], response.Body.Len())
+	gobottest.Assert(t, fields["request_id"], response.Header().Get(RequestIDHeader))
+
+	// tokens in the query are not logged
+	request, _ = http.NewRequest("GET", "/api/robots?access_token=secret&pretty=1", nil)
+	a.ServeHTTP(httptest.NewRecorder(), request)
+	gobottest.Assert(t, (*l.fields)[1]["url"], "/api/robots?pretty=1")
+	gobottest.Assert(t, strings.Contains(fmt.Sprint(*l.fields), "secret"), false)
+}
+
+func TestRecover(t *testing.T) {
+	a := initTestAPI()
+	l := newTestLogger()
+	a.Use(Recover(l))
+	a.gobot.AddCommand("panic", func(params map[string]interface{}) interface{} {
+		panic("boom")
+	})
+
+	request, _ := http.NewRequest("POST", "/api/commands/panic", bytes.NewBufferString("{}"))
+	response := httptest.NewRecorder()
+	a.ServeHTTP(response, request)
+	gobottest.Assert(t, response.Code, 500)
+	var body map[string]interface{}
+	json.NewDecoder(response.Body).Decode(&body)
+	gobottest.Assert(t, errorMessage(body), "Internal Server Error")
+	gobottest.Assert(t, *l.messages, []string{"Request panicked"})
+	gobottest.Assert(t, (*l.fields)[0]["panic"], "boom")
+
+	request, _ = http.NewRequest("POST", "/api/commands/panic?access_token=secret", bytes.NewBufferString("{}"))
+	a.ServeHTTP(httptest.NewRecorder(), request)
+	gobottest.Assert(t, strings.Contains(fmt.Sprint(*l.fields), "secret"), false)
+
+	// the api serves later requests
+	request, _ = http.NewRequest("GET", "/api/", nil)
+	response = httptest.NewRecorder()
+	a.ServeHTTP(response, request)
+	gobottest.Assert(t, response.Code, 200)
+}
+
+func TestRecoverSocket(t *testing.T) {
+	a, server, ws := initTestSocket(t)
+	defer server.Close()
+	defer ws.Close()
+	a.gobot.AddCommand("panic", func(params map[string]interface{}) interface{} {
+		panic("boom")
+	})
+
+	reply := sendSocket(t, ws, SocketMessage{Type: "command", Command: "panic"})
+	gobottest.Assert(t, reply.Type, "error")
+	gobottest.Assert(t, reply.Error, `Command "panic" panicked: boom`)
+}
+
+func TestRateLimit(t *testing.T) {
+	a := initTestAPI()
+	m := RateLimit(2, 3)
+	a.Use(m)
+	serve := func(remote string) *httptest.ResponseRecorder {
+		request, _ := http.NewRequest("GET", "/api/", nil)
+		request.RemoteAddr = remote
+		response := httptest.NewRecorder()
+		a.ServeHTTP(response, request)
+		return response
+	}
+
+	for i := 0; i < 3; i++ {
+		gobottest.Assert(t, serve("10.0.0.1:1000").Code, 200)
+	}
+	response := serve("10.0.0.1:1001")
+	gobottest.Assert(t, response.Code, http.StatusTooManyRequests)
+	gobottest.Assert(t, response.Header().Get("Retry-After"), "1")
+	gobottest.Assert(t, strings.Contains(response.Body.String(), "Too many requests"), true)
+	// other clients have buckets of their own
+	gobottest.Assert(t, serve("10.0.0.2:1000").Code, 200)
+}
+
+func TestRateLimiter(t *testing.T) {
+	now := time.Now()
+	l := &rateLimiter{rate: 2, burst: 2, buckets: make(map[string]*rateBucket), now: func() time.Time { return now }}
+	gobottest.Assert(t, l.take("a"), time.Duration(0))
+	gobottest.Assert(t, l.take("a"), time.Duration(0))
+	gobottest.Assert(t, l.take("a"), 500*time.Millisecond)
+
+	now = now.Add(250 * time.Millisecond)
+	gobottest.Assert(t, l.take("a"), 250*time.Millisecond)
+	now = now.Add(250 * time.Millisecond)
+	gobottest.Assert(t, l.take("a"), time.Duration(0))
+
+	// full buckets are forgotten
+	l.take("b")
+	now = now.Add(2 * time.Minute)
+	l.take("c")
+	gobottest.Assert(t, len(l.buckets), 1)
+}
+
+func TestCORSPreflight(t *testing.T) {
+	a := initTestAPI()
+	a.Use(CORSMiddleware("http://server.com"))
+
+	request, _ := http.NewRequest("OPTIONS", "/api/robots", nil)
+	request.Header.Set("Origin", "http://server.com")
+	request.Header.Set("Access-Control-Request-Method", "POST")
+	response := httptest.NewRecorder()
+	a.ServeHTTP(response, request)
+	gobottest.Assert(t, response.Code, http.StatusNoContent)
+	gobottest.Assert(t, response.Header().Get("Access-Control-Allow-Methods"), "GET,POST")
+}
@@ -0,0 +1,379 @@
+package api
+
+import (
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/hybridgroup/gobot"
+)
+
+// route is a route registered on the api router
+type route struct {
+	method string
+	path   string
+}
+
+// addRoute records a route, for the OpenAPI document
+func (a *API) addRoute(method, path string) {
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+	a.routes = append(a.routes, route{method: method, path: path})
+}
+
+// OpenAPI is an OpenAPI 3 document describing the api.
+type OpenAPI struct {
+	OpenAPI    string                                  `json:"openapi"`
+	Info       OpenAPIInfo                             `json:"info"`
+	Paths      map[string]map[string]*OpenAPIOperation `json:"paths"`
+	Components OpenAPIComponents                       `json:"components"`
+}
+
+// OpenAPIInfo describes the api in an OpenAPI document.
+type OpenAPIInfo struct {
+	Title   string `json:"title"`
+	Version string `json:"version"`
+}
+
+// OpenAPIOperation describes a route and method of the api.
+type OpenAPIOperation struct {
+	OperationID string                      `json:"operationId"`
+	Summary     string                      `json:"summary,omitempty"`
+	Description string                      `json:"description,omitempty"`
+	Tags        []string                    `json:"tags,omitempty"`
+	Parameters  []OpenAPIParameter          `json:"parameters,omitempty"`
+	RequestBody *OpenAPIRequestBody         `json:"requestBody,omitempty"`
+	Responses   map[string]*OpenAPIResponse `json:"responses"`
+}
+
+// OpenAPIParameter describes a parameter in the path of a route.
+type OpenAPIParameter struct {
+	Name     string      `json:"name"`
+	In       string      `json:"in"`
+	Required bool        `json:"required"`
+	Schema   *JSONSchema `json:"schema"`
+}
+
+// OpenAPIRequestBody describes the body of a request.
+type OpenAPIRequestBody struct {
+	Required bool                    `json:"required,omitempty"`
+	Content  map[string]OpenAPIMedia `json:"content"`
+}
+
+// OpenAPIResponse describes a response.
+type OpenAPIResponse struct {
+	Description string                  `json:"description"`
+	Content     map[string]OpenAPIMedia `json:"content,omitempty"`
+}
+
+// OpenAPIMedia describes the content of a request or response body.
+type OpenAPIMedia struct {
+	Schema *JSONSchema `json:"schema"`
+}
+
+// OpenAPIComponents holds the schemas referred to from an OpenAPI document.
+type OpenAPIComponents struct {
+	Schemas map[string]*JSONSchema `json:"schemas"`
+}
+
+// JSONSchema is the subset of the OpenAPI schema object used to describe
+// the api's bodies and the params of commands.
+type JSONSchema struct {
+	Ref                  string                 `json:"$ref,omitempty"`
+	Type                 string                 `json:"type,omitempty"`
+	Description          string                 `json:"description,omitempty"`
+	Properties           map[string]*JSONSchema `json:"properties,omitempty"`
+	Required             []string               `json:"required,omitempty"`
+	Items                *JSONSchema            `json:"items,omitempty"`
+	Default              interface{}            `json:"default,omitempty"`
+	Minimum              *float64               `json:"minimum,omitempty"`
+	Maximum              *float64               `json:"maximum,omitempty"`
+	Enum                 []interface{}          `json:"enum,omitempty"`
+	AdditionalProperties interface{}            `json:"additionalProperties,omitempty"`
+}
+
+// routeSummaries summarizes the routes added by Start
+var routeSummaries = map[string]string{
+	"GET /api/":                                                 "Describes the gobot, its robots and their devices",
+	"GET /api/openapi.json":                                     "Describes the api as an OpenAPI document",
+	"GET /api/commands":                                         "Lists the gobot's commands",
+	"GET /api/commands/:command":                                "Runs a gobot command",
+	"POST /api/commands/:command":                               "Runs a gobot command",
+	"GET /api/events/:event":                                    "Streams the gobot's events",
+	"GET /api/robots":                                           "Lists the robots",
+	"GET /api/robots/:robot":                                    "Describes a robot",
+	"GET /api/robots/:robot/commands":                           "Lists a robot's commands",
+	"GET /api/robots/:robot/commands/:command":                  "Runs a robot command",
+	"POST /api/robots/:robot/commands/:command":                 "Runs a robot command",
+	"GET /api/robots/:robot/events/:event":                      "Streams a robot's events",
+	"GET /api/robots/:robot/devices":                            "Lists a robot's devices",
+	"GET /api/robots/:robot/devices/:device":                    "Describes a device",
+	"GET /api/robots/:robot/devices/:device/events/:event":      "Streams a device's events",
+	"GET /api/robots/:robot/devices/:device/commands":           "Lists a device's commands",
+	"GET /api/robots/:robot/devices/:device/commands/:command":  "Runs a device command",
+	"POST /api/robots/:robot/devices/:device/commands/:command": "Runs a device command",
+	"GET /api/robots/:robot/macros":                             "Lists a robot's macros",
+	"POST /api/robots/:robot/macros":                            "Adds a macro to a robot",
+	"POST /api/robots/:robot/macros/run":                        "Runs a macro on a robot",
+	"POST /api/robots/:robot/macros/cancel":                     "Cancels the macro a robot is running",
+	"GET /api/robots/:robot/macros/:macro":                      "Describes a robot's macro",
+	"GET /api/robots/:robot/store":                              "Lists the values in a robot's store",
+	"GET /api/robots/:robot/store/:key":                         "Reads a value from a robot's store",
+	"PUT /api/robots/:robot/store/:key":                         "Writes a value to a robot's store",
+	"DELETE /api/robots/:robot/store/:key":                      "Removes a value from a robot's store",
+	"GET /api/robots/:robot/devices/:device/store":              "Lists the values in a device's store",
+	"GET /api/robots/:robot/devices/:device/store/:key":         "Reads a value from a device's store",
+	"PUT /api/robots/:robot/devices/:device/store/:key":         "Writes a value to a device's store",
+	"DELETE /api/robots/:robot/devices/:device/store/:key":      "Removes a value from a device's store",
+	"GET /api/robots/:robot/connections":                        "Lists a robot's connections",
+	"GET /api/robots/:robot/connections/:connection":            "Describes a connection",
+	"GET /api/rules":                                            "Lists the rules",
+	"GET /api/rules/:rule":                                      "Describes a rule",
+	"POST /api/rules/:rule/enable":                              "Enables a rule",
+	"POST /api/rules/:rule/disable":                             "Disables a rule",
+	"GET /api/metrics":                                          "Lists the metrics",
+	"GET /metrics":                                              "Lists the metrics in the Prometheus text format",
+	"GET /api/socket":                                           "Opens a WebSocket connection for events and commands",
+}
+
+var routeParam = regexp.MustCompile(`:([^/]+)`)
+
+// openAPI returns openAPI route handler.
+// Writes JSON with the OpenAPI document of the api
+func (a *API) openAPI(res http.ResponseWriter, req *http.Request) {
+	a.writeJSON(a.OpenAPI(), res)
+}
+
+// OpenAPI returns an OpenAPI 3 document describing the api: its routes, and
+// a route for each command of the gobot, its robots and their devices, with
+// the command's params when it has a schema.
+func (a *API) OpenAPI() *OpenAPI {
+	doc := &OpenAPI{
+		OpenAPI:    "3.0.3",
+		Info:       OpenAPIInfo{Title: "Gobot API", Version: gobot.Version()},
+		Paths:      make(map[string]map[string]*OpenAPIOperation),
+		Components: OpenAPIComponents{Schemas: openAPISchemas()},
+	}
+	add := func(method, path string, op *OpenAPIOperation) {
+		if doc.Paths[path] == nil {
+			doc.Paths[path] = make(map[string]*OpenAPIOperation)
+		}
+		doc.Paths[path][strings.ToLower(method)] = op
+	}
+
+	a.mtx.RLock()
+	routes := append([]route{}, a.routes...)
+	a.mtx.RUnlock()
+	for _, r := range routes {
+		if strings.HasPrefix(r.path, "/api/") || r.path == "/metrics" {
+			add(r.method, routeParam.ReplaceAllString(r.path, "{$1}"), routeOperation(r))
+		}
+	}
+
+	addCommands := func(c gobot.Commander, path string, tags ...string) {
+		for name := range c.Commands() {
+			add("POST", path+url.PathEscape(name), commandOperation(c, name, tags))
+		}
+	}
+	addCommands(a.gobot, "/api/commands/")
+	a.gobot.Robots().Each(func(r *gobot.Robot) {
+		robotPath := "/api/robots/" + url.PathEscape(r.Name)
+		addCommands(r, robotPath+"/commands/", r.Name)
+		r.Devices().Each(func(d gobot.Device) {
+			if c, ok := d.(gobot.Commander); ok {
+				addCommands(c, robotPath+"/devices/"+url.PathEscape(d.Name())+"/commands/", r.Name, d.Name())
+			}
+		})
+	})
+	uniqueOperationIDs(doc)
+	return doc
+}
+
+// uniqueOperationIDs numbers the operations whose IDs another operation
+// already has, going through them by path and method, since client
+// generators need unique IDs and names such as "my-led" and "my_led", or a
+// robot's command "led_on" and the command "on" of its device "led", give
+// the same one.
+func uniqueOperationIDs(doc *OpenAPI) {
+	var paths []string
+	used := make(map[string]bool)
+	for path, ops := range doc.Paths {
+		paths = append(paths, path)
+		for _, op := range ops {
+			used[op.OperationID] = true
+		}
+	}
+	sort.Strings(paths)
+
+	seen := make(map[string]bool)
+	for _, path := range paths {
+		var methods []string
+		for method := range doc.Paths[path] {
+			methods = append(methods, method)
+		}
+		sort.Strings(methods)
+		for _, method := range methods {
+			op := doc.Paths[path][method]
+			if seen[op.OperationID] {
+				id := op.OperationID
+				for n := 2; used[id]; n++ {
+					id = op.OperationID + "_" + strconv.Itoa(n)
+				}
+				op.OperationID = id
+				used[id] = true
+			}
+			seen[op.OperationID] = true
+		}
+	}
+}
+
+// routeOperation describes a registered route
+func routeOperation(r route) *OpenAPIOperation {
+	op := &OpenAPIOperation{
+		OperationID: operationID(strings.ToLower(r.method), r.path),
+		Summary:     routeSummaries[r.method+" "+r.path],
+		Responses:   map[string]*OpenAPIResponse{"default": errorResponse()},
+	}
+	if parts := strings.Split(strings.Trim(r.path, "/"), "/"); len(parts) > 1 {
+		op.Tags = []string{parts[1]}
+	}
+	for _, match := range routeParam.FindAllStringSubmatch(r.path, -1) {
+		op.Parameters = append(op.Parameters, OpenAPIParameter{
+			Name:     match[1],
+			In:       "path",
+			Required: true,
+			Schema:   &JSONSchema{Type: "string"},
+		})
+	}
+
+	switch {
+	case r.path == "/api/socket":
+		op.Responses["101"] = &OpenAPIResponse{Description: "Switching to the WebSocket protocol"}
+	case strings.HasSuffix(r.path, "/events/:event"):
+		op.Responses["200"] = &OpenAPIResponse{
+			Description: "Server-sent events, each with the JSON data of an event",
+			Content:     map[string]OpenAPIMedia{"text/event-stream": {Schema: &JSONSchema{Type: "string"}}},
+		}
+	case r.path == "/metrics":
+		op.Responses["200"] = &OpenAPIResponse{
+			Description: "Metrics",
+			Content:     map[string]OpenAPIMedia{"text/plain": {Schema: &JSONSchema{Type: "string"}}},
+		}
+	default:
+		if r.method == "POST" || r.method == "PUT" {
+			op.RequestBody = &OpenAPIRequestBody{
+				Content: map[string]OpenAPIMedia{"application/json": {Schema: &JSONSchema{}}},
+			}
+		}
+		op.Responses["200"] = jsonResponse("Success", &JSONSchema{Type: "object"})
+	}
+	return op
+}
+
+// commandOperation describes running the command name of c
+func commandOperation(c gobot.Commander, name string, tags []string) *OpenAPIOperation {
+	params := &JSONSchema{Type: "object", Properties: map[string]*JSONSchema{}}
+	op := &OpenAPIOperation{
+		OperationID: operationID(append(append([]string{"run"}, tags...), name)...),
+		Summary:     "Runs the command " + name,
+		Tags:        tags,
+		RequestBody: &OpenAPIRequestBody{
+			Content: map[string]OpenAPIMedia{"application/json": {Schema: params}},
+		},
+		Responses: map[string]*OpenAPIResponse{
+			"200":     jsonResponse("The result of the command", &JSONSchema{Ref: "#/components/schemas/CommandResult"}),
+			"default": errorResponse(),
+		},
+	}
+	if len(tags) == 0 {
+		op.Tags = []string{"commands"}
+	}
+
+	schema := c.CommandSchema(name)
+	if schema == nil {
+		params.AdditionalProperties = true
+		return op
+	}
+	if schema.Description != "" {
+		op.Description = schema.Description
+	}
+	params.AdditionalProperties = false
+	for _, p := range schema.Params {
+		params.Properties[p.Name] = paramSchema(p)
+		if p.Required {
+			params.Required = append(params.Required, p.Name)
+		}
+	}
+	op.RequestBody.Required = len(params.Required) > 0
+	op.Responses["400"] = errorResponse()
+	return op
+}
+
+// paramSchema returns the schema of a command param
+func paramSchema(p gobot.Param) *JSONSchema {
+	s := &JSONSchema{
+		Type:        string(p.Type),
+		Description: p.Description,
+		Default:     p.Default,
+		Enum:        p.Enum,
+	}
+	if p.Range != nil {
+		min, max := p.Range.Min, p.Range.Max
+		s.Minimum, s.Maximum = &min, &max
+	}
+	if p.Type == gobot.ArrayParam {
+		s.Items = &JSONSchema{}
+	}
+	return s
+}
+
+// operationID joins parts into an identifier, replacing runs of the
+// characters client generators do not accept in one with an underscore
+func operationID(parts ...string) string {
+	id := strings.Join(parts, "_")
+	id = strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '_' {
+			return r
+		}
+		return '_'
+	}, id)
+	return strings.Join(strings.FieldsFunc(id, func(r rune) bool { return r == '_' }), "_")
+}
+
+func jsonResponse(description string, schema *JSONSchema) *OpenAPIResponse {
+	return &OpenAPIResponse{
+		Description: description,
+		Content:     map[string]OpenAPIMedia{"application/json": {Schema: schema}},
+	}
+}
+
+func errorResponse() *OpenAPIResponse {
+	return jsonResponse("An error", &JSONSchema{Ref: "#/components/schemas/ErrorResponse"})
+}
+
+// openAPISchemas returns the schemas shared by the operations
+func openAPISchemas() map[string]*JSONSchema {
+	return map[string]*JSONSchema{
+		"Error": {
+			Type: "object",
+			Properties: map[string]*JSONSchema{
+				"code":     {Type: "string", Description: "The kind of error, such as not_found"},
+				"message":  {Type: "string"},
+				"resource": {Type: "string", Description: "The kind of resource the error is about"},
+			},
+			Required: []string{"code", "message"},
+		},
+		"ErrorResponse": {
+			Type:       "object",
+			Properties: map[string]*JSONSchema{"error": {Ref: "#/components/schemas/Error"}},
+			Required:   []string{"error"},
+		},
+		"CommandResult": {
+			Type:       "object",
+			Properties: map[string]*JSONSchema{"result": {Description: "The value returned by the command"}},
+		},
+	}
+}
@@ -0,0 +1,111 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/hybridgroup/gobot"
+	"github.com/hybridgroup/gobot/gobottest"
+)
+
+func TestOpenAPI(t *testing.T) {
+	a := initTestAPI()
+	request, _ := http.NewRequest("GET", "/api/openapi.json", nil)
+	response := httptest.NewRecorder()
+	a.ServeHTTP(response, request)
+	gobottest.Assert(t, response.Code, 200)
+
+	var doc map[string]interface{}
+	json.NewDecoder(response.Body).Decode(&doc)
+	gobottest.Assert(t, doc["openapi"], "3.0.3")
+	gobottest.Assert(t, doc["info"].(map[string]interface{})["version"], gobot.Version())
+	paths := doc["paths"].(map[string]interface{})
+
+	// registered routes
+	route := paths["/api/robots/{robot}/devices/{device}/commands/{command}"].(map[string]interface{})
+	gobottest.Refute(t, route["get"], nil)
+	post := route["post"].(map[string]interface{})
+	gobottest.Assert(t, post["summary"], "Runs a device command")
+	gobottest.Assert(t, len(post["parameters"].([]interface{})), 3)
+	gobottest.Refute(t, paths["/api/robots/{robot}/store/{key}"].(map[string]interface{})["delete"], nil)
+	gobottest.Assert(t, paths["/index.html"], nil)
+
+	// live commands
+	for _, path := range []string{
+		"/api/commands/TestFunction",
+		"/api/robots/Robot1/commands/robotTestFunction",
+		"/api/robots/Robot3/devices/Device2/commands/DriverCommand",
+	} {
+		gobottest.Refute(t, paths[path], nil)
+	}
+	command := paths["/api/robots/Robot1/devices/Device1/commands/SchemaCommand"].(map[string]interface{})["post"].(map[string]interface{})
+	gobottest.Assert(t, command["operationId"], "run_Robot1_Device1_SchemaCommand")
+	gobottest.Assert(t, command["tags"], []interface{}{"Robot1", "Device1"})
+	body := command["requestBody"].(map[string]interface{})
+	gobottest.Assert(t, body["required"], true)
+	schema := body["content"].(map[string]interface{})["application/json"].(map[string]interface{})["schema"].(map[string]interface{})
+	gobottest.Assert(t, schema["required"], []interface{}{"level"})
+	gobottest.Assert(t, schema["additionalProperties"], false)
+	gobottest.Assert(t, schema["properties"], map[string]interface{}{
+		"level": map[string]interface{}{"type": "integer", "minimum": 0.0, "maximum": 10.0},
+	})
+
+	// client generators need unique operation IDs
+	ids := map[string]bool{}
+	for _, route := range a.OpenAPI().Paths {
+		for _, op := range route {
+			gobottest.Assert(t, ids[op.OperationID], false)
+			ids[op.OperationID] = true
+		}
+	}
+
+	components := doc["components"].(map[string]interface{})["schemas"].(map[string]interface{})
+	gobottest.Refute(t, components["ErrorResponse"], nil)
+}
+
+func TestOpenAPILive(t *testing.T) {
+	a := initTestAPI()
+	_, ok := a.OpenAPI().Paths["/api/robots/Robot4/commands/robotTestFunction"]
+	gobottest.Assert(t, ok, false)
+
+	a.gobot.AddRobot(newTestRobot("Robot4"))
+	op := a.OpenAPI().Paths["/api/robots/Robot4/commands/robotTestFunction"]["post"]
+	gobottest.Assert(t, op.Summary, "Runs the command robotTestFunction")
+	gobottest.Assert(t, op.RequestBody.Content["application/json"].Schema.AdditionalProperties, true)
+
+	a.Get("/api/custom/:thing", func(res http.ResponseWriter, req *http.Request) {})
+	op = a.OpenAPI().Paths["/api/custom/{thing}"]["get"]
+	gobottest.Assert(t, op.OperationID, "get_api_custom_thing")
+	gobottest.Assert(t, op.Parameters[0].Name, "thing")
+}
+
+func TestOpenAPIUniqueIDs(t *testing.T) {
+	a := initTestAPI()
+	r := a.gobot.Robot("Robot1")
+	r.AddDevice(newTestDriver(newTestAdaptor("Connection1", "/dev/null"), "my-led", "3"))
+	r.AddDevice(newTestDriver(newTestAdaptor("Connection1", "/dev/null"), "my_led", "4"))
+	led := newTestDriver(newTestAdaptor("Connection1", "/dev/null"), "led", "5")
+	led.AddCommand("on", func(params map[string]interface{}) interface{} { return nil })
+	r.AddDevice(led)
+	r.AddCommand("led_on", func(params map[string]interface{}) interface{} { return nil })
+
+	paths := a.OpenAPI().Paths
+	ids := map[string]bool{}
+	for _, route := range paths {
+		for _, op := range route {
+			gobottest.Assert(t, ids[op.OperationID], false)
+			ids[op.OperationID] = true
+		}
+	}
+	gobottest.Assert(t, paths["/api/robots/Robot1/commands/led_on"]["post"].OperationID, "run_Robot1_led_on")
+	gobottest.Assert(t, paths["/api/robots/Robot1/devices/led/commands/on"]["post"].OperationID, "run_Robot1_led_on_2")
+	gobottest.Assert(t, paths["/api/robots/Robot1/devices/my-led/commands/DriverCommand"]["post"].OperationID, "run_Robot1_my_led_DriverCommand")
+	gobottest.Assert(t, paths["/api/robots/Robot1/devices/my_led/commands/DriverCommand"]["post"].OperationID, "run_Robot1_my_led_DriverCommand_2")
+}
+
+func TestOperationID(t *testing.T) {
+	gobottest.Assert(t, operationID("run", "my bot", "led-1", "on"), "run_my_bot_led_1_on")
+	gobottest.Assert(t, operationID("get", "/api/robots/:robot"), "get_api_robots_robot")
+}
@@ -0,0 +1,286 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/hybridgroup/gobot"
+	"golang.org/x/net/websocket"
+)
+
+// SocketMessage is a JSON message sent over the WebSocket endpoint at
+// /api/socket, by the client or by the API. Clients send messages of the
+// types "subscribe", "unsubscribe" and "command"; the API replies with
+// messages of the types "subscribed", "unsubscribed", "result" and "error",
+// carrying the ID of the message they reply to, and sends a message of the
+// type "event" for each event published to a subscription. A subscription
+// ends with an "unsubscribed" message without an ID when its robot stops.
+type SocketMessage struct {
+	// ID is chosen by the client, and copied to the reply to its message
+	ID   interface{} `json:"id,omitempty"`
+	Type string      `json:"type"`
+	// Robot and Device name the publisher of an event or the owner of a
+	// command. Without a Robot they refer to the Gobot, and without a
+	// Device to the Robot
+	Robot  string `json:"robot,omitempty"`
+	Device string `json:"device,omitempty"`
+	// Event is an event name, or pattern as accepted by Eventer.On
+	Event string `json:"event,omitempty"`
+	// Subscription numbers the subscription an event was published to, or
+	// that a client unsubscribes from
+	Subscription int                    `json:"subscription,omitempty"`
+	Command      string                 `json:"command,omitempty"`
+	Params       map[string]interface{} `json:"params,omitempty"`
+	Data         interface{}            `json:"data,omitempty"`
+	Result       interface{}            `json:"result,omitempty"`
+	Error        string                 `json:"error,omitempty"`
+}
+
+// socketSession is the state of a connection to the WebSocket endpoint.
+// Messages are read and handled on the connection's goroutine, and written
+// by another, so slow clients never block the publishers of events.
+type socketSession struct {
+	api  *API
+	ws   *websocket.Conn
+	out  chan SocketMessage
+	done chan struct{}
+	// subs cancels each subscription, by number; it is only used by the
+	// reading goroutine
+	subs map[int]func()
+	last int
+}
+
+// socket serves a connection to the WebSocket endpoint until the client
+// disconnects, and then cancels its subscriptions.
+func (a *API) socket(ws *websocket.Conn) {
+	s := &socketSession{
+		api:  a,
+		ws:   ws,
+		out:  make(chan SocketMessage, gobot.DefaultBufferSize),
+		done: make(chan struct{}),
+		subs: make(map[int]func()),
+	}
+	go s.write()
+	defer s.close()
+
+	for {
+		var msg SocketMessage
+		if err := websocket.JSON.Receive(ws, &msg); err != nil {
+			var syntaxErr *json.SyntaxError
+			var typeErr *json.UnmarshalTypeError
+			if errors.As(err, &syntaxErr) || errors.As(err, &typeErr) {
+				s.send(SocketMessage{Type: "error", Error: err.Error()})
+				continue
+			}
+			return
+		}
+		s.handle(msg)
+	}
+}
+
+func (s *socketSession) handle(msg SocketMessage) {
+	var reply SocketMessage
+	var err error
+	switch msg.Type {
+	case "subscribe":
+		reply, err = s.subscribe(msg)
+	case "unsubscribe":
+		reply, err = s.unsubscribe(msg)
+	case "command":
+		reply, err = s.command(msg)
+	default:
+		err = errors.New("Unknown message type " + msg.Type)
+	}
+	if err != nil {
+		reply = SocketMessage{Type: "error", Error: err.Error()}
+	}
+	reply.ID = msg.ID
+	s.send(reply)
+}
+
+// subscribe starts sending the events published by the robot or device
+// named in msg whose names match its Event.
+func (s *socketSession) subscribe(msg SocketMessage) (SocketMessage, error) {
+	e, err := s.api.eventerFor(msg.Robot, msg.Device)
+	if err != nil {
+		return msg, err
+	}
+	match, err := gobot.MatchPattern(msg.Event)
+	if err != nil {
+		return msg, err
+	}
+	if !strings.ContainsAny(msg.Event, `*?[\`) && e.Event(msg.Event) == "" {
+		return msg, errors.New("No Event found with the name " + msg.Event)
+	}
+
+	events := e.Subscribe(gobot.SubscriberOptions{
+		Buffer: gobot.DefaultBufferSize,
+		Policy: gobot.DropOldest,
+		Filter: match,
+	})
+	s.last++
+	id := s.last
+	stop := make(chan struct{})
+	s.subs[id] = func() {
+		close(stop)
+		e.Unsubscribe(events)
+	}
+
+	go func() {
+		for {
+			select {
+			case evt, ok := <-events:
+				if !ok {
+					// tell the client unless it unsubscribed itself
+					select {
+					case <-stop:
+					default:
+						s.send(SocketMessage{Type: "unsubscribed", Subscription: id, Error: "Event source closed"})
+					}
+					return
+				}
+				s.send(SocketMessage{
+					Type:         "event",
+					Subscription: id,
+					Robot:        evt.Robot,
+					Device:       evt.Device,
+					Event:        evt.Name,
+					Data:         evt.Data,
+				})
+			case <-stop:
+				return
+			case <-s.done:
+				return
+			}
+		}
+	}()
+	return SocketMessage{Type: "subscribed", Subscription: id, Robot: msg.Robot, Device: msg.Device, Event: msg.Event}, nil
+}
+
+func (s *socketSession) unsubscribe(msg SocketMessage) (SocketMessage, error) {
+	cancel, ok := s.subs[msg.Subscription]
+	if !ok {
+		return msg, errors.New("No Subscription found with the number " + strconv.Itoa(msg.Subscription))
+	}
+	cancel()
+	delete(s.subs, msg.Subscription)
+	return SocketMessage{Type: "unsubscribed", Subscription: msg.Subscription}, nil
+}
+
+// command runs the command named in msg and replies with its result.
+func (s *socketSession) command(msg SocketMessage) (reply SocketMessage, err error) {
+	if err = s.authorize(msg); err != nil {
+		return msg, err
+	}
+	f, err := s.api.commandFor(msg.Robot, msg.Device, msg.Command)
+	if err != nil {
+		return msg, err
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			s.api.logger().Error("Command panicked", "command", msg.Command, "panic", r)
+			reply, err = msg, fmt.Errorf("Command %q panicked: %v", msg.Command, r)
+		}
+	}()
+	params := msg.Params
+	if params == nil {
+		params = map[string]interface{}{}
+	}
+	result := f(params)
+	if err, ok := result.(error); ok {
+		return msg, err
+	}
+	return SocketMessage{Type: "result", Robot: msg.Robot, Device: msg.Device, Command: msg.Command, Result: result}, nil
+}
+
+// authorize refuses the command in msg unless the token the connection was
+// opened with has the scope a request running the command over HTTP would
+// need. Connections opened without a TokenAuth may run every command.
+func (s *socketSession) authorize(msg SocketMessage) error {
+	scopes, ok := scopesFrom(s.ws.Request().Context())
+	if !ok {
+		return nil
+	}
+	route := "/api/commands/" + msg.Command
+	if msg.Robot != "" {
+		route = "/api/robots/" + msg.Robot
+		if msg.Device != "" {
+			route += "/devices/" + msg.Device
+		}
+		route += "/commands/" + msg.Command
+	}
+	if scope := RouteScope("POST", route); !hasScope(scopes, scope) {
+		return newError(http.StatusForbidden, "Token does not have the scope "+scope)
+	}
+	return nil
+}
+
+// socketHandshake refuses WebSocket connections opened by pages of other
+// origins than the api's own and its SocketOrigins, so other sites cannot
+// use a browser's credentials. Clients which send no Origin, which are not
+// browsers, are accepted. Of the subprotocols the client offers, the first
+// which does not carry a token is chosen.
+func (a *API) socketHandshake(config *websocket.Config, req *http.Request) error {
+	origin, err := websocket.Origin(config, req)
+	if err != nil {
+		return err
+	}
+	if origin != nil && origin.Host != req.Host {
+		a.mtx.RLock()
+		c := &CORS{AllowOrigins: a.SocketOrigins}
+		a.mtx.RUnlock()
+		c.generatePatterns()
+		if !c.isOriginAllowed(origin.Scheme + "://" + origin.Host) {
+			return errors.New("Origin not allowed: " + origin.String())
+		}
+	}
+	config.Origin = origin
+
+	if len(config.Protocol) > 0 {
+		protocol := config.Protocol[0]
+		for _, p := range config.Protocol {
+			if !strings.HasPrefix(p, SocketTokenProtocol) {
+				protocol = p
+				break
+			}
+		}
+		config.Protocol = []string{protocol}
+	}
+	return nil
+}
+
+// send queues msg for writing, unless the connection has closed.
+func (s *socketSession) send(msg SocketMessage) {
+	select {
+	case s.out <- msg:
+	case <-s.done:
+	}
+}
+
+// write writes queued messages to the connection until it closes.
+func (s *socketSession) write() {
+	for {
+		select {
+		case msg := <-s.out:
+			if err := websocket.JSON.Send(s.ws, msg); err != nil {
+				s.ws.Close()
+				return
+			}
+		case <-s.done:
+			return
+		}
+	}
+}
+
+func (s *socketSession) close() {
+	for _, cancel := range s.subs {
+		cancel()
+	}
+	close(s.done)
+	s.ws.Close()
+	s.api.logger().Info("Closing connection")
+}
@@ -0,0 +1,155 @@
+package api
+
+import (
+	"net/http/httptest"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hybridgroup/gobot/gobottest"
+	"golang.org/x/net/websocket"
+)
+
+func initTestSocket(t *testing.T) (*API, *httptest.Server, *websocket.Conn) {
+	a := initTestAPI()
+	server := httptest.NewServer(a)
+	ws, err := websocket.Dial("ws"+strings.TrimPrefix(server.URL, "http")+"/api/socket", "", server.URL)
+	gobottest.Assert(t, err, nil)
+	return a, server, ws
+}
+
+func sendSocket(t *testing.T, ws *websocket.Conn, msg SocketMessage) SocketMessage {
+	gobottest.Assert(t, websocket.JSON.Send(ws, msg), nil)
+	return receiveSocket(t, ws)
+}
+
+func receiveSocket(t *testing.T, ws *websocket.Conn) (msg SocketMessage) {
+	ws.SetReadDeadline(time.Now().Add(time.Second))
+	gobottest.Assert(t, websocket.JSON.Receive(ws, &msg), nil)
+	return
+}
+
+func TestSocketEvents(t *testing.T) {
+	a, server, ws := initTestSocket(t)
+	defer server.Close()
+	device := a.gobot.Robot("Robot1").Device("Device1").(*testDriver)
+
+	reply := sendSocket(t, ws, SocketMessage{ID: "a", Type: "subscribe", Robot: "Robot1", Device: "Device1", Event: "TestEvent"})
+	gobottest.Assert(t, reply.Type, "subscribed")
+	gobottest.Assert(t, reply.ID, "a")
+	gobottest.Assert(t, reply.Subscription, 1)
+
+	reply = sendSocket(t, ws, SocketMessage{Type: "subscribe", Robot: "Robot1", Device: "Device1", Event: "Test*"})
+	gobottest.Assert(t, reply.Subscription, 2)
+
+	device.Publish("TestEvent", "event-data")
+	for i := 0; i < 2; i++ {
+		msg := receiveSocket(t, ws)
+		gobottest.Assert(t, msg.Type, "event")
+		gobottest.Assert(t, msg.Robot, "Robot1")
+		gobottest.Assert(t, msg.Device, "Device1")
+		gobottest.Assert(t, msg.Event, "TestEvent")
+		gobottest.Assert(t, msg.Data, "event-data")
+	}
+
+	reply = sendSocket(t, ws, SocketMessage{Type: "unsubscribe", Subscription: 1})
+	gobottest.Assert(t, reply.Type, "unsubscribed")
+	device.Publish("TestEvent", "more-data")
+	msg := receiveSocket(t, ws)
+	gobottest.Assert(t, msg.Subscription, 2)
+	gobottest.Assert(t, msg.Data, "more-data")
+
+	// subscriptions end, with a message, when their robot stops
+	a.gobot.Robot("Robot1").Stop()
+	msg = receiveSocket(t, ws)
+	gobottest.Assert(t, msg.Type, "unsubscribed")
+	gobottest.Assert(t, msg.Subscription, 2)
+	gobottest.Assert(t, msg.Error, "Event source closed")
+}
+
+func TestSocketClose(t *testing.T) {
+	_, server, ws := initTestSocket(t)
+	defer server.Close()
+	goroutines := runtime.NumGoroutine()
+	for i := 0; i < 3; i++ {
+		sendSocket(t, ws, SocketMessage{Type: "subscribe", Robot: "Robot1", Device: "Device1", Event: "TestEvent"})
+	}
+	gobottest.Assert(t, runtime.NumGoroutine() > goroutines, true)
+
+	// subscriptions end with the connection
+	ws.Close()
+	for i := 0; runtime.NumGoroutine() > goroutines && i < 200; i++ {
+		time.Sleep(5 * time.Millisecond)
+	}
+	gobottest.Assert(t, runtime.NumGoroutine() <= goroutines, true)
+}
+
+func TestSocketCommands(t *testing.T) {
+	_, server, ws := initTestSocket(t)
+	defer server.Close()
+	defer ws.Close()
+
+	reply := sendSocket(t, ws, SocketMessage{ID: 1.0, Type: "command", Robot: "Robot1", Device: "Device1",
+		Command: "TestDriverCommand", Params: map[string]interface{}{"name": "human"}})
+	gobottest.Assert(t, reply.Type, "result")
+	gobottest.Assert(t, reply.ID, 1.0)
+	gobottest.Assert(t, reply.Result, "hello human")
+
+	reply = sendSocket(t, ws, SocketMessage{Type: "command", Command: "TestFunction",
+		Params: map[string]interface{}{"message": "socket"}})
+	gobottest.Assert(t, reply.Result, "hey socket")
+
+	reply = sendSocket(t, ws, SocketMessage{Type: "command", Robot: "Robot1", Device: "Device1",
+		Command: "SchemaCommand", Params: map[string]interface{}{"level": 11.0}})
+	gobottest.Assert(t, reply.Type, "error")
+	gobottest.Refute(t, reply.Error, "")
+}
+
+func TestSocketErrors(t *testing.T) {
+	_, server, ws := initTestSocket(t)
+	defer server.Close()
+	defer ws.Close()
+
+	for _, test := range []struct {
+		msg SocketMessage
+		err string
+	}{
+		{SocketMessage{Type: "dance"}, "Unknown message type dance"},
+		{SocketMessage{Type: "subscribe", Robot: "Robot9", Event: "TestEvent"}, "No Robot found with the name Robot9"},
+		{SocketMessage{Type: "subscribe", Robot: "Robot1", Device: "Device9", Event: "TestEvent"},
+			"No Device found with the name Device9"},
+		{SocketMessage{Type: "subscribe", Robot: "Robot1", Device: "Device1", Event: "Nope"},
+			"No Event found with the name Nope"},
+		{SocketMessage{Type: "subscribe", Robot: "Robot1", Device: "Device1", Event: "["}, "syntax error in pattern"},
+		{SocketMessage{Type: "unsubscribe", Subscription: 4}, "No Subscription found with the number 4"},
+		{SocketMessage{Type: "command", Robot: "Robot1", Command: "Nope"}, "Unknown Command"},
+	} {
+		reply := sendSocket(t, ws, test.msg)
+		gobottest.Assert(t, reply.Type, "error")
+		gobottest.Assert(t, reply.Error, test.err)
+	}
+
+	websocket.Message.Send(ws, "{")
+	gobottest.Assert(t, receiveSocket(t, ws).Type, "error")
+	reply := sendSocket(t, ws, SocketMessage{Type: "command", Command: "TestFunction",
+		Params: map[string]interface{}{"message": "still here"}})
+	gobottest.Assert(t, reply.Result, "hey still here")
+}
+
+func TestSocketOrigin(t *testing.T) {
+	a, server, ws := initTestSocket(t)
+	defer server.Close()
+	ws.Close()
+	url := "ws" + strings.TrimPrefix(server.URL, "http") + "/api/socket"
+
+	_, err := websocket.Dial(url, "", "http://evil.example.com")
+	gobottest.Refute(t, err, nil)
+
+	a.SocketOrigins = []string{"http://*.example.com"}
+	ws, err = websocket.Dial(url, "", "http://app.example.com")
+	gobottest.Assert(t, err, nil)
+	ws.Close()
+	_, err = websocket.Dial(url, "", "https://app.example.org")
+	gobottest.Refute(t, err, nil)
+}
@@ -0,0 +1,242 @@
+package api
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Scopes granted to tokens. A request needs a token with the scope of its
+// route; ScopeAdmin allows every request.
+const (
+	// ScopeRead allows reading the gobot, its robots, devices, rules and
+	// metrics, and streaming their events
+	ScopeRead = "read"
+	// ScopeCommands allows running the gobot's own (MCP) commands
+	ScopeCommands = "commands"
+	// ScopeRobot, followed by a robot's name, allows running the commands
+	// and macros of the robot and its devices, and changing its macros and
+	// stores. "robot:*" allows it for every robot
+	ScopeRobot = "robot:"
+	// ScopeAdmin allows every request, such as enabling and disabling rules
+	ScopeAdmin = "admin"
+)
+
+// TokenClaims are the claims of a signed token.
+type TokenClaims struct {
+	// Subject names who the token was issued to
+	Subject string `json:"sub,omitempty"`
+	// Scope holds the token's scopes, separated by spaces
+	Scope string `json:"scope"`
+	// ExpiresAt is when the token expires, in seconds since the Unix epoch,
+	// or 0 if it does not
+	ExpiresAt int64 `json:"exp,omitempty"`
+}
+
+// Scopes returns the scopes of the token.
+func (c TokenClaims) Scopes() []string {
+	return strings.Fields(c.Scope)
+}
+
+// TokenAuth checks the bearer token in the Authorization header of each
+// request, or for WebSocket connections as described at
+// SocketTokenProtocol, which is either a static API key or a token signed with HMAC
+// SHA-256 in the JWT compact format, and that the token has the scope of the
+// requested route. Requests without a valid token are refused with 401 Not
+// Authorized, and those with a token lacking the scope with 403 Forbidden.
+//
+//	auth := api.NewTokenAuth([]byte(secret))
+//	auth.AddKey(key, api.ScopeRead, api.ScopeRobot+"bot")
+//	a.Use(auth.Middleware)
+type TokenAuth struct {
+	mtx    sync.RWMutex
+	secret []byte
+	keys   map[string][]string
+	now    func() time.Time
+}
+
+// NewTokenAuth returns a TokenAuth which accepts tokens signed with secret.
+// Without a secret only API keys are accepted.
+func NewTokenAuth(secret []byte) *TokenAuth {
+	return &TokenAuth{
+		secret: secret,
+		keys:   make(map[string][]string),
+		now:    time.Now,
+	}
+}
+
+// AddKey adds a static API key granting scopes.
+func (t *TokenAuth) AddKey(key string, scopes ...string) {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+	t.keys[key] = scopes
+}
+
+// RemoveKey revokes a static API key.
+func (t *TokenAuth) RemoveKey(key string) {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+	delete(t.keys, key)
+}
+
+// Sign returns a token with claims signed with the secret.
+func (t *TokenAuth) Sign(claims TokenClaims) (string, error) {
+	if len(t.secret) == 0 {
+		return "", errors.New("TokenAuth has no secret")
+	}
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	unsigned := tokenHeader + "." + base64.RawURLEncoding.EncodeToString(payload)
+	return unsigned + "." + t.signature(unsigned), nil
+}
+
+// tokenHeader is the encoded header of every signed token
+var tokenHeader = base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"HS256","typ":"JWT"}`))
+
+func (t *TokenAuth) signature(unsigned string) string {
+	mac := hmac.New(sha256.New, t.secret)
+	mac.Write([]byte(unsigned))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// Scopes returns the scopes granted by token, or an error if it is neither
+// an API key nor a valid signed token.
+func (t *TokenAuth) Scopes(token string) ([]string, error) {
+	t.mtx.RLock()
+	scopes, ok := t.keys[token]
+	t.mtx.RUnlock()
+	if ok {
+		return scopes, nil
+	}
+
+	parts := strings.Split(token, ".")
+	if len(t.secret) == 0 || len(parts) != 3 {
+		return nil, errors.New("Invalid token")
+	}
+	unsigned := parts[0] + "." + parts[1]
+	if parts[0] != tokenHeader || !secureCompare(parts[2], t.signature(unsigned)) {
+		return nil, errors.New("Invalid token")
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, errors.New("Invalid token")
+	}
+	var claims TokenClaims
+	if err = json.Unmarshal(payload, &claims); err != nil {
+		return nil, errors.New("Invalid token")
+	}
+	if claims.ExpiresAt != 0 && t.now().Unix() >= claims.ExpiresAt {
+		return nil, errors.New("Token has expired")
+	}
+	return claims.Scopes(), nil
+}
+
+// SocketTokenProtocol prefixes a token offered as a WebSocket subprotocol.
+// Browsers cannot set the Authorization header of WebSocket connections, so
+// they offer the subprotocol "bearer.<token>" or add an access_token query
+// parameter to the url of /api/socket instead.
+const SocketTokenProtocol = "bearer."
+
+// bearerToken returns the token of req, from its Authorization header or,
+// for a WebSocket handshake, from its access_token query parameter or a
+// subprotocol prefixed with SocketTokenProtocol.
+func bearerToken(req *http.Request) (string, bool) {
+	if header := req.Header.Get("Authorization"); strings.HasPrefix(header, "Bearer ") {
+		return strings.TrimPrefix(header, "Bearer "), true
+	}
+	if !strings.EqualFold(req.Header.Get("Upgrade"), "websocket") {
+		return "", false
+	}
+	if token := req.URL.Query().Get("access_token"); token != "" {
+		return token, true
+	}
+	for _, protocol := range strings.Split(req.Header.Get("Sec-WebSocket-Protocol"), ",") {
+		if protocol = strings.TrimSpace(protocol); strings.HasPrefix(protocol, SocketTokenProtocol) {
+			return strings.TrimPrefix(protocol, SocketTokenProtocol), true
+		}
+	}
+	return "", false
+}
+
+type scopesKey struct{}
+
+// scopesFrom returns the scopes of the token a request was authorized with,
+// and false if it passed through no TokenAuth.
+func scopesFrom(ctx context.Context) ([]string, bool) {
+	scopes, ok := ctx.Value(scopesKey{}).([]string)
+	return scopes, ok
+}
+
+// Middleware refuses each request unless it carries a token with the scope
+// of its route, and can be added to an API with Use. The token's scopes are
+// kept with the request, so commands sent over a WebSocket connection are
+// checked against them.
+func (t *TokenAuth) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		token, ok := bearerToken(req)
+		if !ok {
+			t.refuse(res, "Not Authorized")
+			return
+		}
+		scopes, err := t.Scopes(token)
+		if err != nil {
+			t.refuse(res, err.Error())
+			return
+		}
+		if scope := RouteScope(req.Method, req.URL.Path); !hasScope(scopes, scope) {
+			writeError(res, newError(http.StatusForbidden, "Token does not have the scope "+scope))
+			return
+		}
+		if scopes == nil {
+			scopes = []string{}
+		}
+		next.ServeHTTP(res, req.WithContext(context.WithValue(req.Context(), scopesKey{}, scopes)))
+	})
+}
+
+func (t *TokenAuth) refuse(res http.ResponseWriter, reason string) {
+	res.Header().Set("WWW-Authenticate", "Bearer realm=\"Authorization Required\"")
+	writeError(res, newError(http.StatusUnauthorized, reason))
+}
+
+// RouteScope returns the scope a token needs for a request to path with
+// method.
+func RouteScope(method, path string) string {
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	readOnly := method == "GET" || method == "HEAD"
+	switch {
+	case len(parts) == 3 && parts[0] == "api" && parts[1] == "commands":
+		return ScopeCommands
+	case len(parts) >= 3 && parts[0] == "api" && parts[1] == "robots":
+		// commands are run by GET requests too
+		isCommand := len(parts) >= 5 && parts[len(parts)-2] == "commands"
+		if !readOnly || isCommand {
+			return ScopeRobot + parts[2]
+		}
+	}
+	if readOnly {
+		return ScopeRead
+	}
+	return ScopeAdmin
+}
+
+// hasScope returns true if scopes include scope, ScopeAdmin, or "robot:*"
+// for a robot's scope.
+func hasScope(scopes []string, scope string) bool {
+	for _, s := range scopes {
+		if s == scope || s == ScopeAdmin ||
+			(s == ScopeRobot+"*" && strings.HasPrefix(scope, ScopeRobot)) {
+			return true
+		}
+	}
+	return false
+}
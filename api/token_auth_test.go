@@ -0,0 +1,176 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hybridgroup/gobot/gobottest"
+	"golang.org/x/net/websocket"
+)
+
+func tokenRequest(a *API, token, method, path string) *httptest.ResponseRecorder {
+	request, _ := http.NewRequest(method, path,
+		bytes.NewBufferString(`{"message":"hi","robot":"Robot1","name":"human"}`))
+	if token != "" {
+		request.Header.Set("Authorization", "Bearer "+token)
+	}
+	response := httptest.NewRecorder()
+	a.ServeHTTP(response, request)
+	return response
+}
+
+func TestTokenAuthKeys(t *testing.T) {
+	a := initTestAPI()
+	auth := NewTokenAuth(nil)
+	auth.AddKey("reader", ScopeRead)
+	auth.AddKey("operator", ScopeRead, ScopeRobot+"Robot1")
+	a.Use(auth.Middleware)
+
+	response := tokenRequest(a, "", "GET", "/api/robots")
+	gobottest.Assert(t, response.Code, 401)
+	gobottest.Assert(t, response.Header().Get("WWW-Authenticate"), "Bearer realm=\"Authorization Required\"")
+	gobottest.Assert(t, tokenRequest(a, "unknown", "GET", "/api/robots").Code, 401)
+
+	gobottest.Assert(t, tokenRequest(a, "reader", "GET", "/api/robots").Code, 200)
+	response = tokenRequest(a, "reader", "POST", "/api/robots/Robot1/commands/robotTestFunction")
+	gobottest.Assert(t, response.Code, 403)
+	gobottest.Assert(t, response.Header().Get("Content-Type"), "application/json; charset=utf-8")
+	var body map[string]interface{}
+	json.NewDecoder(response.Body).Decode(&body)
+	gobottest.Assert(t, errorMessage(body), "Token does not have the scope robot:Robot1")
+
+	gobottest.Assert(t, tokenRequest(a, "operator", "POST", "/api/robots/Robot1/commands/robotTestFunction").Code, 200)
+	gobottest.Assert(t, tokenRequest(a, "operator", "GET", "/api/robots/Robot1/devices/Device1/commands/TestDriverCommand").Code, 200)
+	gobottest.Assert(t, tokenRequest(a, "operator", "POST", "/api/robots/Robot2/commands/robotTestFunction").Code, 403)
+	gobottest.Assert(t, tokenRequest(a, "operator", "POST", "/api/commands/TestFunction").Code, 403)
+
+	auth.RemoveKey("operator")
+	gobottest.Assert(t, tokenRequest(a, "operator", "GET", "/api/robots").Code, 401)
+}
+
+func TestTokenAuthSigned(t *testing.T) {
+	a := initTestAPI()
+	auth := NewTokenAuth([]byte("secret"))
+	a.Use(auth.Middleware)
+
+	token, err := auth.Sign(TokenClaims{Subject: "mcp", Scope: "read commands"})
+	gobottest.Assert(t, err, nil)
+	gobottest.Assert(t, strings.Count(token, "."), 2)
+	gobottest.Assert(t, tokenRequest(a, token, "GET", "/api/").Code, 200)
+	gobottest.Assert(t, tokenRequest(a, token, "POST", "/api/commands/TestFunction").Code, 200)
+	gobottest.Assert(t, tokenRequest(a, token, "POST", "/api/rules/rule/enable").Code, 403)
+
+	// tokens signed with another secret, or changed, are refused
+	other, _ := NewTokenAuth([]byte("other")).Sign(TokenClaims{Scope: "admin"})
+	gobottest.Assert(t, tokenRequest(a, other, "GET", "/api/").Code, 401)
+	parts := strings.Split(token, ".")
+	admin, _ := auth.Sign(TokenClaims{Scope: "admin"})
+	forged := parts[0] + "." + strings.Split(admin, ".")[1] + "." + parts[2]
+	gobottest.Assert(t, tokenRequest(a, forged, "GET", "/api/").Code, 401)
+
+	now := time.Now()
+	auth.now = func() time.Time { return now }
+	token, _ = auth.Sign(TokenClaims{Scope: "admin", ExpiresAt: now.Add(time.Minute).Unix()})
+	// authorized, but there is no such rule
+	gobottest.Assert(t, tokenRequest(a, token, "POST", "/api/rules/rule/enable").Code, 404)
+	auth.now = func() time.Time { return now.Add(time.Hour) }
+	_, err = auth.Scopes(token)
+	gobottest.Assert(t, err.Error(), "Token has expired")
+	gobottest.Assert(t, tokenRequest(a, token, "GET", "/api/").Code, 401)
+
+	_, err = NewTokenAuth(nil).Sign(TokenClaims{})
+	gobottest.Refute(t, err, nil)
+}
+
+func TestRouteScope(t *testing.T) {
+	for _, test := range []struct {
+		method, path, scope string
+	}{
+		{"GET", "/api/", ScopeRead},
+		{"GET", "/index.html", ScopeRead},
+		{"GET", "/api/robots/bot", ScopeRead},
+		{"GET", "/api/robots/bot/devices/led/commands", ScopeRead},
+		{"GET", "/api/robots/bot/macros/blink", ScopeRead},
+		{"GET", "/api/commands", ScopeRead},
+		{"GET", "/api/commands/hello", ScopeCommands},
+		{"POST", "/api/commands/hello", ScopeCommands},
+		{"GET", "/api/robots/bot/commands/on", "robot:bot"},
+		{"POST", "/api/robots/bot/devices/led/commands/on", "robot:bot"},
+		{"POST", "/api/robots/bot/macros/run", "robot:bot"},
+		{"PUT", "/api/robots/bot/store/count", "robot:bot"},
+		{"POST", "/api/rules/rule/enable", ScopeAdmin},
+	} {
+		gobottest.Assert(t, RouteScope(test.method, test.path), test.scope)
+	}
+
+	gobottest.Assert(t, hasScope([]string{"robot:*"}, "robot:bot"), true)
+	gobottest.Assert(t, hasScope([]string{"robot:*"}, ScopeRead), false)
+	gobottest.Assert(t, hasScope([]string{ScopeAdmin}, ScopeRead), true)
+}
+
+func TestTokenAuthSocket(t *testing.T) {
+	a := initTestAPI()
+	auth := NewTokenAuth(nil)
+	auth.AddKey("operator", ScopeRead, ScopeRobot+"Robot1")
+	a.Use(auth.Middleware)
+	server := httptest.NewServer(a)
+	defer server.Close()
+
+	url := "ws" + strings.TrimPrefix(server.URL, "http") + "/api/socket"
+	_, err := websocket.Dial(url, "", server.URL)
+	gobottest.Refute(t, err, nil)
+
+	config, _ := websocket.NewConfig(url, server.URL)
+	config.Header.Set("Authorization", "Bearer operator")
+	ws, err := websocket.DialConfig(config)
+	gobottest.Assert(t, err, nil)
+	defer ws.Close()
+
+	reply := sendSocket(t, ws, SocketMessage{Type: "command", Robot: "Robot1", Command: "robotTestFunction",
+		Params: map[string]interface{}{"message": "fox", "robot": "Robot1"}})
+	gobottest.Assert(t, reply.Result, "hey Robot1, fox")
+	gobottest.Assert(t, reply.Type, "result")
+	reply = sendSocket(t, ws, SocketMessage{Type: "command", Robot: "Robot2", Command: "robotTestFunction"})
+	gobottest.Assert(t, reply.Type, "error")
+	gobottest.Assert(t, reply.Error, "Token does not have the scope robot:Robot2")
+	reply = sendSocket(t, ws, SocketMessage{Type: "command", Command: "TestFunction"})
+	gobottest.Assert(t, reply.Error, "Token does not have the scope commands")
+}
+
+func TestTokenAuthSocketBrowser(t *testing.T) {
+	a := initTestAPI()
+	auth := NewTokenAuth(nil)
+	auth.AddKey("reader", ScopeRead)
+	auth.AddKey("operator", ScopeRead, ScopeRobot+"Robot1")
+	a.Use(RateLimit(0.001, 2))
+	a.Use(auth.Middleware)
+	server := httptest.NewServer(a)
+	defer server.Close()
+	url := "ws" + strings.TrimPrefix(server.URL, "http") + "/api/socket"
+
+	// the upgrade only needs read access
+	ws, err := websocket.Dial(url+"?access_token=reader", "", server.URL)
+	gobottest.Assert(t, err, nil)
+	reply := sendSocket(t, ws, SocketMessage{Type: "command", Robot: "Robot1", Command: "robotTestFunction"})
+	gobottest.Assert(t, reply.Error, "Token does not have the scope robot:Robot1")
+	ws.Close()
+
+	config, _ := websocket.NewConfig(url, server.URL)
+	config.Protocol = []string{"gobot", SocketTokenProtocol + "operator"}
+	ws, err = websocket.DialConfig(config)
+	gobottest.Assert(t, err, nil)
+	defer ws.Close()
+	gobottest.Assert(t, ws.Config().Protocol, []string{"gobot"})
+
+	// commands are not charged to the rate limit of requests
+	for i := 0; i < 3; i++ {
+		reply = sendSocket(t, ws, SocketMessage{Type: "command", Robot: "Robot1", Command: "robotTestFunction",
+			Params: map[string]interface{}{"message": "fox", "robot": "Robot1"}})
+		gobottest.Assert(t, reply.Result, "hey Robot1, fox")
+	}
+}
@@ -0,0 +1,237 @@
+package gobot
+
+import (
+	"sync"
+	"time"
+)
+
+// BehaviourChanged is published by a BehaviourTree, and by the Robot it was
+// added to, each time the status of the tree's root changes. Its data is a
+// BehaviourChange.
+const BehaviourChanged = "behaviour-changed"
+
+// DefaultBehaviourInterval is the time between ticks of a BehaviourTree
+// when none is given.
+const DefaultBehaviourInterval = 100 * time.Millisecond
+
+// BehaviourStatus is the result of ticking a Behaviour.
+type BehaviourStatus string
+
+const (
+	// Running behaviours have not finished, and are ticked again
+	Running BehaviourStatus = "running"
+	// Success is returned by behaviours which have finished successfully
+	Success BehaviourStatus = "success"
+	// Failure is returned by behaviours which have failed
+	Failure BehaviourStatus = "failure"
+)
+
+// Behaviour is a node of a BehaviourTree.
+type Behaviour interface {
+	// Tick runs the behaviour for one step and returns its status
+	Tick() BehaviourStatus
+}
+
+// BehaviourFunc is a Behaviour which calls itself on every tick.
+type BehaviourFunc func() BehaviourStatus
+
+// Tick calls f.
+func (f BehaviourFunc) Tick() BehaviourStatus {
+	return f()
+}
+
+// Action returns a Behaviour which calls f and succeeds, for steps which
+// always complete in a single tick.
+func Action(f func()) Behaviour {
+	return BehaviourFunc(func() BehaviourStatus {
+		f()
+		return Success
+	})
+}
+
+// Condition returns a Behaviour which succeeds when f returns true and fails
+// otherwise.
+func Condition(f func() bool) Behaviour {
+	return BehaviourFunc(func() BehaviourStatus {
+		if f() {
+			return Success
+		}
+		return Failure
+	})
+}
+
+// Sequence returns a Behaviour which ticks children in order until one of
+// them does not succeed, and returns its status. It succeeds when every
+// child succeeds.
+func Sequence(children ...Behaviour) Behaviour {
+	return BehaviourFunc(func() BehaviourStatus {
+		for _, child := range children {
+			if status := child.Tick(); status != Success {
+				return status
+			}
+		}
+		return Success
+	})
+}
+
+// Selector returns a Behaviour which ticks children in order until one of
+// them does not fail, and returns its status. It fails when every child
+// fails.
+func Selector(children ...Behaviour) Behaviour {
+	return BehaviourFunc(func() BehaviourStatus {
+		for _, child := range children {
+			if status := child.Tick(); status != Failure {
+				return status
+			}
+		}
+		return Failure
+	})
+}
+
+// Invert returns a Behaviour which fails when b succeeds and succeeds when b
+// fails.
+func Invert(b Behaviour) Behaviour {
+	return BehaviourFunc(func() BehaviourStatus {
+		switch status := b.Tick(); status {
+		case Success:
+			return Failure
+		case Failure:
+			return Success
+		default:
+			return status
+		}
+	})
+}
+
+// BehaviourChange describes a change in the status of a BehaviourTree.
+type BehaviourChange struct {
+	Tree   string          `json:"tree"`
+	From   BehaviourStatus `json:"from"`
+	To     BehaviourStatus `json:"to"`
+	Ticked time.Time       `json:"ticked"`
+}
+
+// BehaviourTree ticks the root of a tree of behaviours every Interval while
+// the Robot it was added to is running. An Interval which is not positive
+// is taken as DefaultBehaviourInterval.
+type BehaviourTree struct {
+	Name     string
+	Root     Behaviour
+	Interval time.Duration
+	Eventer
+
+	mtx    sync.Mutex
+	tick   sync.Mutex
+	status BehaviourStatus
+	robot  *Robot
+	job    *Job
+}
+
+// NewBehaviourTree returns a new BehaviourTree which ticks root every
+// DefaultBehaviourInterval, or every v[0] when given and positive.
+func NewBehaviourTree(name string, root Behaviour, v ...time.Duration) *BehaviourTree {
+	t := &BehaviourTree{
+		Name:     name,
+		Root:     root,
+		Interval: DefaultBehaviourInterval,
+		Eventer:  NewEventer(),
+	}
+	if len(v) > 0 && v[0] > 0 {
+		t.Interval = v[0]
+	}
+	t.AddEvent(BehaviourChanged)
+	return t
+}
+
+// Status returns the status returned by the last tick of the root, empty
+// before the first tick.
+func (t *BehaviourTree) Status() BehaviourStatus {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+	return t.status
+}
+
+// Tick ticks the root once and returns its status, publishing
+// BehaviourChanged when the status differs from the last tick. Ticks never
+// overlap.
+func (t *BehaviourTree) Tick() BehaviourStatus {
+	t.tick.Lock()
+	defer t.tick.Unlock()
+
+	status := t.Root.Tick()
+
+	t.mtx.Lock()
+	from := t.status
+	t.status = status
+	robot := t.robot
+	t.mtx.Unlock()
+
+	if status != from {
+		change := BehaviourChange{Tree: t.Name, From: from, To: status, Ticked: time.Now()}
+		t.Publish(BehaviourChanged, change)
+		if robot != nil {
+			robot.Publish(BehaviourChanged, change)
+		}
+	}
+	return status
+}
+
+// start ticks the tree on the Robot's Scheduler.
+func (t *BehaviourTree) start(r *Robot) {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+	if t.job == nil {
+		interval := t.Interval
+		if interval <= 0 {
+			interval = DefaultBehaviourInterval
+		}
+		t.job = r.Every(interval, func() { t.Tick() })
+	}
+}
+
+func (t *BehaviourTree) stop() {
+	t.mtx.Lock()
+	job := t.job
+	t.job = nil
+	t.mtx.Unlock()
+	if job != nil {
+		job.Cancel()
+	}
+}
+
+// JSONBehaviourTree is a JSON representation of a BehaviourTree.
+type JSONBehaviourTree struct {
+	Name   string          `json:"name"`
+	Status BehaviourStatus `json:"status"`
+}
+
+// AddBehaviourTree adds t to the Robot. The tree is ticked while the Robot
+// is running, and its BehaviourChanged events are also published by the
+// Robot.
+func (r *Robot) AddBehaviourTree(t *BehaviourTree) {
+	t.mtx.Lock()
+	t.robot = r
+	t.mtx.Unlock()
+
+	r.mtx.Lock()
+	r.trees = append(r.trees, t)
+	running := r.running
+	r.mtx.Unlock()
+
+	if running {
+		t.start(r)
+	}
+}
+
+// BehaviourTree returns the behaviour tree called name, or nil if there is
+// none.
+func (r *Robot) BehaviourTree(name string) *BehaviourTree {
+	r.mtx.RLock()
+	defer r.mtx.RUnlock()
+	for _, t := range r.trees {
+		if t.Name == name {
+			return t
+		}
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+package gobot
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hybridgroup/gobot/gobottest"
+)
+
+func TestBehaviours(t *testing.T) {
+	var ticked []string
+	step := func(name string, status BehaviourStatus) Behaviour {
+		return BehaviourFunc(func() BehaviourStatus {
+			ticked = append(ticked, name)
+			return status
+		})
+	}
+
+	gobottest.Assert(t, Sequence(step("a", Success), step("b", Running), step("c", Success)).Tick(), Running)
+	gobottest.Assert(t, ticked, []string{"a", "b"})
+
+	ticked = nil
+	gobottest.Assert(t, Selector(step("a", Failure), step("b", Success), step("c", Success)).Tick(), Success)
+	gobottest.Assert(t, ticked, []string{"a", "b"})
+
+	gobottest.Assert(t, Sequence().Tick(), Success)
+	gobottest.Assert(t, Selector().Tick(), Failure)
+	gobottest.Assert(t, Invert(Condition(func() bool { return true })).Tick(), Failure)
+	gobottest.Assert(t, Invert(Condition(func() bool { return false })).Tick(), Success)
+	gobottest.Assert(t, Invert(step("r", Running)).Tick(), Running)
+
+	acted := false
+	gobottest.Assert(t, Action(func() { acted = true }).Tick(), Success)
+	gobottest.Assert(t, acted, true)
+}
+
+func TestBehaviourTreeTick(t *testing.T) {
+	status := Running
+	tree := NewBehaviourTree("patrol", BehaviourFunc(func() BehaviourStatus { return status }))
+	gobottest.Assert(t, tree.Interval, DefaultBehaviourInterval)
+	gobottest.Assert(t, NewBehaviourTree("patrol", tree.Root, 0).Interval, DefaultBehaviourInterval)
+	gobottest.Assert(t, NewBehaviourTree("patrol", tree.Root, -time.Second).Interval, DefaultBehaviourInterval)
+	changes := tree.Subscribe()
+
+	gobottest.Assert(t, tree.Status(), BehaviourStatus(""))
+	tree.Tick()
+	tree.Tick()
+	status = Success
+	tree.Tick()
+
+	gobottest.Assert(t, len(changes), 2)
+	gobottest.Assert(t, (<-changes).Data.(BehaviourChange).To, Running)
+	change := (<-changes).Data.(BehaviourChange)
+	gobottest.Assert(t, change.From, Running)
+	gobottest.Assert(t, change.To, Success)
+	gobottest.Assert(t, tree.Status(), Success)
+}
+
+func TestRobotBehaviourTree(t *testing.T) {
+	r := newTestRobot("Robot99")
+	ticks := make(chan bool, 100)
+	tree := NewBehaviourTree("patrol", Action(func() { ticks <- true }), time.Millisecond)
+	r.AddBehaviourTree(tree)
+	gobottest.Assert(t, r.BehaviourTree("patrol"), tree)
+	gobottest.Assert(t, r.BehaviourTree("none"), (*BehaviourTree)(nil))
+
+	changes := r.Subscribe(SubscriberOptions{Buffer: 8, Filter: func(e *Event) bool { return e.Name == BehaviourChanged }})
+	r.Start()
+	<-ticks
+	<-ticks
+	select {
+	case evt := <-changes:
+		gobottest.Assert(t, evt.Data.(BehaviourChange).To, Success)
+	case <-time.After(time.Second):
+		t.Error("behaviour did not change")
+	}
+	gobottest.Assert(t, NewJSONRobot(r).BehaviourTrees, []*JSONBehaviourTree{{Name: "patrol", Status: Success}})
+
+	r.Stop()
+	tree.mtx.Lock()
+	gobottest.Assert(t, tree.job, (*Job)(nil))
+	tree.mtx.Unlock()
+}
+
+func TestBehaviourTreeZeroInterval(t *testing.T) {
+	r := newTestRobot("Robot99")
+	tree := NewBehaviourTree("patrol", Action(func() {}))
+	tree.Interval = 0
+	r.AddBehaviourTree(tree)
+	gobottest.Assert(t, len(r.Start()), 0)
+	gobottest.Assert(t, len(r.Stop()), 0)
+}
@@ -1,7 +1,23 @@
 package gobot
 
+import (
+	"fmt"
+	"math"
+	"sync"
+	"time"
+)
+
 type commander struct {
 	commands map[string]func(map[string]interface{}) interface{}
+	schemas  map[string]*CommandSchema
+
+	mtx sync.RWMutex
+	// names of the robot and device that own the commander
+	robot  string
+	device string
+	// registry the metrics of commands are recorded in, the
+	// DefaultRegistry when nil
+	registry *Registry
 }
 
 // Commander is the interface which describes the behaviour for a Driver or Adaptor
@@ -13,24 +29,290 @@ type Commander interface {
 	Commands() (commands map[string]func(map[string]interface{}) interface{})
 	// AddCommand adds a command given a name.
 	AddCommand(name string, command func(map[string]interface{}) interface{})
+	// AddCommandSchema adds a command described by schema. Its parameters are
+	// validated against the schema before the command runs.
+	AddCommandSchema(schema CommandSchema, command func(map[string]interface{}) interface{})
+	// CommandSchema returns the schema of a command given a name. Returns nil
+	// if the command was added without a schema.
+	CommandSchema(name string) (schema *CommandSchema)
+	// SetCommandSource sets the robot and device names used to label the
+	// metrics of commands.
+	SetCommandSource(robot, device string)
+	// SetCommandRegistry sets the Registry the metrics of commands are
+	// recorded in, in place of the DefaultRegistry.
+	SetCommandRegistry(r *Registry)
+}
+
+// ParamType is the type of a command parameter, named after its JSON type.
+type ParamType string
+
+const (
+	// StringParam is a string parameter
+	StringParam ParamType = "string"
+	// NumberParam is a numeric parameter, passed to the command as a float64
+	NumberParam ParamType = "number"
+	// IntegerParam is a whole number parameter, passed to the command as a
+	// float64
+	IntegerParam ParamType = "integer"
+	// BooleanParam is a bool parameter
+	BooleanParam ParamType = "boolean"
+	// ObjectParam is a map[string]interface{} parameter
+	ObjectParam ParamType = "object"
+	// ArrayParam is a []interface{} parameter
+	ArrayParam ParamType = "array"
+)
+
+// Range is the inclusive range allowed for a numeric parameter.
+type Range struct {
+	Min float64 `json:"min"`
+	Max float64 `json:"max"`
+}
+
+// Param describes a command parameter.
+type Param struct {
+	Name        string        `json:"name"`
+	Type        ParamType     `json:"type,omitempty"`
+	Description string        `json:"description,omitempty"`
+	Required    bool          `json:"required,omitempty"`
+	Default     interface{}   `json:"default,omitempty"`
+	Range       *Range        `json:"range,omitempty"`
+	Enum        []interface{} `json:"enum,omitempty"`
+}
+
+// CommandSchema describes a command and the parameters it accepts.
+type CommandSchema struct {
+	Name        string  `json:"name"`
+	Description string  `json:"description,omitempty"`
+	Params      []Param `json:"params"`
+}
+
+// ParamError is returned in place of a command's result when the parameters
+// it was called with do not match its CommandSchema.
+type ParamError struct {
+	Command string
+	Param   string
+	Reason  string
+}
+
+func (e *ParamError) Error() string {
+	return fmt.Sprintf("command %q: param %q %v", e.Command, e.Param, e.Reason)
+}
+
+// Validate checks params against the schema. It returns a copy of params
+// with defaults filled in and numbers converted to float64, or a
+// *ParamError describing the first parameter which is invalid. Params not
+// described by the schema are passed through unchanged.
+func (s *CommandSchema) Validate(params map[string]interface{}) (map[string]interface{}, error) {
+	valid := make(map[string]interface{}, len(params))
+	for k, v := range params {
+		valid[k] = v
+	}
+
+	for _, p := range s.Params {
+		v, ok := valid[p.Name]
+		if !ok || v == nil {
+			if p.Default == nil {
+				if p.Required {
+					return nil, &ParamError{Command: s.Name, Param: p.Name, Reason: "is required"}
+				}
+				continue
+			}
+			v = p.Default
+		}
+
+		v, reason := p.check(v)
+		if reason != "" {
+			return nil, &ParamError{Command: s.Name, Param: p.Name, Reason: reason}
+		}
+		valid[p.Name] = v
+	}
+	return valid, nil
+}
+
+// check returns v converted to the type of the parameter, or the reason it
+// is not valid.
+func (p *Param) check(v interface{}) (interface{}, string) {
+	switch p.Type {
+	case NumberParam, IntegerParam:
+		kind := "a number"
+		if p.Type == IntegerParam {
+			kind = "an integer"
+		}
+		f, ok := toFloat64(v)
+		if !ok {
+			return nil, fmt.Sprintf("must be %v, got %T", kind, v)
+		}
+		if p.Type == IntegerParam && f != math.Trunc(f) {
+			return nil, fmt.Sprintf("must be %v, got %v", kind, f)
+		}
+		if p.Range != nil && (f < p.Range.Min || f > p.Range.Max) {
+			return nil, fmt.Sprintf("must be between %v and %v, got %v", p.Range.Min, p.Range.Max, f)
+		}
+		v = f
+	case StringParam:
+		if _, ok := v.(string); !ok {
+			return nil, fmt.Sprintf("must be a string, got %T", v)
+		}
+	case BooleanParam:
+		if _, ok := v.(bool); !ok {
+			return nil, fmt.Sprintf("must be a boolean, got %T", v)
+		}
+	case ObjectParam:
+		if _, ok := v.(map[string]interface{}); !ok {
+			return nil, fmt.Sprintf("must be an object, got %T", v)
+		}
+	case ArrayParam:
+		if _, ok := v.([]interface{}); !ok {
+			return nil, fmt.Sprintf("must be an array, got %T", v)
+		}
+	}
+
+	if len(p.Enum) > 0 {
+		for _, e := range p.Enum {
+			if ef, ok := toFloat64(e); ok {
+				if vf, ok := v.(float64); ok && vf == ef {
+					return v, ""
+				}
+			} else if e == v {
+				return v, ""
+			}
+		}
+		return nil, fmt.Sprintf("must be one of %v, got %v", p.Enum, v)
+	}
+	return v, ""
+}
+
+func toFloat64(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case float32:
+		return float64(n), true
+	case int:
+		return float64(n), true
+	case int8:
+		return float64(n), true
+	case int16:
+		return float64(n), true
+	case int32:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case uint:
+		return float64(n), true
+	case uint8:
+		return float64(n), true
+	case uint16:
+		return float64(n), true
+	case uint32:
+		return float64(n), true
+	case uint64:
+		return float64(n), true
+	}
+	return 0, false
 }
 
 // NewCommander returns a new Commander.
 func NewCommander() Commander {
 	return &commander{
 		commands: make(map[string]func(map[string]interface{}) interface{}),
+		schemas:  make(map[string]*CommandSchema),
 	}
 }
 
 func (c *commander) Command(name string) (command func(map[string]interface{}) interface{}) {
+	c.mtx.RLock()
+	defer c.mtx.RUnlock()
 	command, _ = c.commands[name]
 	return
 }
 
 func (c *commander) Commands() map[string]func(map[string]interface{}) interface{} {
-	return c.commands
+	c.mtx.RLock()
+	defer c.mtx.RUnlock()
+	commands := make(map[string]func(map[string]interface{}) interface{}, len(c.commands))
+	for name, command := range c.commands {
+		commands[name] = command
+	}
+	return commands
 }
 
 func (c *commander) AddCommand(name string, command func(map[string]interface{}) interface{}) {
-	c.commands[name] = command
+	f := c.instrument(name, command)
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	c.commands[name] = f
+	delete(c.schemas, name)
+}
+
+func (c *commander) AddCommandSchema(schema CommandSchema, command func(map[string]interface{}) interface{}) {
+	s := &schema
+	f := c.instrument(s.Name, func(params map[string]interface{}) interface{} {
+		valid, err := s.Validate(params)
+		if err != nil {
+			return err
+		}
+		return command(valid)
+	})
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	c.commands[s.Name] = f
+	c.schemas[s.Name] = s
+}
+
+func (c *commander) SetCommandSource(robot, device string) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	c.robot, c.device = robot, device
+}
+
+func (c *commander) SetCommandRegistry(r *Registry) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	c.registry = r
+}
+
+// instrument wraps command so that its calls are counted and timed in the
+// commander's Registry. Calls returning an error are counted separately.
+func (c *commander) instrument(name string, command func(map[string]interface{}) interface{}) func(map[string]interface{}) interface{} {
+	return func(params map[string]interface{}) interface{} {
+		begin := time.Now()
+		result := command(params)
+		elapsed := time.Since(begin)
+
+		c.mtx.RLock()
+		labels := Labels{"robot": c.robot, "device": c.device, "command": name}
+		r := c.registry
+		c.mtx.RUnlock()
+
+		if r == nil {
+			r = DefaultRegistry()
+		}
+		r.Histogram("gobot_command_duration_seconds", "Time taken to run commands.", nil, labels).
+			Observe(elapsed.Seconds())
+		labels["result"] = "ok"
+		if _, ok := result.(error); ok {
+			labels["result"] = "error"
+		}
+		r.Counter("gobot_commands_total", "Commands run.", labels).Inc()
+		return result
+	}
+}
+
+func (c *commander) CommandSchema(name string) *CommandSchema {
+	c.mtx.RLock()
+	defer c.mtx.RUnlock()
+	return c.schemas[name]
+}
+
+// commandSchemas returns the schemas of every command of c which has one,
+// keyed by command name.
+func commandSchemas(c Commander) map[string]*CommandSchema {
+	schemas := make(map[string]*CommandSchema)
+	for name := range c.Commands() {
+		if schema := c.CommandSchema(name); schema != nil {
+			schemas[name] = schema
+		}
+	}
+	return schemas
 }
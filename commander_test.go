@@ -1,6 +1,7 @@
 package gobot
 
 import (
+	"errors"
 	"testing"
 
 	"github.com/hybridgroup/gobot/gobottest"
@@ -22,3 +23,94 @@ func TestCommaner(t *testing.T) {
 	command = c.Command("booyeah")
 	gobottest.Assert(t, command, (func(map[string]interface{}) interface{})(nil))
 }
+
+func TestCommanderMetrics(t *testing.T) {
+	r := NewRegistry()
+	c := NewCommander()
+	c.SetCommandSource("metricsbot", "led")
+	c.SetCommandRegistry(r)
+	c.AddCommand("on", func(map[string]interface{}) interface{} { return nil })
+	c.AddCommand("off", func(map[string]interface{}) interface{} { return errors.New("off failed") })
+
+	c.Command("on")(nil)
+	c.Command("on")(nil)
+	c.Command("off")(nil)
+
+	labels := Labels{"robot": "metricsbot", "device": "led", "command": "on", "result": "ok"}
+	gobottest.Assert(t, r.Counter("gobot_commands_total", "", labels).Value(), 2.0)
+	labels["command"], labels["result"] = "off", "error"
+	gobottest.Assert(t, r.Counter("gobot_commands_total", "", labels).Value(), 1.0)
+
+	h := r.Histogram("gobot_command_duration_seconds", "", nil,
+		Labels{"robot": "metricsbot", "device": "led", "command": "on"})
+	gobottest.Assert(t, h.count, uint64(2))
+}
+
+func TestCommanderSchema(t *testing.T) {
+	c := NewCommander()
+	c.AddCommandSchema(CommandSchema{
+		Name: "move",
+		Params: []Param{
+			{Name: "speed", Type: IntegerParam, Required: true, Range: &Range{Min: 0, Max: 100}},
+			{Name: "direction", Type: StringParam, Default: "forward", Enum: []interface{}{"forward", "backward"}},
+		},
+	}, func(params map[string]interface{}) interface{} {
+		return params
+	})
+
+	gobottest.Refute(t, c.CommandSchema("move"), (*CommandSchema)(nil))
+	gobottest.Assert(t, c.CommandSchema("booyeah"), (*CommandSchema)(nil))
+
+	result := c.Command("move")(map[string]interface{}{"speed": 10})
+	gobottest.Assert(t, result, map[string]interface{}{"speed": 10.0, "direction": "forward"})
+
+	result = c.Command("move")(map[string]interface{}{})
+	gobottest.Assert(t, result, &ParamError{Command: "move", Param: "speed", Reason: "is required"})
+
+	result = c.Command("move")(map[string]interface{}{"speed": 101.0})
+	gobottest.Assert(t, result.(*ParamError).Error(), `command "move": param "speed" must be between 0 and 100, got 101`)
+
+	result = c.Command("move")(map[string]interface{}{"speed": 1.5})
+	gobottest.Assert(t, result.(*ParamError).Param, "speed")
+
+	result = c.Command("move")(map[string]interface{}{"speed": "fast"})
+	gobottest.Assert(t, result.(*ParamError).Reason, "must be an integer, got string")
+
+	result = c.Command("move")(map[string]interface{}{"speed": 1.0, "direction": "up"})
+	gobottest.Assert(t, result.(*ParamError).Param, "direction")
+
+	c.AddCommand("move", func(map[string]interface{}) interface{} { return nil })
+	gobottest.Assert(t, c.CommandSchema("move"), (*CommandSchema)(nil))
+}
+
+func TestCommandSchemaValidate(t *testing.T) {
+	s := &CommandSchema{
+		Name: "set",
+		Params: []Param{
+			{Name: "on", Type: BooleanParam},
+			{Name: "mode", Type: IntegerParam, Enum: []interface{}{1, 2}},
+		},
+	}
+
+	params := map[string]interface{}{"on": true, "mode": uint8(2), "extra": "x"}
+	valid, err := s.Validate(params)
+	gobottest.Assert(t, err, nil)
+	gobottest.Assert(t, valid, map[string]interface{}{"on": true, "mode": 2.0, "extra": "x"})
+	gobottest.Assert(t, params["mode"], uint8(2))
+
+	_, err = s.Validate(map[string]interface{}{"on": "yes"})
+	gobottest.Assert(t, err.(*ParamError).Param, "on")
+
+	_, err = s.Validate(map[string]interface{}{"mode": 3})
+	gobottest.Assert(t, err.(*ParamError).Param, "mode")
+
+	// defaults are checked and converted like given params
+	s.Params[1].Default = 1
+	valid, err = s.Validate(map[string]interface{}{})
+	gobottest.Assert(t, err, nil)
+	gobottest.Assert(t, valid, map[string]interface{}{"mode": 1.0})
+
+	s.Params[1].Default = 3
+	_, err = s.Validate(map[string]interface{}{})
+	gobottest.Assert(t, err.(*ParamError).Param, "mode")
+}
@@ -0,0 +1,372 @@
+package gobot
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Config describes a Gobot and its robots, as loaded from a YAML or JSON
+// file by LoadConfig.
+type Config struct {
+	// LogLevel is the minimum level logged, "info" when empty
+	LogLevel string `json:"log_level,omitempty" yaml:"log_level,omitempty"`
+	// Store is the path of the file the robots keep their state in, which
+	// is kept in memory when empty
+	Store  string        `json:"store,omitempty" yaml:"store,omitempty"`
+	Robots []RobotConfig `json:"robots" yaml:"robots"`
+	// Rules link the events and commands of the robots' devices
+	Rules []Rule `json:"rules,omitempty" yaml:"rules,omitempty"`
+}
+
+// RobotConfig describes a Robot and its connections and devices.
+type RobotConfig struct {
+	Name        string             `json:"name" yaml:"name"`
+	Connections []ConnectionConfig `json:"connections" yaml:"connections"`
+	Devices     []DeviceConfig     `json:"devices" yaml:"devices"`
+	// Macros are added to the robot as commands, once its devices are
+	Macros []Macro `json:"macros,omitempty" yaml:"macros,omitempty"`
+}
+
+// ConnectionConfig describes a Connection. Type is the name its adaptor was
+// registered with by RegisterAdaptor.
+type ConnectionConfig struct {
+	Name    string                 `json:"name" yaml:"name"`
+	Type    string                 `json:"type" yaml:"type"`
+	Port    string                 `json:"port,omitempty" yaml:"port,omitempty"`
+	Options map[string]interface{} `json:"options,omitempty" yaml:"options,omitempty"`
+}
+
+// DeviceConfig describes a Device. Type is the name its driver was
+// registered with by RegisterDriver. Connection names the connection the
+// device uses, and may be left empty when the robot has only one.
+type DeviceConfig struct {
+	Name       string                 `json:"name" yaml:"name"`
+	Type       string                 `json:"type" yaml:"type"`
+	Connection string                 `json:"connection,omitempty" yaml:"connection,omitempty"`
+	Pin        string                 `json:"pin,omitempty" yaml:"pin,omitempty"`
+	Interval   Duration               `json:"interval,omitempty" yaml:"interval,omitempty"`
+	Options    map[string]interface{} `json:"options,omitempty" yaml:"options,omitempty"`
+}
+
+// Intervals returns the polling interval of the device, ready to be passed
+// to the variadic interval argument of driver constructors. It is empty when
+// no interval was configured, so the driver's default is used.
+func (c DeviceConfig) Intervals() []time.Duration {
+	if c.Interval == 0 {
+		return nil
+	}
+	return []time.Duration{time.Duration(c.Interval)}
+}
+
+// Option returns the option called name as a string, or def when it is not
+// set.
+func (c DeviceConfig) Option(name, def string) string {
+	return option(c.Options, name, def)
+}
+
+// Option returns the option called name as a string, or def when it is not
+// set.
+func (c ConnectionConfig) Option(name, def string) string {
+	return option(c.Options, name, def)
+}
+
+func option(options map[string]interface{}, name, def string) string {
+	if v, ok := options[name]; ok && v != nil {
+		return fmt.Sprint(v)
+	}
+	return def
+}
+
+// Duration is a time.Duration written in config files as a string such as
+// "500ms" or "2s".
+type Duration time.Duration
+
+// UnmarshalJSON parses a duration string, or a number of nanoseconds.
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var v interface{}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	return d.set(v)
+}
+
+// UnmarshalYAML parses a duration string, or a number of nanoseconds.
+func (d *Duration) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var v interface{}
+	if err := unmarshal(&v); err != nil {
+		return err
+	}
+	return d.set(v)
+}
+
+// MarshalJSON writes the duration as a string.
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+func (d *Duration) set(v interface{}) error {
+	switch v := v.(type) {
+	case string:
+		parsed, err := time.ParseDuration(v)
+		if err != nil {
+			return err
+		}
+		*d = Duration(parsed)
+	case float64:
+		*d = Duration(v)
+	case int:
+		*d = Duration(v)
+	default:
+		return fmt.Errorf("Invalid duration %v", v)
+	}
+	return nil
+}
+
+// AdaptorFactory returns a new Connection described by config.
+type AdaptorFactory func(config ConnectionConfig) (Connection, error)
+
+// DriverFactory returns a new Device described by config, which uses
+// connection.
+type DriverFactory func(connection Connection, config DeviceConfig) (Device, error)
+
+var factories = struct {
+	sync.RWMutex
+	adaptors map[string]AdaptorFactory
+	drivers  map[string]DriverFactory
+}{
+	adaptors: make(map[string]AdaptorFactory),
+	drivers:  make(map[string]DriverFactory),
+}
+
+// RegisterAdaptor makes an adaptor available to config files under the
+// connection type name. Adaptor packages call it from init. It panics if
+// name is already registered.
+func RegisterAdaptor(name string, factory AdaptorFactory) {
+	factories.Lock()
+	defer factories.Unlock()
+	if _, ok := factories.adaptors[name]; ok {
+		panic(fmt.Sprintf("adaptor type %q is already registered", name))
+	}
+	factories.adaptors[name] = factory
+}
+
+// RegisterDriver makes a driver available to config files under the device
+// type name. Driver packages call it from init. It panics if name is
+// already registered.
+func RegisterDriver(name string, factory DriverFactory) {
+	factories.Lock()
+	defer factories.Unlock()
+	if _, ok := factories.drivers[name]; ok {
+		panic(fmt.Sprintf("driver type %q is already registered", name))
+	}
+	factories.drivers[name] = factory
+}
+
+// AdaptorTypes returns the sorted names of the registered adaptors.
+func AdaptorTypes() []string {
+	factories.RLock()
+	defer factories.RUnlock()
+	names := []string{}
+	for name := range factories.adaptors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// DriverTypes returns the sorted names of the registered drivers.
+func DriverTypes() []string {
+	factories.RLock()
+	defer factories.RUnlock()
+	names := []string{}
+	for name := range factories.drivers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// LoadConfig reads the Config in the file at path. Files ending in .json are
+// read as JSON, and any other file as YAML.
+func LoadConfig(path string) (*Config, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	format := "yaml"
+	if strings.ToLower(filepath.Ext(path)) == ".json" {
+		format = "json"
+	}
+	return ParseConfig(data, format)
+}
+
+// ParseConfig parses data, in the format "json" or "yaml", into a Config.
+func ParseConfig(data []byte, format string) (config *Config, err error) {
+	config = &Config{}
+	switch format {
+	case "json":
+		err = json.Unmarshal(data, config)
+	case "yaml", "yml":
+		err = yaml.Unmarshal(data, config)
+		for i := range config.Robots {
+			for j := range config.Robots[i].Connections {
+				c := &config.Robots[i].Connections[j]
+				c.Options = stringKeys(c.Options).(map[string]interface{})
+			}
+			for j := range config.Robots[i].Devices {
+				d := &config.Robots[i].Devices[j]
+				d.Options = stringKeys(d.Options).(map[string]interface{})
+			}
+			for _, m := range config.Robots[i].Macros {
+				for j := range m.Steps {
+					step := &m.Steps[j]
+					step.Params = stringKeys(step.Params).(map[string]interface{})
+					if step.If != nil {
+						step.If.Value = stringKeys(step.If.Value)
+					}
+				}
+			}
+		}
+		for i := range config.Rules {
+			then := &config.Rules[i].Then
+			then.Params = stringKeys(then.Params).(map[string]interface{})
+		}
+	default:
+		return nil, fmt.Errorf("Unknown config format %q", format)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// stringKeys converts the map[interface{}]interface{} values produced by
+// the YAML decoder into the map[string]interface{} values which JSON
+// produces, so options look the same whichever format they came from.
+func stringKeys(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, value := range v {
+			v[k] = stringKeys(value)
+		}
+		return v
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for k, value := range v {
+			m[fmt.Sprint(k)] = stringKeys(value)
+		}
+		return m
+	case []interface{}:
+		for i, value := range v {
+			v[i] = stringKeys(value)
+		}
+	}
+	return v
+}
+
+// NewGobotFromConfig returns a new Gobot with the robots and rules described
+// by config. Work functions are not part of the config; set them on the robots
+// before starting the Gobot.
+func NewGobotFromConfig(config *Config) (*Gobot, error) {
+	g := NewGobot()
+	if config.LogLevel != "" {
+		level, err := ParseLogLevel(config.LogLevel)
+		if err != nil {
+			return nil, err
+		}
+		g.SetLogger(NewLogger(nil, level))
+	}
+	if config.Store != "" {
+		store, err := NewFileStore(config.Store)
+		if err != nil {
+			return nil, err
+		}
+		g.SetStore(store)
+	}
+
+	for _, rc := range config.Robots {
+		r, err := NewRobotFromConfig(rc)
+		if err != nil {
+			return nil, err
+		}
+		g.AddRobot(r)
+	}
+	for _, rule := range config.Rules {
+		if err := g.AddRule(rule); err != nil {
+			return nil, err
+		}
+	}
+	return g, nil
+}
+
+// NewRobotFromConfig returns a new Robot with the connections and devices
+// described by config, created by the registered adaptors and drivers, and
+// its macros.
+func NewRobotFromConfig(config RobotConfig) (*Robot, error) {
+	if config.Name == "" {
+		return nil, errors.New("Robot has no name")
+	}
+
+	connections := map[string]Connection{}
+	r := NewRobot(config.Name)
+	for _, cc := range config.Connections {
+		c, err := newConnection(cc)
+		if err != nil {
+			return nil, fmt.Errorf("Robot %q: Connection %q: %w", config.Name, cc.Name, err)
+		}
+		connections[cc.Name] = r.AddConnection(c)
+	}
+
+	for _, dc := range config.Devices {
+		d, err := newDevice(dc, config.Connections, connections)
+		if err != nil {
+			return nil, fmt.Errorf("Robot %q: Device %q: %w", config.Name, dc.Name, err)
+		}
+		r.AddDevice(d)
+	}
+
+	for _, m := range config.Macros {
+		if err := r.AddMacro(m); err != nil {
+			return nil, fmt.Errorf("Robot %q: %w", config.Name, err)
+		}
+	}
+	return r, nil
+}
+
+func newConnection(config ConnectionConfig) (Connection, error) {
+	factories.RLock()
+	factory, ok := factories.adaptors[config.Type]
+	factories.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("Unknown adaptor type %q", config.Type)
+	}
+	return factory(config)
+}
+
+func newDevice(config DeviceConfig, configs []ConnectionConfig, connections map[string]Connection) (Device, error) {
+	factories.RLock()
+	factory, ok := factories.drivers[config.Type]
+	factories.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("Unknown driver type %q", config.Type)
+	}
+
+	name := config.Connection
+	if name == "" && len(configs) == 1 {
+		name = configs[0].Name
+	}
+	connection, ok := connections[name]
+	if !ok {
+		return nil, errors.New("No Connection found with the name " + name)
+	}
+	return factory(connection, config)
+}
@@ -0,0 +1,207 @@
+package gobot
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/hybridgroup/gobot/gobottest"
+)
+
+func init() {
+	RegisterAdaptor("test", func(config ConnectionConfig) (Connection, error) {
+		return newTestAdaptor(config.Name, config.Port), nil
+	})
+	RegisterDriver("test", func(c Connection, config DeviceConfig) (Device, error) {
+		d := newTestDriver(c.(*testAdaptor), config.Name, config.Pin)
+		d.AddCommand("Interval", func(map[string]interface{}) interface{} {
+			return config.Intervals()
+		})
+		d.AddCommand("Mode", func(map[string]interface{}) interface{} {
+			return config.Option("mode", "normal")
+		})
+		return d, nil
+	})
+}
+
+const testYAMLConfig = `
+log_level: debug
+robots:
+  - name: bot
+    connections:
+      - name: board
+        type: test
+        port: /dev/ttyACM0
+    devices:
+      - name: led
+        type: test
+        pin: 13
+      - name: button
+        type: test
+        pin: "2"
+        interval: 50ms
+        options:
+          mode: fast
+    macros:
+      - name: check
+        steps:
+          - device: button
+            command: Mode
+            params:
+              level:
+                high: true
+          - delay: 10ms
+          - device: led
+            command: Mode
+            if:
+              op: ok
+rules:
+  - name: blink
+    when:
+      robot: bot
+      event: pressed
+      op: ">"
+      value: 1
+      debounce: 10ms
+    then:
+      robot: bot
+      device: led
+      command: Mode
+      params:
+        level:
+          high: true
+`
+
+func TestParseConfigYAML(t *testing.T) {
+	config, err := ParseConfig([]byte(testYAMLConfig), "yaml")
+	gobottest.Assert(t, err, nil)
+	gobottest.Assert(t, config.LogLevel, "debug")
+	gobottest.Assert(t, len(config.Robots), 1)
+
+	robot := config.Robots[0]
+	gobottest.Assert(t, robot.Connections[0], ConnectionConfig{Name: "board", Type: "test", Port: "/dev/ttyACM0"})
+	gobottest.Assert(t, robot.Devices[0].Pin, "13")
+	gobottest.Assert(t, robot.Devices[0].Intervals(), []time.Duration(nil))
+	gobottest.Assert(t, robot.Devices[1].Intervals(), []time.Duration{50 * time.Millisecond})
+	gobottest.Assert(t, robot.Devices[1].Options, map[string]interface{}{"mode": "fast"})
+	gobottest.Assert(t, robot.Macros[0].Steps[0].Params, map[string]interface{}{"level": map[string]interface{}{"high": true}})
+	gobottest.Assert(t, robot.Macros[0].Steps[1].Delay, Duration(10*time.Millisecond))
+	gobottest.Assert(t, robot.Macros[0].Steps[2].If.Op, "ok")
+
+	rule := config.Rules[0]
+	gobottest.Assert(t, rule.When, RuleEvent{Robot: "bot", Event: "pressed", Op: ">", Value: 1,
+		Debounce: Duration(10 * time.Millisecond)})
+	gobottest.Assert(t, rule.Then.Params, map[string]interface{}{"level": map[string]interface{}{"high": true}})
+}
+
+func TestParseConfigJSON(t *testing.T) {
+	config, err := ParseConfig([]byte(`{"robots": [{"name": "bot",
+		"connections": [{"name": "board", "type": "test", "options": {"baud": 9600}}],
+		"devices": [{"name": "led", "type": "test", "pin": "13", "interval": "1s"}]}]}`), "json")
+	gobottest.Assert(t, err, nil)
+	gobottest.Assert(t, config.Robots[0].Connections[0].Option("baud", ""), "9600")
+	gobottest.Assert(t, config.Robots[0].Devices[0].Interval, Duration(time.Second))
+
+	_, err = ParseConfig([]byte(`{}`), "toml")
+	gobottest.Assert(t, err.Error(), `Unknown config format "toml"`)
+}
+
+func TestLoadConfig(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "gobot")
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "robots.yml")
+	ioutil.WriteFile(path, []byte(testYAMLConfig), 0644)
+	config, err := LoadConfig(path)
+	gobottest.Assert(t, err, nil)
+	gobottest.Assert(t, config.Robots[0].Name, "bot")
+
+	path = filepath.Join(dir, "robots.json")
+	ioutil.WriteFile(path, []byte(`{"robots": [{"name": "jsonbot"}]}`), 0644)
+	config, err = LoadConfig(path)
+	gobottest.Assert(t, err, nil)
+	gobottest.Assert(t, config.Robots[0].Name, "jsonbot")
+
+	_, err = LoadConfig(filepath.Join(dir, "missing.yml"))
+	gobottest.Refute(t, err, nil)
+}
+
+func TestNewGobotFromConfig(t *testing.T) {
+	config, _ := ParseConfig([]byte(testYAMLConfig), "yaml")
+	g, err := NewGobotFromConfig(config)
+	gobottest.Assert(t, err, nil)
+
+	r := g.Robot("bot")
+	gobottest.Refute(t, r, (*Robot)(nil))
+	gobottest.Assert(t, r.Connection("board").(Porter).Port(), "/dev/ttyACM0")
+	gobottest.Assert(t, r.Device("led").(Pinner).Pin(), "13")
+	gobottest.Assert(t, r.Device("led").Connection().Name(), "board")
+
+	button := r.Device("button").(Commander)
+	gobottest.Assert(t, button.Command("Interval")(nil), []time.Duration{50 * time.Millisecond})
+	gobottest.Assert(t, button.Command("Mode")(nil), "fast")
+	gobottest.Assert(t, g.Rule("blink").Then.Device, "led")
+
+	result := r.Command("check")(nil).(*MacroResult)
+	gobottest.Assert(t, result.Steps[0].Result, "fast")
+	gobottest.Assert(t, result.Steps[2].Result, "normal")
+}
+
+func TestNewGobotFromConfigStore(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "gobot")
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "state.json")
+	ioutil.WriteFile(path, []byte(`{"bot/count": 3}`), 0644)
+
+	g, err := NewGobotFromConfig(&Config{Store: path, Robots: []RobotConfig{{Name: "bot"}}})
+	gobottest.Assert(t, err, nil)
+	gobottest.Assert(t, g.Store().(*FileStore).Path(), path)
+	var count int
+	g.Robot("bot").Store().Get("count", &count)
+	gobottest.Assert(t, count, 3)
+
+	ioutil.WriteFile(path, []byte(`[`), 0644)
+	_, err = NewGobotFromConfig(&Config{Store: path})
+	gobottest.Refute(t, err, nil)
+}
+
+func TestNewRobotFromConfigErrors(t *testing.T) {
+	_, err := NewRobotFromConfig(RobotConfig{})
+	gobottest.Assert(t, err.Error(), "Robot has no name")
+
+	_, err = NewRobotFromConfig(RobotConfig{Name: "bot",
+		Connections: []ConnectionConfig{{Name: "board", Type: "nope"}},
+	})
+	gobottest.Assert(t, err.Error(), `Robot "bot": Connection "board": Unknown adaptor type "nope"`)
+
+	_, err = NewRobotFromConfig(RobotConfig{Name: "bot",
+		Devices: []DeviceConfig{{Name: "led", Type: "nope"}},
+	})
+	gobottest.Assert(t, err.Error(), `Robot "bot": Device "led": Unknown driver type "nope"`)
+
+	_, err = NewRobotFromConfig(RobotConfig{Name: "bot",
+		Connections: []ConnectionConfig{{Name: "a", Type: "test"}, {Name: "b", Type: "test"}},
+		Devices:     []DeviceConfig{{Name: "led", Type: "test"}},
+	})
+	gobottest.Assert(t, err.Error(), `Robot "bot": Device "led": No Connection found with the name `)
+
+	_, err = NewRobotFromConfig(RobotConfig{Name: "bot",
+		Macros: []Macro{{Name: "m", Steps: []MacroStep{{Command: "nope"}}}},
+	})
+	gobottest.Assert(t, err.Error(), `Robot "bot": Macro "m": Step 0: No Command found with the name nope`)
+
+	_, err = NewGobotFromConfig(&Config{LogLevel: "loud"})
+	gobottest.Refute(t, err, nil)
+}
+
+func TestRegisterDuplicate(t *testing.T) {
+	gobottest.Assert(t, AdaptorTypes(), []string{"test"})
+	gobottest.Assert(t, DriverTypes(), []string{"test"})
+
+	defer func() {
+		gobottest.Assert(t, recover(), `driver type "test" is already registered`)
+	}()
+	RegisterDriver("test", nil)
+}
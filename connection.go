@@ -1,23 +1,32 @@
 package gobot
 
 import (
+	"context"
 	"fmt"
-	"log"
 	"reflect"
+	"time"
 )
 
 // JSONConnection is a JSON representation of a Connection.
 type JSONConnection struct {
 	Name    string `json:"name"`
 	Adaptor string `json:"adaptor"`
+	// State is the health of the connection while its robot is supervised
+	State ConnectionState `json:"state,omitempty"`
+	// Pins maps each pin reserved on the connection to the device holding it
+	Pins map[string]string `json:"pins,omitempty"`
 }
 
 // NewJSONConnection returns a JSONConnection given a Connection.
 func NewJSONConnection(connection Connection) *JSONConnection {
-	return &JSONConnection{
+	jsonConnection := &JSONConnection{
 		Name:    connection.Name(),
 		Adaptor: reflect.TypeOf(connection).String(),
 	}
+	if reserver, ok := connection.(PinReserver); ok {
+		jsonConnection.Pins = reserver.ReservedPins()
+	}
+	return jsonConnection
 }
 
 // A Connection is an instance of an Adaptor
@@ -40,19 +49,32 @@ func (c *Connections) Each(f func(Connection)) {
 
 // Start calls Connect on each Connection in c
 func (c *Connections) Start() (errs []error) {
-	log.Println("Starting connections...")
+	return c.StartContext(context.Background(), 0, nil)
+}
+
+// StartContext calls Connect on each Connection in c, or ConnectContext for
+// connections which implement ContextAdaptor. Each connection is given at most
+// timeout to connect, zero meaning no limit. It stops at the first connection
+// that fails or does not connect in time. Progress is logged to logger, such
+// as the Logger of the connections' Robot, or to the DefaultLogger if it is
+// nil.
+func (c *Connections) StartContext(ctx context.Context, timeout time.Duration, logger Logger) (errs []error) {
+	if logger == nil {
+		logger = DefaultLogger()
+	}
+	logger.Info("Starting connections")
 	for _, connection := range *c {
-		info := "Starting connection " + connection.Name()
+		fields := []interface{}{"connection", connection.Name()}
 
 		if porter, ok := connection.(Porter); ok {
-			info = info + " on port " + porter.Port()
+			fields = append(fields, "port", porter.Port())
 		}
 
-		log.Println(info + "...")
+		logger.Info("Starting connection", fields...)
 
-		if errs = connection.Connect(); len(errs) > 0 {
+		if errs = connect(ctx, timeout, connection); len(errs) > 0 {
 			for i, err := range errs {
-				errs[i] = fmt.Errorf("Connection %q: %v", connection.Name(), err)
+				errs[i] = fmt.Errorf("Connection %q: %w", connection.Name(), err)
 			}
 			return
 		}
@@ -62,13 +84,45 @@ func (c *Connections) Start() (errs []error) {
 
 // Finalize calls Finalize on each Connection in c
 func (c *Connections) Finalize() (errs []error) {
-	for _, connection := range *c {
-		if cerrs := connection.Finalize(); cerrs != nil {
+	return c.FinalizeContext(context.Background(), 0)
+}
+
+// FinalizeContext calls Finalize on each Connection in c, or FinalizeContext
+// for connections which implement ContextAdaptor. Each connection is given at
+// most timeout to finalize, zero meaning no limit, and its share of the time
+// left before ctx's deadline. Every connection is finalized even if an
+// earlier one fails or runs out of time.
+func (c *Connections) FinalizeContext(ctx context.Context, timeout time.Duration) (errs []error) {
+	for i, connection := range *c {
+		cctx, cancel := share(ctx, 1, len(*c)-i)
+		cerrs := finalize(cctx, timeout, connection)
+		cancel()
+		if cerrs != nil {
 			for i, err := range cerrs {
-				cerrs[i] = fmt.Errorf("Connection %q: %v", connection.Name(), err)
+				cerrs[i] = fmt.Errorf("Connection %q: %w", connection.Name(), err)
 			}
 			errs = append(errs, cerrs...)
 		}
 	}
 	return errs
 }
+
+func connect(ctx context.Context, timeout time.Duration, connection Connection) []error {
+	ctx, cancel := withTimeout(ctx, timeout)
+	defer cancel()
+
+	if ca, ok := connection.(ContextAdaptor); ok {
+		return ca.ConnectContext(ctx)
+	}
+	return runContext(ctx, "Connect", connection.Connect)
+}
+
+func finalize(ctx context.Context, timeout time.Duration, connection Connection) []error {
+	ctx, cancel := withTimeout(ctx, timeout)
+	defer cancel()
+
+	if ca, ok := connection.(ContextAdaptor); ok {
+		return ca.FinalizeContext(ctx)
+	}
+	return runContext(ctx, "Finalize", connection.Finalize)
+}
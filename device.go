@@ -1,9 +1,10 @@
 package gobot
 
 import (
+	"context"
 	"fmt"
-	"log"
 	"reflect"
+	"time"
 )
 
 // JSONDevice is a JSON representation of a Device.
@@ -12,6 +13,8 @@ type JSONDevice struct {
 	Driver     string   `json:"driver"`
 	Connection string   `json:"connection"`
 	Commands   []string `json:"commands"`
+	// Schemas describes the parameters of commands which declare them
+	Schemas map[string]*CommandSchema `json:"schemas,omitempty"`
 }
 
 // NewJSONDevice returns a JSONDevice given a Device.
@@ -29,6 +32,7 @@ func NewJSONDevice(device Device) *JSONDevice {
 		for command := range commander.Commands() {
 			jsonDevice.Commands = append(jsonDevice.Commands, command)
 		}
+		jsonDevice.Schemas = commandSchemas(commander)
 	}
 	return jsonDevice
 }
@@ -53,18 +57,30 @@ func (d *Devices) Each(f func(Device)) {
 
 // Start calls Start on each Device in d
 func (d *Devices) Start() (errs []error) {
-	log.Println("Starting devices...")
+	return d.StartContext(context.Background(), 0, nil)
+}
+
+// StartContext calls Start on each Device in d, or StartContext for devices
+// which implement ContextDriver. Each device is given at most timeout to
+// start, zero meaning no limit. It stops at the first device that fails or
+// does not start in time. Progress is logged to logger, such as the Logger of
+// the devices' Robot, or to the DefaultLogger if it is nil.
+func (d *Devices) StartContext(ctx context.Context, timeout time.Duration, logger Logger) (errs []error) {
+	if logger == nil {
+		logger = DefaultLogger()
+	}
+	logger.Info("Starting devices")
 	for _, device := range *d {
-		info := "Starting device " + device.Name()
+		fields := []interface{}{"device", device.Name()}
 
 		if pinner, ok := device.(Pinner); ok {
-			info = info + " on pin " + pinner.Pin()
+			fields = append(fields, "pin", pinner.Pin())
 		}
 
-		log.Println(info + "...")
-		if errs = device.Start(); len(errs) > 0 {
+		logger.Info("Starting device", fields...)
+		if errs = start(ctx, timeout, device); len(errs) > 0 {
 			for i, err := range errs {
-				errs[i] = fmt.Errorf("Device %q: %v", device.Name(), err)
+				errs[i] = fmt.Errorf("Device %q: %w", device.Name(), err)
 			}
 			return
 		}
@@ -74,13 +90,108 @@ func (d *Devices) Start() (errs []error) {
 
 // Halt calls Halt on each Device in d
 func (d *Devices) Halt() (errs []error) {
-	for _, device := range *d {
-		if derrs := device.Halt(); len(derrs) > 0 {
+	return d.HaltContext(context.Background(), 0)
+}
+
+// HaltContext calls Halt on each Device in d, or HaltContext for devices
+// which implement ContextDriver. Each device is given at most timeout to
+// halt, zero meaning no limit, and its share of the time left before ctx's
+// deadline. Every device is halted even if an earlier one fails or runs out
+// of time.
+func (d *Devices) HaltContext(ctx context.Context, timeout time.Duration) (errs []error) {
+	for i, device := range *d {
+		dctx, cancel := share(ctx, 1, len(*d)-i)
+		derrs := halt(dctx, timeout, device)
+		cancel()
+		if len(derrs) > 0 {
 			for i, err := range derrs {
-				derrs[i] = fmt.Errorf("Device %q: %v", device.Name(), err)
+				derrs[i] = fmt.Errorf("Device %q: %w", device.Name(), err)
 			}
 			errs = append(errs, derrs...)
 		}
 	}
 	return
 }
+
+// SafeState calls SafeState on each Device in d which implements SafeStater
+func (d *Devices) SafeState() (errs []error) {
+	return d.SafeStateContext(context.Background(), 0)
+}
+
+// SafeStateContext calls SafeState on each Device in d which implements
+// SafeStater. Each device is given at most timeout, zero meaning no limit,
+// and its share of the time left before ctx's deadline. Every device is put
+// in its safe state even if an earlier one fails or runs out of time.
+func (d *Devices) SafeStateContext(ctx context.Context, timeout time.Duration) (errs []error) {
+	safe := d.safeStaters()
+	for i, device := range safe {
+		dctx, cancel := share(ctx, 1, len(safe)-i)
+		derrs := safeState(dctx, timeout, device)
+		cancel()
+		if len(derrs) > 0 {
+			for i, err := range derrs {
+				derrs[i] = fmt.Errorf("Device %q: %w", device.Name(), err)
+			}
+			errs = append(errs, derrs...)
+		}
+	}
+	return
+}
+
+// safeStaters returns the devices in d which implement SafeStater.
+func (d *Devices) safeStaters() (safe Devices) {
+	for _, device := range *d {
+		if _, ok := device.(SafeStater); ok {
+			safe = append(safe, device)
+		}
+	}
+	return
+}
+
+// start reserves the pin of device and starts it, releasing the pin again if
+// it fails to start.
+func start(ctx context.Context, timeout time.Duration, device Device) (errs []error) {
+	if err := reservePin(device); err != nil {
+		return []error{err}
+	}
+	ctx, cancel := withTimeout(ctx, timeout)
+	defer cancel()
+
+	if cd, ok := device.(ContextDriver); ok {
+		errs = cd.StartContext(ctx)
+	} else {
+		errs = runContext(ctx, "Start", device.Start)
+	}
+	if len(errs) > 0 {
+		releasePin(device)
+	}
+	return
+}
+
+// halt halts device and releases its pin.
+func halt(ctx context.Context, timeout time.Duration, device Device) []error {
+	defer releasePin(device)
+	ctx, cancel := withTimeout(ctx, timeout)
+	defer cancel()
+
+	if cd, ok := device.(ContextDriver); ok {
+		return cd.HaltContext(ctx)
+	}
+	return runContext(ctx, "Halt", device.Halt)
+}
+
+func safeState(ctx context.Context, timeout time.Duration, device Device) []error {
+	safe, ok := device.(SafeStater)
+	if !ok {
+		return nil
+	}
+	ctx, cancel := withTimeout(ctx, timeout)
+	defer cancel()
+
+	return runContext(ctx, "SafeState", func() []error {
+		if err := safe.SafeState(); err != nil {
+			return []error{err}
+		}
+		return nil
+	})
+}
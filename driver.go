@@ -1,5 +1,7 @@
 package gobot
 
+import "context"
+
 // Driver is the interface that describes a driver in gobot
 type Driver interface {
 	// Name returns the label for the Driver
@@ -16,3 +18,21 @@ type Driver interface {
 type Pinner interface {
 	Pin() string
 }
+
+// ContextDriver is the interface that describes a driver which supports
+// cancellation and deadlines while starting and halting. Devices which
+// implement it are preferred over the plain Driver methods.
+type ContextDriver interface {
+	// StartContext initiates the Driver, giving up when ctx is done
+	StartContext(ctx context.Context) []error
+	// HaltContext terminates the Driver, giving up when ctx is done
+	HaltContext(ctx context.Context) []error
+}
+
+// SafeStater is the interface that describes a driver which can put its
+// hardware in a safe state, such as a motor stopped or a drone landed. Robots
+// do so as they stop, before halting their devices.
+type SafeStater interface {
+	// SafeState puts the hardware in a safe state
+	SafeState() error
+}
@@ -1,10 +1,22 @@
 package gobot
 
+import "time"
+
 // Event represents when something asyncronous happens in a Driver
 // or Adaptor
 type Event struct {
-	Name string
-	Data interface{}
+	Name string      `json:"name"`
+	Data interface{} `json:"data"`
+	// Time is when the Event was published
+	Time time.Time `json:"time"`
+	// Seq is the position of the Event among all events published by the
+	// same Eventer, starting at 1
+	Seq uint64 `json:"seq"`
+	// Robot is the name of the Robot the publisher belongs to, if any
+	Robot string `json:"robot,omitempty"`
+	// Device is the name of the Device or Connection which published the
+	// Event, if any
+	Device string `json:"device,omitempty"`
 }
 
 // NewEvent returns a new Event and its associated data.
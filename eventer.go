@@ -1,20 +1,182 @@
 package gobot
 
+import (
+	"path"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
 type eventChannel chan *Event
 
+// BufferPolicy decides what happens to a published Event when a subscriber's
+// buffer is full.
+type BufferPolicy int
+
+const (
+	// Block waits until the subscriber has room for the Event, slowing down
+	// the publisher.
+	Block BufferPolicy = iota
+	// DropOldest discards the oldest buffered Event to make room.
+	DropOldest
+	// DropNewest discards the Event being published.
+	DropNewest
+)
+
+// DefaultBufferSize is the number of events buffered for a subscriber when
+// no SubscriberOptions are given.
+const DefaultBufferSize = 16
+
+// SubscriberOptions describes how events are buffered for a subscriber.
+type SubscriberOptions struct {
+	// Buffer is the number of events held for the subscriber
+	Buffer int
+	// Policy is applied when the buffer is full
+	Policy BufferPolicy
+	// Filter, when set, is called for each published Event; events for
+	// which it returns false are never buffered for the subscriber
+	Filter func(*Event) bool
+}
+
+// Subscription is a handle to a subscriber of an Eventer.
+type Subscription struct {
+	events  eventChannel
+	policy  BufferPolicy
+	filters []func(*Event) bool
+	done    chan struct{}
+	once    sync.Once
+	eventer *eventer
+	// mtx is held to deliver events, so events is not closed meanwhile
+	mtx sync.RWMutex
+}
+
+// Cancel stops delivering events to the subscriber and closes its channel,
+// after any events still buffered in it. It is safe to call Cancel more than
+// once and from within the subscriber's handler.
+func (s *Subscription) Cancel() {
+	s.once.Do(func() {
+		close(s.done)
+		s.eventer.remove(s)
+		s.mtx.Lock()
+		close(s.events)
+		s.mtx.Unlock()
+	})
+}
+
+// Done returns a channel which is closed once the Subscription is cancelled.
+func (s *Subscription) Done() <-chan struct{} {
+	return s.done
+}
+
+// accepts returns true if evt passes every filter of the subscriber.
+func (s *Subscription) accepts(evt *Event) bool {
+	for _, filter := range s.filters {
+		if !filter(evt) {
+			return false
+		}
+	}
+	return true
+}
+
+// deliver hands evt to the subscriber according to its BufferPolicy. It
+// returns the number of events dropped to do so.
+func (s *Subscription) deliver(evt *Event) (dropped int) {
+	if !s.accepts(evt) {
+		return
+	}
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+	select {
+	case <-s.done:
+		return
+	default:
+	}
+
+	switch s.policy {
+	case DropNewest:
+		select {
+		case s.events <- evt:
+		default:
+			dropped++
+		}
+	case DropOldest:
+		for {
+			select {
+			case s.events <- evt:
+				return
+			default:
+			}
+			select {
+			case <-s.events:
+				dropped++
+			default:
+			}
+		}
+	default:
+		select {
+		case s.events <- evt:
+		case <-s.done:
+		}
+	}
+	return
+}
+
+// handle calls f for each delivered event until the Subscription is
+// cancelled, or after the first call when once is set.
+func (s *Subscription) handle(f func(s interface{}), once bool) {
+	for {
+		select {
+		case <-s.done:
+			return
+		case evt, ok := <-s.events:
+			if !ok {
+				return
+			}
+			select {
+			case <-s.done:
+				return
+			default:
+			}
+			f(evt.Data)
+			if once {
+				s.Cancel()
+				return
+			}
+		}
+	}
+}
+
 type eventer struct {
+	mtx sync.RWMutex
+
+	// sequence number of the last published Event
+	seq uint64
+
+	// names of the robot and device that own the eventer
+	robot  string
+	device string
+
 	// map of valid Event names
 	eventnames map[string]string
 
-	// new events get put in to the event channel
-	in eventChannel
-
 	// map of out channels used by subscribers
-	outs map[eventChannel]eventChannel
+	outs map[eventChannel]*Subscription
+
+	// metrics of each published Event name, and of the subscriber queues
+	metrics map[string]*eventMetrics
+	depth   *Gauge
+	// registry the metrics are recorded in, the DefaultRegistry when nil
+	registry *Registry
+}
+
+type eventMetrics struct {
+	published *Counter
+	dropped   *Counter
 }
 
 // Eventer is the interface which describes how a Driver or Adaptor
-// handles events.
+// handles events. It is safe for concurrent use.
 type Eventer interface {
 	// Events returns the map of valid Event names.
 	Events() (eventnames map[string]string)
@@ -33,113 +195,273 @@ type Eventer interface {
 	Publish(name string, data interface{})
 
 	// Subscribe to events
-	Subscribe() (events eventChannel)
+	Subscribe(opts ...SubscriberOptions) (events eventChannel)
 
 	// Unsubscribe from an event channel
 	Unsubscribe(events eventChannel)
 
-	// Event handler
-	On(name string, f func(s interface{})) (err error)
+	// Event handler for events matching a name or pattern
+	On(pattern string, f func(s interface{}), opts ...SubscriberOptions) (sub *Subscription, err error)
 
 	// Event handler, only executes one time
-	Once(name string, f func(s interface{})) (err error)
+	Once(pattern string, f func(s interface{}), opts ...SubscriberOptions) (sub *Subscription, err error)
+
+	// Close cancels every subscription
+	Close()
+
+	// SetEventSource sets the robot and device names stamped on every
+	// published Event
+	SetEventSource(robot, device string)
+
+	// SetEventRegistry sets the Registry the metrics of events are recorded
+	// in, in place of the DefaultRegistry
+	SetEventRegistry(r *Registry)
 }
 
 // NewEventer returns a new Eventer.
 func NewEventer() Eventer {
-	evtr := &eventer{
+	return &eventer{
 		eventnames: make(map[string]string),
-		in:         make(eventChannel, 1),
-		outs:       make(map[eventChannel]eventChannel),
+		outs:       make(map[eventChannel]*Subscription),
+		metrics:    make(map[string]*eventMetrics),
 	}
-
-	// goroutine to cascade "in" events to all "out" event channels
-	go func() {
-		for {
-			select {
-			case evt := <-evtr.in:
-				for _, out := range evtr.outs {
-					out <- evt
-				}
-			}
-		}
-	}()
-
-	return evtr
 }
 
-// Events returns the map of valid Event names.
+// Events returns a copy of the map of valid Event names.
 func (e *eventer) Events() map[string]string {
-	return e.eventnames
+	e.mtx.RLock()
+	defer e.mtx.RUnlock()
+
+	eventnames := make(map[string]string, len(e.eventnames))
+	for k, v := range e.eventnames {
+		eventnames[k] = v
+	}
+	return eventnames
 }
 
 // Event returns an Event string from map of valid Event names.
 // Mostly used to validate that an Event name is valid.
 func (e *eventer) Event(name string) string {
+	e.mtx.RLock()
+	defer e.mtx.RUnlock()
 	return e.eventnames[name]
 }
 
 // AddEvent registers a new Event name.
 func (e *eventer) AddEvent(name string) {
+	e.mtx.Lock()
+	defer e.mtx.Unlock()
 	e.eventnames[name] = name
 }
 
 // DeleteEvent removes a previously registered Event name.
 func (e *eventer) DeleteEvent(name string) {
+	e.mtx.Lock()
+	defer e.mtx.Unlock()
 	delete(e.eventnames, name)
 }
 
-// Publish new events to anyone that is subscribed
+// Publish new events to anyone that is subscribed. Each event is stamped
+// with the time of publishing, a sequence number and the eventer's source.
+// Each subscriber receives the event according to its own BufferPolicy, so
+// only subscribers using Block can hold up the publisher. Published and
+// dropped events, and the deepest subscriber queue, are recorded in the
+// eventer's Registry.
 func (e *eventer) Publish(name string, data interface{}) {
 	evt := NewEvent(name, data)
-	e.in <- evt
+	evt.Time = time.Now()
+	evt.Seq = atomic.AddUint64(&e.seq, 1)
+
+	e.mtx.RLock()
+	evt.Robot, evt.Device = e.robot, e.device
+	e.mtx.RUnlock()
+
+	metrics, depth := e.metricsFor(name)
+	metrics.published.Inc()
+
+	deepest := 0
+	for _, sub := range e.subscribers() {
+		if dropped := sub.deliver(evt); dropped > 0 {
+			metrics.dropped.Add(float64(dropped))
+		}
+		if n := len(sub.events); n > deepest {
+			deepest = n
+		}
+	}
+	depth.Set(float64(deepest))
 }
 
-// Subscribe to any events from this eventer
-func (e *eventer) Subscribe() eventChannel {
-	out := make(eventChannel)
-	e.outs[out] = out
-	return out
+// Subscribe to any events from this eventer. Without opts the subscriber
+// gets a buffer of DefaultBufferSize events and the Block policy. The
+// channel is closed once the subscriber unsubscribes or the eventer is
+// closed, such as when the Robot owning it stops.
+func (e *eventer) Subscribe(opts ...SubscriberOptions) eventChannel {
+	return e.subscribe(opts).events
 }
 
 // Unsubscribe from the event channel
 func (e *eventer) Unsubscribe(events eventChannel) {
-	delete(e.outs, events)
-}
+	e.mtx.RLock()
+	sub, ok := e.outs[events]
+	e.mtx.RUnlock()
 
-// On executes the event handler f when e is Published to.
-func (e *eventer) On(n string, f func(s interface{})) (err error) {
-	out := e.Subscribe()
-	go func() {
-		for {
-			select {
-			case evt := <-out:
-				if evt.Name == n {
-					f(evt.Data)
-				}
-			}
-		}
-	}()
+	if ok {
+		sub.Cancel()
+	}
+}
 
+// On executes the event handler f when an event whose name matches pattern
+// is Published to e. The pattern is either an event name or uses the syntax
+// of path.Match, so "AnalogRead*" matches "AnalogRead0", "AnalogRead1" and so
+// on. Other events are discarded before reaching f. The returned Subscription
+// stops the handler when cancelled.
+func (e *eventer) On(pattern string, f func(s interface{}), opts ...SubscriberOptions) (sub *Subscription, err error) {
+	if sub, err = e.subscribePattern(pattern, opts); err != nil {
+		return
+	}
+	go sub.handle(f, false)
 	return
 }
 
 // Once is similar to On except that it only executes f one time.
-func (e *eventer) Once(n string, f func(s interface{})) (err error) {
-	out := e.Subscribe()
-	go func() {
-	ProcessEvents:
-		for {
-			select {
-			case evt := <-out:
-				if evt.Name == n {
-					f(evt.Data)
-					e.Unsubscribe(out)
-					break ProcessEvents
-				}
-			}
-		}
-	}()
-
+func (e *eventer) Once(pattern string, f func(s interface{}), opts ...SubscriberOptions) (sub *Subscription, err error) {
+	if sub, err = e.subscribePattern(pattern, opts); err != nil {
+		return
+	}
+	go sub.handle(f, true)
 	return
 }
+
+// Close cancels every subscription, stopping all handler goroutines. The
+// Eventer remains usable afterwards.
+func (e *eventer) Close() {
+	for _, sub := range e.subscribers() {
+		sub.Cancel()
+	}
+}
+
+// SetEventSource sets the robot and device names stamped on every published
+// Event. Robots call it for their own Eventer and for every Device and
+// Connection added to them.
+func (e *eventer) SetEventSource(robot, device string) {
+	e.mtx.Lock()
+	defer e.mtx.Unlock()
+	e.robot, e.device = robot, device
+	e.metrics = make(map[string]*eventMetrics)
+	e.depth = nil
+}
+
+// SetEventRegistry sets the Registry the metrics of events are recorded
+// in.
+func (e *eventer) SetEventRegistry(r *Registry) {
+	e.mtx.Lock()
+	defer e.mtx.Unlock()
+	e.registry = r
+	e.metrics = make(map[string]*eventMetrics)
+	e.depth = nil
+}
+
+// metricsFor returns the metrics of events called name, and of the
+// subscriber queues, labelled with the eventer's source.
+func (e *eventer) metricsFor(name string) (*eventMetrics, *Gauge) {
+	e.mtx.RLock()
+	m, depth := e.metrics[name], e.depth
+	robot, device, registry := e.robot, e.device, e.registry
+	e.mtx.RUnlock()
+	if m != nil && depth != nil {
+		return m, depth
+	}
+
+	r := registry
+	if r == nil {
+		r = DefaultRegistry()
+	}
+	labels := Labels{"robot": robot, "device": device}
+	depth = r.Gauge("gobot_event_queue_depth",
+		"Events waiting in the fullest subscriber queue after the last publish.", labels)
+	labels["event"] = name
+	m = &eventMetrics{
+		published: r.Counter("gobot_events_published_total", "Events published.", labels),
+		dropped:   r.Counter("gobot_events_dropped_total", "Events dropped because a subscriber queue was full.", labels),
+	}
+
+	e.mtx.Lock()
+	defer e.mtx.Unlock()
+	if e.robot == robot && e.device == device && e.registry == registry {
+		e.metrics[name], e.depth = m, depth
+	}
+	return m, depth
+}
+
+func (e *eventer) subscribe(opts []SubscriberOptions, filters ...func(*Event) bool) *Subscription {
+	o := SubscriberOptions{Buffer: DefaultBufferSize, Policy: Block}
+	if len(opts) > 0 {
+		o = opts[0]
+	}
+	if o.Buffer < 1 && o.Policy != Block {
+		o.Buffer = 1
+	}
+
+	sub := &Subscription{
+		events:  make(eventChannel, o.Buffer),
+		policy:  o.Policy,
+		done:    make(chan struct{}),
+		filters: filters,
+		eventer: e,
+	}
+	if o.Filter != nil {
+		sub.filters = append(sub.filters, o.Filter)
+	}
+
+	e.mtx.Lock()
+	e.outs[sub.events] = sub
+	e.mtx.Unlock()
+
+	return sub
+}
+
+// subscribePattern is like subscribe, but only delivers events whose name
+// matches pattern. A malformed pattern returns path.ErrBadPattern.
+func (e *eventer) subscribePattern(pattern string, opts []SubscriberOptions) (*Subscription, error) {
+	match, err := MatchPattern(pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	return e.subscribe(opts, match), nil
+}
+
+// MatchPattern returns a filter, for SubscriberOptions, which accepts events
+// whose name matches pattern as On does. Patterns without any special
+// characters are compared directly.
+func MatchPattern(pattern string) (func(*Event) bool, error) {
+	if !strings.ContainsAny(pattern, `*?[\`) {
+		return func(evt *Event) bool { return evt.Name == pattern }, nil
+	}
+	if _, err := path.Match(pattern, ""); err != nil {
+		return nil, err
+	}
+	return func(evt *Event) bool {
+		matched, _ := path.Match(pattern, evt.Name)
+		return matched
+	}, nil
+}
+
+// subscribers returns a snapshot of the current subscriptions, so events can
+// be delivered without holding the lock.
+func (e *eventer) subscribers() []*Subscription {
+	e.mtx.RLock()
+	defer e.mtx.RUnlock()
+
+	subs := make([]*Subscription, 0, len(e.outs))
+	for _, sub := range e.outs {
+		subs = append(subs, sub)
+	}
+	return subs
+}
+
+func (e *eventer) remove(sub *Subscription) {
+	e.mtx.Lock()
+	delete(e.outs, sub.events)
+	e.mtx.Unlock()
+}
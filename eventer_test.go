@@ -1,8 +1,12 @@
 package gobot
 
 import (
+	"path"
+	"sync"
 	"testing"
 	"time"
+
+	"github.com/hybridgroup/gobot/gobottest"
 )
 
 func TestEventerAddEvent(t *testing.T) {
@@ -73,3 +77,220 @@ func TestEventerOnce(t *testing.T) {
 	case <-time.After(10 * time.Millisecond):
 	}
 }
+
+func TestEventerOnCancel(t *testing.T) {
+	e := NewEventer()
+	e.AddEvent("test")
+
+	sem := make(chan bool, 1)
+	sub, _ := e.On("test", func(data interface{}) {
+		sem <- true
+	})
+	sub.Cancel()
+	sub.Cancel()
+
+	e.Publish("test", true)
+
+	select {
+	case <-sem:
+		t.Errorf("On was called after Cancel")
+	case <-time.After(10 * time.Millisecond):
+	}
+	gobottest.Assert(t, len(e.(*eventer).outs), 0)
+}
+
+func TestEventerClose(t *testing.T) {
+	e := NewEventer()
+	e.AddEvent("test")
+
+	on, _ := e.On("test", func(data interface{}) {})
+	once, _ := e.Once("test", func(data interface{}) {})
+	events := e.Subscribe()
+	e.Publish("test", 1)
+
+	e.Close()
+
+	evt, ok := <-events
+	gobottest.Assert(t, ok, true)
+	gobottest.Assert(t, evt.Data, 1)
+	_, ok = <-events
+	gobottest.Assert(t, ok, false)
+
+	for _, sub := range []*Subscription{on, once} {
+		select {
+		case <-sub.Done():
+		default:
+			t.Errorf("Subscription was not cancelled by Close")
+		}
+	}
+	gobottest.Assert(t, len(e.(*eventer).outs), 0)
+}
+
+func TestEventerUnsubscribe(t *testing.T) {
+	e := NewEventer()
+	events := e.Subscribe()
+	e.Unsubscribe(events)
+	gobottest.Assert(t, len(e.(*eventer).outs), 0)
+
+	e.Publish("test", true)
+	gobottest.Assert(t, len(events), 0)
+}
+
+func TestEventerDropNewest(t *testing.T) {
+	e := NewEventer()
+	events := e.Subscribe(SubscriberOptions{Buffer: 2, Policy: DropNewest})
+
+	for i := 0; i < 5; i++ {
+		e.Publish("test", i)
+	}
+
+	gobottest.Assert(t, len(events), 2)
+	gobottest.Assert(t, (<-events).Data, 0)
+	gobottest.Assert(t, (<-events).Data, 1)
+}
+
+func TestEventerDropOldest(t *testing.T) {
+	e := NewEventer()
+	events := e.Subscribe(SubscriberOptions{Buffer: 2, Policy: DropOldest})
+
+	for i := 0; i < 5; i++ {
+		e.Publish("test", i)
+	}
+
+	gobottest.Assert(t, len(events), 2)
+	gobottest.Assert(t, (<-events).Data, 3)
+	gobottest.Assert(t, (<-events).Data, 4)
+}
+
+func TestEventerMetrics(t *testing.T) {
+	r := NewRegistry()
+	e := NewEventer()
+	e.SetEventSource("metricsbot", "button")
+	e.SetEventRegistry(r)
+	e.Subscribe(SubscriberOptions{Buffer: 2, Policy: DropNewest})
+
+	for i := 0; i < 5; i++ {
+		e.Publish("push", i)
+	}
+
+	labels := Labels{"robot": "metricsbot", "device": "button", "event": "push"}
+	gobottest.Assert(t, r.Counter("gobot_events_published_total", "", labels).Value(), 5.0)
+	gobottest.Assert(t, r.Counter("gobot_events_dropped_total", "", labels).Value(), 3.0)
+	gobottest.Assert(t, r.Gauge("gobot_event_queue_depth", "",
+		Labels{"robot": "metricsbot", "device": "button"}).Value(), 2.0)
+}
+
+func TestEventerSlowSubscriberDoesNotBlock(t *testing.T) {
+	e := NewEventer()
+	e.AddEvent("test")
+
+	block := make(chan bool)
+	defer close(block)
+	e.On("test", func(data interface{}) {
+		<-block
+	}, SubscriberOptions{Buffer: 1, Policy: DropNewest})
+
+	sem := make(chan bool, 1)
+	e.On("test", func(data interface{}) {
+		if data.(int) == 99 {
+			sem <- true
+		}
+	}, SubscriberOptions{Buffer: 100, Policy: Block})
+
+	for i := 0; i < 100; i++ {
+		e.Publish("test", i)
+	}
+
+	select {
+	case <-sem:
+	case <-time.After(100 * time.Millisecond):
+		t.Errorf("Publisher was blocked by a slow subscriber")
+	}
+}
+
+func TestEventerConcurrentUse(t *testing.T) {
+	e := NewEventer()
+	e.AddEvent("test")
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			sub, _ := e.On("test", func(data interface{}) {})
+			e.Publish("test", true)
+			sub.Cancel()
+		}()
+		go func() {
+			defer wg.Done()
+			events := e.Subscribe(SubscriberOptions{Policy: DropOldest})
+			e.Publish("test", true)
+			e.Unsubscribe(events)
+			e.Events()
+		}()
+	}
+	wg.Wait()
+	gobottest.Assert(t, len(e.(*eventer).outs), 0)
+}
+
+func TestEventerOnPattern(t *testing.T) {
+	e := NewEventer()
+
+	sem := make(chan string, 3)
+	e.On("AnalogRead*", func(data interface{}) {
+		sem <- data.(string)
+	})
+
+	e.Publish("DigitalRead1", "d1")
+	e.Publish("AnalogRead0", "a0")
+	e.Publish("AnalogRead12", "a12")
+
+	for _, want := range []string{"a0", "a12"} {
+		select {
+		case data := <-sem:
+			gobottest.Assert(t, data, want)
+		case <-time.After(10 * time.Millisecond):
+			t.Errorf("On was not called for %v", want)
+		}
+	}
+}
+
+func TestEventerOnBadPattern(t *testing.T) {
+	e := NewEventer()
+
+	sub, err := e.On("AnalogRead[", func(data interface{}) {})
+	gobottest.Assert(t, err, path.ErrBadPattern)
+	gobottest.Assert(t, sub, (*Subscription)(nil))
+	gobottest.Assert(t, len(e.(*eventer).outs), 0)
+}
+
+func TestEventerFilter(t *testing.T) {
+	e := NewEventer()
+	events := e.Subscribe(SubscriberOptions{Buffer: 4, Filter: func(evt *Event) bool {
+		return evt.Data.(int) > 800
+	}})
+
+	e.Publish("data", 100)
+	e.Publish("data", 900)
+
+	gobottest.Assert(t, len(events), 1)
+	gobottest.Assert(t, (<-events).Data, 900)
+}
+
+func TestEventerPublishMetadata(t *testing.T) {
+	e := NewEventer()
+	e.SetEventSource("Robot1", "Device1")
+	events := e.Subscribe()
+
+	before := time.Now()
+	e.Publish("test", 1)
+	e.Publish("test", 2)
+
+	first, second := <-events, <-events
+	gobottest.Assert(t, first.Seq, uint64(1))
+	gobottest.Assert(t, second.Seq, uint64(2))
+	gobottest.Assert(t, first.Robot, "Robot1")
+	gobottest.Assert(t, first.Device, "Device1")
+	gobottest.Assert(t, first.Time.Before(before), false)
+	gobottest.Assert(t, second.Time.Before(first.Time), false)
+}
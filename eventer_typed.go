@@ -0,0 +1,40 @@
+//go:build go1.18
+// +build go1.18
+
+package gobot
+
+// OnData is a typed variant of Eventer.On. It executes f with the data of
+// every event matching pattern whose data is a T, for example an int for
+// gpio.Data or a sphero.DataStreamingPacket. Events carrying any other type
+// are discarded before reaching f.
+func OnData[T any](e Eventer, pattern string, f func(data T), opts ...SubscriberOptions) (*Subscription, error) {
+	return e.On(pattern, func(data interface{}) {
+		f(data.(T))
+	}, typedOptions[T](opts))
+}
+
+// OnceData is a typed variant of Eventer.Once. It executes f one time, with
+// the data of the first event matching pattern whose data is a T.
+func OnceData[T any](e Eventer, pattern string, f func(data T), opts ...SubscriberOptions) (*Subscription, error) {
+	return e.Once(pattern, func(data interface{}) {
+		f(data.(T))
+	}, typedOptions[T](opts))
+}
+
+// typedOptions returns opts with a Filter that only accepts events whose
+// data is a T, on top of any Filter already given.
+func typedOptions[T any](opts []SubscriberOptions) SubscriberOptions {
+	o := SubscriberOptions{Buffer: DefaultBufferSize, Policy: Block}
+	if len(opts) > 0 {
+		o = opts[0]
+	}
+
+	filter := o.Filter
+	o.Filter = func(evt *Event) bool {
+		if _, ok := evt.Data.(T); !ok {
+			return false
+		}
+		return filter == nil || filter(evt)
+	}
+	return o
+}
@@ -0,0 +1,57 @@
+//go:build go1.18
+// +build go1.18
+
+package gobot
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hybridgroup/gobot/gobottest"
+)
+
+func TestOnData(t *testing.T) {
+	e := NewEventer()
+
+	sem := make(chan int, 2)
+	OnData(e, "AnalogRead*", func(data int) {
+		sem <- data
+	})
+
+	e.Publish("AnalogRead0", "not an int")
+	e.Publish("DigitalRead0", 1)
+	e.Publish("AnalogRead1", 42)
+
+	select {
+	case data := <-sem:
+		gobottest.Assert(t, data, 42)
+	case <-time.After(10 * time.Millisecond):
+		t.Errorf("OnData was not called")
+	}
+}
+
+func TestOnceData(t *testing.T) {
+	e := NewEventer()
+
+	sem := make(chan string, 2)
+	sub, _ := OnceData(e, "test", func(data string) {
+		sem <- data
+	}, SubscriberOptions{Buffer: 4, Filter: func(evt *Event) bool {
+		return evt.Data != "skip"
+	}})
+
+	e.Publish("test", 1)
+	e.Publish("test", "skip")
+	e.Publish("test", "first")
+	e.Publish("test", "second")
+
+	select {
+	case data := <-sem:
+		gobottest.Assert(t, data, "first")
+	case <-time.After(10 * time.Millisecond):
+		t.Errorf("OnceData was not called")
+	}
+
+	<-sub.Done()
+	gobottest.Assert(t, len(sem), 0)
+}
@@ -13,7 +13,7 @@ func main() {
 	gbot := gobot.NewGobot()
 
 	a := api.NewAPI(gbot)
-	a.AddHandler(api.BasicAuth("gort", "klatuu"))
+	a.Use(api.BasicAuthMiddleware("gort", "klatuu"))
 	a.Debug()
 
 	a.AddHandler(func(w http.ResponseWriter, r *http.Request) {
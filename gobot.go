@@ -1,15 +1,24 @@
 package gobot
 
 import (
-	"log"
+	"context"
 	"os"
 	"os/signal"
+	"sync"
+	"syscall"
+	"time"
 )
 
+// DefaultShutdownTimeout is how long a Gobot waits for its robots to stop
+// once it receives a shutdown signal, unless ShutdownTimeout is changed.
+const DefaultShutdownTimeout = 10 * time.Second
+
 // JSONGobot is a JSON representation of a Gobot.
 type JSONGobot struct {
 	Robots   []*JSONRobot `json:"robots"`
 	Commands []string     `json:"commands"`
+	// Schemas describes the parameters of commands which declare them
+	Schemas map[string]*CommandSchema `json:"schemas,omitempty"`
 }
 
 // NewJSONGobot returns a JSONGobt given a Gobot.
@@ -22,50 +31,94 @@ func NewJSONGobot(gobot *Gobot) *JSONGobot {
 	for command := range gobot.Commands() {
 		jsonGobot.Commands = append(jsonGobot.Commands, command)
 	}
+	jsonGobot.Schemas = commandSchemas(gobot)
 
-	gobot.robots.Each(func(r *Robot) {
+	gobot.Robots().Each(func(r *Robot) {
 		jsonGobot.Robots = append(jsonGobot.Robots, NewJSONRobot(r))
 	})
 	return jsonGobot
 }
 
 // Gobot is the main type of your Gobot application and contains a collection of
-// Robots, API commands and Events.
+// Robots, API commands and Events. When Concurrent is set, its robots are
+// started at the same time rather than one after the other. When AutoStop is
+// set, Start waits for one of Signals and then stops the robots, giving up
+// on any which have not stopped within ShutdownTimeout; a second signal
+// exits at once.
 type Gobot struct {
-	robots   *Robots
-	trap     func(chan os.Signal)
-	AutoStop bool
+	robots          *Robots
+	trap            func(chan os.Signal)
+	exit            func(int)
+	running         bool
+	rules           rules
+	logger          Logger
+	store           Store
+	registry        *Registry
+	mtx             sync.RWMutex
+	lifecycle       sync.Mutex
+	AutoStop        bool
+	Concurrent      bool
+	Signals         []os.Signal
+	ShutdownTimeout time.Duration
 	Commander
 	Eventer
 }
 
-// NewGobot returns a new Gobot
+// NewGobot returns a new Gobot, which shuts down on an interrupt, SIGTERM or
+// SIGHUP
 func NewGobot() *Gobot {
-	return &Gobot{
-		robots: &Robots{},
-		trap: func(c chan os.Signal) {
-			signal.Notify(c, os.Interrupt)
-		},
-		AutoStop:  true,
-		Commander: NewCommander(),
-		Eventer:   NewEventer(),
+	g := &Gobot{
+		robots:          &Robots{},
+		exit:            os.Exit,
+		AutoStop:        true,
+		Signals:         []os.Signal{os.Interrupt, syscall.SIGTERM, syscall.SIGHUP},
+		ShutdownTimeout: DefaultShutdownTimeout,
+		Commander:       NewCommander(),
+		Eventer:         NewEventer(),
+		rules:           rules{scheduler: NewScheduler()},
+	}
+	g.trap = func(c chan os.Signal) {
+		signal.Notify(c, g.Signals...)
 	}
+	g.AddEvent(RobotAdded)
+	g.AddEvent(RobotRemoved)
+	g.AddEvent(RobotStarted)
+	g.AddEvent(RuleTriggered)
+	return g
 }
 
 // Start calls the Start method on each robot in its collection of robots. On
 // error, call Stop to ensure that all robots are returned to a sane, stopped
-// state.
+// state. Once every robot has started, the Gobot's rules start watching
+// events.
 func (g *Gobot) Start() (errs []error) {
-	if rerrs := g.robots.Start(); len(rerrs) > 0 {
+	g.lifecycle.Lock()
+	robots := g.Robots()
+	start := robots.Start
+	if g.Concurrent {
+		start = func() []error { return robots.StartConcurrent(context.Background()) }
+	}
+
+	if rerrs := start(); len(rerrs) > 0 {
 		for _, err := range rerrs {
-			log.Println("Error:", err)
+			g.Logger().Error("Start failed", "error", err)
 			errs = append(errs, err)
 		}
+	} else {
+		g.mtx.Lock()
+		g.running = true
+		g.mtx.Unlock()
+		for _, err := range g.startRules() {
+			g.Logger().Error("Starting rule failed", "error", err)
+		}
+		g.watchRules()
 	}
+	g.lifecycle.Unlock()
 
 	if g.AutoStop {
-		c := make(chan os.Signal, 1)
+		c := make(chan os.Signal, 2)
 		g.trap(c)
+		defer signal.Stop(c)
 		if len(errs) > 0 {
 			// there was an error during start, so we immediately pass the interrupt
 			// in order to disconnect the initialized robots, connections and devices
@@ -73,20 +126,56 @@ func (g *Gobot) Start() (errs []error) {
 		}
 
 		// waiting for interrupt coming on the channel
-		<-c
-
-		// Stop calls the Stop method on each robot in its collection of robots.
-		g.Stop()
+		sig := <-c
+		g.Logger().Info("Shutting down", "signal", sig)
+		g.shutdown(c)
 	}
 
 	return errs
 }
 
-// Stop calls the Stop method on each robot in its collection of robots.
+// shutdown stops the Gobot within ShutdownTimeout, exiting if another
+// signal arrives on c first.
+func (g *Gobot) shutdown(c chan os.Signal) {
+	stopped := make(chan struct{})
+	defer close(stopped)
+	go func() {
+		select {
+		case sig := <-c:
+			g.Logger().Error("Forcing exit", "signal", sig)
+			g.exit(1)
+		case <-stopped:
+		}
+	}()
+
+	ctx := context.Background()
+	if g.ShutdownTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, g.ShutdownTimeout)
+		defer cancel()
+	}
+	g.StopContext(ctx)
+}
+
+// Stop stops the Gobot's rules and calls the Stop method on each robot in its
+// collection of robots, in the reverse of the order they were added.
 func (g *Gobot) Stop() (errs []error) {
-	if rerrs := g.robots.Stop(); len(rerrs) > 0 {
+	return g.StopContext(context.Background())
+}
+
+// StopContext is like Stop, but gives up on connections and devices which
+// have not stopped when ctx is done.
+func (g *Gobot) StopContext(ctx context.Context) (errs []error) {
+	g.lifecycle.Lock()
+	defer g.lifecycle.Unlock()
+	g.mtx.Lock()
+	g.running = false
+	g.mtx.Unlock()
+	g.stopRules()
+
+	if rerrs := g.Robots().StopContext(ctx); len(rerrs) > 0 {
 		for _, err := range rerrs {
-			log.Println("Error:", err)
+			g.Logger().Error("Stop failed", "error", err)
 			errs = append(errs, err)
 		}
 	}
@@ -94,24 +183,110 @@ func (g *Gobot) Stop() (errs []error) {
 	return errs
 }
 
-// Robots returns all robots associated with this Gobot.
+// Running returns true while the Gobot's robots are started.
+func (g *Gobot) Running() bool {
+	g.mtx.RLock()
+	defer g.mtx.RUnlock()
+	return g.running
+}
+
+// Robots returns a snapshot of the robots associated with this Gobot.
 func (g *Gobot) Robots() *Robots {
-	return g.robots
+	g.mtx.RLock()
+	defer g.mtx.RUnlock()
+
+	robots := append(Robots{}, *g.robots...)
+	return &robots
 }
 
-// AddRobot adds a new robot to the internal collection of robots. Returns the
-// added robot
+// AddRobot adds a new robot to the internal collection of robots and
+// publishes RobotAdded. The robot is not started, see AddRobotContext.
+// Returns the added robot
 func (g *Gobot) AddRobot(r *Robot) *Robot {
+	g.mtx.Lock()
 	*g.robots = append(*g.robots, r)
+	g.mtx.Unlock()
+
+	r.mtx.Lock()
+	r.gobot = g
+	r.mtx.Unlock()
+	r.adoptAll()
+
+	g.Publish(RobotAdded, r.Name)
 	return r
 }
 
 // Robot returns a robot given name. Returns nil if the Robot does not exist.
 func (g *Gobot) Robot(name string) *Robot {
-	for _, robot := range *g.Robots() {
+	g.mtx.RLock()
+	defer g.mtx.RUnlock()
+	for _, robot := range *g.robots {
 		if robot.Name == name {
 			return robot
 		}
 	}
 	return nil
 }
+
+// Logger returns the Logger of the Gobot, which is the DefaultLogger unless
+// SetLogger was called.
+func (g *Gobot) Logger() Logger {
+	g.mtx.RLock()
+	defer g.mtx.RUnlock()
+	if g.logger == nil {
+		return DefaultLogger()
+	}
+	return g.logger
+}
+
+// SetLogger sets the Logger of the Gobot. Robots which were not given a
+// Logger of their own, and their devices and connections, inherit it.
+func (g *Gobot) SetLogger(l Logger) {
+	g.mtx.Lock()
+	g.logger = l
+	g.mtx.Unlock()
+	g.Robots().Each(func(r *Robot) { r.adoptAll() })
+}
+
+// Store returns the Store of the Gobot, which is the DefaultStore unless
+// SetStore was called.
+func (g *Gobot) Store() Store {
+	g.mtx.RLock()
+	defer g.mtx.RUnlock()
+	if g.store == nil {
+		return DefaultStore()
+	}
+	return g.store
+}
+
+// Registry returns the Registry the metrics of the Gobot and its robots are
+// recorded in, which is the DefaultRegistry unless SetRegistry was called.
+func (g *Gobot) Registry() *Registry {
+	g.mtx.RLock()
+	defer g.mtx.RUnlock()
+	if g.registry == nil {
+		return DefaultRegistry()
+	}
+	return g.registry
+}
+
+// SetRegistry sets the Registry of the Gobot. The metrics of its robots,
+// their devices and connections, and those served by the api are recorded in
+// it.
+func (g *Gobot) SetRegistry(r *Registry) {
+	g.mtx.Lock()
+	g.registry = r
+	g.mtx.Unlock()
+	g.Eventer.SetEventRegistry(r)
+	g.Commander.SetCommandRegistry(r)
+	g.Robots().Each(func(r *Robot) { r.adoptAll() })
+}
+
+// SetStore sets the Store of the Gobot. Robots which were not given a Store
+// of their own, and their devices and connections, keep their state in it.
+func (g *Gobot) SetStore(s Store) {
+	g.mtx.Lock()
+	g.store = s
+	g.mtx.Unlock()
+	g.Robots().Each(func(r *Robot) { r.adoptAll() })
+}
@@ -1,10 +1,16 @@
 package gobot
 
 import (
+	"bytes"
+	"context"
 	"errors"
 	"log"
 	"os"
+	"strings"
+	"sync"
+	"syscall"
 	"testing"
+	"time"
 
 	"github.com/hybridgroup/gobot/gobottest"
 )
@@ -127,3 +133,274 @@ func TestGobotStartErrors(t *testing.T) {
 	gobottest.Assert(t, len(g.Start()), 0)
 	gobottest.Assert(t, len(g.Stop()), 2)
 }
+
+func TestRobotStopTimeout(t *testing.T) {
+	log.SetOutput(&NullReadWriteCloser{})
+	r := newTestRobot("Robot1")
+	r.Timeouts.Halt = 10 * time.Millisecond
+	testAdaptorFinalize = func() (errs []error) { return }
+
+	var wg sync.WaitGroup
+	block := make(chan bool)
+	testDriverHalt = func() (errs []error) {
+		defer wg.Done()
+		<-block
+		return
+	}
+	wg.Add(r.Devices().Len())
+
+	errs := r.Stop()
+	close(block)
+	wg.Wait()
+	testDriverHalt = func() (errs []error) { return }
+
+	gobottest.Assert(t, len(errs), 3)
+	// devices halt in the reverse of their start order
+	gobottest.Assert(t, errs[2].Error(),
+		"Device \"Device1\": Halt did not complete: context deadline exceeded")
+	gobottest.Assert(t, errors.Is(errs[0], context.DeadlineExceeded), true)
+}
+
+func TestRobotStartContextCancelled(t *testing.T) {
+	log.SetOutput(&NullReadWriteCloser{})
+	r := newTestRobot("Robot1")
+
+	var wg sync.WaitGroup
+	block := make(chan bool)
+	testAdaptorConnect = func() (errs []error) {
+		defer wg.Done()
+		<-block
+		return
+	}
+	wg.Add(1)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	errs := r.StartContext(ctx)
+	close(block)
+	wg.Wait()
+	testAdaptorConnect = func() (errs []error) { return }
+
+	gobottest.Assert(t, len(errs), 1)
+	gobottest.Assert(t, errors.Is(errs[0], context.Canceled), true)
+}
+
+type testContextDriver struct {
+	*testDriver
+	ctx context.Context
+}
+
+func (t *testContextDriver) StartContext(ctx context.Context) (errs []error) {
+	t.ctx = ctx
+	return
+}
+
+func (t *testContextDriver) HaltContext(ctx context.Context) (errs []error) {
+	t.ctx = ctx
+	return
+}
+
+func TestDevicesStartContextDriver(t *testing.T) {
+	d := &testContextDriver{testDriver: newTestDriver(newTestAdaptor("Connection1", "/dev/null"), "Device1", "0")}
+	devices := &Devices{d}
+
+	var buf bytes.Buffer
+	logger := NewLogger(log.New(&buf, "", 0), InfoLevel)
+	gobottest.Assert(t, len(devices.StartContext(context.Background(), time.Second, logger)), 0)
+	gobottest.Assert(t, strings.Contains(buf.String(), "Starting device device=Device1"), true)
+	_, ok := d.ctx.Deadline()
+	gobottest.Assert(t, ok, true)
+
+	gobottest.Assert(t, len(devices.HaltContext(context.Background(), 0)), 0)
+	_, ok = d.ctx.Deadline()
+	gobottest.Assert(t, ok, false)
+}
+
+func TestRobotEventSource(t *testing.T) {
+	log.SetOutput(&NullReadWriteCloser{})
+	adaptor := newTestAdaptor("Connection1", "/dev/null")
+	driver := &testEventDriver{
+		testDriver: newTestDriver(adaptor, "Device1", "0"),
+		Eventer:    NewEventer(),
+	}
+	r := NewRobot("Robot1", []Connection{adaptor}, []Device{driver})
+
+	events := driver.Subscribe()
+	driver.Publish("test", true)
+	evt := <-events
+	gobottest.Assert(t, evt.Robot, "Robot1")
+	gobottest.Assert(t, evt.Device, "Device1")
+
+	events = r.Subscribe()
+	r.Publish("test", true)
+	evt = <-events
+	gobottest.Assert(t, evt.Robot, "Robot1")
+	gobottest.Assert(t, evt.Device, "")
+}
+
+func TestRobotStopClosesEventers(t *testing.T) {
+	log.SetOutput(&NullReadWriteCloser{})
+	adaptor := newTestAdaptor("Connection1", "/dev/null")
+	driver := &testEventDriver{
+		testDriver: newTestDriver(adaptor, "Device1", "0"),
+		Eventer:    NewEventer(),
+	}
+	r := NewRobot("Robot1", []Connection{adaptor}, []Device{driver})
+	gobottest.Assert(t, len(r.Start()), 0)
+
+	deviceSub, _ := driver.On("push", func(interface{}) {})
+	robotSub, _ := r.On("test", func(interface{}) {})
+	gobottest.Assert(t, len(r.Stop()), 0)
+	for _, sub := range []*Subscription{deviceSub, robotSub} {
+		select {
+		case <-sub.Done():
+		default:
+			t.Error("subscription was not cancelled")
+		}
+	}
+}
+
+type testSafeDriver struct {
+	*testDriver
+	log      *[]string
+	mtx      *sync.Mutex
+	safeErr  error
+	haltErrs []error
+	halt     func()
+}
+
+func (t *testSafeDriver) record(step string) {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+	*t.log = append(*t.log, step+" "+t.name)
+}
+
+func (t *testSafeDriver) SafeState() error {
+	t.record("safe")
+	return t.safeErr
+}
+
+func (t *testSafeDriver) Halt() []error {
+	if t.halt != nil {
+		t.halt()
+	}
+	t.record("halt")
+	return t.haltErrs
+}
+
+func newTestSafeRobot(name string, log *[]string, mtx *sync.Mutex, devices ...string) *Robot {
+	adaptor := newTestAdaptor("Connection1", "/dev/null")
+	r := NewRobot(name, []Connection{adaptor})
+	for _, device := range devices {
+		r.AddDevice(&testSafeDriver{testDriver: newTestDriver(adaptor, device, "0"), log: log, mtx: mtx})
+	}
+	return r
+}
+
+func TestRobotStopOrder(t *testing.T) {
+	log.SetOutput(&NullReadWriteCloser{})
+	var steps []string
+	r := newTestSafeRobot("Robot1", &steps, &sync.Mutex{}, "B", "A", "C")
+	r.DependsOn("B", "A")
+	r.Device("B").(*testSafeDriver).safeErr = errors.New("stuck")
+	r.Device("C").(*testSafeDriver).haltErrs = []error{errors.New("busy")}
+
+	errs := r.Stop()
+	gobottest.Assert(t, steps, []string{"safe C", "safe B", "safe A", "halt C", "halt B", "halt A"})
+	gobottest.Assert(t, len(errs), 2)
+	gobottest.Assert(t, errs[0].Error(), `Device "B": stuck`)
+	gobottest.Assert(t, errs[1].Error(), `Device "C": busy`)
+}
+
+func TestRobotsStopOrder(t *testing.T) {
+	log.SetOutput(&NullReadWriteCloser{})
+	var steps []string
+	mtx := &sync.Mutex{}
+	robots := &Robots{
+		newTestSafeRobot("Robot1", &steps, mtx, "A"),
+		newTestSafeRobot("Robot2", &steps, mtx, "B"),
+	}
+	(*robots)[1].Device("B").(*testSafeDriver).haltErrs = []error{errors.New("busy")}
+
+	errs := robots.Stop()
+	gobottest.Assert(t, steps, []string{"safe B", "halt B", "safe A", "halt A"})
+	gobottest.Assert(t, len(errs), 1)
+	gobottest.Assert(t, errs[0].Error(), `Robot "Robot2": Device "B": busy`)
+}
+
+func TestRobotsStopShare(t *testing.T) {
+	log.SetOutput(&NullReadWriteCloser{})
+	var steps []string
+	robots := &Robots{
+		newTestSafeRobot("Robot1", &steps, &sync.Mutex{}, "A"),
+		newTestSafeRobot("Robot2", &steps, &sync.Mutex{}, "B"),
+	}
+	block := make(chan struct{})
+	defer close(block)
+	(*robots)[1].Device("B").(*testSafeDriver).halt = func() { <-block }
+
+	// the hung device only uses up its own share of the deadline
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	errs := robots.StopContext(ctx)
+	gobottest.Assert(t, len(errs), 1)
+	gobottest.Assert(t, strings.HasPrefix(errs[0].Error(), `Robot "Robot2": Device "B": Halt did not complete`), true)
+}
+
+func TestGobotSignals(t *testing.T) {
+	g := NewGobot()
+	gobottest.Assert(t, g.Signals, []os.Signal{os.Interrupt, syscall.SIGTERM, syscall.SIGHUP})
+	gobottest.Assert(t, g.ShutdownTimeout, DefaultShutdownTimeout)
+}
+
+func TestGobotShutdownTimeout(t *testing.T) {
+	log.SetOutput(&NullReadWriteCloser{})
+	var steps []string
+	g := NewGobot()
+	g.ShutdownTimeout = 10 * time.Millisecond
+	g.trap = func(c chan os.Signal) {
+		c <- syscall.SIGTERM
+	}
+	r := g.AddRobot(newTestSafeRobot("Robot1", &steps, &sync.Mutex{}, "A"))
+	block := make(chan struct{})
+	defer close(block)
+	r.Device("A").(*testSafeDriver).halt = func() { <-block }
+
+	done := make(chan []error)
+	go func() { done <- g.Start() }()
+	select {
+	case errs := <-done:
+		gobottest.Assert(t, len(errs), 0)
+	case <-time.After(time.Second):
+		t.Fatal("Gobot did not shut down")
+	}
+	gobottest.Assert(t, g.Running(), false)
+}
+
+func TestGobotForcedExit(t *testing.T) {
+	log.SetOutput(&NullReadWriteCloser{})
+	var steps []string
+	g := NewGobot()
+	g.trap = func(c chan os.Signal) {
+		c <- os.Interrupt
+		c <- os.Interrupt
+	}
+	exited := make(chan int, 1)
+	g.exit = func(code int) {
+		exited <- code
+	}
+	r := g.AddRobot(newTestSafeRobot("Robot1", &steps, &sync.Mutex{}, "A"))
+	r.Device("A").(*testSafeDriver).halt = func() {
+		// halting only finishes once the second signal has forced an exit
+		exited <- <-exited
+	}
+
+	g.Start()
+	gobottest.Assert(t, <-exited, 1)
+}
+
+func TestRobotDefaultTimeouts(t *testing.T) {
+	r := NewRobot("Robot1")
+	gobottest.Assert(t, r.Timeouts, Timeouts{Halt: DefaultHaltTimeout, Finalize: DefaultFinalizeTimeout})
+}
@@ -80,3 +80,8 @@ func newTestRobot(name string) *Robot {
 
 	return r
 }
+
+type testEventDriver struct {
+	*testDriver
+	Eventer
+}
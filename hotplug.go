@@ -0,0 +1,245 @@
+package gobot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// Events published when robots, devices and connections are added or
+// removed, and when a robot of a Gobot starts. The event data is the name of
+// the robot, device or connection. Robot events are published on the Gobot's
+// Eventer, the others on the Robot's Eventer.
+const (
+	// RobotAdded is published when a Robot is added to a Gobot
+	RobotAdded = "robot-added"
+	// RobotRemoved is published when a Robot is removed from a Gobot
+	RobotRemoved = "robot-removed"
+	// RobotStarted is published when a Robot of a Gobot has started, before
+	// its work runs
+	RobotStarted = "robot-started"
+	// DeviceAdded is published when a Device is added to a Robot
+	DeviceAdded = "device-added"
+	// DeviceRemoved is published when a Device is removed from a Robot
+	DeviceRemoved = "device-removed"
+	// ConnectionAdded is published when a Connection is added to a Robot
+	ConnectionAdded = "connection-added"
+	// ConnectionRemoved is published when a Connection is removed from a
+	// Robot
+	ConnectionRemoved = "connection-removed"
+)
+
+// Running returns true if the Robot has been started and not yet stopped.
+func (r *Robot) Running() bool {
+	r.mtx.RLock()
+	defer r.mtx.RUnlock()
+	return r.running
+}
+
+// AddDeviceContext adds a Device to the Robot, unless it already has a
+// device with the same name. If the Robot is running, the device is started
+// first and only added once it has started, giving up when ctx is done or
+// Timeouts.Start expires. The Robot does not start or stop meanwhile.
+func (r *Robot) AddDeviceContext(ctx context.Context, d Device) (errs []error) {
+	r.lifecycle.Lock()
+	defer r.lifecycle.Unlock()
+	if r.Device(d.Name()) != nil {
+		return []error{fmt.Errorf("Device %q already exists", d.Name())}
+	}
+	if r.Running() {
+		r.adopt(d)
+		if errs = start(ctx, r.Timeouts.Start, d); len(errs) > 0 {
+			for i, err := range errs {
+				errs[i] = fmt.Errorf("Device %q: %w", d.Name(), err)
+			}
+			return
+		}
+	}
+	r.AddDevice(d)
+	return
+}
+
+// RemoveDevice removes a device given a name, halting it if the Robot is
+// running.
+func (r *Robot) RemoveDevice(name string) (errs []error) {
+	return r.RemoveDeviceContext(context.Background(), name)
+}
+
+// RemoveDeviceContext removes a device given a name and publishes
+// DeviceRemoved. If the Robot is running the device is halted, giving up when
+// ctx is done or Timeouts.Halt expires, and subscriptions to its events are
+// cancelled. The device is removed even if it fails to halt.
+func (r *Robot) RemoveDeviceContext(ctx context.Context, name string) (errs []error) {
+	r.lifecycle.Lock()
+	defer r.lifecycle.Unlock()
+	r.mtx.Lock()
+	var device Device
+	for i, d := range *r.devices {
+		if d.Name() == name {
+			device = d
+			*r.devices = append((*r.devices)[:i:i], (*r.devices)[i+1:]...)
+			break
+		}
+	}
+	running, s := r.running, r.supervisor
+	r.mtx.Unlock()
+
+	if device == nil {
+		return []error{errors.New("No Device found with the name " + name)}
+	}
+	if running && (s == nil || !s.isHalted(device)) {
+		if errs = halt(ctx, r.Timeouts.Halt, device); len(errs) > 0 {
+			for i, err := range errs {
+				errs[i] = fmt.Errorf("Device %q: %w", name, err)
+			}
+		}
+	}
+	if running {
+		closeEventer(device)
+	}
+	r.Publish(DeviceRemoved, name)
+	return
+}
+
+// AddConnectionContext adds a Connection to the Robot, unless it already has
+// a connection with the same name. If the Robot is running, the connection is
+// connected first and only added once it has connected, giving up when ctx is
+// done or Timeouts.Connect expires; the Robot does not start or stop
+// meanwhile. Running robots with Supervision start supervising the
+// connection.
+func (r *Robot) AddConnectionContext(ctx context.Context, c Connection) (errs []error) {
+	r.lifecycle.Lock()
+	defer r.lifecycle.Unlock()
+	if r.Connection(c.Name()) != nil {
+		return []error{fmt.Errorf("Connection %q already exists", c.Name())}
+	}
+	if r.Running() {
+		r.adopt(c)
+		if errs = connect(ctx, r.Timeouts.Connect, c); len(errs) > 0 {
+			for i, err := range errs {
+				errs[i] = fmt.Errorf("Connection %q: %w", c.Name(), err)
+			}
+			return
+		}
+	}
+	r.AddConnection(c)
+
+	r.mtx.RLock()
+	s := r.supervisor
+	r.mtx.RUnlock()
+	if s != nil {
+		s.watch(c)
+	}
+	return
+}
+
+// RemoveConnection removes a connection given a name, finalizing it if the
+// Robot is running.
+func (r *Robot) RemoveConnection(name string) (errs []error) {
+	return r.RemoveConnectionContext(context.Background(), name)
+}
+
+// RemoveConnectionContext removes a connection given a name and publishes
+// ConnectionRemoved. A connection can only be removed once no device uses
+// it. If the Robot is running the connection is finalized, giving up when
+// ctx is done or Timeouts.Finalize expires, and subscriptions to its events
+// are cancelled. The connection is removed even if it fails to finalize.
+func (r *Robot) RemoveConnectionContext(ctx context.Context, name string) (errs []error) {
+	r.lifecycle.Lock()
+	defer r.lifecycle.Unlock()
+	r.mtx.Lock()
+	var connection Connection
+	index := 0
+	for i, c := range *r.connections {
+		if c.Name() == name {
+			connection, index = c, i
+			break
+		}
+	}
+	if connection == nil {
+		r.mtx.Unlock()
+		return []error{errors.New("No Connection found with the name " + name)}
+	}
+	for _, d := range *r.devices {
+		if d.Connection() == connection {
+			r.mtx.Unlock()
+			return []error{fmt.Errorf("Connection %q is used by device %q", name, d.Name())}
+		}
+	}
+	*r.connections = append((*r.connections)[:index:index], (*r.connections)[index+1:]...)
+	running, s := r.running, r.supervisor
+	r.mtx.Unlock()
+
+	if s != nil {
+		s.unwatch(connection)
+	}
+	if running {
+		if errs = finalize(ctx, r.Timeouts.Finalize, connection); len(errs) > 0 {
+			for i, err := range errs {
+				errs[i] = fmt.Errorf("Connection %q: %w", name, err)
+			}
+		}
+		closeEventer(connection)
+	}
+	r.Publish(ConnectionRemoved, name)
+	return
+}
+
+// AddRobotContext adds a Robot to the Gobot, unless it already has a robot
+// with the same name. If the Gobot is running, the robot is started first and
+// only added once it has started; the Gobot does not start or stop
+// meanwhile, so AddRobotContext must not be called from the Work of a robot
+// the Gobot is starting.
+func (g *Gobot) AddRobotContext(ctx context.Context, r *Robot) (errs []error) {
+	g.lifecycle.Lock()
+	defer g.lifecycle.Unlock()
+	if g.Robot(r.Name) != nil {
+		return []error{fmt.Errorf("Robot %q already exists", r.Name)}
+	}
+	if g.Running() {
+		if errs = r.StartContext(ctx); len(errs) > 0 {
+			for i, err := range errs {
+				errs[i] = fmt.Errorf("Robot %q: %w", r.Name, err)
+			}
+			return
+		}
+	}
+	g.AddRobot(r)
+	return
+}
+
+// RemoveRobot removes a robot given a name, stopping it if it is running.
+func (g *Gobot) RemoveRobot(name string) (errs []error) {
+	return g.RemoveRobotContext(context.Background(), name)
+}
+
+// RemoveRobotContext removes a robot given a name and publishes
+// RobotRemoved. If the robot is running it is stopped, giving up when ctx is
+// done. The robot is removed even if it fails to stop.
+func (g *Gobot) RemoveRobotContext(ctx context.Context, name string) (errs []error) {
+	g.lifecycle.Lock()
+	defer g.lifecycle.Unlock()
+	g.mtx.Lock()
+	var robot *Robot
+	for i, r := range *g.robots {
+		if r.Name == name {
+			robot = r
+			*g.robots = append((*g.robots)[:i:i], (*g.robots)[i+1:]...)
+			break
+		}
+	}
+	g.mtx.Unlock()
+
+	if robot == nil {
+		return []error{errors.New("No Robot found with the name " + name)}
+	}
+	if robot.Running() {
+		if errs = robot.StopContext(ctx); len(errs) > 0 {
+			for i, err := range errs {
+				errs[i] = fmt.Errorf("Robot %q: %w", name, err)
+			}
+		}
+	}
+	g.Publish(RobotRemoved, name)
+	return
+}
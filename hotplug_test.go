@@ -0,0 +1,244 @@
+package gobot
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/hybridgroup/gobot/gobottest"
+)
+
+type testHotplugDriver struct {
+	*testDriver
+	starts int32
+	halts  int32
+	err    error
+}
+
+func (t *testHotplugDriver) Start() (errs []error) {
+	atomic.AddInt32(&t.starts, 1)
+	if t.err != nil {
+		return []error{t.err}
+	}
+	return
+}
+
+func (t *testHotplugDriver) Halt() (errs []error) {
+	atomic.AddInt32(&t.halts, 1)
+	return
+}
+
+func newTestHotplugDriver(c Connection, name string) *testHotplugDriver {
+	return &testHotplugDriver{testDriver: &testDriver{name: name, connection: c, Commander: NewCommander()}}
+}
+
+func waitForEvent(t *testing.T, events chan interface{}, want interface{}) {
+	select {
+	case data := <-events:
+		gobottest.Assert(t, data, want)
+	case <-time.After(time.Second):
+		t.Errorf("event %v was not published", want)
+	}
+}
+
+func TestRobotAddRemoveDevice(t *testing.T) {
+	adaptor := newTestAdaptor("Connection1", "/dev/null")
+	r := NewRobot("Robot1", []Connection{adaptor})
+
+	added := make(chan interface{}, 1)
+	removed := make(chan interface{}, 1)
+	r.On(DeviceAdded, func(data interface{}) { added <- data })
+	r.On(DeviceRemoved, func(data interface{}) { removed <- data })
+
+	// not running, so the device is only added
+	d1 := newTestHotplugDriver(adaptor, "Device1")
+	gobottest.Assert(t, len(r.AddDeviceContext(context.Background(), d1)), 0)
+	waitForEvent(t, added, "Device1")
+	gobottest.Assert(t, atomic.LoadInt32(&d1.starts), int32(0))
+
+	gobottest.Assert(t, len(r.Start()), 0)
+	gobottest.Assert(t, r.Running(), true)
+	gobottest.Assert(t, atomic.LoadInt32(&d1.starts), int32(1))
+
+	r.On(DeviceAdded, func(data interface{}) { added <- data })
+	r.On(DeviceRemoved, func(data interface{}) { removed <- data })
+
+	d2 := newTestHotplugDriver(adaptor, "Device2")
+	gobottest.Assert(t, len(r.AddDeviceContext(context.Background(), d2)), 0)
+	waitForEvent(t, added, "Device2")
+	gobottest.Assert(t, atomic.LoadInt32(&d2.starts), int32(1))
+	gobottest.Assert(t, r.Devices().Len(), 2)
+
+	broken := newTestHotplugDriver(adaptor, "Broken")
+	broken.err = errors.New("start error")
+	errs := r.AddDeviceContext(context.Background(), broken)
+	gobottest.Assert(t, errs[0].Error(), `Device "Broken": start error`)
+	gobottest.Assert(t, r.Device("Broken"), (Device)(nil))
+
+	gobottest.Assert(t, len(r.RemoveDevice("Device2")), 0)
+	waitForEvent(t, removed, "Device2")
+	gobottest.Assert(t, atomic.LoadInt32(&d2.halts), int32(1))
+	gobottest.Assert(t, r.Device("Device2"), (Device)(nil))
+	gobottest.Assert(t, r.Devices().Len(), 1)
+
+	errs = r.RemoveDevice("Device2")
+	gobottest.Assert(t, errs[0].Error(), "No Device found with the name Device2")
+
+	gobottest.Assert(t, len(r.Stop()), 0)
+	gobottest.Assert(t, r.Running(), false)
+	gobottest.Assert(t, atomic.LoadInt32(&d1.halts), int32(1))
+}
+
+func TestRobotAddRemoveConnection(t *testing.T) {
+	adaptor := newTestAdaptor("Connection1", "/dev/null")
+	r := NewRobot("Robot1",
+		[]Connection{adaptor},
+		[]Device{newTestHotplugDriver(adaptor, "Device1")},
+	)
+	r.Supervision = &Supervision{}
+	gobottest.Assert(t, len(r.Start()), 0)
+
+	errs := r.RemoveConnection("Connection1")
+	gobottest.Assert(t, errs[0].Error(), `Connection "Connection1" is used by device "Device1"`)
+
+	supervised := &testSupervisedAdaptor{
+		testAdaptor: newTestAdaptor("Connection2", "/dev/null"),
+		Eventer:     NewEventer(),
+	}
+	gobottest.Assert(t, len(r.AddConnectionContext(context.Background(), supervised)), 0)
+	gobottest.Assert(t, atomic.LoadInt32(&supervised.connects), int32(1))
+	gobottest.Assert(t, r.ConnectionState("Connection2"), Connected)
+
+	errs = r.AddConnectionContext(context.Background(), &testSupervisedAdaptor{
+		testAdaptor: newTestAdaptor("Connection3", "/dev/null"),
+		Eventer:     NewEventer(),
+		failures:    1,
+	})
+	gobottest.Assert(t, errs[0].Error(), `Connection "Connection3": connect error`)
+	gobottest.Assert(t, r.Connection("Connection3"), (Connection)(nil))
+
+	gobottest.Assert(t, len(r.RemoveConnection("Connection2")), 0)
+	gobottest.Assert(t, r.Connection("Connection2"), (Connection)(nil))
+	gobottest.Assert(t, r.ConnectionState("Connection2"), ConnectionState(""))
+
+	errs = r.RemoveConnection("Connection2")
+	gobottest.Assert(t, errs[0].Error(), "No Connection found with the name Connection2")
+
+	gobottest.Assert(t, len(r.Stop()), 0)
+}
+
+func TestGobotAddRemoveRobot(t *testing.T) {
+	g := initTestGobot()
+	g.AutoStop = false
+
+	added := make(chan interface{}, 1)
+	removed := make(chan interface{}, 1)
+	g.On(RobotAdded, func(data interface{}) { added <- data })
+	g.On(RobotRemoved, func(data interface{}) { removed <- data })
+
+	gobottest.Assert(t, len(g.Start()), 0)
+
+	r := newTestRobot("Robot4")
+	gobottest.Assert(t, len(g.AddRobotContext(context.Background(), r)), 0)
+	waitForEvent(t, added, "Robot4")
+	gobottest.Assert(t, r.Running(), true)
+	gobottest.Assert(t, g.Robots().Len(), 4)
+
+	gobottest.Assert(t, len(g.RemoveRobot("Robot4")), 0)
+	waitForEvent(t, removed, "Robot4")
+	gobottest.Assert(t, r.Running(), false)
+	gobottest.Assert(t, g.Robot("Robot4"), (*Robot)(nil))
+
+	errs := g.RemoveRobot("Robot4")
+	gobottest.Assert(t, errs[0].Error(), "No Robot found with the name Robot4")
+
+	gobottest.Assert(t, len(g.Stop()), 0)
+}
+
+func TestRobotConcurrentAddRemove(t *testing.T) {
+	adaptor := newTestAdaptor("Connection1", "/dev/null")
+	r := NewRobot("Robot1", []Connection{adaptor})
+	gobottest.Assert(t, len(r.Start()), 0)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(2)
+		name := string(rune('a' + i))
+		go func() {
+			defer wg.Done()
+			r.AddDeviceContext(context.Background(), newTestHotplugDriver(adaptor, name))
+			r.RemoveDevice(name)
+		}()
+		go func() {
+			defer wg.Done()
+			NewJSONRobot(r)
+			r.Device(name)
+		}()
+	}
+	wg.Wait()
+
+	gobottest.Assert(t, r.Devices().Len(), 0)
+	gobottest.Assert(t, len(r.Stop()), 0)
+}
+
+func TestHotplugDuplicateNames(t *testing.T) {
+	adaptor := newTestAdaptor("Connection1", "/dev/null")
+	r := NewRobot("Robot1", []Connection{adaptor})
+	gobottest.Assert(t, len(r.AddDeviceContext(context.Background(), newTestHotplugDriver(adaptor, "Device1"))), 0)
+
+	d := newTestHotplugDriver(adaptor, "Device1")
+	errs := r.AddDeviceContext(context.Background(), d)
+	gobottest.Assert(t, errs[0].Error(), `Device "Device1" already exists`)
+	gobottest.Assert(t, r.Devices().Len(), 1)
+
+	errs = r.AddConnectionContext(context.Background(), newTestAdaptor("Connection1", "/dev/null"))
+	gobottest.Assert(t, errs[0].Error(), `Connection "Connection1" already exists`)
+	gobottest.Assert(t, r.Connections().Len(), 1)
+
+	g := NewGobot()
+	gobottest.Assert(t, len(g.AddRobotContext(context.Background(), r)), 0)
+	errs = g.AddRobotContext(context.Background(), NewRobot("Robot1"))
+	gobottest.Assert(t, errs[0].Error(), `Robot "Robot1" already exists`)
+	gobottest.Assert(t, g.Robots().Len(), 1)
+}
+
+type testBlockingDriver struct {
+	*testDriver
+	starting chan struct{}
+	release  chan struct{}
+}
+
+func (t *testBlockingDriver) Start() (errs []error) {
+	close(t.starting)
+	<-t.release
+	return
+}
+
+func TestRobotAddDeviceWhileStarting(t *testing.T) {
+	adaptor := newTestAdaptor("Connection1", "/dev/null")
+	r := NewRobot("Robot1", []Connection{adaptor})
+	blocking := &testBlockingDriver{
+		testDriver: newTestDriver(adaptor, "Blocking", "0"),
+		starting:   make(chan struct{}),
+		release:    make(chan struct{}),
+	}
+	r.AddDevice(blocking)
+	started := make(chan []error)
+	go func() { started <- r.Start() }()
+	<-blocking.starting
+
+	// the robot has already chosen the devices it starts
+	d := newTestHotplugDriver(adaptor, "Device1")
+	added := make(chan []error)
+	go func() { added <- r.AddDeviceContext(context.Background(), d) }()
+	time.Sleep(10 * time.Millisecond)
+	close(blocking.release)
+
+	gobottest.Assert(t, len(<-started), 0)
+	gobottest.Assert(t, len(<-added), 0)
+	gobottest.Assert(t, atomic.LoadInt32(&d.starts), int32(1))
+	r.Stop()
+}
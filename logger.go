@@ -0,0 +1,172 @@
+package gobot
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+)
+
+// LogLevel is the severity of a log message.
+type LogLevel int
+
+const (
+	// DebugLevel is for detailed messages useful when debugging a driver
+	DebugLevel LogLevel = iota
+	// InfoLevel is for the lifecycle messages Gobot logs by default
+	InfoLevel
+	// WarnLevel is for problems Gobot recovers from
+	WarnLevel
+	// ErrorLevel is for failures
+	ErrorLevel
+	// SilentLevel logs nothing
+	SilentLevel
+)
+
+var logLevelNames = map[LogLevel]string{
+	DebugLevel:  "debug",
+	InfoLevel:   "info",
+	WarnLevel:   "warn",
+	ErrorLevel:  "error",
+	SilentLevel: "silent",
+}
+
+func (l LogLevel) String() string {
+	if name, ok := logLevelNames[l]; ok {
+		return name
+	}
+	return fmt.Sprintf("LogLevel(%d)", int(l))
+}
+
+// ParseLogLevel returns the LogLevel given its name, such as "debug" or
+// "warn".
+func ParseLogLevel(name string) (LogLevel, error) {
+	for level, n := range logLevelNames {
+		if strings.EqualFold(n, name) {
+			return level, nil
+		}
+	}
+	return InfoLevel, fmt.Errorf("unknown log level %q", name)
+}
+
+// Logger is the interface which describes how Gobot, its robots and their
+// drivers and adaptors log messages. Fields are alternating keys and values,
+// such as "robot", "bot" or "pin", "13".
+type Logger interface {
+	// Debug logs a message at DebugLevel
+	Debug(msg string, fields ...interface{})
+	// Info logs a message at InfoLevel
+	Info(msg string, fields ...interface{})
+	// Warn logs a message at WarnLevel
+	Warn(msg string, fields ...interface{})
+	// Error logs a message at ErrorLevel
+	Error(msg string, fields ...interface{})
+	// With returns a Logger which adds fields to every message
+	With(fields ...interface{}) Logger
+}
+
+// Loggable is the interface that describes a driver or adaptor which logs
+// through a Logger. Robots give each Loggable device and connection a Logger
+// carrying the robot's name and the device's or connection's name.
+type Loggable interface {
+	SetLogger(l Logger)
+}
+
+// Logging implements Loggable, and can be embedded in drivers and adaptors.
+// Until SetLogger is called, Logger returns the DefaultLogger.
+type Logging struct {
+	mtx    sync.RWMutex
+	logger Logger
+}
+
+// SetLogger sets the Logger to use.
+func (l *Logging) SetLogger(logger Logger) {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	l.logger = logger
+}
+
+// Logger returns the Logger to use.
+func (l *Logging) Logger() Logger {
+	l.mtx.RLock()
+	defer l.mtx.RUnlock()
+	if l.logger == nil {
+		return DefaultLogger()
+	}
+	return l.logger
+}
+
+type stdLogger struct {
+	logger *log.Logger
+	level  LogLevel
+	fields []interface{}
+}
+
+// NewLogger returns a Logger which writes messages at level or above to l,
+// or to the standard logger of the log package when l is nil. Messages are
+// written on a single line followed by their fields as key=value pairs, and
+// are prefixed with their level unless it is InfoLevel.
+func NewLogger(l *log.Logger, level LogLevel) Logger {
+	return &stdLogger{logger: l, level: level}
+}
+
+var defaultLogger = NewLogger(nil, InfoLevel)
+
+// DefaultLogger returns the Logger used when none is set. It writes messages
+// at InfoLevel and above to the standard logger of the log package.
+func DefaultLogger() Logger {
+	return defaultLogger
+}
+
+func (s *stdLogger) Debug(msg string, fields ...interface{}) { s.log(DebugLevel, msg, fields) }
+func (s *stdLogger) Info(msg string, fields ...interface{})  { s.log(InfoLevel, msg, fields) }
+func (s *stdLogger) Warn(msg string, fields ...interface{})  { s.log(WarnLevel, msg, fields) }
+func (s *stdLogger) Error(msg string, fields ...interface{}) { s.log(ErrorLevel, msg, fields) }
+
+func (s *stdLogger) With(fields ...interface{}) Logger {
+	return &stdLogger{
+		logger: s.logger,
+		level:  s.level,
+		fields: append(append([]interface{}{}, s.fields...), fields...),
+	}
+}
+
+func (s *stdLogger) log(level LogLevel, msg string, fields []interface{}) {
+	if level < s.level || s.level == SilentLevel {
+		return
+	}
+
+	var b strings.Builder
+	if level != InfoLevel {
+		b.WriteString(strings.ToUpper(level.String()))
+		b.WriteString(" ")
+	}
+	b.WriteString(msg)
+	writeFields(&b, s.fields)
+	writeFields(&b, fields)
+
+	if s.logger != nil {
+		s.logger.Println(b.String())
+	} else {
+		log.Println(b.String())
+	}
+}
+
+func writeFields(b *strings.Builder, fields []interface{}) {
+	for i := 0; i < len(fields); i += 2 {
+		b.WriteString(" ")
+		if i+1 == len(fields) {
+			fmt.Fprintf(b, "%v=<missing>", fields[i])
+			return
+		}
+		fmt.Fprintf(b, "%v=%v", fields[i], formatValue(fields[i+1]))
+	}
+}
+
+func formatValue(v interface{}) string {
+	s := fmt.Sprint(v)
+	if s == "" || strings.ContainsAny(s, " \t\n\"=") {
+		return fmt.Sprintf("%q", s)
+	}
+	return s
+}
@@ -0,0 +1,74 @@
+package gobot
+
+import (
+	"bytes"
+	"log"
+	"testing"
+
+	"github.com/hybridgroup/gobot/gobottest"
+)
+
+type testLoggableDriver struct {
+	*testDriver
+	Logging
+}
+
+func newTestBufferLogger(level LogLevel) (Logger, *bytes.Buffer) {
+	buf := &bytes.Buffer{}
+	return NewLogger(log.New(buf, "", 0), level), buf
+}
+
+func TestLogger(t *testing.T) {
+	logger, buf := newTestBufferLogger(InfoLevel)
+
+	logger.Debug("hidden")
+	logger.Info("Starting", "robot", "bot", "pin", 13)
+	logger.Warn("Slow", "message", "took a while")
+	logger.Error("Failed", "error", "")
+	logger.With("robot", "bot").Info("odd", "key")
+
+	gobottest.Assert(t, buf.String(),
+		"Starting robot=bot pin=13\n"+
+			"WARN Slow message=\"took a while\"\n"+
+			"ERROR Failed error=\"\"\n"+
+			"odd robot=bot key=<missing>\n")
+
+	logger, buf = newTestBufferLogger(SilentLevel)
+	logger.Error("hidden")
+	gobottest.Assert(t, buf.Len(), 0)
+}
+
+func TestParseLogLevel(t *testing.T) {
+	level, err := ParseLogLevel("WARN")
+	gobottest.Assert(t, err, nil)
+	gobottest.Assert(t, level, WarnLevel)
+	gobottest.Assert(t, level.String(), "warn")
+
+	_, err = ParseLogLevel("loud")
+	gobottest.Refute(t, err, nil)
+	gobottest.Assert(t, LogLevel(42).String(), "LogLevel(42)")
+}
+
+func TestLoggerInheritance(t *testing.T) {
+	logger, buf := newTestBufferLogger(DebugLevel)
+
+	adaptor := newTestAdaptor("Connection1", "/dev/null")
+	driver := &testLoggableDriver{testDriver: newTestDriver(adaptor, "Device1", "13")}
+	gobottest.Assert(t, driver.Logger(), DefaultLogger())
+
+	r := NewRobot("Robot1", []Connection{adaptor}, []Device{driver})
+	g := NewGobot()
+	g.AddRobot(r)
+	g.SetLogger(logger)
+
+	driver.Logger().Debug("Reading")
+	r.Logger().Info("Working")
+	gobottest.Assert(t, buf.String(),
+		"DEBUG Reading robot=Robot1 device=Device1 pin=13\n"+
+			"Working robot=Robot1\n")
+
+	own, ownBuf := newTestBufferLogger(InfoLevel)
+	r.SetLogger(own)
+	driver.Logger().Info("Moved")
+	gobottest.Assert(t, ownBuf.String(), "Moved robot=Robot1 device=Device1 pin=13\n")
+}
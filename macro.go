@@ -0,0 +1,340 @@
+package gobot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"time"
+)
+
+// Macro is an ordered list of commands of a Robot and its devices, run one
+// after the other with optional delays and conditions.
+type Macro struct {
+	Name        string      `json:"name,omitempty" yaml:"name,omitempty"`
+	Description string      `json:"description,omitempty" yaml:"description,omitempty"`
+	Steps       []MacroStep `json:"steps" yaml:"steps"`
+	// ContinueOnError runs the remaining steps after a step fails, rather
+	// than skipping them
+	ContinueOnError bool `json:"continue_on_error,omitempty" yaml:"continue_on_error,omitempty"`
+}
+
+// MacroStep is a step of a Macro. It waits for Delay and then runs Command,
+// unless its condition does not hold. A step without a Command only waits.
+type MacroStep struct {
+	// Name, when set, allows the conditions of later steps to refer to the
+	// step
+	Name string `json:"name,omitempty" yaml:"name,omitempty"`
+	// Device has the command, or the Robot itself when empty
+	Device  string                 `json:"device,omitempty" yaml:"device,omitempty"`
+	Command string                 `json:"command,omitempty" yaml:"command,omitempty"`
+	Params  map[string]interface{} `json:"params,omitempty" yaml:"params,omitempty"`
+	Delay   Duration               `json:"delay,omitempty" yaml:"delay,omitempty"`
+	If      *MacroCondition        `json:"if,omitempty" yaml:"if,omitempty"`
+}
+
+// MacroCondition tests the outcome of an earlier step of a Macro.
+type MacroCondition struct {
+	// Step names the step tested, or is empty for the step before
+	Step string `json:"step,omitempty" yaml:"step,omitempty"`
+	// Op is "ok" or "failed" to test whether the step succeeded, or one of
+	// ==, !=, >, >=, < and <= to compare its result with Value
+	Op    string      `json:"op" yaml:"op"`
+	Value interface{} `json:"value,omitempty" yaml:"value,omitempty"`
+}
+
+// MacroStepStatus is the outcome of a step of a Macro.
+type MacroStepStatus string
+
+const (
+	// StepSucceeded means the step ran its command, or waited, without error
+	StepSucceeded MacroStepStatus = "ok"
+	// StepFailed means the step's command returned an error
+	StepFailed MacroStepStatus = "failed"
+	// StepSkipped means the step's condition did not hold, or an earlier
+	// step failed
+	StepSkipped MacroStepStatus = "skipped"
+	// StepCancelled means the macro was cancelled before the step finished
+	StepCancelled MacroStepStatus = "cancelled"
+)
+
+// MacroStepResult describes how a step of a Macro ran.
+type MacroStepResult struct {
+	Step    int             `json:"step"`
+	Name    string          `json:"name,omitempty"`
+	Device  string          `json:"device,omitempty"`
+	Command string          `json:"command,omitempty"`
+	Status  MacroStepStatus `json:"status"`
+	Result  interface{}     `json:"result,omitempty"`
+	Error   string          `json:"error,omitempty"`
+}
+
+// MacroResult describes a run of a Macro.
+type MacroResult struct {
+	Macro     string            `json:"macro,omitempty"`
+	Steps     []MacroStepResult `json:"steps"`
+	Cancelled bool              `json:"cancelled"`
+	// Error is the error of the first step which failed
+	Error    string        `json:"error,omitempty"`
+	Duration time.Duration `json:"duration"`
+}
+
+// checkMacro returns an error describing the first step of m which cannot run on
+// the Robot.
+func (r *Robot) checkMacro(m Macro) error {
+	names := map[string]bool{}
+	for i, step := range m.Steps {
+		if step.Command == "" && step.Delay <= 0 {
+			return fmt.Errorf("Step %v: needs a command or a delay", i)
+		}
+		if step.Command != "" {
+			if _, err := r.macroCommand(step); err != nil {
+				return fmt.Errorf("Step %v: %w", i, err)
+			}
+			// macros run one at a time, so one cannot wait on another
+			if step.Device == "" && r.Macro(step.Command) != nil {
+				return fmt.Errorf("Step %v: cannot run the macro %q", i, step.Command)
+			}
+		}
+		if c := step.If; c != nil {
+			if c.Step != "" && !names[c.Step] {
+				return fmt.Errorf("Step %v: condition refers to unknown step %q", i, c.Step)
+			}
+			if c.Step == "" && i == 0 {
+				return fmt.Errorf("Step %v: condition has no step before it", i)
+			}
+			if _, ok := ruleOps[c.Op]; !ok && c.Op != "ok" && c.Op != "failed" {
+				return fmt.Errorf("Step %v: unknown op %q", i, c.Op)
+			}
+		}
+		if step.Name != "" {
+			names[step.Name] = true
+		}
+	}
+	return nil
+}
+
+// macroCommand returns the command run by step.
+func (r *Robot) macroCommand(step MacroStep) (func(map[string]interface{}) interface{}, error) {
+	var commander Commander = r
+	if step.Device != "" {
+		device := r.Device(step.Device)
+		if device == nil {
+			return nil, errors.New("No Device found with the name " + step.Device)
+		}
+		c, ok := device.(Commander)
+		if !ok {
+			return nil, fmt.Errorf("Device %q has no commands", step.Device)
+		}
+		commander = c
+	}
+	command := commander.Command(step.Command)
+	if command == nil {
+		return nil, errors.New("No Command found with the name " + step.Command)
+	}
+	return command, nil
+}
+
+// RunMacro runs the steps of m in order and returns how each of them ran.
+// Every step is checked before any runs, so a macro which refers to unknown
+// devices or commands returns an error without running at all. The macros
+// of a Robot run one at a time; RunMacro waits for any macro already
+// running. Cancelling ctx, calling CancelMacro or stopping the Robot
+// cancels the remaining steps.
+func (r *Robot) RunMacro(ctx context.Context, m Macro) (*MacroResult, error) {
+	if err := r.checkMacro(m); err != nil {
+		if m.Name != "" {
+			return nil, fmt.Errorf("Macro %q: %w", m.Name, err)
+		}
+		return nil, err
+	}
+
+	select {
+	case r.macroRun <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	defer func() { <-r.macroRun }()
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	r.mtx.Lock()
+	r.macroCancel = cancel
+	r.mtx.Unlock()
+	defer func() {
+		r.mtx.Lock()
+		r.macroCancel = nil
+		r.mtx.Unlock()
+	}()
+
+	begin := time.Now()
+	result := &MacroResult{Macro: m.Name, Steps: []MacroStepResult{}}
+	named := map[string]int{}
+	failed := false
+	for i, step := range m.Steps {
+		sr := MacroStepResult{Step: i, Name: step.Name, Device: step.Device, Command: step.Command}
+		switch {
+		case ctx.Err() != nil:
+			sr.Status = StepCancelled
+		case failed && !m.ContinueOnError:
+			sr.Status = StepSkipped
+		case step.If != nil && !step.If.holds(result.Steps, named):
+			sr.Status = StepSkipped
+		default:
+			r.runStep(ctx, step, &sr)
+		}
+
+		switch sr.Status {
+		case StepCancelled:
+			result.Cancelled = true
+		case StepFailed:
+			if !failed {
+				result.Error = fmt.Sprintf("Step %v: %v", i, sr.Error)
+			}
+			failed = true
+		}
+		result.Steps = append(result.Steps, sr)
+		if step.Name != "" {
+			named[step.Name] = i
+		}
+	}
+	result.Duration = time.Since(begin)
+	return result, nil
+}
+
+// runStep waits for the delay of step and runs its command, recording the
+// outcome in sr.
+func (r *Robot) runStep(ctx context.Context, step MacroStep, sr *MacroStepResult) {
+	if step.Delay > 0 {
+		t := time.NewTimer(time.Duration(step.Delay))
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			t.Stop()
+			sr.Status = StepCancelled
+			return
+		}
+	}
+
+	sr.Status = StepSucceeded
+	if step.Command == "" {
+		return
+	}
+	command, err := r.macroCommand(step)
+	if err == nil {
+		sr.Result = command(step.Params)
+		err, _ = sr.Result.(error)
+	}
+	if err != nil {
+		sr.Status = StepFailed
+		sr.Result = nil
+		sr.Error = err.Error()
+	}
+}
+
+// holds returns true if the condition is met by the results of the steps so
+// far.
+func (c *MacroCondition) holds(results []MacroStepResult, named map[string]int) bool {
+	i := len(results) - 1
+	if c.Step != "" {
+		i = named[c.Step]
+	}
+	step := results[i]
+
+	switch c.Op {
+	case "ok":
+		return step.Status == StepSucceeded
+	case "failed":
+		return step.Status == StepFailed
+	}
+	if step.Status != StepSucceeded {
+		return false
+	}
+
+	v, vok := toFloat64(step.Result)
+	value, valueok := toFloat64(c.Value)
+	if vok && valueok {
+		return ruleOps[c.Op](v, value)
+	}
+	switch c.Op {
+	case "==":
+		return reflect.DeepEqual(step.Result, c.Value)
+	case "!=":
+		return !reflect.DeepEqual(step.Result, c.Value)
+	}
+	return false
+}
+
+// CancelMacro cancels the macro the Robot is running, returning false if
+// there is none.
+func (r *Robot) CancelMacro() bool {
+	r.mtx.RLock()
+	cancel := r.macroCancel
+	r.mtx.RUnlock()
+	if cancel == nil {
+		return false
+	}
+	cancel()
+	return true
+}
+
+// AddMacro checks m and stores it, adding a robot command named after the
+// macro which runs it and returns its MacroResult. A macro with the same
+// name is replaced, but other robot commands are not. The steps of a macro
+// cannot run stored macros.
+func (r *Robot) AddMacro(m Macro) error {
+	if m.Name == "" {
+		return errors.New("Macro has no name")
+	}
+	if err := r.checkMacro(m); err != nil {
+		return fmt.Errorf("Macro %q: %w", m.Name, err)
+	}
+
+	r.mtx.Lock()
+	if _, ok := r.macros[m.Name]; !ok && r.Command(m.Name) != nil {
+		r.mtx.Unlock()
+		return fmt.Errorf("Macro %q: a command with the name already exists", m.Name)
+	}
+	if r.macros == nil {
+		r.macros = make(map[string]Macro)
+	}
+	r.macros[m.Name] = m
+	r.mtx.Unlock()
+
+	description := m.Description
+	if description == "" {
+		description = "Runs the macro " + m.Name
+	}
+	r.AddCommandSchema(CommandSchema{Name: m.Name, Description: description},
+		func(params map[string]interface{}) interface{} {
+			result, err := r.RunMacro(context.Background(), m)
+			if err != nil {
+				return err
+			}
+			return result
+		})
+	return nil
+}
+
+// Macro returns the stored macro called name, or nil if there is none.
+func (r *Robot) Macro(name string) *Macro {
+	r.mtx.RLock()
+	defer r.mtx.RUnlock()
+	if m, ok := r.macros[name]; ok {
+		return &m
+	}
+	return nil
+}
+
+// Macros returns the stored macros, sorted by name.
+func (r *Robot) Macros() []Macro {
+	r.mtx.RLock()
+	defer r.mtx.RUnlock()
+	macros := []Macro{}
+	for _, m := range r.macros {
+		macros = append(macros, m)
+	}
+	sort.Slice(macros, func(i, j int) bool { return macros[i].Name < macros[j].Name })
+	return macros
+}
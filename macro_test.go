@@ -0,0 +1,233 @@
+package gobot
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hybridgroup/gobot/gobottest"
+)
+
+// newTestMacroRobot returns a robot with commands which record their calls.
+func newTestMacroRobot() (*Robot, *[]string) {
+	calls := []string{}
+	record := func(name string, result interface{}) func(map[string]interface{}) interface{} {
+		return func(params map[string]interface{}) interface{} {
+			calls = append(calls, name)
+			if v, ok := params["value"]; ok {
+				return v
+			}
+			return result
+		}
+	}
+
+	adaptor := newTestAdaptor("Connection1", "/dev/null")
+	driver := newTestDriver(adaptor, "Device1", "0")
+	driver.AddCommand("read", record("read", 42))
+	r := NewRobot("bot", []Connection{adaptor}, []Device{driver})
+	r.AddCommand("on", record("on", "ok"))
+	r.AddCommand("off", record("off", nil))
+	r.AddCommand("fail", record("fail", errors.New("boom")))
+	return r, &calls
+}
+
+func TestRunMacro(t *testing.T) {
+	r, calls := newTestMacroRobot()
+	result, err := r.RunMacro(context.Background(), Macro{
+		Name: "blink",
+		Steps: []MacroStep{
+			{Command: "on"},
+			{Delay: Duration(10 * time.Millisecond)},
+			{Name: "reading", Device: "Device1", Command: "read"},
+			{Command: "off"},
+		},
+	})
+	gobottest.Assert(t, err, nil)
+	gobottest.Assert(t, *calls, []string{"on", "read", "off"})
+	gobottest.Assert(t, result.Macro, "blink")
+	gobottest.Assert(t, result.Cancelled, false)
+	gobottest.Assert(t, result.Error, "")
+	gobottest.Assert(t, len(result.Steps), 4)
+	gobottest.Assert(t, result.Steps[0].Result, "ok")
+	gobottest.Assert(t, result.Steps[1].Status, StepSucceeded)
+	gobottest.Assert(t, result.Steps[2].Device, "Device1")
+	gobottest.Assert(t, result.Steps[2].Result, 42)
+	gobottest.Assert(t, result.Duration >= 10*time.Millisecond, true)
+}
+
+func TestRunMacroInvalid(t *testing.T) {
+	r, calls := newTestMacroRobot()
+	for _, test := range []struct {
+		steps []MacroStep
+		err   string
+	}{
+		{[]MacroStep{{Command: "on"}, {Command: "unknown"}}, "Step 1: No Command found with the name unknown"},
+		{[]MacroStep{{Device: "unknown", Command: "read"}}, "Step 0: No Device found with the name unknown"},
+		{[]MacroStep{{}}, "Step 0: needs a command or a delay"},
+		{[]MacroStep{{Command: "on", If: &MacroCondition{Op: "ok"}}}, "Step 0: condition has no step before it"},
+		{[]MacroStep{{Command: "on"}, {Command: "on", If: &MacroCondition{Step: "x", Op: "ok"}}},
+			`Step 1: condition refers to unknown step "x"`},
+		{[]MacroStep{{Command: "on"}, {Command: "on", If: &MacroCondition{Op: "~"}}}, `Step 1: unknown op "~"`},
+	} {
+		_, err := r.RunMacro(context.Background(), Macro{Steps: test.steps})
+		gobottest.Assert(t, err.Error(), test.err)
+	}
+	gobottest.Assert(t, len(*calls), 0)
+
+	_, err := r.RunMacro(context.Background(), Macro{Name: "bad", Steps: []MacroStep{{}}})
+	gobottest.Assert(t, err.Error(), `Macro "bad": Step 0: needs a command or a delay`)
+}
+
+func TestRunMacroErrors(t *testing.T) {
+	r, calls := newTestMacroRobot()
+	m := Macro{Steps: []MacroStep{{Command: "on"}, {Command: "fail"}, {Command: "off"}}}
+	result, _ := r.RunMacro(context.Background(), m)
+	gobottest.Assert(t, *calls, []string{"on", "fail"})
+	gobottest.Assert(t, result.Error, "Step 1: boom")
+	gobottest.Assert(t, result.Steps[1].Status, StepFailed)
+	gobottest.Assert(t, result.Steps[1].Error, "boom")
+	gobottest.Assert(t, result.Steps[2].Status, StepSkipped)
+
+	*calls = []string{}
+	m.ContinueOnError = true
+	result, _ = r.RunMacro(context.Background(), m)
+	gobottest.Assert(t, *calls, []string{"on", "fail", "off"})
+	gobottest.Assert(t, result.Error, "Step 1: boom")
+	gobottest.Assert(t, result.Steps[2].Status, StepSucceeded)
+}
+
+func TestRunMacroConditions(t *testing.T) {
+	r, calls := newTestMacroRobot()
+	result, _ := r.RunMacro(context.Background(), Macro{
+		ContinueOnError: true,
+		Steps: []MacroStep{
+			{Name: "reading", Device: "Device1", Command: "read"},
+			{Command: "on", If: &MacroCondition{Op: ">", Value: 40}},
+			{Command: "off", If: &MacroCondition{Step: "reading", Op: "<", Value: 40}},
+			{Name: "state", Command: "on", Params: map[string]interface{}{"value": "high"}},
+			{Command: "off", If: &MacroCondition{Step: "state", Op: "==", Value: "high"}},
+			{Name: "broken", Command: "fail"},
+			{Command: "on", If: &MacroCondition{Op: "ok"}},
+			{Command: "off", If: &MacroCondition{Step: "broken", Op: "failed"}},
+		},
+	})
+	gobottest.Assert(t, *calls, []string{"read", "on", "on", "off", "fail", "off"})
+	gobottest.Assert(t, result.Steps[2].Status, StepSkipped)
+	gobottest.Assert(t, result.Steps[6].Status, StepSkipped)
+}
+
+func TestRunMacroCancel(t *testing.T) {
+	r, calls := newTestMacroRobot()
+	gobottest.Assert(t, r.CancelMacro(), false)
+
+	m := Macro{Steps: []MacroStep{
+		{Command: "on"},
+		{Delay: Duration(time.Minute)},
+		{Command: "off"},
+	}}
+	done := make(chan *MacroResult)
+	go func() {
+		result, _ := r.RunMacro(context.Background(), m)
+		done <- result
+	}()
+
+	waitMacro(r)
+	gobottest.Assert(t, r.CancelMacro(), true)
+	select {
+	case result := <-done:
+		gobottest.Assert(t, result.Cancelled, true)
+		gobottest.Assert(t, result.Steps[0].Status, StepSucceeded)
+		gobottest.Assert(t, result.Steps[1].Status, StepCancelled)
+		gobottest.Assert(t, result.Steps[2].Status, StepCancelled)
+	case <-time.After(time.Second):
+		t.Fatal("macro was not cancelled")
+	}
+	gobottest.Assert(t, *calls, []string{"on"})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	result, _ := r.RunMacro(ctx, m)
+	gobottest.Assert(t, result.Cancelled, true)
+}
+
+// waitMacro waits until r is running a macro.
+func waitMacro(r *Robot) {
+	for {
+		r.mtx.RLock()
+		running := r.macroCancel != nil
+		r.mtx.RUnlock()
+		if running {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestRunMacroOneAtATime(t *testing.T) {
+	r, calls := newTestMacroRobot()
+	go r.RunMacro(context.Background(), Macro{Steps: []MacroStep{{Delay: Duration(time.Minute)}}})
+	waitMacro(r)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err := r.RunMacro(ctx, Macro{Steps: []MacroStep{{Command: "on"}}})
+	gobottest.Assert(t, err, context.DeadlineExceeded)
+	gobottest.Assert(t, len(*calls), 0)
+	r.CancelMacro()
+}
+
+func TestRobotStopCancelsMacro(t *testing.T) {
+	r, _ := newTestMacroRobot()
+	done := make(chan *MacroResult)
+	go func() {
+		result, _ := r.RunMacro(context.Background(), Macro{Steps: []MacroStep{{Delay: Duration(time.Minute)}}})
+		done <- result
+	}()
+	waitMacro(r)
+
+	r.Stop()
+	select {
+	case result := <-done:
+		gobottest.Assert(t, result.Cancelled, true)
+	case <-time.After(time.Second):
+		t.Fatal("macro was not cancelled")
+	}
+}
+
+func TestAddMacro(t *testing.T) {
+	r, calls := newTestMacroRobot()
+	gobottest.Assert(t, r.AddMacro(Macro{Steps: []MacroStep{{Command: "on"}}}).Error(), "Macro has no name")
+	gobottest.Assert(t, strings.HasPrefix(r.AddMacro(Macro{Name: "bad", Steps: []MacroStep{{Command: "x"}}}).Error(),
+		`Macro "bad": Step 0:`), true)
+	gobottest.Assert(t, r.Macro("bad") == nil, true)
+
+	gobottest.Assert(t, r.AddMacro(Macro{Name: "toggle", Steps: []MacroStep{{Command: "on"}, {Command: "off"}}}), nil)
+	gobottest.Assert(t, r.AddMacro(Macro{Name: "blink", Steps: []MacroStep{{Command: "on"}}}), nil)
+	gobottest.Assert(t, r.Macro("toggle").Name, "toggle")
+	gobottest.Assert(t, len(r.Macros()), 2)
+	gobottest.Assert(t, r.Macros()[0].Name, "blink")
+
+	result := r.Command("toggle")(nil).(*MacroResult)
+	gobottest.Assert(t, *calls, []string{"on", "off"})
+	gobottest.Assert(t, len(result.Steps), 2)
+	gobottest.Assert(t, r.CommandSchema("toggle").Description, "Runs the macro toggle")
+
+	// other commands cannot be replaced
+	gobottest.Assert(t, r.AddMacro(Macro{Name: "on", Steps: []MacroStep{{Command: "off"}}}).Error(),
+		`Macro "on": a command with the name already exists`)
+	gobottest.Assert(t, r.Macro("on") == nil, true)
+}
+
+func TestAddMacroNested(t *testing.T) {
+	r, _ := newTestMacroRobot()
+	gobottest.Assert(t, r.AddMacro(Macro{Name: "inner", Steps: []MacroStep{{Command: "on"}}}), nil)
+
+	gobottest.Assert(t, r.AddMacro(Macro{Name: "outer", Steps: []MacroStep{{Command: "inner"}}}).Error(),
+		`Macro "outer": Step 0: cannot run the macro "inner"`)
+	gobottest.Assert(t, r.AddMacro(Macro{Name: "inner", Steps: []MacroStep{{Command: "inner"}}}).Error(),
+		`Macro "inner": Step 0: cannot run the macro "inner"`)
+	_, err := r.RunMacro(context.Background(), Macro{Steps: []MacroStep{{Command: "inner"}}})
+	gobottest.Assert(t, err.Error(), `Step 0: cannot run the macro "inner"`)
+}
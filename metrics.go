@@ -0,0 +1,353 @@
+package gobot
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// MetricType is the kind of a metric, named as in the Prometheus text
+// format.
+type MetricType string
+
+const (
+	// CounterMetric only ever increases
+	CounterMetric MetricType = "counter"
+	// GaugeMetric goes up and down
+	GaugeMetric MetricType = "gauge"
+	// HistogramMetric counts observations in buckets
+	HistogramMetric MetricType = "histogram"
+)
+
+// DefaultBuckets are the upper bounds, in seconds, of the buckets used for
+// durations.
+var DefaultBuckets = []float64{0.0005, 0.001, 0.005, 0.01, 0.05, 0.1, 0.5, 1, 5}
+
+// Labels are the names and values which distinguish the metrics of a
+// family, such as robot and device.
+type Labels map[string]string
+
+// Counter is a metric which only ever increases. It is safe for concurrent
+// use.
+type Counter struct {
+	bits uint64
+}
+
+// Inc adds one to the Counter.
+func (c *Counter) Inc() {
+	c.Add(1)
+}
+
+// Add adds v, which must not be negative, to the Counter.
+func (c *Counter) Add(v float64) {
+	addFloat64(&c.bits, v)
+}
+
+// Value returns the current value of the Counter.
+func (c *Counter) Value() float64 {
+	return math.Float64frombits(atomic.LoadUint64(&c.bits))
+}
+
+// Gauge is a metric which goes up and down. It is safe for concurrent use.
+type Gauge struct {
+	bits uint64
+}
+
+// Set sets the Gauge to v.
+func (g *Gauge) Set(v float64) {
+	atomic.StoreUint64(&g.bits, math.Float64bits(v))
+}
+
+// Add adds v to the Gauge.
+func (g *Gauge) Add(v float64) {
+	addFloat64(&g.bits, v)
+}
+
+// Value returns the current value of the Gauge.
+func (g *Gauge) Value() float64 {
+	return math.Float64frombits(atomic.LoadUint64(&g.bits))
+}
+
+// Histogram counts observations in buckets. It is safe for concurrent use.
+type Histogram struct {
+	mtx     sync.Mutex
+	bounds  []float64
+	buckets []uint64
+	count   uint64
+	sum     float64
+}
+
+// Observe adds v to the Histogram.
+func (h *Histogram) Observe(v float64) {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+
+	for i, bound := range h.bounds {
+		if v <= bound {
+			h.buckets[i]++
+		}
+	}
+	h.count++
+	h.sum += v
+}
+
+func addFloat64(bits *uint64, v float64) {
+	for {
+		old := atomic.LoadUint64(bits)
+		new := math.Float64bits(math.Float64frombits(old) + v)
+		if atomic.CompareAndSwapUint64(bits, old, new) {
+			return
+		}
+	}
+}
+
+// Bucket is the number of observations of a Histogram less than or equal to
+// UpperBound.
+type Bucket struct {
+	UpperBound float64 `json:"upper_bound"`
+	Count      uint64  `json:"count"`
+}
+
+// Metric is a snapshot of a single metric of a family.
+type Metric struct {
+	Labels Labels `json:"labels,omitempty"`
+	// Value is the value of a counter or gauge
+	Value float64 `json:"value"`
+	// Buckets, Count and Sum describe a histogram. Buckets does not
+	// include the +Inf bucket, whose count is Count.
+	Buckets []Bucket `json:"buckets,omitempty"`
+	Count   uint64   `json:"count,omitempty"`
+	Sum     float64  `json:"sum,omitempty"`
+}
+
+// MetricFamily is a snapshot of the metrics sharing a name.
+type MetricFamily struct {
+	Name    string     `json:"name"`
+	Help    string     `json:"help"`
+	Type    MetricType `json:"type"`
+	Metrics []Metric   `json:"metrics"`
+}
+
+type family struct {
+	name    string
+	help    string
+	kind    MetricType
+	bounds  []float64
+	metrics map[string]*labelledMetric
+}
+
+type labelledMetric struct {
+	labels Labels
+	metric interface{}
+}
+
+// Registry holds metrics by name and labels. It is safe for concurrent use.
+type Registry struct {
+	mtx      sync.RWMutex
+	families map[string]*family
+}
+
+// NewRegistry returns a new, empty Registry.
+func NewRegistry() *Registry {
+	return &Registry{families: make(map[string]*family)}
+}
+
+var defaultRegistry = NewRegistry()
+
+// DefaultRegistry returns the Registry which Gobot's eventers, commanders,
+// supervisors and, through the adaptors built on sysfs, sysfs devices record
+// their metrics in, unless Gobot.SetRegistry is called.
+func DefaultRegistry() *Registry {
+	return defaultRegistry
+}
+
+// Counter returns the Counter with name and labels, creating it if needed.
+// It panics if name is already used by a metric of another type.
+func (r *Registry) Counter(name, help string, labels Labels) *Counter {
+	return r.metric(name, help, CounterMetric, nil, labels, func(*family) interface{} {
+		return &Counter{}
+	}).(*Counter)
+}
+
+// Count adds v to the Counter with name and labels, creating it if needed.
+// It lets packages which cannot import gobot, such as sysfs, record in a
+// Registry.
+func (r *Registry) Count(name, help string, labels map[string]string, v float64) {
+	r.Counter(name, help, labels).Add(v)
+}
+
+// Gauge returns the Gauge with name and labels, creating it if needed. It
+// panics if name is already used by a metric of another type.
+func (r *Registry) Gauge(name, help string, labels Labels) *Gauge {
+	return r.metric(name, help, GaugeMetric, nil, labels, func(*family) interface{} {
+		return &Gauge{}
+	}).(*Gauge)
+}
+
+// Histogram returns the Histogram with name and labels, creating it if
+// needed. buckets are the upper bounds of its buckets, DefaultBuckets when
+// nil; they are fixed by the first call for a name. It panics if name is
+// already used by a metric of another type.
+func (r *Registry) Histogram(name, help string, buckets []float64, labels Labels) *Histogram {
+	if buckets == nil {
+		buckets = DefaultBuckets
+	}
+	return r.metric(name, help, HistogramMetric, buckets, labels, func(f *family) interface{} {
+		return &Histogram{bounds: f.bounds, buckets: make([]uint64, len(f.bounds))}
+	}).(*Histogram)
+}
+
+func (r *Registry) metric(name, help string, kind MetricType, bounds []float64, labels Labels, create func(*family) interface{}) interface{} {
+	key := labelsKey(labels)
+
+	r.mtx.RLock()
+	f, ok := r.families[name]
+	if ok && f.kind == kind {
+		if m, ok := f.metrics[key]; ok {
+			r.mtx.RUnlock()
+			return m.metric
+		}
+	}
+	r.mtx.RUnlock()
+
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	if f, ok = r.families[name]; !ok {
+		bounds = append([]float64{}, bounds...)
+		sort.Float64s(bounds)
+		f = &family{name: name, help: help, kind: kind, bounds: bounds, metrics: make(map[string]*labelledMetric)}
+		r.families[name] = f
+	}
+	if f.kind != kind {
+		panic(fmt.Sprintf("metric %q is a %v, not a %v", name, f.kind, kind))
+	}
+	if m, ok := f.metrics[key]; ok {
+		return m.metric
+	}
+
+	metric := create(f)
+	copied := Labels{}
+	for k, v := range labels {
+		copied[k] = v
+	}
+	f.metrics[key] = &labelledMetric{labels: copied, metric: metric}
+	return metric
+}
+
+// Snapshot returns the current value of every metric, sorted by name and
+// labels.
+func (r *Registry) Snapshot() []MetricFamily {
+	r.mtx.RLock()
+	defer r.mtx.RUnlock()
+
+	names := make([]string, 0, len(r.families))
+	for name := range r.families {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	families := make([]MetricFamily, 0, len(names))
+	for _, name := range names {
+		f := r.families[name]
+		keys := make([]string, 0, len(f.metrics))
+		for key := range f.metrics {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		mf := MetricFamily{Name: f.name, Help: f.help, Type: f.kind, Metrics: []Metric{}}
+		for _, key := range keys {
+			lm := f.metrics[key]
+			m := Metric{Labels: lm.labels}
+			switch metric := lm.metric.(type) {
+			case *Counter:
+				m.Value = metric.Value()
+			case *Gauge:
+				m.Value = metric.Value()
+			case *Histogram:
+				metric.mtx.Lock()
+				for i, bound := range metric.bounds {
+					m.Buckets = append(m.Buckets, Bucket{UpperBound: bound, Count: metric.buckets[i]})
+				}
+				m.Count, m.Sum = metric.count, metric.sum
+				metric.mtx.Unlock()
+			}
+			mf.Metrics = append(mf.Metrics, m)
+		}
+		families = append(families, mf)
+	}
+	return families
+}
+
+// WritePrometheus writes every metric to w in the Prometheus text exposition
+// format.
+func (r *Registry) WritePrometheus(w io.Writer) error {
+	b := bufio.NewWriter(w)
+	for _, f := range r.Snapshot() {
+		fmt.Fprintf(b, "# HELP %v %v\n", f.Name, escapeHelp(f.Help))
+		fmt.Fprintf(b, "# TYPE %v %v\n", f.Name, f.Type)
+		for _, m := range f.Metrics {
+			if f.Type != HistogramMetric {
+				fmt.Fprintf(b, "%v%v %v\n", f.Name, formatLabels(m.Labels, "", ""), formatFloat(m.Value))
+				continue
+			}
+			for _, bucket := range m.Buckets {
+				fmt.Fprintf(b, "%v_bucket%v %v\n", f.Name, formatLabels(m.Labels, "le", formatFloat(bucket.UpperBound)), bucket.Count)
+			}
+			fmt.Fprintf(b, "%v_bucket%v %v\n", f.Name, formatLabels(m.Labels, "le", "+Inf"), m.Count)
+			fmt.Fprintf(b, "%v_sum%v %v\n", f.Name, formatLabels(m.Labels, "", ""), formatFloat(m.Sum))
+			fmt.Fprintf(b, "%v_count%v %v\n", f.Name, formatLabels(m.Labels, "", ""), m.Count)
+		}
+	}
+	return b.Flush()
+}
+
+func labelsKey(labels Labels) string {
+	return formatLabels(labels, "", "")
+}
+
+// formatLabels returns labels in the Prometheus text format, sorted by name,
+// with an extra label appended when name is not empty.
+func formatLabels(labels Labels, name, value string) string {
+	if len(labels) == 0 && name == "" {
+		return ""
+	}
+
+	names := make([]string, 0, len(labels))
+	for n := range labels {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+
+	pairs := make([]string, 0, len(names)+1)
+	for _, n := range names {
+		pairs = append(pairs, n+`="`+escapeLabel(labels[n])+`"`)
+	}
+	if name != "" {
+		pairs = append(pairs, name+`="`+escapeLabel(value)+`"`)
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+var helpEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+
+func escapeLabel(s string) string { return labelEscaper.Replace(s) }
+func escapeHelp(s string) string  { return helpEscaper.Replace(s) }
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
@@ -0,0 +1,86 @@
+package gobot
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/hybridgroup/gobot/gobottest"
+)
+
+func TestRegistryCounter(t *testing.T) {
+	r := NewRegistry()
+	c := r.Counter("requests_total", "Requests.", Labels{"robot": "bot"})
+	c.Inc()
+	c.Add(2.5)
+	gobottest.Assert(t, c.Value(), 3.5)
+	gobottest.Assert(t, r.Counter("requests_total", "Requests.", Labels{"robot": "bot"}), c)
+	gobottest.Refute(t, r.Counter("requests_total", "Requests.", Labels{"robot": "other"}), c)
+}
+
+func TestRegistryGauge(t *testing.T) {
+	r := NewRegistry()
+	g := r.Gauge("depth", "Depth.", nil)
+	g.Set(4)
+	g.Add(-1.5)
+	gobottest.Assert(t, g.Value(), 2.5)
+}
+
+func TestRegistryTypeMismatch(t *testing.T) {
+	r := NewRegistry()
+	r.Counter("things", "Things.", nil)
+
+	defer func() {
+		gobottest.Assert(t, recover(), `metric "things" is a counter, not a gauge`)
+	}()
+	r.Gauge("things", "Things.", nil)
+}
+
+func TestRegistrySnapshot(t *testing.T) {
+	r := NewRegistry()
+	r.Gauge("b", "B.", Labels{"x": "2"}).Set(2)
+	r.Gauge("b", "B.", Labels{"x": "1"}).Set(1)
+	r.Counter("a", "A.", nil).Inc()
+	h := r.Histogram("c", "C.", []float64{1, 0.1}, nil)
+	h.Observe(0.05)
+	h.Observe(0.5)
+	h.Observe(2)
+
+	families := r.Snapshot()
+	gobottest.Assert(t, len(families), 3)
+	gobottest.Assert(t, families[0].Name, "a")
+	gobottest.Assert(t, families[1].Metrics, []Metric{
+		{Labels: Labels{"x": "1"}, Value: 1},
+		{Labels: Labels{"x": "2"}, Value: 2},
+	})
+	gobottest.Assert(t, families[2].Metrics[0].Buckets, []Bucket{
+		{UpperBound: 0.1, Count: 1},
+		{UpperBound: 1, Count: 2},
+	})
+	gobottest.Assert(t, families[2].Metrics[0].Count, uint64(3))
+	gobottest.Assert(t, families[2].Metrics[0].Sum, 2.55)
+
+	_, err := json.Marshal(families)
+	gobottest.Assert(t, err, nil)
+}
+
+func TestRegistryWritePrometheus(t *testing.T) {
+	r := NewRegistry()
+	r.Counter("events_total", "Events published.", Labels{"robot": "bot", "event": `say "hi"`}).Add(3)
+	h := r.Histogram("duration_seconds", "Durations.", []float64{0.5}, Labels{"robot": "bot"})
+	h.Observe(0.25)
+	h.Observe(1)
+
+	var buf bytes.Buffer
+	gobottest.Assert(t, r.WritePrometheus(&buf), nil)
+	gobottest.Assert(t, buf.String(), `# HELP duration_seconds Durations.
+# TYPE duration_seconds histogram
+duration_seconds_bucket{robot="bot",le="0.5"} 1
+duration_seconds_bucket{robot="bot",le="+Inf"} 2
+duration_seconds_sum{robot="bot"} 1.25
+duration_seconds_count{robot="bot"} 2
+# HELP events_total Events published.
+# TYPE events_total counter
+events_total{event="say \"hi\"",robot="bot"} 3
+`)
+}
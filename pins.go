@@ -0,0 +1,90 @@
+package gobot
+
+import (
+	"fmt"
+	"sync"
+)
+
+// PinReserver is the interface that describes an adaptor which keeps track
+// of the pins its devices use. As a Robot starts each device implementing
+// Pinner, it reserves the device's pin on the device's connection, and
+// releases it once the device halts, so that two devices cannot use the same
+// pin.
+type PinReserver interface {
+	// ReservePin claims pin for device, returning an error if another
+	// device holds it
+	ReservePin(pin, device string) error
+	// ReleasePin gives up the claim of device on pin
+	ReleasePin(pin, device string)
+	// ReservedPins returns the name of the device holding each reserved pin
+	ReservedPins() map[string]string
+}
+
+// PinRegistry implements PinReserver, for adaptors to embed. The zero value
+// is an empty registry.
+type PinRegistry struct {
+	mtx      sync.Mutex
+	reserved map[string]string
+}
+
+// ReservePin claims pin for device. It returns an error if another device
+// holds it; claiming a pin the device already holds succeeds.
+func (p *PinRegistry) ReservePin(pin, device string) error {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	if holder, ok := p.reserved[pin]; ok && holder != device {
+		return fmt.Errorf("Pin %q is already reserved by device %q", pin, holder)
+	}
+	if p.reserved == nil {
+		p.reserved = make(map[string]string)
+	}
+	p.reserved[pin] = device
+	return nil
+}
+
+// ReleasePin gives up the claim of device on pin. Pins held by other devices
+// are left reserved.
+func (p *PinRegistry) ReleasePin(pin, device string) {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	if p.reserved[pin] == device {
+		delete(p.reserved, pin)
+	}
+}
+
+// ReservedPins returns the name of the device holding each reserved pin.
+func (p *PinRegistry) ReservedPins() map[string]string {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	pins := make(map[string]string, len(p.reserved))
+	for pin, device := range p.reserved {
+		pins[pin] = device
+	}
+	return pins
+}
+
+// devicePin returns the pin of device and the registry of its connection, if
+// it has both.
+func devicePin(device Device) (pin string, reserver PinReserver, ok bool) {
+	pinner, ok := device.(Pinner)
+	if !ok || pinner.Pin() == "" {
+		return "", nil, false
+	}
+	reserver, ok = device.Connection().(PinReserver)
+	return pinner.Pin(), reserver, ok
+}
+
+// reservePin reserves the pin of device on its connection.
+func reservePin(device Device) error {
+	if pin, reserver, ok := devicePin(device); ok {
+		return reserver.ReservePin(pin, device.Name())
+	}
+	return nil
+}
+
+// releasePin releases the pin of device on its connection.
+func releasePin(device Device) {
+	if pin, reserver, ok := devicePin(device); ok {
+		reserver.ReleasePin(pin, device.Name())
+	}
+}
@@ -0,0 +1,51 @@
+package gobot
+
+import (
+	"log"
+	"testing"
+
+	"github.com/hybridgroup/gobot/gobottest"
+)
+
+type testPinAdaptor struct {
+	*testAdaptor
+	PinRegistry
+}
+
+var _ PinReserver = (*testPinAdaptor)(nil)
+
+func TestPinRegistry(t *testing.T) {
+	p := &PinRegistry{}
+	gobottest.Assert(t, p.ReservedPins(), map[string]string{})
+	gobottest.Assert(t, p.ReservePin("7", "led"), nil)
+	gobottest.Assert(t, p.ReservePin("7", "led"), nil)
+	gobottest.Assert(t, p.ReservePin("7", "button").Error(), `Pin "7" is already reserved by device "led"`)
+
+	p.ReleasePin("7", "button")
+	gobottest.Assert(t, p.ReservedPins(), map[string]string{"7": "led"})
+	p.ReleasePin("7", "led")
+	gobottest.Assert(t, p.ReservePin("7", "button"), nil)
+}
+
+func TestRobotPinConflict(t *testing.T) {
+	log.SetOutput(&NullReadWriteCloser{})
+	adaptor := &testPinAdaptor{testAdaptor: newTestAdaptor("board", "/dev/null")}
+	r := NewRobot("Robot1",
+		[]Connection{adaptor},
+		[]Device{
+			newTestDriver(adaptor.testAdaptor, "led", "7"),
+			newTestDriver(adaptor.testAdaptor, "button", "7"),
+		},
+	)
+	// the drivers use the reserving adaptor as their connection
+	r.Devices().Each(func(d Device) { d.(*testDriver).connection = adaptor })
+
+	errs := r.Start()
+	gobottest.Assert(t, len(errs), 1)
+	gobottest.Assert(t, errs[0].Error(), `Device "button": Pin "7" is already reserved by device "led"`)
+	gobottest.Assert(t, NewJSONConnection(adaptor).Pins, map[string]string{"7": "led"})
+
+	r.Stop()
+	gobottest.Assert(t, adaptor.ReservedPins(), map[string]string{})
+	gobottest.Assert(t, NewJSONConnection(adaptor).Pins, map[string]string{})
+}
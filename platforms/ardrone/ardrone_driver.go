@@ -51,6 +51,12 @@ func (a *ArdroneDriver) Halt() (errs []error) {
 	return
 }
 
+// SafeState lands the drone
+func (a *ArdroneDriver) SafeState() (err error) {
+	a.Land()
+	return
+}
+
 // TakeOff makes the drone start flying, and publishes `flying` event
 func (a *ArdroneDriver) TakeOff() {
 	a.Publish(a.Event("flying"), a.adaptor().drone.Takeoff())
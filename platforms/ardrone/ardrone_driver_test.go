@@ -9,6 +9,8 @@ import (
 
 var _ gobot.Driver = (*ArdroneDriver)(nil)
 
+var _ gobot.SafeStater = (*ArdroneDriver)(nil)
+
 func initTestArdroneDriver() *ArdroneDriver {
 	a := NewArdroneAdaptor("drone")
 	a.connect = func(a *ArdroneAdaptor) (drone, error) {
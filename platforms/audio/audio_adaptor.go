@@ -3,14 +3,16 @@ package audio
 
 import (
 	"errors"
-	"log"
 	"os"
 	"os/exec"
 	"path"
+
+	"github.com/hybridgroup/gobot"
 )
 
 type AudioAdaptor struct {
 	name string
+	gobot.Logging
 }
 
 func NewAudioAdaptor(name string) *AudioAdaptor {
@@ -29,14 +31,14 @@ func (a *AudioAdaptor) Sound(fileName string) []error {
 	var errorsList []error
 
 	if fileName == "" {
-		log.Println("Requires filename for audio file.")
+		a.Logger().Error("Requires filename for audio file.")
 		errorsList = append(errorsList, errors.New("Requires filename for audio file."))
 		return errorsList
 	}
 
 	_, err := os.Stat(fileName)
 	if err != nil {
-		log.Println(err)
+		a.Logger().Error("Could not play audio file", "file", fileName, "error", err)
 		errorsList = append(errorsList, err)
 		return errorsList
 	}
@@ -44,14 +46,14 @@ func (a *AudioAdaptor) Sound(fileName string) []error {
 	// command to play audio file based on file type
 	commandName, err := CommandName(fileName)
 	if err != nil {
-		log.Println(err)
+		a.Logger().Error("Could not play audio file", "file", fileName, "error", err)
 		errorsList = append(errorsList, err)
 		return errorsList
 	}
 
 	err = RunCommand(commandName, fileName)
 	if err != nil {
-		log.Println(err)
+		a.Logger().Error("Could not play audio file", "file", fileName, "error", err)
 		errorsList = append(errorsList, err)
 		return errorsList
 	}
@@ -120,10 +120,18 @@ type BeagleboneAdaptor struct {
 	ocp         string
 	helper      string
 	slots       string
+	gobot.PinRegistry
+}
+
+func init() {
+	gobot.RegisterAdaptor("beaglebone", func(config gobot.ConnectionConfig) (gobot.Connection, error) {
+		return NewBeagleboneAdaptor(config.Name), nil
+	})
 }
 
 // NewBeagleboneAdaptor returns a new BeagleboneAdaptor with specified name
 func NewBeagleboneAdaptor(name string) *BeagleboneAdaptor {
+	sysfs.SetDefaultMetrics(gobot.DefaultRegistry())
 	b := &BeagleboneAdaptor{
 		name:        name,
 		digitalPins: make([]sysfs.DigitalPin, 120),
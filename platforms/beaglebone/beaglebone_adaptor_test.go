@@ -13,6 +13,7 @@ import (
 )
 
 var _ gobot.Adaptor = (*BeagleboneAdaptor)(nil)
+var _ gobot.PinReserver = (*BeagleboneAdaptor)(nil)
 
 var _ gpio.DigitalReader = (*BeagleboneAdaptor)(nil)
 var _ gpio.DigitalWriter = (*BeagleboneAdaptor)(nil)
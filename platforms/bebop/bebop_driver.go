@@ -48,6 +48,12 @@ func (a *BebopDriver) Halt() (errs []error) {
 	return
 }
 
+// SafeState lands the drone
+func (a *BebopDriver) SafeState() (err error) {
+	a.Land()
+	return
+}
+
 // TakeOff makes the drone start flying
 func (a *BebopDriver) TakeOff() {
 	a.Publish(a.Event("flying"), a.adaptor().drone.TakeOff())
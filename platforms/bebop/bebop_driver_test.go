@@ -3,3 +3,5 @@ package bebop
 import "github.com/hybridgroup/gobot"
 
 var _ gobot.Driver = (*BebopDriver)(nil)
+
+var _ gobot.SafeStater = (*BebopDriver)(nil)
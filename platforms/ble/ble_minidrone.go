@@ -113,6 +113,11 @@ func (b *BLEMinidroneDriver) Halt() (errs []error) {
 	return
 }
 
+// SafeState lands the minidrone
+func (b *BLEMinidroneDriver) SafeState() (err error) {
+	return b.Land()
+}
+
 func (b *BLEMinidroneDriver) Init() (err error) {
 	b.GenerateAllStates()
 
@@ -3,6 +3,7 @@ package chip
 import (
 	"errors"
 
+	"github.com/hybridgroup/gobot"
 	"github.com/hybridgroup/gobot/sysfs"
 )
 
@@ -10,6 +11,7 @@ type ChipAdaptor struct {
 	name        string
 	digitalPins map[int]sysfs.DigitalPin
 	i2cDevice   sysfs.I2cDevice
+	gobot.PinRegistry
 }
 
 var pins = map[string]int{
@@ -23,8 +25,15 @@ var pins = map[string]int{
 	"XIO-P7": 415,
 }
 
+func init() {
+	gobot.RegisterAdaptor("chip", func(config gobot.ConnectionConfig) (gobot.Connection, error) {
+		return NewChipAdaptor(config.Name), nil
+	})
+}
+
 // NewChipAdaptor creates a ChipAdaptor with the specified name
 func NewChipAdaptor(name string) *ChipAdaptor {
+	sysfs.SetDefaultMetrics(gobot.DefaultRegistry())
 	c := &ChipAdaptor{
 		name:        name,
 		digitalPins: make(map[int]sysfs.DigitalPin),
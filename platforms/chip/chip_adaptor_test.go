@@ -12,6 +12,7 @@ import (
 )
 
 var _ gobot.Adaptor = (*ChipAdaptor)(nil)
+var _ gobot.PinReserver = (*ChipAdaptor)(nil)
 
 var _ gpio.DigitalReader = (*ChipAdaptor)(nil)
 var _ gpio.DigitalWriter = (*ChipAdaptor)(nil)
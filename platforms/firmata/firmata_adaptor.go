@@ -1,6 +1,8 @@
 package firmata
 
 import (
+	"context"
+	"fmt"
 	"io"
 	"strconv"
 	"time"
@@ -34,6 +36,13 @@ type FirmataAdaptor struct {
 	conn   io.ReadWriteCloser
 	openSP func(port string) (io.ReadWriteCloser, error)
 	gobot.Eventer
+	gobot.PinRegistry
+}
+
+func init() {
+	gobot.RegisterAdaptor("firmata", func(config gobot.ConnectionConfig) (gobot.Connection, error) {
+		return NewFirmataAdaptor(config.Name, config.Port), nil
+	})
 }
 
 // NewFirmataAdaptor returns a new FirmataAdaptor with specified name and optionally accepts:
@@ -71,17 +80,39 @@ func NewFirmataAdaptor(name string, args ...interface{}) *FirmataAdaptor {
 
 // Connect starts a connection to the board.
 func (f *FirmataAdaptor) Connect() (errs []error) {
+	return f.ConnectContext(context.Background())
+}
+
+// ConnectContext starts a connection to the board, giving up when ctx is
+// done before the board has answered. The connection is then closed, and a
+// serial port the adaptor opened is opened again by the next Connect.
+func (f *FirmataAdaptor) ConnectContext(ctx context.Context) (errs []error) {
+	opened := false
 	if f.conn == nil {
 		sp, err := f.openSP(f.Port())
 		if err != nil {
 			return []error{err}
 		}
 		f.conn = sp
+		opened = true
 	}
-	if err := f.board.Connect(f.conn); err != nil {
-		return []error{err}
+
+	board, conn := f.board, f.conn
+	done := make(chan error, 1)
+	go func() { done <- board.Connect(conn) }()
+	select {
+	case err := <-done:
+		if err != nil {
+			return []error{err}
+		}
+		return
+	case <-ctx.Done():
+		conn.Close()
+		if opened {
+			f.conn = nil
+		}
+		return []error{fmt.Errorf("Connect did not complete: %w", ctx.Err())}
 	}
-	return
 }
 
 // Disconnect closes the io connection to the board
@@ -94,10 +125,23 @@ func (f *FirmataAdaptor) Disconnect() (err error) {
 
 // Finalize terminates the firmata connection
 func (f *FirmataAdaptor) Finalize() (errs []error) {
-	if err := f.Disconnect(); err != nil {
-		return []error{err}
+	return f.FinalizeContext(context.Background())
+}
+
+// FinalizeContext terminates the firmata connection, giving up when ctx is
+// done before the connection has closed.
+func (f *FirmataAdaptor) FinalizeContext(ctx context.Context) (errs []error) {
+	done := make(chan error, 1)
+	go func() { done <- f.Disconnect() }()
+	select {
+	case err := <-done:
+		if err != nil {
+			return []error{err}
+		}
+		return
+	case <-ctx.Done():
+		return []error{fmt.Errorf("Finalize did not complete: %w", ctx.Err())}
 	}
-	return
 }
 
 // Port returns the  FirmataAdaptors port
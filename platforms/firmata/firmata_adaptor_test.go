@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"errors"
 	"fmt"
+	"context"
 	"io"
 	"strings"
 	"testing"
@@ -17,6 +18,8 @@ import (
 )
 
 var _ gobot.Adaptor = (*FirmataAdaptor)(nil)
+var _ gobot.PinReserver = (*FirmataAdaptor)(nil)
+var _ gobot.ContextAdaptor = (*FirmataAdaptor)(nil)
 
 var _ gpio.DigitalReader = (*FirmataAdaptor)(nil)
 var _ gpio.DigitalWriter = (*FirmataAdaptor)(nil)
@@ -134,6 +137,68 @@ func TestFirmataAdaptorConnect(t *testing.T) {
 
 }
 
+// silentFirmataBoard is a board which never answers, until its connection
+// is closed, and does not disconnect until released.
+type silentFirmataBoard struct {
+	*mockFirmataBoard
+	released chan struct{}
+}
+
+func (silentFirmataBoard) Connect(conn io.ReadWriteCloser) error {
+	<-conn.(*closingReadWriteCloser).closed
+	return errors.New("connection closed")
+}
+
+func (b silentFirmataBoard) Disconnect() error {
+	<-b.released
+	return nil
+}
+
+type closingReadWriteCloser struct {
+	readWriteCloser
+	closed chan struct{}
+}
+
+func (c *closingReadWriteCloser) Close() error {
+	close(c.closed)
+	return nil
+}
+
+func TestFirmataAdaptorConnectContext(t *testing.T) {
+	opened := 0
+	a := NewFirmataAdaptor("board", "/dev/null")
+	a.board = silentFirmataBoard{newMockFirmataBoard(), nil}
+	a.openSP = func(port string) (io.ReadWriteCloser, error) {
+		opened++
+		return &closingReadWriteCloser{closed: make(chan struct{})}, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	errs := a.ConnectContext(ctx)
+	gobottest.Assert(t, len(errs), 1)
+	gobottest.Assert(t, errors.Is(errs[0], context.DeadlineExceeded), true)
+
+	// the port is opened again by the next attempt
+	a.board = newMockFirmataBoard()
+	gobottest.Assert(t, len(a.ConnectContext(context.Background())), 0)
+	gobottest.Assert(t, opened, 2)
+}
+
+func TestFirmataAdaptorFinalizeContext(t *testing.T) {
+	a := initTestFirmataAdaptor()
+	gobottest.Assert(t, len(a.FinalizeContext(context.Background())), 0)
+
+	board := silentFirmataBoard{newMockFirmataBoard(), make(chan struct{})}
+	defer close(board.released)
+	a.board = board
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	errs := a.FinalizeContext(ctx)
+	gobottest.Assert(t, len(errs), 1)
+	gobottest.Assert(t, errors.Is(errs[0], context.DeadlineExceeded), true)
+}
+
 func TestFirmataAdaptorServoWrite(t *testing.T) {
 	a := initTestFirmataAdaptor()
 	a.ServoWrite("1", 50)
@@ -1,8 +1,11 @@
 package gpio
 
 import (
-	"github.com/hybridgroup/gobot"
+	"context"
+	"fmt"
 	"time"
+
+	"github.com/hybridgroup/gobot"
 )
 
 // ButtonDriver Represents a digital Button
@@ -70,10 +73,28 @@ func (b *ButtonDriver) Start() (errs []error) {
 	return
 }
 
+// StartContext is Start, which does not block, unless ctx is already done.
+func (b *ButtonDriver) StartContext(ctx context.Context) (errs []error) {
+	if err := ctx.Err(); err != nil {
+		return []error{fmt.Errorf("Start did not complete: %w", err)}
+	}
+	return b.Start()
+}
+
 // Halt stops polling the button for new information
 func (b *ButtonDriver) Halt() (errs []error) {
-	b.halt <- true
-	return
+	return b.HaltContext(context.Background())
+}
+
+// HaltContext stops polling the button for new information, giving up when
+// ctx is done, such as when the button was never started.
+func (b *ButtonDriver) HaltContext(ctx context.Context) (errs []error) {
+	select {
+	case b.halt <- true:
+		return
+	case <-ctx.Done():
+		return []error{fmt.Errorf("Halt did not complete: %w", ctx.Err())}
+	}
 }
 
 // Name returns the ButtonDrivers name
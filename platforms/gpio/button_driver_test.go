@@ -1,6 +1,7 @@
 package gpio
 
 import (
+	"context"
 	"errors"
 	"testing"
 	"time"
@@ -11,6 +12,8 @@ import (
 
 var _ gobot.Driver = (*ButtonDriver)(nil)
 
+var _ gobot.ContextDriver = (*ButtonDriver)(nil)
+
 const BUTTON_TEST_DELAY = 150
 
 func initTestButtonDriver() *ButtonDriver {
@@ -25,6 +28,30 @@ func TestButtonDriverHalt(t *testing.T) {
 	gobottest.Assert(t, len(d.Halt()), 0)
 }
 
+func TestButtonDriverHaltContext(t *testing.T) {
+	d := initTestButtonDriver()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	errs := d.HaltContext(ctx)
+	gobottest.Assert(t, len(errs), 1)
+	gobottest.Assert(t, errors.Is(errs[0], context.DeadlineExceeded), true)
+
+	gobottest.Assert(t, len(d.Start()), 0)
+	gobottest.Assert(t, len(d.HaltContext(context.Background())), 0)
+}
+
+func TestButtonDriverStartContext(t *testing.T) {
+	d := initTestButtonDriver()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	errs := d.StartContext(ctx)
+	gobottest.Assert(t, len(errs), 1)
+	gobottest.Assert(t, errors.Is(errs[0], context.Canceled), true)
+
+	gobottest.Assert(t, len(d.StartContext(context.Background())), 0)
+	gobottest.Assert(t, len(d.Halt()), 0)
+}
+
 func TestButtonDriver(t *testing.T) {
 	d := NewButtonDriver(newGpioTestAdaptor("adaptor"), "bot", "1")
 	gobottest.Assert(t, d.Name(), "bot")
@@ -0,0 +1,77 @@
+package gpio
+
+import (
+	"github.com/hybridgroup/gobot"
+)
+
+// The drivers of this package are registered under these types for use in
+// gobot config files.
+func init() {
+	gobot.RegisterDriver("led", func(c gobot.Connection, config gobot.DeviceConfig) (gobot.Device, error) {
+		w, ok := c.(DigitalWriter)
+		if !ok {
+			return nil, ErrDigitalWriteUnsupported
+		}
+		return NewLedDriver(w, config.Name, config.Pin), nil
+	})
+	gobot.RegisterDriver("rgb_led", func(c gobot.Connection, config gobot.DeviceConfig) (gobot.Device, error) {
+		w, ok := c.(DigitalWriter)
+		if !ok {
+			return nil, ErrDigitalWriteUnsupported
+		}
+		return NewRgbLedDriver(w, config.Name,
+			config.Option("red", ""), config.Option("green", ""), config.Option("blue", "")), nil
+	})
+	gobot.RegisterDriver("relay", func(c gobot.Connection, config gobot.DeviceConfig) (gobot.Device, error) {
+		w, ok := c.(DigitalWriter)
+		if !ok {
+			return nil, ErrDigitalWriteUnsupported
+		}
+		return NewRelayDriver(w, config.Name, config.Pin), nil
+	})
+	gobot.RegisterDriver("buzzer", func(c gobot.Connection, config gobot.DeviceConfig) (gobot.Device, error) {
+		w, ok := c.(DigitalWriter)
+		if !ok {
+			return nil, ErrDigitalWriteUnsupported
+		}
+		return NewBuzzerDriver(w, config.Name, config.Pin), nil
+	})
+	gobot.RegisterDriver("motor", func(c gobot.Connection, config gobot.DeviceConfig) (gobot.Device, error) {
+		w, ok := c.(DigitalWriter)
+		if !ok {
+			return nil, ErrDigitalWriteUnsupported
+		}
+		return NewMotorDriver(w, config.Name, config.Pin), nil
+	})
+	gobot.RegisterDriver("servo", func(c gobot.Connection, config gobot.DeviceConfig) (gobot.Device, error) {
+		w, ok := c.(ServoWriter)
+		if !ok {
+			return nil, ErrServoWriteUnsupported
+		}
+		return NewServoDriver(w, config.Name, config.Pin), nil
+	})
+	gobot.RegisterDriver("button", func(c gobot.Connection, config gobot.DeviceConfig) (gobot.Device, error) {
+		r, ok := c.(DigitalReader)
+		if !ok {
+			return nil, ErrDigitalReadUnsupported
+		}
+		return NewButtonDriver(r, config.Name, config.Pin, config.Intervals()...), nil
+	})
+	gobot.RegisterDriver("makey_button", func(c gobot.Connection, config gobot.DeviceConfig) (gobot.Device, error) {
+		r, ok := c.(DigitalReader)
+		if !ok {
+			return nil, ErrDigitalReadUnsupported
+		}
+		return NewMakeyButtonDriver(r, config.Name, config.Pin, config.Intervals()...), nil
+	})
+	gobot.RegisterDriver("analog_sensor", func(c gobot.Connection, config gobot.DeviceConfig) (gobot.Device, error) {
+		r, ok := c.(AnalogReader)
+		if !ok {
+			return nil, ErrAnalogReadUnsupported
+		}
+		return NewAnalogSensorDriver(r, config.Name, config.Pin, config.Intervals()...), nil
+	})
+	gobot.RegisterDriver("direct_pin", func(c gobot.Connection, config gobot.DeviceConfig) (gobot.Device, error) {
+		return NewDirectPinDriver(c, config.Name, config.Pin), nil
+	})
+}
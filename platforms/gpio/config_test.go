@@ -0,0 +1,59 @@
+package gpio
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hybridgroup/gobot"
+	"github.com/hybridgroup/gobot/gobottest"
+)
+
+func init() {
+	gobot.RegisterAdaptor("gpio_test", func(config gobot.ConnectionConfig) (gobot.Connection, error) {
+		return newGpioTestAdaptor(config.Name), nil
+	})
+	gobot.RegisterAdaptor("gpio_test_bare", func(config gobot.ConnectionConfig) (gobot.Connection, error) {
+		return &gpioTestBareAdaptor{}, nil
+	})
+}
+
+func TestConfigDrivers(t *testing.T) {
+	config, err := gobot.ParseConfig([]byte(`
+robots:
+  - name: bot
+    connections:
+      - name: board
+        type: gpio_test
+    devices:
+      - name: led
+        type: led
+        pin: 13
+      - name: rgb
+        type: rgb_led
+        options: {red: 1, green: 2, blue: 3}
+      - name: button
+        type: button
+        pin: 2
+        interval: 20ms
+      - name: servo
+        type: servo
+        pin: 3
+`), "yaml")
+	gobottest.Assert(t, err, nil)
+
+	r, err := gobot.NewRobotFromConfig(config.Robots[0])
+	gobottest.Assert(t, err, nil)
+	gobottest.Assert(t, r.Device("led").(*LedDriver).Pin(), "13")
+	gobottest.Assert(t, r.Device("rgb").(*RgbLedDriver).Pin(), "r=1, g=2, b=3")
+	gobottest.Assert(t, r.Device("button").(*ButtonDriver).interval, 20*time.Millisecond)
+	gobottest.Assert(t, r.Device("servo").(*ServoDriver).Connection().Name(), "board")
+}
+
+func TestConfigDriversUnsupported(t *testing.T) {
+	_, err := gobot.NewRobotFromConfig(gobot.RobotConfig{
+		Name:        "bot",
+		Connections: []gobot.ConnectionConfig{{Name: "board", Type: "gpio_test_bare"}},
+		Devices:     []gobot.DeviceConfig{{Name: "button", Type: "button", Pin: "2"}},
+	})
+	gobottest.Assert(t, err.Error(), `Robot "bot": Device "button": `+ErrDigitalReadUnsupported.Error())
+}
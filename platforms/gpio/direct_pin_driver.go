@@ -1,10 +1,11 @@
 package gpio
 
-import (
-	"strconv"
+import "github.com/hybridgroup/gobot"
 
-	"github.com/hybridgroup/gobot"
-)
+// directPinLevelParams are the params of the commands which write to the pin
+var directPinLevelParams = []gobot.Param{
+	{Name: "level", Type: gobot.IntegerParam, Required: true, Range: &gobot.Range{Min: 0, Max: 255}},
+}
 
 // DirectPinDriver represents a GPIO pin
 type DirectPinDriver struct {
@@ -35,21 +36,30 @@ func NewDirectPinDriver(a gobot.Connection, name string, pin string) *DirectPinD
 		val, err := d.DigitalRead()
 		return map[string]interface{}{"val": val, "err": err}
 	})
-	d.AddCommand("DigitalWrite", func(params map[string]interface{}) interface{} {
-		level, _ := strconv.Atoi(params["level"].(string))
-		return d.DigitalWrite(byte(level))
+	d.AddCommandSchema(gobot.CommandSchema{
+		Name:        "DigitalWrite",
+		Description: "Writes the level to the pin",
+		Params:      directPinLevelParams,
+	}, func(params map[string]interface{}) interface{} {
+		return d.DigitalWrite(byte(params["level"].(float64)))
 	})
 	d.AddCommand("AnalogRead", func(params map[string]interface{}) interface{} {
 		val, err := d.AnalogRead()
 		return map[string]interface{}{"val": val, "err": err}
 	})
-	d.AddCommand("PwmWrite", func(params map[string]interface{}) interface{} {
-		level, _ := strconv.Atoi(params["level"].(string))
-		return d.PwmWrite(byte(level))
+	d.AddCommandSchema(gobot.CommandSchema{
+		Name:        "PwmWrite",
+		Description: "Writes the PWM level to the pin",
+		Params:      directPinLevelParams,
+	}, func(params map[string]interface{}) interface{} {
+		return d.PwmWrite(byte(params["level"].(float64)))
 	})
-	d.AddCommand("ServoWrite", func(params map[string]interface{}) interface{} {
-		level, _ := strconv.Atoi(params["level"].(string))
-		return d.ServoWrite(byte(level))
+	d.AddCommandSchema(gobot.CommandSchema{
+		Name:        "ServoWrite",
+		Description: "Writes the servo angle to the pin",
+		Params:      directPinLevelParams,
+	}, func(params map[string]interface{}) interface{} {
+		return d.ServoWrite(byte(params["level"].(float64)))
 	})
 
 	return d
@@ -45,18 +45,21 @@ func TestDirectPinDriver(t *testing.T) {
 	gobottest.Assert(t, ret["val"].(int), 1)
 	gobottest.Assert(t, ret["err"], nil)
 
-	err = d.Command("DigitalWrite")(map[string]interface{}{"level": "1"})
+	err = d.Command("DigitalWrite")(map[string]interface{}{"level": 1})
 	gobottest.Assert(t, err.(error), errors.New("write error"))
 
+	err = d.Command("DigitalWrite")(map[string]interface{}{"level": "high"})
+	gobottest.Assert(t, err.(error).Error(), `command "DigitalWrite": param "level" must be an integer, got string`)
+
 	ret = d.Command("AnalogRead")(nil).(map[string]interface{})
 
 	gobottest.Assert(t, ret["val"].(int), 80)
 	gobottest.Assert(t, ret["err"], nil)
 
-	err = d.Command("PwmWrite")(map[string]interface{}{"level": "1"})
+	err = d.Command("PwmWrite")(map[string]interface{}{"level": 1})
 	gobottest.Assert(t, err.(error), errors.New("write error"))
 
-	err = d.Command("ServoWrite")(map[string]interface{}{"level": "1"})
+	err = d.Command("ServoWrite")(map[string]interface{}{"level": 1})
 	gobottest.Assert(t, err.(error), errors.New("write error"))
 }
 
@@ -2,6 +2,8 @@ package gpio
 
 import (
 	"time"
+
+	"github.com/hybridgroup/gobot"
 )
 
 // GroveRelayDriver represents a Relay with a Grove connector
@@ -82,6 +84,7 @@ func NewGroveLightSensorDriver(a AnalogReader, name string, pin string, v ...tim
 // with a Grove connector
 type GrovePiezoVibrationSensorDriver struct {
 	*AnalogSensorDriver
+	vibration *gobot.Subscription
 }
 
 // NewGrovePiezoVibrationSensorDriver returns a new GrovePiezoVibrationSensorDriver with a polling interval of
@@ -99,13 +102,38 @@ func NewGrovePiezoVibrationSensorDriver(a AnalogReader, name string, pin string,
 
 	sensor.AddEvent(Vibration)
 
-	sensor.On(sensor.Event(Data), func(data interface{}) {
+	return sensor
+}
+
+// Start starts the GrovePiezoVibrationSensorDriver and reads the sensor at the
+// given interval.
+// Emits the Events:
+//	Data int - Event is emitted on change and represents the current reading from the sensor.
+//	Vibration int - Event is emitted when the reading is above 1000.
+//	Error error - Event is emitted on error reading from the sensor.
+func (g *GrovePiezoVibrationSensorDriver) Start() (errs []error) {
+	if g.vibration != nil {
+		g.vibration.Cancel()
+	}
+	sub, err := g.On(g.Event(Data), func(data interface{}) {
 		if data.(int) > 1000 {
-			sensor.Publish(sensor.Event(Vibration), data)
+			g.Publish(g.Event(Vibration), data)
 		}
 	})
+	if err != nil {
+		return []error{err}
+	}
+	g.vibration = sub
+	return g.AnalogSensorDriver.Start()
+}
 
-	return sensor
+// Halt stops polling the sensor for new information
+func (g *GrovePiezoVibrationSensorDriver) Halt() (errs []error) {
+	if g.vibration != nil {
+		g.vibration.Cancel()
+		g.vibration = nil
+	}
+	return g.AnalogSensorDriver.Halt()
 }
 
 // GroveBuzzerDriver represents a buzzer
@@ -1,7 +1,11 @@
 package gpio
 
 import (
+	"testing"
+	"time"
+
 	"github.com/hybridgroup/gobot"
+	"github.com/hybridgroup/gobot/gobottest"
 )
 
 var _ gobot.Driver = (*GroveTouchDriver)(nil)
@@ -13,3 +17,33 @@ var _ gobot.Driver = (*GrovePiezoVibrationSensorDriver)(nil)
 var _ gobot.Driver = (*GroveLedDriver)(nil)
 var _ gobot.Driver = (*GroveRotaryDriver)(nil)
 var _ gobot.Driver = (*GroveRelayDriver)(nil)
+
+func TestGrovePiezoVibrationSensorDriverRestart(t *testing.T) {
+	d := NewGrovePiezoVibrationSensorDriver(newGpioTestAdaptor("adaptor"), "sensor", "1")
+	r := gobot.NewRobot("bot",
+		[]gobot.Connection{d.Connection()},
+		[]gobot.Device{d},
+	)
+	testAdaptorAnalogRead = func() (val int, err error) {
+		return 0, nil
+	}
+	gobottest.Assert(t, len(r.Start()), 0)
+	gobottest.Assert(t, len(r.Stop()), 0)
+	gobottest.Assert(t, len(r.Start()), 0)
+	defer r.Stop()
+
+	sem := make(chan interface{}, 1)
+	d.Once(d.Event(Vibration), func(data interface{}) {
+		sem <- data
+	})
+	testAdaptorAnalogRead = func() (val int, err error) {
+		return 1001, nil
+	}
+
+	select {
+	case data := <-sem:
+		gobottest.Assert(t, data, 1001)
+	case <-time.After(time.Second):
+		t.Errorf("GrovePiezoVibrationSensor Event \"Vibration\" was not published after a restart")
+	}
+}
@@ -29,7 +29,13 @@ func NewLedDriver(a DigitalWriter, name string, pin string) *LedDriver {
 		Commander:  gobot.NewCommander(),
 	}
 
-	l.AddCommand("Brightness", func(params map[string]interface{}) interface{} {
+	l.AddCommandSchema(gobot.CommandSchema{
+		Name:        "Brightness",
+		Description: "Sets the brightness level of the led",
+		Params: []gobot.Param{
+			{Name: "level", Type: gobot.IntegerParam, Required: true, Range: &gobot.Range{Min: 0, Max: 255}},
+		},
+	}, func(params map[string]interface{}) interface{} {
 		level := byte(params["level"].(float64))
 		return l.Brightness(level)
 	})
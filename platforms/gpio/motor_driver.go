@@ -44,6 +44,9 @@ func (m *MotorDriver) Start() (errs []error) { return }
 // Halt implements the Driver interface
 func (m *MotorDriver) Halt() (errs []error) { return }
 
+// SafeState implements the gobot.SafeStater interface, turning the motor off
+func (m *MotorDriver) SafeState() (err error) { return m.Off() }
+
 // Off turns the motor off or sets the motor to a 0 speed
 func (m *MotorDriver) Off() (err error) {
 	if m.isDigital() {
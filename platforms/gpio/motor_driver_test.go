@@ -9,6 +9,8 @@ import (
 
 var _ gobot.Driver = (*MotorDriver)(nil)
 
+var _ gobot.SafeStater = (*MotorDriver)(nil)
+
 func initTestMotorDriver() *MotorDriver {
 	return NewMotorDriver(newGpioTestAdaptor("adaptor"), "bot", "1")
 }
@@ -29,6 +31,14 @@ func TestMotorDriverHalt(t *testing.T) {
 	gobottest.Assert(t, len(d.Halt()), 0)
 }
 
+func TestMotorDriverSafeState(t *testing.T) {
+	testAdaptorPwmWrite = func() (err error) { return }
+	d := initTestMotorDriver()
+	d.Speed(100)
+	gobottest.Assert(t, d.SafeState(), nil)
+	gobottest.Assert(t, d.CurrentSpeed, uint8(0))
+}
+
 func TestMotorDriverIsOn(t *testing.T) {
 	d := initTestMotorDriver()
 	d.CurrentMode = "digital"
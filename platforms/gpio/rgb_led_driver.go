@@ -35,10 +35,18 @@ func NewRgbLedDriver(a DigitalWriter, name string, redPin string, greenPin strin
 		Commander:  gobot.NewCommander(),
 	}
 
-	l.AddCommand("SetRGB", func(params map[string]interface{}) interface{} {
-		r := byte(params["r"].(int))
-		g := byte(params["g"].(int))
-		b := byte(params["b"].(int))
+	l.AddCommandSchema(gobot.CommandSchema{
+		Name:        "SetRGB",
+		Description: "Sets the color of the led",
+		Params: []gobot.Param{
+			{Name: "r", Type: gobot.IntegerParam, Required: true, Range: &gobot.Range{Min: 0, Max: 255}},
+			{Name: "g", Type: gobot.IntegerParam, Required: true, Range: &gobot.Range{Min: 0, Max: 255}},
+			{Name: "b", Type: gobot.IntegerParam, Required: true, Range: &gobot.Range{Min: 0, Max: 255}},
+		},
+	}, func(params map[string]interface{}) interface{} {
+		r := byte(params["r"].(float64))
+		g := byte(params["g"].(float64))
+		b := byte(params["b"].(float64))
 		return l.SetRGB(r, g, b)
 	})
 
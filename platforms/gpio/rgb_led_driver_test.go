@@ -51,6 +51,9 @@ func TestRgbLedDriver(t *testing.T) {
 	err = d.Command("SetRGB")(map[string]interface{}{"r": 0xff, "g": 0xff, "b": 0xff})
 	gobottest.Assert(t, err.(error), errors.New("pwm error"))
 
+	err = d.Command("SetRGB")(map[string]interface{}{"r": 0xff, "g": 0xff})
+	gobottest.Assert(t, err.(error).Error(), `command "SetRGB": param "b" is required`)
+
 }
 
 func TestRgbLedDriverStart(t *testing.T) {
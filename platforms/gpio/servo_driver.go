@@ -27,7 +27,13 @@ func NewServoDriver(a ServoWriter, name string, pin string) *ServoDriver {
 		CurrentAngle: 0,
 	}
 
-	s.AddCommand("Move", func(params map[string]interface{}) interface{} {
+	s.AddCommandSchema(gobot.CommandSchema{
+		Name:        "Move",
+		Description: "Moves the servo to the given angle",
+		Params: []gobot.Param{
+			{Name: "angle", Type: gobot.IntegerParam, Required: true, Range: &gobot.Range{Min: 0, Max: 180}},
+		},
+	}, func(params map[string]interface{}) interface{} {
 		angle := byte(params["angle"].(float64))
 		return s.Move(angle)
 	})
@@ -60,6 +66,9 @@ func (s *ServoDriver) Start() (errs []error) { return }
 // Halt implements the Driver interface
 func (s *ServoDriver) Halt() (errs []error) { return }
 
+// SafeState implements the gobot.SafeStater interface, centering the servo
+func (s *ServoDriver) SafeState() (err error) { return s.Center() }
+
 // Move sets the servo to the specified angle. Acceptable angles are 0-180
 func (s *ServoDriver) Move(angle uint8) (err error) {
 	if !(angle >= 0 && angle <= 180) {
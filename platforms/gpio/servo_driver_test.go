@@ -10,6 +10,8 @@ import (
 
 var _ gobot.Driver = (*ServoDriver)(nil)
 
+var _ gobot.SafeStater = (*ServoDriver)(nil)
+
 func initTestServoDriver() *ServoDriver {
 	return NewServoDriver(newGpioTestAdaptor("adaptor"), "bot", "1")
 }
@@ -39,6 +41,9 @@ func TestServoDriver(t *testing.T) {
 	err = d.Command("Move")(map[string]interface{}{"angle": 100.0})
 	gobottest.Assert(t, err.(error), errors.New("pwm error"))
 
+	err = d.Command("Move")(map[string]interface{}{"angle": 200.0})
+	gobottest.Assert(t, err.(*gobot.ParamError).Param, "angle")
+
 }
 
 func TestServoDriverStart(t *testing.T) {
@@ -76,3 +81,11 @@ func TestServoDriverCenter(t *testing.T) {
 	d.Center()
 	gobottest.Assert(t, d.CurrentAngle, uint8(90))
 }
+
+func TestServoDriverSafeState(t *testing.T) {
+	testAdaptorServoWrite = func() (err error) { return }
+	d := initTestServoDriver()
+	d.Move(10)
+	gobottest.Assert(t, d.SafeState(), nil)
+	gobottest.Assert(t, d.CurrentAngle, uint8(90))
+}
@@ -1,7 +1,7 @@
 package i2c
 
 import (
-	"log"
+	"fmt"
 	"math"
 	"time"
 
@@ -9,8 +9,7 @@ import (
 )
 
 var (
-	_             gobot.Driver = (*AdafruitMotorHatDriver)(nil)
-	adafruitDebug              = false // Set this to true to see debug output
+	_ gobot.Driver = (*AdafruitMotorHatDriver)(nil)
 )
 
 var (
@@ -100,6 +99,8 @@ type AdafruitMotorHatDriver struct {
 	name       string
 	connection I2c
 	gobot.Commander
+	gobot.Logging
+	gobot.Storage
 	dcMotors      []adaFruitDCMotor
 	stepperMotors []adaFruitStepperMotor
 }
@@ -156,7 +157,9 @@ func NewAdafruitMotorHatDriver(a I2c, name string) *AdafruitMotorHatDriver {
 	return driver
 }
 
-// Start initializes both I2C-addressable Adafruit Motor HAT drivers
+// Start initializes both I2C-addressable Adafruit Motor HAT drivers, and
+// restores the servo frequency last set by SetServoMotorFreq from the
+// driver's Store.
 func (a *AdafruitMotorHatDriver) Start() (errs []error) {
 
 	addrs := []int{motorHatAddress, servoHatAddress}
@@ -194,6 +197,13 @@ func (a *AdafruitMotorHatDriver) Start() (errs []error) {
 			<-time.After(5 * time.Millisecond)
 		}
 	}
+
+	var freq float64
+	if ok, _ := a.Store().Get("servo_frequency", &freq); ok {
+		if err := a.setPWMFreq(servoHatAddress, freq); err != nil {
+			return []error{err}
+		}
+	}
 	return
 }
 
@@ -217,12 +227,13 @@ func (a *AdafruitMotorHatDriver) setPWM(i2cAddr int, pin byte, on, off int32) (e
 	return
 }
 
-// SetServoMotorFreq sets the frequency for the currently addressed PWM Servo HAT.
+// SetServoMotorFreq sets the frequency for the currently addressed PWM Servo HAT,
+// and keeps it in the driver's Store so Start can restore it.
 func (a *AdafruitMotorHatDriver) SetServoMotorFreq(freq float64) (err error) {
 	if err = a.setPWMFreq(servoHatAddress, freq); err != nil {
 		return
 	}
-	return
+	return a.Store().Set("servo_frequency", freq)
 }
 
 // SetServoMotorPulse is a convenience function to specify the 'tick' value,
@@ -246,11 +257,11 @@ func (a *AdafruitMotorHatDriver) setPWMFreq(i2cAddr int, freq float64) (err erro
 	preScaleVal /= freq
 	preScaleVal -= 1.0
 	preScale := math.Floor(preScaleVal + 0.5)
-	if adafruitDebug {
-		log.Printf("Setting PWM frequency to:	%.2f Hz", freq)
-		log.Printf("Estimated pre-scale: 		%.2f", preScaleVal)
-		log.Printf("Final pre-scale: 			%.2f", preScale)
-	}
+	a.Logger().Debug("Setting PWM frequency",
+		"frequency", fmt.Sprintf("%.2f", freq),
+		"estimated_prescale", fmt.Sprintf("%.2f", preScaleVal),
+		"prescale", fmt.Sprintf("%.2f", preScale),
+	)
 	// default (and only) reads register 0
 	oldMode, err := a.connection.I2cRead(i2cAddr, 1)
 	if err != nil {
@@ -443,11 +454,11 @@ func (a *AdafruitMotorHatDriver) oneStep(motor int, dir AdafruitDirection, style
 		// step-2-coils is initialized in init()
 		coils = step2coils[(currStep / (stepperMicrosteps / 2))]
 	}
-	if adafruitDebug {
-		log.Printf("[adafruit_driver] currStep: %d, index into step2coils: %d\n",
-			currStep, (currStep / (stepperMicrosteps / 2)))
-		log.Printf("[adafruit_driver] coils state = %v", coils)
-	}
+	a.Logger().Debug("Stepping",
+		"step", currStep,
+		"step2coils_index", currStep/(stepperMicrosteps/2),
+		"coils", coils,
+	)
 	if err = a.setPin(motorHatAddress, a.stepperMotors[motor].ain2, coils[0]); err != nil {
 		return
 	}
@@ -482,9 +493,7 @@ func (a *AdafruitMotorHatDriver) Step(motor, steps int, dir AdafruitDirection, s
 		secPerStep /= float64(stepperMicrosteps)
 		steps *= stepperMicrosteps
 	}
-	if adafruitDebug {
-		log.Printf("[adafruit_driver] %f seconds per step", secPerStep)
-	}
+	a.Logger().Debug("Stepping", "seconds_per_step", secPerStep)
 	for i := 0; i < steps; i++ {
 		if latestStep, err = a.oneStep(motor, dir, style); err != nil {
 			return
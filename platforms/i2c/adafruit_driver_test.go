@@ -4,6 +4,7 @@ import (
 	"errors"
 	"testing"
 
+	"github.com/hybridgroup/gobot"
 	"github.com/hybridgroup/gobot/gobottest"
 )
 
@@ -67,6 +68,33 @@ func TestAdafruitMotorHatDriverSetServoMotorFreq(t *testing.T) {
 	gobottest.Assert(t, err, nil)
 }
 
+func TestAdafruitMotorHatDriverServoMotorFreqStore(t *testing.T) {
+	ada, adaptor := initTestAdafruitMotorHatDriverWithStubbedAdaptor()
+	adaptor.i2cReadImpl = func() ([]byte, error) {
+		return []byte{0}, nil
+	}
+	writes := 0
+	adaptor.i2cWriteImpl = func() error {
+		writes++
+		return nil
+	}
+	ada.Start()
+	started := writes
+
+	store := gobot.NewMemoryStore()
+	ada.SetStore(store)
+	gobottest.Assert(t, ada.SetServoMotorFreq(50.0), nil)
+	var freq float64
+	ok, _ := store.Get("servo_frequency", &freq)
+	gobottest.Assert(t, ok, true)
+	gobottest.Assert(t, freq, 50.0)
+
+	// the frequency is written again on start
+	writes = 0
+	ada.Start()
+	gobottest.Assert(t, writes > started, true)
+}
+
 func TestAdafruitMotorHatDriverSetServoMotorPulse(t *testing.T) {
 	ada, _ := initTestAdafruitMotorHatDriverWithStubbedAdaptor()
 
@@ -4,6 +4,7 @@ import (
 	"errors"
 	"testing"
 
+	"github.com/hybridgroup/gobot"
 	"github.com/hybridgroup/gobot/gobottest"
 )
 
@@ -44,6 +45,18 @@ func TestNewBlinkMDriverCommands_Fade(t *testing.T) {
 	gobottest.Assert(t, result, nil)
 }
 
+func TestNewBlinkMDriverCommands_InvalidParams(t *testing.T) {
+	blinkM := initTestBlinkMDriver()
+
+	result := blinkM.Command("Rgb")(map[string]interface{}{"red": 256.0, "green": 0.0, "blue": 0.0})
+	gobottest.Assert(t, result.(*gobot.ParamError).Param, "red")
+
+	result = blinkM.Command("Fade")(map[string]interface{}{"red": 0.0, "green": 0.0})
+	gobottest.Assert(t, result.(*gobot.ParamError).Param, "blue")
+
+	gobottest.Assert(t, len(blinkM.CommandSchema("Rgb").Params), 3)
+}
+
 func TestNewBlinkMDriverCommands_FirmwareVersion(t *testing.T) {
 	blinkM, adaptor := initTestBlinkDriverWithStubbedAdaptor()
 
@@ -0,0 +1,47 @@
+package i2c
+
+import (
+	"github.com/hybridgroup/gobot"
+)
+
+// The drivers of this package are registered under these types for use in
+// gobot config files.
+func init() {
+	register := func(name string, f func(I2c, gobot.DeviceConfig) gobot.Device) {
+		gobot.RegisterDriver(name, func(c gobot.Connection, config gobot.DeviceConfig) (gobot.Device, error) {
+			a, ok := c.(I2c)
+			if !ok {
+				return nil, ErrI2cUnsupported
+			}
+			return f(a, config), nil
+		})
+	}
+
+	register("blinkm", func(a I2c, config gobot.DeviceConfig) gobot.Device {
+		return NewBlinkMDriver(a, config.Name)
+	})
+	register("hmc6352", func(a I2c, config gobot.DeviceConfig) gobot.Device {
+		return NewHMC6352Driver(a, config.Name)
+	})
+	register("mma7660", func(a I2c, config gobot.DeviceConfig) gobot.Device {
+		return NewMMA7660Driver(a, config.Name)
+	})
+	register("lidarlite", func(a I2c, config gobot.DeviceConfig) gobot.Device {
+		return NewLIDARLiteDriver(a, config.Name)
+	})
+	register("jhd1313m1", func(a I2c, config gobot.DeviceConfig) gobot.Device {
+		return NewJHD1313M1Driver(a, config.Name)
+	})
+	register("adafruit_motor_hat", func(a I2c, config gobot.DeviceConfig) gobot.Device {
+		return NewAdafruitMotorHatDriver(a, config.Name)
+	})
+	register("wiichuck", func(a I2c, config gobot.DeviceConfig) gobot.Device {
+		return NewWiichuckDriver(a, config.Name, config.Intervals()...)
+	})
+	register("mpu6050", func(a I2c, config gobot.DeviceConfig) gobot.Device {
+		return NewMPU6050Driver(a, config.Name, config.Intervals()...)
+	})
+	register("mpl115a2", func(a I2c, config gobot.DeviceConfig) gobot.Device {
+		return NewMPL115A2Driver(a, config.Name, config.Intervals()...)
+	})
+}
@@ -11,6 +11,7 @@ var (
 	ErrNotEnoughBytes  = errors.New("Not enough bytes read")
 	ErrNotReady        = errors.New("Device is not ready")
 	ErrInvalidPosition = errors.New("Invalid position value")
+	ErrI2cUnsupported  = errors.New("I2C is not supported by this platform")
 )
 
 const (
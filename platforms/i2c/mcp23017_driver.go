@@ -18,7 +18,7 @@ package i2c
 
 import (
 	"fmt"
-	"log"
+	"strconv"
 	"strings"
 	"time"
 
@@ -26,7 +26,6 @@ import (
 )
 
 var (
-	debug = false // Set this to true to see debugging information
 	// Register this Driver
 	_ gobot.Driver = (*MCP23017Driver)(nil)
 )
@@ -78,6 +77,15 @@ func (mc *MCP23017Config) GetUint8Value() uint8 {
 	return mc.Bank<<7 | mc.Mirror<<6 | mc.Seqop<<5 | mc.Disslw<<4 | mc.Haen<<3 | mc.Odr<<2 | mc.Intpol<<1
 }
 
+var (
+	mcp23017PinParam = gobot.Param{
+		Name: "pin", Type: gobot.IntegerParam, Required: true, Range: &gobot.Range{Min: 0, Max: 7},
+	}
+	mcp23017PortParam = gobot.Param{
+		Name: "port", Type: gobot.StringParam, Required: true, Enum: []interface{}{"A", "B"},
+	}
+)
+
 // MCP23017Driver contains the driver configuration parameters.
 type MCP23017Driver struct {
 	name            string
@@ -87,6 +95,16 @@ type MCP23017Driver struct {
 	interval        time.Duration
 	gobot.Commander
 	gobot.Eventer
+	gobot.Logging
+	gobot.Storage
+}
+
+// mcp23017Settings are the registers set by PinMode, SetPullUp and
+// SetGPIOPolarity, by the names the settings are stored under.
+var mcp23017Settings = map[string]func(port) uint8{
+	"mode":     func(p port) uint8 { return p.IODIR },
+	"pullup":   func(p port) uint8 { return p.GPPU },
+	"polarity": func(p port) uint8 { return p.IPOL },
 }
 
 // NewMCP23017Driver creates a new driver with specified name and i2c interface.
@@ -100,16 +118,28 @@ func NewMCP23017Driver(a I2c, name string, conf MCP23017Config, deviceAddress in
 		Eventer:         gobot.NewEventer(),
 	}
 
-	m.AddCommand("WriteGPIO", func(params map[string]interface{}) interface{} {
-		pin := params["pin"].(uint8)
-		val := params["val"].(uint8)
+	m.AddCommandSchema(gobot.CommandSchema{
+		Name:        "WriteGPIO",
+		Description: "Writes a value to a GPIO pin",
+		Params: []gobot.Param{
+			mcp23017PinParam,
+			{Name: "val", Type: gobot.IntegerParam, Required: true, Enum: []interface{}{0, 1}},
+			mcp23017PortParam,
+		},
+	}, func(params map[string]interface{}) interface{} {
+		pin := uint8(params["pin"].(float64))
+		val := uint8(params["val"].(float64))
 		port := params["port"].(string)
 		err := m.WriteGPIO(pin, val, port)
 		return map[string]interface{}{"err": err}
 	})
 
-	m.AddCommand("ReadGPIO", func(params map[string]interface{}) interface{} {
-		pin := params["pin"].(uint8)
+	m.AddCommandSchema(gobot.CommandSchema{
+		Name:        "ReadGPIO",
+		Description: "Reads the value of a GPIO pin",
+		Params:      []gobot.Param{mcp23017PinParam, mcp23017PortParam},
+	}, func(params map[string]interface{}) interface{} {
+		pin := uint8(params["pin"].(float64))
 		port := params["port"].(string)
 		val, err := m.ReadGPIO(pin, port)
 		return map[string]interface{}{"val": val, "err": err}
@@ -127,7 +157,8 @@ func (m *MCP23017Driver) Connection() gobot.Connection { return m.connection.(go
 // Halt stops the driver.
 func (m *MCP23017Driver) Halt() (err []error) { return }
 
-// Start writes the device configuration.
+// Start writes the device configuration, and restores the pin settings made
+// by PinMode, SetPullUp and SetGPIOPolarity from the driver's Store.
 func (m *MCP23017Driver) Start() (errs []error) {
 	if err := m.connection.I2cStart(m.mcp23017Address); err != nil {
 		return []error{err}
@@ -138,6 +169,9 @@ func (m *MCP23017Driver) Start() (errs []error) {
 	if err := m.connection.I2cWrite(m.mcp23017Address, []uint8{ioconReg, ioconVal}); err != nil {
 		return []error{err}
 	}
+	if err := m.restoreSettings(); err != nil {
+		return []error{err}
+	}
 	return
 }
 
@@ -159,12 +193,8 @@ func (m *MCP23017Driver) WriteGPIO(pin uint8, val uint8, portStr string) (err er
 // val (0 output 1 input)
 // port (A or B).
 func (m *MCP23017Driver) PinMode(pin, val uint8, portStr string) (err error) {
-	selectedPort := m.getPort(portStr)
 	// Set IODIR register bit for given pin to an output/input.
-	if err = m.write(selectedPort.IODIR, uint8(pin), val); err != nil {
-		return
-	}
-	return
+	return m.setPin("mode", pin, val, portStr)
 }
 
 // ReadGPIO reads a value from a given gpio pin (0-7) and a
@@ -182,16 +212,51 @@ func (m *MCP23017Driver) ReadGPIO(pin uint8, portStr string) (val uint8, err err
 // val = 1 pull up enabled.
 // val = 0 pull up disabled.
 func (m *MCP23017Driver) SetPullUp(pin uint8, val uint8, portStr string) error {
-	selectedPort := m.getPort(portStr)
-	return m.write(selectedPort.GPPU, pin, val)
+	return m.setPin("pullup", pin, val, portStr)
 }
 
 // SetGPIOPolarity will change a given pin's polarity based on the value:
 // val = 1 opposite logic state of the input pin.
 // val = 0 same logic state of the input pin.
 func (m *MCP23017Driver) SetGPIOPolarity(pin uint8, val uint8, portStr string) (err error) {
-	selectedPort := m.getPort(portStr)
-	return m.write(selectedPort.IPOL, pin, val)
+	return m.setPin("polarity", pin, val, portStr)
+}
+
+// setPin writes the bit of the register for setting, and keeps the value in
+// the driver's Store under a key such as "mode/A7" so Start can restore it.
+func (m *MCP23017Driver) setPin(setting string, pin uint8, val uint8, portStr string) error {
+	if err := m.write(mcp23017Settings[setting](m.getPort(portStr)), pin, val); err != nil {
+		return err
+	}
+	portStr = strings.ToUpper(portStr)
+	if portStr != "B" {
+		portStr = "A"
+	}
+	return m.Store().Set(fmt.Sprintf("%v/%v%v", setting, portStr, pin), val)
+}
+
+// restoreSettings writes the pin settings kept in the driver's Store.
+func (m *MCP23017Driver) restoreSettings() error {
+	store := m.Store()
+	for _, key := range store.Keys() {
+		parts := strings.SplitN(key, "/", 2)
+		register, ok := mcp23017Settings[parts[0]]
+		if !ok || len(parts) < 2 || len(parts[1]) < 2 {
+			continue
+		}
+		pin, err := strconv.Atoi(parts[1][1:])
+		if err != nil {
+			continue
+		}
+		var val uint8
+		if _, err = store.Get(key, &val); err != nil {
+			return err
+		}
+		if err = m.write(register(m.getPort(parts[1][:1])), uint8(pin), val); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 // write gets the value of the passed in register, and then overwrites
@@ -207,9 +272,11 @@ func (m *MCP23017Driver) write(reg uint8, pin uint8, val uint8) (err error) {
 	} else if val == 1 {
 		ioval = setBit(iodir, uint8(pin))
 	}
-	if debug {
-		log.Printf("Writing: MCP address: 0x%X, register: 0x%X\t, value: 0x%X\n", m.mcp23017Address, reg, ioval)
-	}
+	m.Logger().Debug("Writing",
+		"address", fmt.Sprintf("0x%X", m.mcp23017Address),
+		"register", fmt.Sprintf("0x%X", reg),
+		"value", fmt.Sprintf("0x%X", ioval),
+	)
 	if err = m.connection.I2cWrite(m.mcp23017Address, []uint8{reg, ioval}); err != nil {
 		return err
 	}
@@ -230,9 +297,11 @@ func (m *MCP23017Driver) read(reg uint8) (val uint8, err error) {
 	if len(v) != bytesToRead {
 		return val, fmt.Errorf("Read was unable to get %d bytes for register: 0x%X\n", bytesToRead, reg)
 	}
-	if debug {
-		log.Printf("Reading: MCP address: 0x%X, register:0x%X\t,value: 0x%X\n", m.mcp23017Address, reg, v[register])
-	}
+	m.Logger().Debug("Reading",
+		"address", fmt.Sprintf("0x%X", m.mcp23017Address),
+		"register", fmt.Sprintf("0x%X", reg),
+		"value", fmt.Sprintf("0x%X", v[register]),
+	)
 	return v[register], nil
 }
 
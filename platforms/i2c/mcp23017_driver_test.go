@@ -1,12 +1,12 @@
 package i2c
 
 import (
+	"bytes"
 	"errors"
-	"io/ioutil"
 	"log"
-	"os"
 	"testing"
 
+	"github.com/hybridgroup/gobot"
 	"github.com/hybridgroup/gobot/gobottest"
 )
 
@@ -122,6 +122,19 @@ func TestMCP23017DriverCommandsReadGPIO(t *testing.T) {
 	gobottest.Assert(t, result.(map[string]interface{})["err"], nil)
 }
 
+func TestMCP23017DriverCommandsInvalidParams(t *testing.T) {
+	mcp := initTestMCP23017Driver(0)
+
+	result := mcp.Command("WriteGPIO")(map[string]interface{}{"pin": 8, "val": 0, "port": "A"})
+	gobottest.Assert(t, result.(*gobot.ParamError).Param, "pin")
+
+	result = mcp.Command("WriteGPIO")(map[string]interface{}{"pin": 7, "val": 2, "port": "A"})
+	gobottest.Assert(t, result.(*gobot.ParamError).Param, "val")
+
+	result = mcp.Command("ReadGPIO")(map[string]interface{}{"pin": 7, "port": "C"})
+	gobottest.Assert(t, result.(*gobot.ParamError).Param, "port")
+}
+
 func TestMCP23017DriverWriteGPIO(t *testing.T) {
 	mcp, adaptor := initTestMCP23017DriverWithStubbedAdaptor(0)
 	adaptor.i2cMcpReadImpl = func(a int, b int) ([]byte, error) {
@@ -210,6 +223,33 @@ func TestMCP23017DriverPinMode(t *testing.T) {
         gobottest.Assert(t, err, errors.New("write error"))
 }
 
+func TestMCP23017DriverSettingsStore(t *testing.T) {
+	mcp, adaptor := initTestMCP23017DriverWithStubbedAdaptor(0)
+	adaptor.i2cMcpReadImpl = func(a int, b int) ([]byte, error) {
+		return make([]byte, b), nil
+	}
+	store := gobot.NewMemoryStore()
+	mcp.SetStore(store)
+	gobottest.Assert(t, mcp.PinMode(7, 1, "A"), nil)
+	gobottest.Assert(t, mcp.SetPullUp(3, 1, "b"), nil)
+	gobottest.Assert(t, mcp.SetGPIOPolarity(2, 0, "x"), nil)
+	gobottest.Assert(t, store.Keys(), []string{"mode/A7", "polarity/A2", "pullup/B3"})
+
+	// the settings are written again on start
+	writes := 0
+	adaptor.i2cMcpWriteImpl = func() error {
+		writes++
+		return nil
+	}
+	gobottest.Assert(t, len(mcp.Start()), 0)
+	gobottest.Assert(t, writes, 4)
+
+	adaptor.i2cMcpReadImpl = func(a int, b int) ([]byte, error) {
+		return nil, errors.New("read error")
+	}
+	gobottest.Assert(t, mcp.Start()[0], errors.New("read error"))
+}
+
 func TestMCP23017DriverSetPullUp(t *testing.T) {
 	mcp, adaptor := initTestMCP23017DriverWithStubbedAdaptor(0)
 	adaptor.i2cMcpReadImpl = func(a int, b int) ([]byte, error) {
@@ -302,8 +342,8 @@ func TestMCP23017DriverWrite(t *testing.T) {
 	gobottest.Assert(t, err, errors.New("read error"))
 
 	//debug
-	debug = true
-	log.SetOutput(ioutil.Discard)
+	var buf bytes.Buffer
+	mcp.SetLogger(gobot.NewLogger(log.New(&buf, "", 0), gobot.DebugLevel))
 	adaptor.i2cMcpReadImpl = func(a int, b int) ([]byte, error) {
 		return make([]byte, b), nil
 	}
@@ -312,8 +352,8 @@ func TestMCP23017DriverWrite(t *testing.T) {
 	}
 	err = mcp.write(port.IODIR, uint8(7), 1)
 	gobottest.Assert(t, err, nil)
-	debug = false
-	log.SetOutput(os.Stdout)
+	gobottest.Assert(t, buf.String(), "DEBUG Reading address=0x20 register=0x1 value=0x0\n"+
+		"DEBUG Writing address=0x20 register=0x1 value=0x80\n")
 }
 
 func TestMCP23017DriverReadPort(t *testing.T) {
@@ -346,9 +386,9 @@ func TestMCP23017DriverReadPort(t *testing.T) {
 	gobottest.Assert(t, err, errors.New("Read was unable to get 1 bytes for register: 0x0\n"))
 
 	// debug
-	debug = true
-	log.SetOutput(ioutil.Discard)
+	var buf bytes.Buffer
 	mcp, adaptor = initTestMCP23017DriverWithStubbedAdaptor(0)
+	mcp.SetLogger(gobot.NewLogger(log.New(&buf, "", 0), gobot.DebugLevel))
 	port = mcp.getPort("A")
 
 	adaptor.i2cMcpReadImpl = func(a int, b int) ([]byte, error) {
@@ -357,8 +397,7 @@ func TestMCP23017DriverReadPort(t *testing.T) {
 
 	val, _ = mcp.read(port.IODIR)
 	gobottest.Assert(t, val, uint8(255))
-	debug = false
-	log.SetOutput(os.Stdout)
+	gobottest.Assert(t, buf.String(), "DEBUG Reading address=0x20 register=0x0 value=0xFF\n")
 }
 
 func TestMCP23017DriverGetPort(t *testing.T) {
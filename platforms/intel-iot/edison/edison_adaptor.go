@@ -55,6 +55,7 @@ type EdisonAdaptor struct {
 	pwmPins     map[int]*pwmPin
 	i2cDevice   sysfs.I2cDevice
 	connect     func(e *EdisonAdaptor) (err error)
+	gobot.PinRegistry
 }
 
 var sysfsPinMap = map[string]sysfsPin{
@@ -178,8 +179,15 @@ func changePinMode(pin, mode string) (err error) {
 	return
 }
 
+func init() {
+	gobot.RegisterAdaptor("edison", func(config gobot.ConnectionConfig) (gobot.Connection, error) {
+		return NewEdisonAdaptor(config.Name), nil
+	})
+}
+
 // NewEdisonAdaptor returns a new EdisonAdaptor with specified name
 func NewEdisonAdaptor(name string) *EdisonAdaptor {
+	sysfs.SetDefaultMetrics(gobot.DefaultRegistry())
 	return &EdisonAdaptor{
 		name: name,
 		//i2cDevices: make(map[int]io.ReadWriteCloser),
@@ -12,6 +12,7 @@ import (
 )
 
 var _ gobot.Adaptor = (*EdisonAdaptor)(nil)
+var _ gobot.PinReserver = (*EdisonAdaptor)(nil)
 
 var _ gpio.DigitalReader = (*EdisonAdaptor)(nil)
 var _ gpio.DigitalWriter = (*EdisonAdaptor)(nil)
@@ -47,6 +47,7 @@ type JouleAdaptor struct {
 	pwmPins     map[int]*pwmPin
 	i2cDevice   sysfs.I2cDevice
 	connect     func(e *JouleAdaptor) (err error)
+	gobot.PinRegistry
 }
 
 var sysfsPinMap = map[string]sysfsPin{
@@ -449,8 +450,15 @@ var sysfsPinMap = map[string]sysfsPin{
 	},
 }
 
+func init() {
+	gobot.RegisterAdaptor("joule", func(config gobot.ConnectionConfig) (gobot.Connection, error) {
+		return NewJouleAdaptor(config.Name), nil
+	})
+}
+
 // NewJouleAdaptor returns a new JouleAdaptor with specified name
 func NewJouleAdaptor(name string) *JouleAdaptor {
+	sysfs.SetDefaultMetrics(gobot.DefaultRegistry())
 	return &JouleAdaptor{
 		name: name,
 		connect: func(e *JouleAdaptor) (err error) {
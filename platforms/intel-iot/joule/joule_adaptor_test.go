@@ -12,6 +12,7 @@ import (
 )
 
 var _ gobot.Adaptor = (*JouleAdaptor)(nil)
+var _ gobot.PinReserver = (*JouleAdaptor)(nil)
 
 var _ gpio.DigitalReader = (*JouleAdaptor)(nil)
 var _ gpio.DigitalWriter = (*JouleAdaptor)(nil)
@@ -23,6 +23,7 @@ type RaspiAdaptor struct {
 	digitalPins map[int]sysfs.DigitalPin
 	pwmPins     []int
 	i2cDevice   sysfs.I2cDevice
+	gobot.PinRegistry
 }
 
 var pins = map[string]map[string]int{
@@ -112,8 +113,15 @@ var pins = map[string]map[string]int{
 	},
 }
 
+func init() {
+	gobot.RegisterAdaptor("raspi", func(config gobot.ConnectionConfig) (gobot.Connection, error) {
+		return NewRaspiAdaptor(config.Name), nil
+	})
+}
+
 // NewRaspiAdaptor creates a RaspiAdaptor with specified name and
 func NewRaspiAdaptor(name string) *RaspiAdaptor {
+	sysfs.SetDefaultMetrics(gobot.DefaultRegistry())
 	r := &RaspiAdaptor{
 		name:        name,
 		digitalPins: make(map[int]sysfs.DigitalPin),
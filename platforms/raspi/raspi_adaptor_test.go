@@ -12,6 +12,7 @@ import (
 )
 
 var _ gobot.Adaptor = (*RaspiAdaptor)(nil)
+var _ gobot.PinReserver = (*RaspiAdaptor)(nil)
 
 var _ gpio.DigitalReader = (*RaspiAdaptor)(nil)
 var _ gpio.DigitalWriter = (*RaspiAdaptor)(nil)
@@ -0,0 +1,8 @@
+/*
+Package sim contains a Gobot adaptor for simulated hardware, so that robots
+can be run and tested without boards.
+
+For further information refer to the sim README:
+https://github.com/hybridgroup/gobot/blob/master/platforms/sim/README.md
+*/
+package sim
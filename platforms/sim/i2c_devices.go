@@ -0,0 +1,223 @@
+package sim
+
+import (
+	"encoding/binary"
+	"math"
+	"strings"
+	"sync"
+)
+
+// I2cDevice is a model of an I2C device, which answers the reads and writes
+// a SimAdaptor receives for its address.
+type I2cDevice interface {
+	I2cWrite(data []byte) error
+	I2cRead(size int) ([]byte, error)
+}
+
+// Registers models an I2C device as 256 byte registers. The first byte of a
+// write selects a register, and any further bytes are written to it and the
+// registers after it. Reads start at the selected register and move on
+// through the ones after it. The zero value is ready to use.
+type Registers struct {
+	mtx     sync.Mutex
+	regs    [256]byte
+	pointer byte
+}
+
+// I2cWrite selects the register given by the first byte of data and writes
+// the rest of data from there
+func (r *Registers) I2cWrite(data []byte) error {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	if len(data) == 0 {
+		return nil
+	}
+	r.pointer = data[0]
+	for _, b := range data[1:] {
+		r.regs[r.pointer] = b
+		r.pointer++
+	}
+	return nil
+}
+
+// I2cRead reads size bytes from the selected register onwards
+func (r *Registers) I2cRead(size int) ([]byte, error) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	data := make([]byte, size)
+	for i := range data {
+		data[i] = r.regs[r.pointer]
+		r.pointer++
+	}
+	return data, nil
+}
+
+// Register returns the value of reg
+func (r *Registers) Register(reg byte) byte {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	return r.regs[reg]
+}
+
+// SetRegister sets reg, and the registers after it, to vals without
+// moving the selected register
+func (r *Registers) SetRegister(reg byte, vals ...byte) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	for _, v := range vals {
+		r.regs[reg] = v
+		reg++
+	}
+}
+
+func (r *Registers) setInt16(reg byte, vals ...int16) {
+	buf := make([]byte, 2*len(vals))
+	for i, v := range vals {
+		binary.BigEndian.PutUint16(buf[2*i:], uint16(v))
+	}
+	r.SetRegister(reg, buf...)
+}
+
+// MPU6050 models the MPU6050 accelerometer and gyroscope, usually at address
+// 0x68.
+type MPU6050 struct {
+	Registers
+}
+
+// NewMPU6050 returns a new MPU6050 model at rest
+func NewMPU6050() *MPU6050 {
+	return &MPU6050{}
+}
+
+// SetAccelerometer sets the raw accelerometer readings
+func (m *MPU6050) SetAccelerometer(x, y, z int16) {
+	m.setInt16(0x3B, x, y, z)
+}
+
+// SetTemperature sets the temperature reading in degrees Celsius
+func (m *MPU6050) SetTemperature(celsius float64) {
+	m.setInt16(0x41, int16(math.Round(celsius*340-12412)))
+}
+
+// SetGyroscope sets the raw gyroscope readings
+func (m *MPU6050) SetGyroscope(x, y, z int16) {
+	m.setInt16(0x43, x, y, z)
+}
+
+// LIDARLite models the LIDAR-Lite distance sensor, usually at address 0x62.
+type LIDARLite struct {
+	Registers
+}
+
+// NewLIDARLite returns a new LIDARLite model measuring 0cm
+func NewLIDARLite() *LIDARLite {
+	return &LIDARLite{}
+}
+
+// SetDistance sets the distance measured in cm
+func (l *LIDARLite) SetDistance(cm int) {
+	l.SetRegister(0x0F, byte(cm>>8), byte(cm))
+}
+
+// HMC6352 models the HMC6352 compass, usually at address 0x21. Reads return
+// the heading as after an "A" command; commands are otherwise ignored.
+type HMC6352 struct {
+	mtx     sync.Mutex
+	heading uint16
+}
+
+// NewHMC6352 returns a new HMC6352 model heading north
+func NewHMC6352() *HMC6352 {
+	return &HMC6352{}
+}
+
+// SetHeading sets the heading in degrees
+func (h *HMC6352) SetHeading(degrees float64) {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+	h.heading = uint16(math.Round(degrees * 10))
+}
+
+// I2cWrite accepts a command
+func (h *HMC6352) I2cWrite(data []byte) error { return nil }
+
+// I2cRead reads the heading in tenths of a degree
+func (h *HMC6352) I2cRead(size int) ([]byte, error) {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+	data := make([]byte, size)
+	if size >= 2 {
+		binary.BigEndian.PutUint16(data, h.heading)
+	}
+	return data, nil
+}
+
+// MCP23017 registers in the default bank 0 layout
+const (
+	mcp23017IODIRA = 0x00
+	mcp23017IPOLA  = 0x02
+	mcp23017GPIOA  = 0x12
+	mcp23017OLATA  = 0x14
+	mcp23017Size   = 0x16
+)
+
+// MCP23017 models the MCP23017 port expander, usually at addresses 0x20 to
+// 0x27, with its registers in the default bank 0 layout. Like the
+// MCP23017Driver expects, reads start at the first register. Reading a GPIO
+// register returns the levels set by SetInputs for input pins and the
+// output latch for output pins.
+type MCP23017 struct {
+	Registers
+	inputs [2]byte
+}
+
+// NewMCP23017 returns a new MCP23017 model with every pin an input, as at
+// power on
+func NewMCP23017() *MCP23017 {
+	m := &MCP23017{}
+	m.SetRegister(mcp23017IODIRA, 0xFF, 0xFF)
+	return m
+}
+
+// I2cRead reads size bytes from the first register onwards
+func (m *MCP23017) I2cRead(size int) ([]byte, error) {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	data := make([]byte, size)
+	copy(data, m.regs[:mcp23017Size])
+	for port := 0; port < 2 && mcp23017GPIOA+port < size; port++ {
+		data[mcp23017GPIOA+port] = m.gpio(port)
+	}
+	return data, nil
+}
+
+// SetInputs sets the levels of the input pins of port "A" or "B", one bit
+// per pin
+func (m *MCP23017) SetInputs(port string, levels byte) {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	m.inputs[mcp23017Port(port)] = levels
+}
+
+// Outputs returns the levels of the output pins of port "A" or "B", one bit
+// per pin. Bits of input pins are 0.
+func (m *MCP23017) Outputs(port string) byte {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	p := mcp23017Port(port)
+	return m.regs[mcp23017OLATA+p] &^ m.regs[mcp23017IODIRA+p]
+}
+
+// gpio returns the value of the GPIO register of port
+func (m *MCP23017) gpio(port int) byte {
+	iodir := m.regs[mcp23017IODIRA+port]
+	inputs := m.inputs[port] ^ m.regs[mcp23017IPOLA+port]
+	return inputs&iodir | m.regs[mcp23017OLATA+port]&^iodir
+}
+
+func mcp23017Port(port string) int {
+	if strings.ToUpper(port) == "B" {
+		return 1
+	}
+	return 0
+}
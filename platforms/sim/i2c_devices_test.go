@@ -0,0 +1,96 @@
+package sim
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+
+	"github.com/hybridgroup/gobot/gobottest"
+	"github.com/hybridgroup/gobot/platforms/i2c"
+)
+
+func TestRegisters(t *testing.T) {
+	r := &Registers{}
+	r.I2cWrite([]byte{0xFE, 1, 2, 3})
+	gobottest.Assert(t, r.Register(0xFF), byte(2))
+	gobottest.Assert(t, r.Register(0x00), byte(3))
+
+	r.SetRegister(0x20, 4, 5)
+	r.I2cWrite([]byte{0x20})
+	data, err := r.I2cRead(3)
+	gobottest.Assert(t, err, nil)
+	gobottest.Assert(t, data, []byte{4, 5, 0})
+	gobottest.Assert(t, r.I2cWrite(nil), nil)
+}
+
+func TestMPU6050(t *testing.T) {
+	a := initTestSimAdaptor()
+	m := NewMPU6050()
+	m.SetAccelerometer(1, -2, 3)
+	m.SetGyroscope(-100, 200, 300)
+	m.SetTemperature(25)
+	a.AddI2cDevice(0x68, m)
+
+	// read as the MPU6050Driver does
+	a.I2cWrite(0x68, []byte{0x3B})
+	ret, err := a.I2cRead(0x68, 14)
+	gobottest.Assert(t, err, nil)
+	var accel, gyro i2c.ThreeDData
+	var temp int16
+	buf := bytes.NewBuffer(ret)
+	binary.Read(buf, binary.BigEndian, &accel)
+	binary.Read(buf, binary.BigEndian, &temp)
+	binary.Read(buf, binary.BigEndian, &gyro)
+	gobottest.Assert(t, accel, i2c.ThreeDData{X: 1, Y: -2, Z: 3})
+	gobottest.Assert(t, gyro, i2c.ThreeDData{X: -100, Y: 200, Z: 300})
+	gobottest.Assert(t, (temp+12412)/340, int16(25))
+}
+
+func TestLIDARLite(t *testing.T) {
+	a := initTestSimAdaptor()
+	l := NewLIDARLite()
+	l.SetDistance(1234)
+	a.AddI2cDevice(0x62, l)
+
+	d := i2c.NewLIDARLiteDriver(a, "lidar")
+	gobottest.Assert(t, len(d.Start()), 0)
+	distance, err := d.Distance()
+	gobottest.Assert(t, err, nil)
+	gobottest.Assert(t, distance, 1234)
+}
+
+func TestHMC6352(t *testing.T) {
+	a := initTestSimAdaptor()
+	h := NewHMC6352()
+	h.SetHeading(271.5)
+	a.AddI2cDevice(0x21, h)
+
+	d := i2c.NewHMC6352Driver(a, "compass")
+	gobottest.Assert(t, len(d.Start()), 0)
+	heading, err := d.Heading()
+	gobottest.Assert(t, err, nil)
+	gobottest.Assert(t, heading, uint16(271))
+}
+
+func TestMCP23017(t *testing.T) {
+	a := initTestSimAdaptor()
+	m := NewMCP23017()
+	a.AddI2cDevice(0x20, m)
+
+	d := i2c.NewMCP23017Driver(a, "expander", i2c.MCP23017Config{}, 0x20)
+	gobottest.Assert(t, len(d.Start()), 0)
+
+	gobottest.Assert(t, d.WriteGPIO(3, 1, "A"), nil)
+	gobottest.Assert(t, m.Outputs("A"), uint8(1<<3))
+	gobottest.Assert(t, m.Outputs("B"), uint8(0))
+	val, err := d.ReadGPIO(3, "A")
+	gobottest.Assert(t, err, nil)
+	gobottest.Assert(t, val, uint8(1<<3))
+
+	m.SetInputs("B", 1<<5)
+	val, _ = d.ReadGPIO(5, "B")
+	gobottest.Assert(t, val, uint8(1<<5))
+	d.SetGPIOPolarity(5, 1, "B")
+	val, _ = d.ReadGPIO(5, "B")
+	gobottest.Assert(t, val, uint8(0))
+}
@@ -0,0 +1,185 @@
+package sim
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/hybridgroup/gobot"
+)
+
+func init() {
+	gobot.RegisterAdaptor("sim", func(config gobot.ConnectionConfig) (gobot.Connection, error) {
+		return NewSimAdaptor(config.Name), nil
+	})
+}
+
+// SimAdaptor is an adaptor for simulated hardware. Its virtual pins hold
+// the values written to them, and may be set or given analog waveforms by
+// tests. I2C reads and writes are answered by the device models added to
+// it.
+type SimAdaptor struct {
+	name      string
+	mtx       sync.Mutex
+	digital   map[string]int
+	analog    map[string]int
+	waveforms map[string]waveform
+	pwm       map[string]byte
+	servo     map[string]byte
+	devices   map[int]I2cDevice
+	now       func() time.Time
+	gobot.PinRegistry
+}
+
+type waveform struct {
+	w     Waveform
+	start time.Time
+}
+
+// NewSimAdaptor returns a new SimAdaptor with the given name. Every pin
+// reads 0 until it is set or written.
+func NewSimAdaptor(name string) *SimAdaptor {
+	return &SimAdaptor{
+		name:      name,
+		digital:   make(map[string]int),
+		analog:    make(map[string]int),
+		waveforms: make(map[string]waveform),
+		pwm:       make(map[string]byte),
+		servo:     make(map[string]byte),
+		devices:   make(map[int]I2cDevice),
+		now:       time.Now,
+	}
+}
+
+// Name returns the name of the SimAdaptor
+func (s *SimAdaptor) Name() string { return s.name }
+
+// Connect does nothing, as there is no hardware to connect to
+func (s *SimAdaptor) Connect() (errs []error) { return }
+
+// Finalize does nothing, as there is no hardware to release
+func (s *SimAdaptor) Finalize() (errs []error) { return }
+
+// SetDigital sets the value read from a digital pin
+func (s *SimAdaptor) SetDigital(pin string, val int) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	s.digital[pin] = val
+}
+
+// Digital returns the value last written to, or set on, a digital pin
+func (s *SimAdaptor) Digital(pin string) int {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	return s.digital[pin]
+}
+
+// SetAnalog sets the value read from an analog pin, replacing its waveform
+func (s *SimAdaptor) SetAnalog(pin string, val int) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	delete(s.waveforms, pin)
+	s.analog[pin] = val
+}
+
+// SetWaveform makes the values read from an analog pin follow w, starting
+// now.
+func (s *SimAdaptor) SetWaveform(pin string, w Waveform) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	s.waveforms[pin] = waveform{w: w, start: s.now()}
+}
+
+// Pwm returns the value last written to a pwm pin
+func (s *SimAdaptor) Pwm(pin string) byte {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	return s.pwm[pin]
+}
+
+// Servo returns the angle last written to a servo pin
+func (s *SimAdaptor) Servo(pin string) byte {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	return s.servo[pin]
+}
+
+// AddI2cDevice adds a device model which answers I2C reads and writes at
+// address, replacing any model already there.
+func (s *SimAdaptor) AddI2cDevice(address int, d I2cDevice) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	s.devices[address] = d
+}
+
+// DigitalRead reads the value of a digital pin
+func (s *SimAdaptor) DigitalRead(pin string) (val int, err error) {
+	return s.Digital(pin), nil
+}
+
+// DigitalWrite writes a value to a digital pin
+func (s *SimAdaptor) DigitalWrite(pin string, val byte) (err error) {
+	s.SetDigital(pin, int(val))
+	return
+}
+
+// AnalogRead reads the value of an analog pin, following its waveform if
+// it has one
+func (s *SimAdaptor) AnalogRead(pin string) (val int, err error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	if w, ok := s.waveforms[pin]; ok {
+		return w.w(s.now().Sub(w.start)), nil
+	}
+	return s.analog[pin], nil
+}
+
+// PwmWrite writes a pwm value to a pin
+func (s *SimAdaptor) PwmWrite(pin string, val byte) (err error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	s.pwm[pin] = val
+	return
+}
+
+// ServoWrite writes a servo angle to a pin
+func (s *SimAdaptor) ServoWrite(pin string, angle byte) (err error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	s.servo[pin] = angle
+	return
+}
+
+// I2cStart starts the device model at address. It returns an error if
+// there is none.
+func (s *SimAdaptor) I2cStart(address int) (err error) {
+	_, err = s.i2cDevice(address)
+	return
+}
+
+// I2cWrite writes data to the device model at address
+func (s *SimAdaptor) I2cWrite(address int, data []byte) (err error) {
+	d, err := s.i2cDevice(address)
+	if err != nil {
+		return
+	}
+	return d.I2cWrite(data)
+}
+
+// I2cRead reads size bytes from the device model at address
+func (s *SimAdaptor) I2cRead(address int, size int) (data []byte, err error) {
+	d, err := s.i2cDevice(address)
+	if err != nil {
+		return
+	}
+	return d.I2cRead(size)
+}
+
+func (s *SimAdaptor) i2cDevice(address int) (I2cDevice, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	if d, ok := s.devices[address]; ok {
+		return d, nil
+	}
+	return nil, fmt.Errorf("No I2C device at address 0x%02x", address)
+}
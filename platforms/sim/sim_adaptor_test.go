@@ -0,0 +1,138 @@
+package sim
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hybridgroup/gobot"
+	"github.com/hybridgroup/gobot/gobottest"
+	"github.com/hybridgroup/gobot/platforms/gpio"
+	"github.com/hybridgroup/gobot/platforms/i2c"
+)
+
+var _ gobot.Adaptor = (*SimAdaptor)(nil)
+var _ gobot.PinReserver = (*SimAdaptor)(nil)
+
+var _ gpio.DigitalReader = (*SimAdaptor)(nil)
+var _ gpio.DigitalWriter = (*SimAdaptor)(nil)
+var _ gpio.AnalogReader = (*SimAdaptor)(nil)
+var _ gpio.PwmWriter = (*SimAdaptor)(nil)
+var _ gpio.ServoWriter = (*SimAdaptor)(nil)
+
+var _ i2c.I2c = (*SimAdaptor)(nil)
+
+func initTestSimAdaptor() *SimAdaptor {
+	a := NewSimAdaptor("sim")
+	a.Connect()
+	return a
+}
+
+func TestSimAdaptor(t *testing.T) {
+	a := initTestSimAdaptor()
+	gobottest.Assert(t, a.Name(), "sim")
+	gobottest.Assert(t, len(a.Finalize()), 0)
+}
+
+func TestSimAdaptorDigitalIO(t *testing.T) {
+	a := initTestSimAdaptor()
+	val, err := a.DigitalRead("7")
+	gobottest.Assert(t, err, nil)
+	gobottest.Assert(t, val, 0)
+
+	a.SetDigital("7", 1)
+	val, _ = a.DigitalRead("7")
+	gobottest.Assert(t, val, 1)
+
+	gobottest.Assert(t, a.DigitalWrite("13", 1), nil)
+	gobottest.Assert(t, a.Digital("13"), 1)
+}
+
+func TestSimAdaptorAnalogRead(t *testing.T) {
+	a := initTestSimAdaptor()
+	now := time.Now()
+	a.now = func() time.Time { return now }
+
+	a.SetAnalog("A0", 512)
+	val, err := a.AnalogRead("A0")
+	gobottest.Assert(t, err, nil)
+	gobottest.Assert(t, val, 512)
+
+	a.SetWaveform("A0", Steps(time.Second, 1, 2, 3))
+	val, _ = a.AnalogRead("A0")
+	gobottest.Assert(t, val, 1)
+	now = now.Add(1500 * time.Millisecond)
+	val, _ = a.AnalogRead("A0")
+	gobottest.Assert(t, val, 2)
+
+	a.SetAnalog("A0", 100)
+	val, _ = a.AnalogRead("A0")
+	gobottest.Assert(t, val, 100)
+}
+
+func TestSimAdaptorPwmAndServo(t *testing.T) {
+	a := initTestSimAdaptor()
+	gobottest.Assert(t, a.PwmWrite("3", 128), nil)
+	gobottest.Assert(t, a.Pwm("3"), uint8(128))
+	gobottest.Assert(t, a.ServoWrite("5", 90), nil)
+	gobottest.Assert(t, a.Servo("5"), uint8(90))
+}
+
+func TestSimAdaptorI2c(t *testing.T) {
+	a := initTestSimAdaptor()
+	gobottest.Assert(t, a.I2cStart(0x42).Error(), "No I2C device at address 0x42")
+	gobottest.Refute(t, a.I2cWrite(0x42, []byte{0}), nil)
+	_, err := a.I2cRead(0x42, 1)
+	gobottest.Refute(t, err, nil)
+
+	a.AddI2cDevice(0x42, &Registers{})
+	gobottest.Assert(t, a.I2cStart(0x42), nil)
+	gobottest.Assert(t, a.I2cWrite(0x42, []byte{0x10, 0xAB}), nil)
+	a.I2cWrite(0x42, []byte{0x10})
+	data, err := a.I2cRead(0x42, 1)
+	gobottest.Assert(t, err, nil)
+	gobottest.Assert(t, data, []byte{0xAB})
+}
+
+func TestSimAdaptorGpioDrivers(t *testing.T) {
+	a := initTestSimAdaptor()
+
+	led := gpio.NewLedDriver(a, "led", "13")
+	led.On()
+	gobottest.Assert(t, a.Digital("13"), 1)
+	led.Brightness(200)
+	gobottest.Assert(t, a.Pwm("13"), uint8(200))
+
+	servo := gpio.NewServoDriver(a, "servo", "9")
+	servo.Center()
+	gobottest.Assert(t, a.Servo("9"), uint8(90))
+
+	button := gpio.NewButtonDriver(a, "button", "2", time.Millisecond)
+	events := button.Subscribe()
+	button.Start()
+	defer button.Halt()
+	a.SetDigital("2", 1)
+	select {
+	case evt := <-events:
+		gobottest.Assert(t, evt.Name, gpio.ButtonPush)
+	case <-time.After(time.Second):
+		t.Error("Button was not pushed")
+	}
+
+	sensor := gpio.NewAnalogSensorDriver(a, "sensor", "A1")
+	a.SetWaveform("A1", Constant(300))
+	val, err := sensor.Read()
+	gobottest.Assert(t, err, nil)
+	gobottest.Assert(t, val, 300)
+}
+
+func TestSimAdaptorPinConflict(t *testing.T) {
+	a := initTestSimAdaptor()
+	led := gpio.NewLedDriver(a, "led", "7")
+	button := gpio.NewButtonDriver(a, "button", "7")
+	robot := gobot.NewRobot("bot", []gobot.Connection{a}, []gobot.Device{led, button})
+
+	errs := robot.Start()
+	gobottest.Assert(t, len(errs), 1)
+	gobottest.Assert(t, errs[0].Error(), `Device "button": Pin "7" is already reserved by device "led"`)
+	gobottest.Assert(t, gobot.NewJSONConnection(a).Pins, map[string]string{"7": "led"})
+}
@@ -0,0 +1,74 @@
+package sim
+
+import (
+	"math"
+	"time"
+)
+
+// Waveform returns the value of an analog pin the given time after the
+// waveform was set on it.
+type Waveform func(elapsed time.Duration) int
+
+// Constant returns a Waveform which is always val.
+func Constant(val int) Waveform {
+	return func(time.Duration) int { return val }
+}
+
+// Sine returns a Waveform which swings between min and max once every
+// period, starting halfway between them and rising. It panics if period is
+// not positive.
+func Sine(min, max int, period time.Duration) Waveform {
+	if period <= 0 {
+		panic("non-positive period for Sine")
+	}
+	mid := float64(min+max) / 2
+	amplitude := float64(max-min) / 2
+	return func(elapsed time.Duration) int {
+		phase := 2 * math.Pi * float64(elapsed%period) / float64(period)
+		return int(math.Round(mid + amplitude*math.Sin(phase)))
+	}
+}
+
+// Square returns a Waveform which is low for the first half of every period
+// and high for the second. It panics if period is not positive.
+func Square(low, high int, period time.Duration) Waveform {
+	if period <= 0 {
+		panic("non-positive period for Square")
+	}
+	return func(elapsed time.Duration) int {
+		if elapsed%period < period/2 {
+			return low
+		}
+		return high
+	}
+}
+
+// Ramp returns a Waveform which moves linearly from one value to another
+// over d, and then stays there.
+func Ramp(from, to int, d time.Duration) Waveform {
+	return func(elapsed time.Duration) int {
+		if elapsed >= d {
+			return to
+		}
+		return from + int(float64(to-from)*float64(elapsed)/float64(d))
+	}
+}
+
+// Steps returns a Waveform which is each of values in turn for step, and
+// then stays at the last of them. It is 0 when there are no values. It
+// panics if step is not positive.
+func Steps(step time.Duration, values ...int) Waveform {
+	if step <= 0 {
+		panic("non-positive step for Steps")
+	}
+	return func(elapsed time.Duration) int {
+		if len(values) == 0 {
+			return 0
+		}
+		i := int(elapsed / step)
+		if i >= len(values) {
+			i = len(values) - 1
+		}
+		return values[i]
+	}
+}
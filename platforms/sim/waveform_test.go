@@ -0,0 +1,58 @@
+package sim
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hybridgroup/gobot/gobottest"
+)
+
+func TestConstant(t *testing.T) {
+	gobottest.Assert(t, Constant(7)(time.Hour), 7)
+}
+
+func TestSine(t *testing.T) {
+	w := Sine(0, 1000, 4*time.Second)
+	gobottest.Assert(t, w(0), 500)
+	gobottest.Assert(t, w(time.Second), 1000)
+	gobottest.Assert(t, w(2*time.Second), 500)
+	gobottest.Assert(t, w(3*time.Second), 0)
+	gobottest.Assert(t, w(5*time.Second), 1000)
+}
+
+func TestSquare(t *testing.T) {
+	w := Square(0, 1023, time.Second)
+	gobottest.Assert(t, w(0), 0)
+	gobottest.Assert(t, w(600*time.Millisecond), 1023)
+	gobottest.Assert(t, w(1100*time.Millisecond), 0)
+}
+
+func TestRamp(t *testing.T) {
+	w := Ramp(100, 200, time.Second)
+	gobottest.Assert(t, w(0), 100)
+	gobottest.Assert(t, w(500*time.Millisecond), 150)
+	gobottest.Assert(t, w(2*time.Second), 200)
+}
+
+func TestSteps(t *testing.T) {
+	w := Steps(time.Second, 5, 10)
+	gobottest.Assert(t, w(0), 5)
+	gobottest.Assert(t, w(time.Second), 10)
+	gobottest.Assert(t, w(time.Minute), 10)
+	gobottest.Assert(t, Steps(time.Second)(0), 0)
+}
+
+func TestWaveformPeriod(t *testing.T) {
+	for want, build := range map[string]func(){
+		"non-positive period for Sine":   func() { Sine(0, 1, 0) },
+		"non-positive period for Square": func() { Square(0, 1, -time.Second) },
+		"non-positive step for Steps":    func() { Steps(0, 5) },
+	} {
+		func() {
+			defer func() {
+				gobottest.Assert(t, recover(), want)
+			}()
+			build()
+		}()
+	}
+}
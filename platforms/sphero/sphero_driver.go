@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"encoding/binary"
 	"errors"
+	"math"
 	"time"
 
 	"github.com/hybridgroup/gobot"
@@ -35,6 +36,12 @@ type SpheroDriver struct {
 	responseChannel chan []uint8
 	gobot.Eventer
 	gobot.Commander
+	gobot.Storage
+}
+
+// intParam returns a required integer param allowed between min and max.
+func intParam(name string, min, max float64) gobot.Param {
+	return gobot.Param{Name: name, Type: gobot.IntegerParam, Required: true, Range: &gobot.Range{Min: min, Max: max}}
 }
 
 // NewSpheroDriver returns a new SpheroDriver given a SpheroAdaptor and name.
@@ -64,7 +71,11 @@ func NewSpheroDriver(a *SpheroAdaptor, name string) *SpheroDriver {
 	s.AddEvent(Collision)
 	s.AddEvent(SensorData)
 
-	s.AddCommand("SetRGB", func(params map[string]interface{}) interface{} {
+	s.AddCommandSchema(gobot.CommandSchema{
+		Name:        "SetRGB",
+		Description: "Sets the color of the LED",
+		Params:      []gobot.Param{intParam("r", 0, 255), intParam("g", 0, 255), intParam("b", 0, 255)},
+	}, func(params map[string]interface{}) interface{} {
 		r := uint8(params["r"].(float64))
 		g := uint8(params["g"].(float64))
 		b := uint8(params["b"].(float64))
@@ -72,7 +83,11 @@ func NewSpheroDriver(a *SpheroAdaptor, name string) *SpheroDriver {
 		return nil
 	})
 
-	s.AddCommand("Roll", func(params map[string]interface{}) interface{} {
+	s.AddCommandSchema(gobot.CommandSchema{
+		Name:        "Roll",
+		Description: "Rolls at the speed towards the heading in degrees",
+		Params:      []gobot.Param{intParam("speed", 0, 255), intParam("heading", 0, 359)},
+	}, func(params map[string]interface{}) interface{} {
 		speed := uint8(params["speed"].(float64))
 		heading := uint16(params["heading"].(float64))
 		s.Roll(speed, heading)
@@ -92,31 +107,57 @@ func NewSpheroDriver(a *SpheroAdaptor, name string) *SpheroDriver {
 		return s.ReadLocator()
 	})
 
-	s.AddCommand("SetBackLED", func(params map[string]interface{}) interface{} {
+	s.AddCommandSchema(gobot.CommandSchema{
+		Name:        "SetBackLED",
+		Description: "Sets the brightness of the back LED",
+		Params:      []gobot.Param{intParam("level", 0, 255)},
+	}, func(params map[string]interface{}) interface{} {
 		level := uint8(params["level"].(float64))
 		s.SetBackLED(level)
 		return nil
 	})
 
-	s.AddCommand("SetRotationRate", func(params map[string]interface{}) interface{} {
+	s.AddCommandSchema(gobot.CommandSchema{
+		Name:        "SetRotationRate",
+		Description: "Sets the rotation rate",
+		Params:      []gobot.Param{intParam("level", 0, 255)},
+	}, func(params map[string]interface{}) interface{} {
 		level := uint8(params["level"].(float64))
 		s.SetRotationRate(level)
 		return nil
 	})
 
-	s.AddCommand("SetHeading", func(params map[string]interface{}) interface{} {
+	s.AddCommandSchema(gobot.CommandSchema{
+		Name:        "SetHeading",
+		Description: "Sets the current heading in degrees",
+		Params:      []gobot.Param{intParam("heading", 0, 359)},
+	}, func(params map[string]interface{}) interface{} {
 		heading := uint16(params["heading"].(float64))
 		s.SetHeading(heading)
 		return nil
 	})
 
-	s.AddCommand("SetStabilization", func(params map[string]interface{}) interface{} {
+	s.AddCommandSchema(gobot.CommandSchema{
+		Name:        "SetStabilization",
+		Description: "Turns stabilization on or off",
+		Params:      []gobot.Param{{Name: "enable", Type: gobot.BooleanParam, Required: true}},
+	}, func(params map[string]interface{}) interface{} {
 		on := params["enable"].(bool)
 		s.SetStabilization(on)
 		return nil
 	})
 
-	s.AddCommand("SetDataStreaming", func(params map[string]interface{}) interface{} {
+	s.AddCommandSchema(gobot.CommandSchema{
+		Name:        "SetDataStreaming",
+		Description: "Sets which sensor data is streamed and how often",
+		Params: []gobot.Param{
+			intParam("N", 0, math.MaxUint16),
+			intParam("M", 0, math.MaxUint16),
+			intParam("Mask", 0, math.MaxUint32),
+			intParam("Pcnt", 0, math.MaxUint8),
+			intParam("Mask2", 0, math.MaxUint32),
+		},
+	}, func(params map[string]interface{}) interface{} {
 		N := uint16(params["N"].(float64))
 		M := uint16(params["M"].(float64))
 		Mask := uint32(params["Mask"].(float64))
@@ -127,7 +168,16 @@ func NewSpheroDriver(a *SpheroAdaptor, name string) *SpheroDriver {
 		return nil
 	})
 
-	s.AddCommand("ConfigureLocator", func(params map[string]interface{}) interface{} {
+	s.AddCommandSchema(gobot.CommandSchema{
+		Name:        "ConfigureLocator",
+		Description: "Configures the locator",
+		Params: []gobot.Param{
+			intParam("Flags", 0, math.MaxUint8),
+			intParam("X", math.MinInt16, math.MaxInt16),
+			intParam("Y", math.MinInt16, math.MaxInt16),
+			intParam("YawTare", math.MinInt16, math.MaxInt16),
+		},
+	}, func(params map[string]interface{}) interface{} {
 		Flags := uint8(params["Flags"].(float64))
 		X := int16(params["X"].(float64))
 		Y := int16(params["Y"].(float64))
@@ -147,7 +197,8 @@ func (s *SpheroDriver) adaptor() *SpheroAdaptor {
 	return s.Connection().(*SpheroAdaptor)
 }
 
-// Start starts the SpheroDriver and enables Collision Detection.
+// Start starts the SpheroDriver and enables Collision Detection. The heading
+// last set by SetHeading is restored from the driver's Store.
 // Returns true on successful start.
 //
 // Emits the Events:
@@ -210,6 +261,11 @@ func (s *SpheroDriver) Start() (errs []error) {
 	s.ConfigureCollisionDetection(DefaultCollisionConfig())
 	s.enableStopOnDisconnect()
 
+	var heading uint16
+	if ok, _ := s.Store().Get("heading", &heading); ok {
+		s.sendHeading(heading)
+	}
+
 	return
 }
 
@@ -261,8 +317,16 @@ func (s *SpheroDriver) SetRotationRate(level uint8) {
 	s.packetChannel <- s.craftPacket([]uint8{level}, 0x02, 0x03)
 }
 
-// SetHeading sets the heading of the Sphero
+// SetHeading sets the heading of the Sphero, and keeps it in the driver's
+// Store so it is restored when the driver next starts.
 func (s *SpheroDriver) SetHeading(heading uint16) {
+	s.sendHeading(heading)
+	if err := s.Store().Set("heading", heading); err != nil {
+		s.Publish(Error, err)
+	}
+}
+
+func (s *SpheroDriver) sendHeading(heading uint16) {
 	s.packetChannel <- s.craftPacket([]uint8{uint8(heading >> 8), uint8(heading & 0xFF)}, 0x02, 0x01)
 }
 
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"encoding/binary"
 	"testing"
+	"time"
 
 	"github.com/hybridgroup/gobot"
 	"github.com/hybridgroup/gobot/gobottest"
@@ -31,6 +32,11 @@ func TestSpheroDriver(t *testing.T) {
 	)
 	gobottest.Assert(t, ret, nil)
 
+	ret = d.Command("Roll")(
+		map[string]interface{}{"speed": 100.0, "heading": "north"},
+	)
+	gobottest.Assert(t, ret.(error).Error(), `command "Roll": param "heading" must be an integer, got string`)
+
 	ret = d.Command("SetBackLED")(
 		map[string]interface{}{"level": 100.0},
 	)
@@ -79,6 +85,47 @@ func TestSpheroDriverStart(t *testing.T) {
 	gobottest.Assert(t, len(d.Start()), 0)
 }
 
+type packetRecorder struct {
+	nullReadWriteCloser
+	packets chan []byte
+}
+
+func (r packetRecorder) Write(p []byte) (int, error) {
+	r.packets <- append([]byte{}, p...)
+	return len(p), nil
+}
+
+func TestSpheroDriverHeadingStore(t *testing.T) {
+	store := gobot.NewMemoryStore()
+	d := initTestSpheroDriver()
+	d.SetStore(store)
+	d.SetHeading(300)
+	gobottest.Assert(t, (<-d.packetChannel).body, []uint8{0x01, 0x2C})
+
+	var heading uint16
+	ok, _ := store.Get("heading", &heading)
+	gobottest.Assert(t, ok, true)
+	gobottest.Assert(t, heading, uint16(300))
+
+	// the heading is restored on start
+	recorder := packetRecorder{packets: make(chan []byte, 16)}
+	d = initTestSpheroDriver()
+	d.adaptor().sp = recorder
+	d.SetStore(store)
+	d.Start()
+	for {
+		select {
+		case p := <-recorder.packets:
+			if p[2] == 0x02 && p[3] == 0x01 {
+				gobottest.Assert(t, p[6:8], []byte{0x01, 0x2C})
+				return
+			}
+		case <-time.After(time.Second):
+			t.Fatal("heading was not restored")
+		}
+	}
+}
+
 func TestSpheroDriverHalt(t *testing.T) {
 	d := initTestSpheroDriver()
 	d.adaptor().connected = true
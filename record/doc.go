@@ -0,0 +1,51 @@
+/*
+Package record provides recording and replay of Gobot event streams.
+
+A Recorder subscribes to any gobot.Eventer, such as a driver, an adaptor or
+a firmata Client, and writes every published event to a file. A ReplayAdaptor
+reads such a file back and republishes the events through ReplayDrivers, so a
+robot's work function can run against a recorded session without hardware.
+
+Example:
+
+    package main
+
+    import (
+    	"os"
+
+    	"github.com/hybridgroup/gobot"
+    	"github.com/hybridgroup/gobot/platforms/firmata"
+    	"github.com/hybridgroup/gobot/platforms/gpio"
+    	"github.com/hybridgroup/gobot/record"
+    )
+
+    func main() {
+    	gbot := gobot.NewGobot()
+
+    	firmataAdaptor := firmata.NewFirmataAdaptor("arduino", "/dev/ttyACM0")
+    	sensor := gpio.NewAnalogSensorDriver(firmataAdaptor, "sensor", "0")
+
+    	f, _ := os.Create("session.jsonl")
+    	recorder, _ := record.NewRecorder(f, record.JSON)
+    	recorder.Record(sensor)
+
+    	gbot.AddRobot(gobot.NewRobot("recorder",
+    		[]gobot.Connection{firmataAdaptor},
+    		[]gobot.Device{sensor},
+    	))
+
+    	gbot.Start()
+    	recorder.Close()
+    }
+
+Replaying the session later, twice as fast:
+
+    	replay := record.NewReplayAdaptor("replay", "session.jsonl")
+    	replay.Speed = 2
+    	sensor := record.NewReplayDriver(replay, "sensor")
+
+Events are stored with their name, data, time, sequence number and source.
+Data of basic types, errors and types passed to Register keep their Go type
+when read back.
+*/
+package record
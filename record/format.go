@@ -0,0 +1,354 @@
+package record
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/hybridgroup/gobot"
+)
+
+// Format is the encoding used for a recorded event stream.
+type Format int
+
+const (
+	// JSON writes one JSON object per line, preceded by a header line.
+	JSON Format = iota
+	// Binary writes length prefixed records, preceded by a magic header.
+	Binary
+)
+
+// Version is the version of the recording format written by Writer.
+const Version = 1
+
+const jsonFormatName = "gobot-events"
+
+var binaryMagic = []byte("GBEV")
+
+// MaxRecordSize is the largest encoded event a Binary recording may hold.
+const MaxRecordSize = 1 << 20
+
+var (
+	// ErrUnknownFormat is the error resulting when a stream is neither a JSON
+	// nor a Binary recording
+	ErrUnknownFormat = errors.New("record: unknown recording format")
+	// ErrUnsupportedVersion is the error resulting when a recording was
+	// written by a newer version of the format
+	ErrUnsupportedVersion = errors.New("record: unsupported recording version")
+	// ErrRecordTooLarge is the error resulting when an event of a Binary
+	// recording is larger than MaxRecordSize
+	ErrRecordTooLarge = errors.New("record: event larger than MaxRecordSize")
+)
+
+const errorType = "error"
+
+var (
+	typesMtx sync.RWMutex
+	types    = make(map[string]reflect.Type)
+)
+
+func init() {
+	for _, v := range []interface{}{
+		false, "", []byte{},
+		int(0), int8(0), int16(0), int32(0), int64(0),
+		uint(0), uint8(0), uint16(0), uint32(0), uint64(0),
+		float32(0), float64(0),
+		map[string]interface{}{}, []interface{}{},
+	} {
+		Register(v)
+	}
+}
+
+// Register records the type of value, so event data of that type is read
+// back as the same Go type instead of generic JSON values. Driver specific
+// types such as sphero.DataStreamingPacket or leap.Frame should be
+// registered before reading a recording which contains them.
+func Register(value interface{}) {
+	t := reflect.TypeOf(value)
+	typesMtx.Lock()
+	defer typesMtx.Unlock()
+	types[t.String()] = t
+}
+
+// entry is the stored form of a gobot.Event.
+type entry struct {
+	Name   string          `json:"name"`
+	Type   string          `json:"type,omitempty"`
+	Data   json.RawMessage `json:"data,omitempty"`
+	Time   time.Time       `json:"time"`
+	Seq    uint64          `json:"seq"`
+	Robot  string          `json:"robot,omitempty"`
+	Device string          `json:"device,omitempty"`
+}
+
+type header struct {
+	Format  string `json:"format"`
+	Version int    `json:"version"`
+}
+
+func newEntry(evt *gobot.Event) (e *entry, err error) {
+	e = &entry{
+		Name:   evt.Name,
+		Time:   evt.Time,
+		Seq:    evt.Seq,
+		Robot:  evt.Robot,
+		Device: evt.Device,
+	}
+
+	switch data := evt.Data.(type) {
+	case nil:
+	case error:
+		e.Type = errorType
+		e.Data, err = json.Marshal(data.Error())
+	default:
+		e.Type = reflect.TypeOf(data).String()
+		e.Data, err = json.Marshal(data)
+	}
+	return
+}
+
+func (e *entry) event() (evt *gobot.Event, err error) {
+	evt = gobot.NewEvent(e.Name, nil)
+	evt.Time = e.Time
+	evt.Seq = e.Seq
+	evt.Robot = e.Robot
+	evt.Device = e.Device
+
+	switch e.Type {
+	case "":
+	case errorType:
+		var msg string
+		if err = json.Unmarshal(e.Data, &msg); err == nil {
+			evt.Data = errors.New(msg)
+		}
+	default:
+		typesMtx.RLock()
+		t, ok := types[e.Type]
+		typesMtx.RUnlock()
+
+		if ok {
+			v := reflect.New(t)
+			if err = json.Unmarshal(e.Data, v.Interface()); err == nil {
+				evt.Data = v.Elem().Interface()
+			}
+		} else {
+			err = json.Unmarshal(e.Data, &evt.Data)
+		}
+	}
+	return
+}
+
+// Writer writes events to a recording.
+type Writer struct {
+	w      *bufio.Writer
+	format Format
+	buf    bytes.Buffer
+}
+
+// NewWriter returns a new Writer which writes the header for format to w.
+func NewWriter(w io.Writer, format Format) (*Writer, error) {
+	wr := &Writer{w: bufio.NewWriter(w), format: format}
+
+	switch format {
+	case JSON:
+		h, _ := json.Marshal(header{Format: jsonFormatName, Version: Version})
+		wr.w.Write(h)
+		wr.w.WriteByte('\n')
+	case Binary:
+		wr.w.Write(binaryMagic)
+		wr.writeUvarint(Version)
+	default:
+		return nil, ErrUnknownFormat
+	}
+	return wr, wr.w.Flush()
+}
+
+// Write writes evt to the recording. Call Flush to make sure it has been
+// written to the underlying io.Writer.
+func (w *Writer) Write(evt *gobot.Event) error {
+	e, err := newEntry(evt)
+	if err != nil {
+		return fmt.Errorf("record: event %q: %v", evt.Name, err)
+	}
+
+	if w.format == JSON {
+		line, err := json.Marshal(e)
+		if err != nil {
+			return err
+		}
+		w.w.Write(line)
+		return w.w.WriteByte('\n')
+	}
+
+	w.buf.Reset()
+	for _, s := range []string{e.Name, e.Type, e.Robot, e.Device} {
+		w.putString(s)
+	}
+	w.putUvarint(e.Seq)
+	w.putVarint(unixNano(e.Time))
+	w.putString(string(e.Data))
+	if w.buf.Len() > MaxRecordSize {
+		return fmt.Errorf("record: event %q: %w", evt.Name, ErrRecordTooLarge)
+	}
+
+	w.writeUvarint(uint64(w.buf.Len()))
+	_, err = w.w.Write(w.buf.Bytes())
+	return err
+}
+
+// Flush writes any buffered events to the underlying io.Writer.
+func (w *Writer) Flush() error {
+	return w.w.Flush()
+}
+
+func (w *Writer) writeUvarint(v uint64) {
+	b := make([]byte, binary.MaxVarintLen64)
+	w.w.Write(b[:binary.PutUvarint(b, v)])
+}
+
+func (w *Writer) putUvarint(v uint64) {
+	b := make([]byte, binary.MaxVarintLen64)
+	w.buf.Write(b[:binary.PutUvarint(b, v)])
+}
+
+func (w *Writer) putVarint(v int64) {
+	b := make([]byte, binary.MaxVarintLen64)
+	w.buf.Write(b[:binary.PutVarint(b, v)])
+}
+
+func (w *Writer) putString(s string) {
+	w.putUvarint(uint64(len(s)))
+	w.buf.WriteString(s)
+}
+
+// Reader reads events from a recording written by Writer.
+type Reader struct {
+	r       *bufio.Reader
+	format  Format
+	version int
+}
+
+// NewReader returns a new Reader for r. The format of the recording is
+// detected from its header.
+func NewReader(r io.Reader) (*Reader, error) {
+	rd := &Reader{r: bufio.NewReader(r)}
+
+	b, err := rd.r.Peek(1)
+	if err != nil {
+		return nil, err
+	}
+
+	if b[0] == '{' {
+		line, err := rd.r.ReadBytes('\n')
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+		var h header
+		if json.Unmarshal(line, &h) != nil || h.Format != jsonFormatName {
+			return nil, ErrUnknownFormat
+		}
+		rd.format, rd.version = JSON, h.Version
+	} else {
+		magic := make([]byte, len(binaryMagic))
+		if _, err := io.ReadFull(rd.r, magic); err != nil || !bytes.Equal(magic, binaryMagic) {
+			return nil, ErrUnknownFormat
+		}
+		v, err := binary.ReadUvarint(rd.r)
+		if err != nil {
+			return nil, ErrUnknownFormat
+		}
+		rd.format, rd.version = Binary, int(v)
+	}
+
+	if rd.version < 1 || rd.version > Version {
+		return nil, ErrUnsupportedVersion
+	}
+	return rd, nil
+}
+
+// Format returns the format of the recording.
+func (r *Reader) Format() Format { return r.format }
+
+// Version returns the format version of the recording.
+func (r *Reader) Version() int { return r.version }
+
+// Read returns the next event of the recording, or io.EOF once there are
+// no more events.
+func (r *Reader) Read() (*gobot.Event, error) {
+	e := &entry{}
+
+	if r.format == JSON {
+		line, err := r.r.ReadBytes('\n')
+		if len(bytes.TrimSpace(line)) == 0 {
+			if err == nil {
+				return r.Read()
+			}
+			return nil, err
+		}
+		if err := json.Unmarshal(line, e); err != nil {
+			return nil, err
+		}
+		return e.event()
+	}
+
+	n, err := binary.ReadUvarint(r.r)
+	if err != nil {
+		return nil, err
+	}
+	if n > MaxRecordSize {
+		return nil, ErrRecordTooLarge
+	}
+	b := make([]byte, n)
+	if _, err := io.ReadFull(r.r, b); err != nil {
+		return nil, io.ErrUnexpectedEOF
+	}
+
+	buf := bytes.NewBuffer(b)
+	for _, s := range []*string{&e.Name, &e.Type, &e.Robot, &e.Device} {
+		if *s, err = getString(buf); err != nil {
+			return nil, err
+		}
+	}
+	if e.Seq, err = binary.ReadUvarint(buf); err != nil {
+		return nil, io.ErrUnexpectedEOF
+	}
+	nsec, err := binary.ReadVarint(buf)
+	if err != nil {
+		return nil, io.ErrUnexpectedEOF
+	}
+	if nsec != 0 {
+		e.Time = time.Unix(0, nsec)
+	}
+	data, err := getString(buf)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > 0 {
+		e.Data = json.RawMessage(data)
+	}
+	return e.event()
+}
+
+// unixNano returns t in nanoseconds since the Unix epoch, or 0 for the zero
+// time.
+func unixNano(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.UnixNano()
+}
+
+func getString(buf *bytes.Buffer) (string, error) {
+	n, err := binary.ReadUvarint(buf)
+	if err != nil || uint64(buf.Len()) < n {
+		return "", io.ErrUnexpectedEOF
+	}
+	return string(buf.Next(int(n))), nil
+}
@@ -0,0 +1,144 @@
+package record
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/hybridgroup/gobot"
+	"github.com/hybridgroup/gobot/gobottest"
+)
+
+type testPacket struct {
+	X int16
+	Y int16
+}
+
+func init() {
+	Register(testPacket{})
+}
+
+func testEvents() []*gobot.Event {
+	now := time.Now()
+	events := []*gobot.Event{
+		gobot.NewEvent("data", 512),
+		gobot.NewEvent("error", errors.New("read failed")),
+		gobot.NewEvent("push", nil),
+		gobot.NewEvent("packet", testPacket{X: 1, Y: -2}),
+		gobot.NewEvent("raw", []byte{1, 2, 3}),
+	}
+	for i, evt := range events {
+		evt.Seq = uint64(i + 1)
+		evt.Time = now.Add(time.Duration(i) * time.Millisecond)
+		evt.Robot = "bot"
+		evt.Device = "sensor"
+	}
+	return events
+}
+
+func testRoundTrip(t *testing.T, format Format) {
+	buf := &bytes.Buffer{}
+	w, err := NewWriter(buf, format)
+	gobottest.Assert(t, err, nil)
+
+	events := testEvents()
+	for _, evt := range events {
+		gobottest.Assert(t, w.Write(evt), nil)
+	}
+	gobottest.Assert(t, w.Flush(), nil)
+
+	r, err := NewReader(buf)
+	gobottest.Assert(t, err, nil)
+	gobottest.Assert(t, r.Format(), format)
+	gobottest.Assert(t, r.Version(), Version)
+
+	for _, want := range events {
+		evt, err := r.Read()
+		gobottest.Assert(t, err, nil)
+		gobottest.Assert(t, evt.Name, want.Name)
+		gobottest.Assert(t, evt.Data, want.Data)
+		gobottest.Assert(t, evt.Seq, want.Seq)
+		gobottest.Assert(t, evt.Time.Equal(want.Time), true)
+		gobottest.Assert(t, evt.Robot, want.Robot)
+		gobottest.Assert(t, evt.Device, want.Device)
+	}
+
+	_, err = r.Read()
+	gobottest.Assert(t, err, io.EOF)
+}
+
+func TestJSONRoundTrip(t *testing.T) {
+	testRoundTrip(t, JSON)
+}
+
+func TestBinaryRoundTrip(t *testing.T) {
+	testRoundTrip(t, Binary)
+}
+
+func TestBinaryIsCompact(t *testing.T) {
+	jsonBuf, binaryBuf := &bytes.Buffer{}, &bytes.Buffer{}
+	jw, _ := NewWriter(jsonBuf, JSON)
+	bw, _ := NewWriter(binaryBuf, Binary)
+	for _, evt := range testEvents() {
+		jw.Write(evt)
+		bw.Write(evt)
+	}
+	jw.Flush()
+	bw.Flush()
+
+	gobottest.Assert(t, binaryBuf.Len() < jsonBuf.Len(), true)
+}
+
+func TestReadUnregisteredType(t *testing.T) {
+	buf := bytes.NewBufferString(`{"format":"gobot-events","version":1}
+{"name":"frame","type":"leap.Frame","data":{"id":3},"time":"2016-01-02T15:04:05Z","seq":1}
+`)
+	r, _ := NewReader(buf)
+	evt, err := r.Read()
+	gobottest.Assert(t, err, nil)
+	gobottest.Assert(t, evt.Data, map[string]interface{}{"id": 3.0})
+}
+
+func TestNewReaderErrors(t *testing.T) {
+	_, err := NewReader(bytes.NewBufferString("not a recording"))
+	gobottest.Assert(t, err, ErrUnknownFormat)
+
+	_, err = NewReader(bytes.NewBufferString(`{"format":"something-else","version":1}`))
+	gobottest.Assert(t, err, ErrUnknownFormat)
+
+	_, err = NewReader(bytes.NewBufferString(`{"format":"gobot-events","version":2}`))
+	gobottest.Assert(t, err, ErrUnsupportedVersion)
+
+	_, err = NewReader(&bytes.Buffer{})
+	gobottest.Assert(t, err, io.EOF)
+
+	_, err = NewWriter(&bytes.Buffer{}, Format(42))
+	gobottest.Assert(t, err, ErrUnknownFormat)
+}
+
+func TestReadTruncatedBinary(t *testing.T) {
+	buf := &bytes.Buffer{}
+	w, _ := NewWriter(buf, Binary)
+	w.Write(testEvents()[0])
+	w.Flush()
+
+	r, _ := NewReader(bytes.NewBuffer(buf.Bytes()[:buf.Len()-2]))
+	_, err := r.Read()
+	gobottest.Assert(t, err, io.ErrUnexpectedEOF)
+}
+
+func TestBinaryRecordTooLarge(t *testing.T) {
+	buf := &bytes.Buffer{}
+	w, _ := NewWriter(buf, Binary)
+	err := w.Write(&gobot.Event{Name: "blob", Data: make([]byte, MaxRecordSize)})
+	gobottest.Assert(t, errors.Is(err, ErrRecordTooLarge), true)
+	w.Flush()
+
+	// a corrupt length is refused before it is allocated
+	buf.Write([]byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x7f})
+	r, _ := NewReader(buf)
+	_, err = r.Read()
+	gobottest.Assert(t, err, ErrRecordTooLarge)
+}
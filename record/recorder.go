@@ -0,0 +1,116 @@
+package record
+
+import (
+	"io"
+	"sync"
+
+	"github.com/hybridgroup/gobot"
+)
+
+// RecorderBufferSize is the number of events buffered for each Eventer a
+// Recorder subscribes to, unless other SubscriberOptions are given.
+const RecorderBufferSize = 1024
+
+// Recorder writes the events published by one or more Eventers to a
+// recording.
+type Recorder struct {
+	mtx    sync.Mutex
+	w      *Writer
+	err    error
+	wg     sync.WaitGroup
+	halt   chan bool
+	closed bool
+	stops  []func()
+}
+
+// NewRecorder returns a new Recorder which writes a recording in the given
+// format to w.
+func NewRecorder(w io.Writer, format Format) (*Recorder, error) {
+	wr, err := NewWriter(w, format)
+	if err != nil {
+		return nil, err
+	}
+	return &Recorder{w: wr, halt: make(chan bool)}, nil
+}
+
+// Record subscribes to e and records every event it publishes until Close
+// is called. Optionally accepts gobot.SubscriberOptions to control how events
+// are buffered while they wait to be written.
+func (r *Recorder) Record(e gobot.Eventer, opts ...gobot.SubscriberOptions) {
+	if len(opts) == 0 {
+		opts = []gobot.SubscriberOptions{{Buffer: RecorderBufferSize, Policy: gobot.Block}}
+	}
+
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	if r.closed {
+		return
+	}
+
+	events := e.Subscribe(opts...)
+	r.stops = append(r.stops, func() { e.Unsubscribe(events) })
+
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+		for {
+			select {
+			case evt, ok := <-events:
+				if !ok {
+					return
+				}
+				r.write(evt)
+			case <-r.halt:
+				for len(events) > 0 {
+					r.write(<-events)
+				}
+				return
+			}
+		}
+	}()
+}
+
+// Close stops recording, writes any pending events and returns the first
+// error that occurred while recording.
+func (r *Recorder) Close() error {
+	r.mtx.Lock()
+	if r.closed {
+		r.mtx.Unlock()
+		return r.err
+	}
+	r.closed = true
+	for _, stop := range r.stops {
+		stop()
+	}
+	r.mtx.Unlock()
+
+	close(r.halt)
+	r.wg.Wait()
+
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	if err := r.w.Flush(); err != nil && r.err == nil {
+		r.err = err
+	}
+	return r.err
+}
+
+// Err returns the first error that occurred while recording, if any.
+func (r *Recorder) Err() error {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	return r.err
+}
+
+func (r *Recorder) write(evt *gobot.Event) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	if r.err != nil {
+		return
+	}
+	if err := r.w.Write(evt); err != nil {
+		r.err = err
+		return
+	}
+	r.err = r.w.Flush()
+}
@@ -0,0 +1,53 @@
+package record
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/hybridgroup/gobot"
+	"github.com/hybridgroup/gobot/gobottest"
+)
+
+type failingWriter struct{}
+
+func (failingWriter) Write(p []byte) (int, error) {
+	return 0, errors.New("disk full")
+}
+
+func TestRecorder(t *testing.T) {
+	buf := &bytes.Buffer{}
+	rec, err := NewRecorder(buf, JSON)
+	gobottest.Assert(t, err, nil)
+
+	sensor, button := gobot.NewEventer(), gobot.NewEventer()
+	sensor.SetEventSource("bot", "sensor")
+	button.SetEventSource("bot", "button")
+	rec.Record(sensor)
+	rec.Record(button)
+
+	sensor.Publish("data", 1)
+	sensor.Publish("data", 2)
+	button.Publish("push", 1)
+
+	gobottest.Assert(t, rec.Close(), nil)
+	gobottest.Assert(t, rec.Close(), nil)
+
+	sensor.Publish("data", 3)
+
+	r, _ := NewReader(buf)
+	counts := map[string]int{}
+	for {
+		evt, err := r.Read()
+		if err != nil {
+			break
+		}
+		counts[evt.Device]++
+	}
+	gobottest.Assert(t, counts, map[string]int{"sensor": 2, "button": 1})
+}
+
+func TestRecorderWriteError(t *testing.T) {
+	_, err := NewRecorder(failingWriter{}, Binary)
+	gobottest.Assert(t, err.Error(), "disk full")
+}
@@ -0,0 +1,182 @@
+package record
+
+import (
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/hybridgroup/gobot"
+)
+
+var _ gobot.Adaptor = (*ReplayAdaptor)(nil)
+
+const (
+	// End event
+	End = "end"
+	// Error event
+	Error = "error"
+)
+
+var openRecording = func(path string) (io.ReadCloser, error) {
+	return os.Open(path)
+}
+
+// ReplayAdaptor plays back a recording through the ReplayDrivers that use it
+// as their connection.
+type ReplayAdaptor struct {
+	name string
+	path string
+	// Speed scales the recorded timing of events. 1 replays them with their
+	// original timing, 2 twice as fast, and 0 as fast as possible.
+	Speed float64
+
+	mtx     sync.Mutex
+	file    io.ReadCloser
+	reader  *Reader
+	drivers map[string]*ReplayDriver
+	started int
+	halt    chan bool
+	done    chan bool
+	gobot.Eventer
+}
+
+// NewReplayAdaptor returns a new ReplayAdaptor given a name and the path to
+// a recording. Events are replayed with their original timing.
+//
+// Emits the Events:
+//	End - Once every event of the recording has been replayed
+//	Error error - On error reading the recording
+func NewReplayAdaptor(name string, path string) *ReplayAdaptor {
+	a := &ReplayAdaptor{
+		name:    name,
+		path:    path,
+		Speed:   1,
+		drivers: make(map[string]*ReplayDriver),
+		Eventer: gobot.NewEventer(),
+	}
+
+	a.AddEvent(End)
+	a.AddEvent(Error)
+
+	return a
+}
+
+// Name returns the ReplayAdaptors name
+func (a *ReplayAdaptor) Name() string { return a.name }
+
+// Port returns the path of the recording
+func (a *ReplayAdaptor) Port() string { return a.path }
+
+// Connect opens the recording.
+func (a *ReplayAdaptor) Connect() (errs []error) {
+	file, err := openRecording(a.path)
+	if err != nil {
+		return []error{err}
+	}
+	reader, err := NewReader(file)
+	if err != nil {
+		file.Close()
+		return []error{err}
+	}
+
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+	a.file, a.reader, a.started = file, reader, 0
+	return
+}
+
+// Finalize stops replaying and closes the recording.
+func (a *ReplayAdaptor) Finalize() (errs []error) {
+	a.stop()
+
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+	if a.file != nil {
+		if err := a.file.Close(); err != nil {
+			errs = append(errs, err)
+		}
+		a.file, a.reader = nil, nil
+	}
+	return
+}
+
+// addDriver routes recorded events of device to d.
+func (a *ReplayAdaptor) addDriver(device string, d *ReplayDriver) {
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+	a.drivers[device] = d
+}
+
+// driverStarted begins the replay once every ReplayDriver has started, so
+// no recorded event is missed by a driver which is not yet running.
+func (a *ReplayAdaptor) driverStarted() {
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+
+	a.started++
+	if a.started != len(a.drivers) || a.reader == nil || a.halt != nil {
+		return
+	}
+
+	a.halt = make(chan bool)
+	a.done = make(chan bool)
+	go a.play(a.reader, a.halt, a.done)
+}
+
+// stop halts a running replay and waits for it to finish. The replay resumes
+// where it stopped once every ReplayDriver has been started again.
+func (a *ReplayAdaptor) stop() {
+	a.mtx.Lock()
+	halt, done := a.halt, a.done
+	a.halt, a.done, a.started = nil, nil, 0
+	a.mtx.Unlock()
+
+	if halt != nil {
+		close(halt)
+		<-done
+	}
+}
+
+func (a *ReplayAdaptor) play(reader *Reader, halt chan bool, done chan bool) {
+	defer close(done)
+
+	var first time.Time
+	begin := time.Now()
+
+	for {
+		evt, err := reader.Read()
+		if err == io.EOF {
+			a.Publish(End, nil)
+			return
+		} else if err != nil {
+			a.Publish(Error, err)
+			return
+		}
+
+		if first.IsZero() {
+			first = evt.Time
+		}
+		if a.Speed > 0 && !evt.Time.IsZero() {
+			offset := time.Duration(float64(evt.Time.Sub(first)) / a.Speed)
+			select {
+			case <-time.After(begin.Add(offset).Sub(time.Now())):
+			case <-halt:
+				return
+			}
+		} else {
+			select {
+			case <-halt:
+				return
+			default:
+			}
+		}
+
+		a.mtx.Lock()
+		d, ok := a.drivers[evt.Device]
+		a.mtx.Unlock()
+		if ok {
+			d.replay(evt)
+		}
+	}
+}
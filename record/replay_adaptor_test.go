@@ -0,0 +1,126 @@
+package record
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/hybridgroup/gobot"
+	"github.com/hybridgroup/gobot/gobottest"
+)
+
+func initTestReplayAdaptor(format Format, events ...*gobot.Event) *ReplayAdaptor {
+	buf := &bytes.Buffer{}
+	w, _ := NewWriter(buf, format)
+	for _, evt := range events {
+		w.Write(evt)
+	}
+	w.Flush()
+
+	openRecording = func(path string) (io.ReadCloser, error) {
+		return ioutil.NopCloser(bytes.NewReader(buf.Bytes())), nil
+	}
+	return NewReplayAdaptor("replay", "session.jsonl")
+}
+
+func TestReplayAdaptor(t *testing.T) {
+	a := NewReplayAdaptor("replay", "session.jsonl")
+	gobottest.Assert(t, a.Name(), "replay")
+	gobottest.Assert(t, a.Port(), "session.jsonl")
+	gobottest.Assert(t, a.Speed, 1.0)
+}
+
+func TestReplayAdaptorConnectError(t *testing.T) {
+	openRecording = func(path string) (io.ReadCloser, error) {
+		return nil, errors.New("no such file")
+	}
+	a := NewReplayAdaptor("replay", "missing.jsonl")
+	gobottest.Assert(t, len(a.Connect()), 1)
+
+	openRecording = func(path string) (io.ReadCloser, error) {
+		return ioutil.NopCloser(bytes.NewBufferString("garbage")), nil
+	}
+	gobottest.Assert(t, a.Connect()[0], ErrUnknownFormat)
+}
+
+func TestReplayAdaptorReplay(t *testing.T) {
+	begin := time.Now()
+	events := testEvents()
+	events[0].Device = "button"
+	for i, evt := range events {
+		evt.Time = begin.Add(time.Duration(i*20) * time.Millisecond)
+	}
+
+	a := initTestReplayAdaptor(Binary, events...)
+	a.Speed = 2
+	button := NewReplayDriver(a, "button")
+	sensor := NewReplayDriver(a, "my-sensor", "sensor")
+
+	buttonEvents := button.Subscribe()
+	sensorEvents := sensor.Subscribe()
+	end := make(chan bool)
+	a.Once(End, func(data interface{}) {
+		end <- true
+	})
+
+	gobottest.Assert(t, len(a.Connect()), 0)
+	gobottest.Assert(t, len(button.Start()), 0)
+	gobottest.Assert(t, len(sensor.Start()), 0)
+
+	start := time.Now()
+	select {
+	case <-end:
+	case <-time.After(time.Second):
+		t.Fatalf("Replay did not end")
+	}
+
+	// 4 gaps of 20ms at twice the speed
+	if time.Since(start) < 40*time.Millisecond {
+		t.Errorf("Replay did not keep the recorded timing")
+	}
+
+	gobottest.Assert(t, len(buttonEvents), 1)
+	gobottest.Assert(t, len(sensorEvents), 4)
+	gobottest.Assert(t, (<-buttonEvents).Data, 512)
+	gobottest.Assert(t, sensor.Event("packet"), "packet")
+
+	gobottest.Assert(t, len(a.Finalize()), 0)
+}
+
+func TestReplayAdaptorHalt(t *testing.T) {
+	begin := time.Now()
+	events := testEvents()
+	for i, evt := range events {
+		evt.Time = begin.Add(time.Duration(i) * time.Hour)
+	}
+
+	a := initTestReplayAdaptor(JSON, events...)
+	d := NewReplayDriver(a, "sensor")
+	sensorEvents := d.Subscribe()
+
+	a.Connect()
+	d.Start()
+
+	select {
+	case <-sensorEvents:
+	case <-time.After(100 * time.Millisecond):
+		t.Errorf("First event was not replayed")
+	}
+
+	done := make(chan bool)
+	go func() {
+		d.Halt()
+		a.Finalize()
+		done <- true
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(100 * time.Millisecond):
+		t.Errorf("Replay did not halt")
+	}
+	gobottest.Assert(t, len(sensorEvents), 0)
+}
@@ -0,0 +1,64 @@
+package record
+
+import "github.com/hybridgroup/gobot"
+
+var _ gobot.Driver = (*ReplayDriver)(nil)
+
+// ReplayDriver is a fake device which republishes the recorded events of a
+// device, as played back by its ReplayAdaptor.
+type ReplayDriver struct {
+	name       string
+	device     string
+	connection *ReplayAdaptor
+	gobot.Eventer
+}
+
+// NewReplayDriver returns a new ReplayDriver given a ReplayAdaptor and name.
+// It republishes the recorded events of the device with the same name.
+//
+// Optionally accepts:
+//	string: Name of the recorded device to replay, if it differs from name
+func NewReplayDriver(a *ReplayAdaptor, name string, v ...string) *ReplayDriver {
+	d := &ReplayDriver{
+		name:       name,
+		device:     name,
+		connection: a,
+		Eventer:    gobot.NewEventer(),
+	}
+
+	if len(v) > 0 {
+		d.device = v[0]
+	}
+
+	a.addDriver(d.device, d)
+
+	return d
+}
+
+// Start starts the ReplayDriver. The replay begins once every ReplayDriver
+// of the ReplayAdaptor has been started.
+func (d *ReplayDriver) Start() (errs []error) {
+	d.connection.driverStarted()
+	return
+}
+
+// Halt stops the replay
+func (d *ReplayDriver) Halt() (errs []error) {
+	d.connection.stop()
+	return
+}
+
+// Name returns the ReplayDrivers name
+func (d *ReplayDriver) Name() string { return d.name }
+
+// Connection returns the ReplayDrivers Connection
+func (d *ReplayDriver) Connection() gobot.Connection { return d.connection }
+
+// replay publishes the recorded evt, registering its name first so it can
+// be found through Event.
+func (d *ReplayDriver) replay(evt *gobot.Event) {
+	if d.Event(evt.Name) == "" {
+		d.AddEvent(evt.Name)
+	}
+	d.Publish(evt.Name, evt.Data)
+}
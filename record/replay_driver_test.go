@@ -0,0 +1,29 @@
+package record
+
+import (
+	"testing"
+
+	"github.com/hybridgroup/gobot"
+	"github.com/hybridgroup/gobot/gobottest"
+)
+
+func TestReplayDriver(t *testing.T) {
+	a := NewReplayAdaptor("replay", "session.jsonl")
+	d := NewReplayDriver(a, "sensor")
+	gobottest.Assert(t, d.Name(), "sensor")
+	gobottest.Assert(t, d.Connection().Name(), "replay")
+	gobottest.Assert(t, a.drivers["sensor"], d)
+
+	d = NewReplayDriver(a, "replayed", "recorded")
+	gobottest.Assert(t, a.drivers["recorded"], d)
+}
+
+func TestReplayDriverReplay(t *testing.T) {
+	d := NewReplayDriver(NewReplayAdaptor("replay", "session.jsonl"), "sensor")
+	events := d.Subscribe()
+
+	d.replay(gobot.NewEvent("data", 42))
+
+	gobottest.Assert(t, d.Event("data"), "data")
+	gobottest.Assert(t, (<-events).Data, 42)
+}
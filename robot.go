@@ -1,8 +1,10 @@
 package gobot
 
 import (
+	"context"
 	"fmt"
-	"log"
+	"sync"
+	"time"
 )
 
 // JSONRobot a JSON representation of a Robot.
@@ -11,6 +13,14 @@ type JSONRobot struct {
 	Commands    []string          `json:"commands"`
 	Connections []*JSONConnection `json:"connections"`
 	Devices     []*JSONDevice     `json:"devices"`
+	// Schemas describes the parameters of commands which declare them
+	Schemas map[string]*CommandSchema `json:"schemas,omitempty"`
+	// Start reports what was started the last time the robot started
+	Start *StartReport `json:"start,omitempty"`
+	// StateMachines are the current states of the robot's state machines
+	StateMachines []*JSONStateMachine `json:"state_machines,omitempty"`
+	// BehaviourTrees are the last statuses of the robot's behaviour trees
+	BehaviourTrees []*JSONBehaviourTree `json:"behaviour_trees,omitempty"`
 }
 
 // NewJSONRobot returns a JSONRobot given a Robot.
@@ -25,23 +35,76 @@ func NewJSONRobot(robot *Robot) *JSONRobot {
 	for command := range robot.Commands() {
 		jsonRobot.Commands = append(jsonRobot.Commands, command)
 	}
+	jsonRobot.Schemas = commandSchemas(robot)
+	jsonRobot.Start = robot.StartReport()
+	for _, m := range robot.stateMachines() {
+		jsonRobot.StateMachines = append(jsonRobot.StateMachines, &JSONStateMachine{Name: m.Name, State: m.State()})
+	}
+	for _, t := range robot.behaviourTrees() {
+		jsonRobot.BehaviourTrees = append(jsonRobot.BehaviourTrees, &JSONBehaviourTree{Name: t.Name, Status: t.Status()})
+	}
 
 	robot.Devices().Each(func(device Device) {
 		jsonDevice := NewJSONDevice(device)
-		jsonRobot.Connections = append(jsonRobot.Connections, NewJSONConnection(robot.Connection(jsonDevice.Connection)))
+		jsonConnection := NewJSONConnection(robot.Connection(jsonDevice.Connection))
+		jsonConnection.State = robot.ConnectionState(jsonConnection.Name)
+		jsonRobot.Connections = append(jsonRobot.Connections, jsonConnection)
 		jsonRobot.Devices = append(jsonRobot.Devices, jsonDevice)
 	})
 	return jsonRobot
 }
 
+// Default timeouts of the robots returned by NewRobot, so a device or
+// connection which hangs while stopping does not stop the Robot stopping.
+const (
+	DefaultHaltTimeout     = 5 * time.Second
+	DefaultFinalizeTimeout = 5 * time.Second
+)
+
+// Timeouts bounds how long a single connection or device may take in each
+// phase of a Robot's lifecycle. A zero value means no limit for that phase;
+// NewRobot sets Halt and Finalize to DefaultHaltTimeout and
+// DefaultFinalizeTimeout.
+type Timeouts struct {
+	// Connect bounds each Connection's Connect
+	Connect time.Duration
+	// Start bounds each Device's Start
+	Start time.Duration
+	// Halt bounds each Device's Halt
+	Halt time.Duration
+	// Finalize bounds each Connection's Finalize
+	Finalize time.Duration
+}
+
 // Robot is a named entity that manages a collection of connections and devices.
 // It contains its own work routine and a collection of
 // custom commands to control a robot remotely via the Gobot api.
+// When Supervision is set, connections which fail while the Robot is running
+// are reconnected. FailurePolicy decides what happens when a connection or
+// device fails to start.
 type Robot struct {
-	Name        string
-	Work        func()
-	connections *Connections
-	devices     *Devices
+	Name          string
+	Work          func()
+	Timeouts      Timeouts
+	Supervision   *Supervision
+	FailurePolicy FailurePolicy
+	connections   *Connections
+	supervisor    *supervisor
+	deps          map[string][]string
+	report        *StartReport
+	running       bool
+	scheduler     *Scheduler
+	machines      []*StateMachine
+	trees         []*BehaviourTree
+	macros        map[string]Macro
+	macroRun      chan struct{}
+	macroCancel   context.CancelFunc
+	logger        Logger
+	store         Store
+	gobot         *Gobot
+	mtx           sync.RWMutex
+	lifecycle     sync.Mutex
+	devices       *Devices
 	Commander
 	Eventer
 }
@@ -59,7 +122,7 @@ func (r *Robots) Start() (errs []error) {
 	for _, robot := range *r {
 		if errs = robot.Start(); len(errs) > 0 {
 			for i, err := range errs {
-				errs[i] = fmt.Errorf("Robot %q: %v", robot.Name, err)
+				errs[i] = fmt.Errorf("Robot %q: %w", robot.Name, err)
 			}
 			return
 		}
@@ -69,12 +132,21 @@ func (r *Robots) Start() (errs []error) {
 
 // Stop calls the Stop method of each Robot in the collection
 func (r *Robots) Stop() (errs []error) {
-	for _, robot := range *r {
-		if errs = robot.Stop(); len(errs) > 0 {
-			for i, err := range errs {
-				errs[i] = fmt.Errorf("Robot %q: %v", robot.Name, err)
-			}
-			return
+	return r.StopContext(context.Background())
+}
+
+// StopContext calls the StopContext method of each Robot in the collection,
+// in reverse order, each with its share of the time left before ctx's
+// deadline. Every Robot is stopped even if an earlier one fails, and the
+// errors of all of them are returned.
+func (r *Robots) StopContext(ctx context.Context) (errs []error) {
+	for i := len(*r) - 1; i >= 0; i-- {
+		robot := (*r)[i]
+		rctx, cancel := share(ctx, 1, i+1)
+		rerrs := robot.StopContext(rctx)
+		cancel()
+		for _, err := range rerrs {
+			errs = append(errs, fmt.Errorf("Robot %q: %w", robot.Name, err))
 		}
 	}
 	return
@@ -92,6 +164,7 @@ func (r *Robots) Each(f func(*Robot)) {
 // 	[]Connection: Connections which are automatically started and stopped with the robot
 //	[]Device: Devices which are automatically started and stopped with the robot
 //	func(): The work routine the robot will execute once all devices and connections have been initialized and started
+//
 // A name will be automaically generated if no name is supplied.
 func NewRobot(name string, v ...interface{}) *Robot {
 	if name == "" {
@@ -103,25 +176,40 @@ func NewRobot(name string, v ...interface{}) *Robot {
 		connections: &Connections{},
 		devices:     &Devices{},
 		Work:        nil,
+		Timeouts:    Timeouts{Halt: DefaultHaltTimeout, Finalize: DefaultFinalizeTimeout},
 		Eventer:     NewEventer(),
 		Commander:   NewCommander(),
+		scheduler:   NewScheduler(),
+		macroRun:    make(chan struct{}, 1),
 	}
+	r.Eventer.SetEventSource(r.Name, "")
+	r.Commander.SetCommandSource(r.Name, "")
+	r.AddEvent(ConnectionLost)
+	r.AddEvent(Reconnected)
+	r.AddEvent(ReconnectFailed)
+	r.AddEvent(DeviceAdded)
+	r.AddEvent(DeviceRemoved)
+	r.AddEvent(ConnectionAdded)
+	r.AddEvent(ConnectionRemoved)
+	r.AddEvent(StateChanged)
+	r.AddEvent(BehaviourChanged)
 
-	log.Println("Initializing Robot", r.Name, "...")
+	logger := r.Logger()
+	logger.Info("Initializing Robot")
 
 	for i := range v {
 		switch v[i].(type) {
 		case []Connection:
-			log.Println("Initializing connections...")
+			logger.Info("Initializing connections")
 			for _, connection := range v[i].([]Connection) {
 				c := r.AddConnection(connection)
-				log.Println("Initializing connection", c.Name(), "...")
+				logger.Info("Initializing connection", "connection", c.Name())
 			}
 		case []Device:
-			log.Println("Initializing devices...")
+			logger.Info("Initializing devices")
 			for _, device := range v[i].([]Device) {
 				d := r.AddDevice(device)
-				log.Println("Initializing device", d.Name(), "...")
+				logger.Info("Initializing device", "device", d.Name())
 			}
 		case func():
 			r.Work = v[i].(func())
@@ -133,39 +221,198 @@ func NewRobot(name string, v ...interface{}) *Robot {
 
 // Start a Robot's Connections, Devices, and work.
 func (r *Robot) Start() (errs []error) {
-	log.Println("Starting Robot", r.Name, "...")
-	if cerrs := r.Connections().Start(); len(cerrs) > 0 {
-		errs = append(errs, cerrs...)
+	return r.StartContext(context.Background())
+}
+
+// StartContext starts a Robot's Connections, Devices, and work, giving up
+// when ctx is done or when a connection or device exceeds its Timeouts.
+// Devices are started after the devices they depend on. Failures are
+// handled according to the Robot's FailurePolicy; only Abort returns them,
+// the others log them and carry on. Either way they are recorded in the
+// Robot's StartReport.
+func (r *Robot) StartContext(ctx context.Context) (errs []error) {
+	r.Logger().Info("Starting Robot")
+	report := &StartReport{Robot: r.Name}
+	begin := time.Now()
+	defer func() {
+		report.Duration = time.Since(begin)
+		r.mtx.Lock()
+		r.report = report
+		r.mtx.Unlock()
+	}()
+
+	r.lifecycle.Lock()
+	errs, ok := r.startAll(ctx, report)
+	if !ok {
+		r.lifecycle.Unlock()
 		return
 	}
-	if derrs := r.Devices().Start(); len(derrs) > 0 {
-		errs = append(errs, derrs...)
-		return
+	var s *supervisor
+	if r.Supervision != nil {
+		s = newSupervisor(r, *r.Supervision)
+		s.start()
+	}
+	r.mtx.Lock()
+	r.supervisor = s
+	r.running = true
+	r.mtx.Unlock()
+	r.lifecycle.Unlock()
+	for _, m := range r.stateMachines() {
+		if err := m.Start(); err != nil {
+			r.Logger().Error("Starting state machine failed", "machine", m.Name, "error", err)
+			errs = append(errs, err)
+		}
+	}
+	for _, t := range r.behaviourTrees() {
+		t.start(r)
+	}
+	r.mtx.RLock()
+	g := r.gobot
+	r.mtx.RUnlock()
+	if g != nil {
+		g.Publish(RobotStarted, r.Name)
 	}
 	if r.Work != nil {
-		log.Println("Starting work...")
+		r.Logger().Info("Starting work")
 		r.Work()
+		report.Work = true
 	}
 	return
 }
 
 // Stop stops a Robot's connections and Devices
 func (r *Robot) Stop() (errs []error) {
-	log.Println("Stopping Robot", r.Name, "...")
-	errs = append(errs, r.Devices().Halt()...)
-	errs = append(errs, r.Connections().Finalize()...)
+	return r.StopContext(context.Background())
+}
+
+// StopContext stops a Robot's Devices and Connections, giving up on any which
+// do not halt or finalize before ctx is done or their Timeouts expire.
+// Devices which implement SafeStater are first put in their safe state. Each
+// step runs for every device, in the reverse of their start order, and then
+// for every connection, in the reverse of the order they were added, even
+// when earlier ones fail, skipping those the supervisor has already halted or
+// finalized. Each step is given an equal share of the time left before ctx's
+// deadline, so one device which hangs does not leave the others out of time.
+// Supervision ends, a running macro is cancelled, state machines and
+// behaviour trees stop and the Robot's scheduled jobs are cancelled. Finally
+// every subscription to the events of the Robot, its devices and its
+// connections is cancelled, including those made outside the Robot, such as
+// event streams of the api, and the channels of their subscribers are closed.
+// Drivers which handle their own events must subscribe in Start, and others
+// subscribe again when the Robot restarts.
+func (r *Robot) StopContext(ctx context.Context) (errs []error) {
+	r.Logger().Info("Stopping Robot")
+	r.CancelMacro()
+	for _, m := range r.stateMachines() {
+		m.Stop()
+	}
+	for _, t := range r.behaviourTrees() {
+		t.stop()
+	}
+	r.scheduler.Stop()
+	r.lifecycle.Lock()
+	defer r.lifecycle.Unlock()
+	r.mtx.Lock()
+	s := r.supervisor
+	r.supervisor = nil
+	r.running = false
+	r.mtx.Unlock()
+	if s != nil {
+		s.stop()
+	}
+	devices, _ := r.startOrder()
+	reversed := make(Devices, len(devices))
+	for i, device := range devices {
+		reversed[len(devices)-1-i] = device
+	}
+	connections := *r.Connections()
+	reversedConnections := make(Connections, len(connections))
+	for i, connection := range connections {
+		reversedConnections[len(connections)-1-i] = connection
+	}
+	if s != nil {
+		// devices and connections the supervisor stopped are already down
+		reversed, reversedConnections = s.running(reversed, reversedConnections)
+	}
+	safe := len(reversed.safeStaters())
+	sctx, cancel := share(ctx, safe, safe+len(reversed)+len(reversedConnections))
+	errs = append(errs, reversed.SafeStateContext(sctx, r.Timeouts.Halt)...)
+	cancel()
+	hctx, cancel := share(ctx, len(reversed), len(reversed)+len(reversedConnections))
+	errs = append(errs, reversed.HaltContext(hctx, r.Timeouts.Halt)...)
+	cancel()
+	errs = append(errs, reversedConnections.FinalizeContext(ctx, r.Timeouts.Finalize)...)
+	r.Devices().Each(func(d Device) { closeEventer(d) })
+	r.Connections().Each(func(c Connection) { closeEventer(c) })
+	r.Eventer.Close()
 	return errs
 }
 
-// Devices returns all devices associated with this Robot.
+// closeEventer cancels the subscriptions to v's events, stopping their
+// handler goroutines, if v is an Eventer.
+func closeEventer(v interface{}) {
+	if e, ok := v.(Eventer); ok {
+		e.Close()
+	}
+}
+
+// Scheduler returns the Scheduler whose jobs are cancelled when the Robot
+// stops.
+func (r *Robot) Scheduler() *Scheduler {
+	return r.scheduler
+}
+
+// Every runs f every d until the returned Job is cancelled or the Robot
+// stops. See Scheduler.Every.
+func (r *Robot) Every(d time.Duration, f func(), opts ...JobOptions) *Job {
+	return r.scheduler.Every(d, f, opts...)
+}
+
+// After runs f once after d, unless the returned Job is cancelled or the
+// Robot stops first.
+func (r *Robot) After(d time.Duration, f func()) *Job {
+	return r.scheduler.After(d, f)
+}
+
+// Cron runs f at the times described by the cron expression spec until the
+// returned Job is cancelled or the Robot stops. See ParseCron.
+func (r *Robot) Cron(spec string, f func(), opts ...JobOptions) (*Job, error) {
+	return r.scheduler.Cron(spec, f, opts...)
+}
+
+// stateMachines returns a snapshot of the Robot's state machines.
+func (r *Robot) stateMachines() []*StateMachine {
+	r.mtx.RLock()
+	defer r.mtx.RUnlock()
+	return append([]*StateMachine{}, r.machines...)
+}
+
+// behaviourTrees returns a snapshot of the Robot's behaviour trees.
+func (r *Robot) behaviourTrees() []*BehaviourTree {
+	r.mtx.RLock()
+	defer r.mtx.RUnlock()
+	return append([]*BehaviourTree{}, r.trees...)
+}
+
+// Devices returns a snapshot of the devices associated with this Robot.
 func (r *Robot) Devices() *Devices {
-	return r.devices
+	r.mtx.RLock()
+	defer r.mtx.RUnlock()
+
+	devices := append(Devices{}, *r.devices...)
+	return &devices
 }
 
-// AddDevice adds a new Device to the robots collection of devices. Returns the
-// added device.
+// AddDevice adds a new Device to the robots collection of devices and
+// publishes DeviceAdded. Events published by the device are stamped with the
+// robot and device names, and Loggable devices log with the Robot's Logger.
+// The device is not started, see AddDeviceContext. Returns the added device.
 func (r *Robot) AddDevice(d Device) Device {
-	*r.devices = append(*r.Devices(), d)
+	r.adopt(d)
+	r.mtx.Lock()
+	*r.devices = append(*r.devices, d)
+	r.mtx.Unlock()
+	r.Publish(DeviceAdded, d.Name())
 	return d
 }
 
@@ -174,6 +421,8 @@ func (r *Robot) Device(name string) Device {
 	if r == nil {
 		return nil
 	}
+	r.mtx.RLock()
+	defer r.mtx.RUnlock()
 	for _, device := range *r.devices {
 		if device.Name() == name {
 			return device
@@ -182,15 +431,27 @@ func (r *Robot) Device(name string) Device {
 	return nil
 }
 
-// Connections returns all connections associated with this robot.
+// Connections returns a snapshot of the connections associated with this
+// robot.
 func (r *Robot) Connections() *Connections {
-	return r.connections
+	r.mtx.RLock()
+	defer r.mtx.RUnlock()
+
+	connections := append(Connections{}, *r.connections...)
+	return &connections
 }
 
-// AddConnection adds a new connection to the robots collection of connections.
-// Returns the added connection.
+// AddConnection adds a new connection to the robots collection of
+// connections and publishes ConnectionAdded. Events published by the
+// connection are stamped with the robot and connection names, and Loggable
+// connections log with the Robot's Logger. The connection is not connected,
+// see AddConnectionContext. Returns the added connection.
 func (r *Robot) AddConnection(c Connection) Connection {
-	*r.connections = append(*r.Connections(), c)
+	r.adopt(c)
+	r.mtx.Lock()
+	*r.connections = append(*r.connections, c)
+	r.mtx.Unlock()
+	r.Publish(ConnectionAdded, c.Name())
 	return c
 }
 
@@ -200,6 +461,8 @@ func (r *Robot) Connection(name string) Connection {
 	if r == nil {
 		return nil
 	}
+	r.mtx.RLock()
+	defer r.mtx.RUnlock()
 	for _, connection := range *r.connections {
 		if connection.Name() == name {
 			return connection
@@ -207,3 +470,136 @@ func (r *Robot) Connection(name string) Connection {
 	}
 	return nil
 }
+
+// ConnectionState returns the state of a connection given a name. The state
+// is only tracked while the Robot is running with Supervision, otherwise it
+// is empty.
+func (r *Robot) ConnectionState(name string) ConnectionState {
+	r.mtx.RLock()
+	s := r.supervisor
+	r.mtx.RUnlock()
+
+	if s == nil {
+		return ""
+	}
+	if connection := r.Connection(name); connection != nil {
+		return s.state(connection)
+	}
+	return ""
+}
+
+// Logger returns the Logger of the Robot, which adds the robot's name to
+// every message. Unless SetLogger was called, it is derived from the Logger
+// of the Gobot the Robot was added to, or the DefaultLogger.
+func (r *Robot) Logger() Logger {
+	r.mtx.RLock()
+	logger, g := r.logger, r.gobot
+	r.mtx.RUnlock()
+
+	if logger == nil {
+		if g != nil {
+			logger = g.Logger()
+		} else {
+			logger = DefaultLogger()
+		}
+	}
+	return logger.With("robot", r.Name)
+}
+
+// SetLogger sets the Logger of the Robot and of its Loggable devices and
+// connections.
+func (r *Robot) SetLogger(l Logger) {
+	r.mtx.Lock()
+	r.logger = l
+	r.mtx.Unlock()
+	r.adoptAll()
+}
+
+// Store returns the Store the Robot keeps its state in. Its keys are scoped
+// to the Robot, and include those of the Robot's Storable devices and
+// connections, prefixed with their names and a "/". Unless SetStore was
+// called, it is scoped within the Store of the Robot's Gobot.
+func (r *Robot) Store() Store {
+	r.mtx.RLock()
+	store, g := r.store, r.gobot
+	r.mtx.RUnlock()
+
+	if store == nil {
+		if g != nil {
+			store = g.Store()
+		} else {
+			store = DefaultStore()
+		}
+	}
+	return ScopeStore(store, r.Name+"/")
+}
+
+// SetStore sets the Store of the Robot and of its Storable devices and
+// connections.
+func (r *Robot) SetStore(s Store) {
+	r.mtx.Lock()
+	r.store = s
+	r.mtx.Unlock()
+	r.adoptAll()
+}
+
+// Registry returns the Registry the metrics of the Robot are recorded in,
+// which is the Registry of the Gobot the Robot was added to, or the
+// DefaultRegistry.
+func (r *Robot) Registry() *Registry {
+	r.mtx.RLock()
+	g := r.gobot
+	r.mtx.RUnlock()
+
+	if g != nil {
+		return g.Registry()
+	}
+	return DefaultRegistry()
+}
+
+// adoptAll gives the Robot, and every device and connection of it, its
+// current Logger, Store and Registry.
+func (r *Robot) adoptAll() {
+	registry := r.Registry()
+	r.Eventer.SetEventRegistry(registry)
+	r.Commander.SetCommandRegistry(registry)
+	r.Connections().Each(func(c Connection) { r.adopt(c) })
+	r.Devices().Each(func(d Device) { r.adopt(d) })
+}
+
+// adopt sets the event source, Logger, Store and Registry of a device or
+// connection of r.
+func (r *Robot) adopt(component interface{}) {
+	var name string
+	var fields []interface{}
+	switch c := component.(type) {
+	case Device:
+		name = c.Name()
+		fields = []interface{}{"device", name}
+		if pinner, ok := c.(Pinner); ok {
+			fields = append(fields, "pin", pinner.Pin())
+		}
+	case Connection:
+		name = c.Name()
+		fields = []interface{}{"connection", name}
+		if porter, ok := c.(Porter); ok {
+			fields = append(fields, "port", porter.Port())
+		}
+	}
+
+	registry := r.Registry()
+	if e, ok := component.(Eventer); ok {
+		e.SetEventSource(r.Name, name)
+		e.SetEventRegistry(registry)
+	}
+	if c, ok := component.(Commander); ok {
+		c.SetCommandSource(r.Name, name)
+		c.SetCommandRegistry(registry)
+	}
+	if l, ok := component.(Loggable); ok {
+		l.SetLogger(r.Logger().With(fields...))
+	}
+	if s, ok := component.(Storable); ok {
+		s.SetStore(ScopeStore(r.Store(), name+"/"))
+	}
+}
@@ -0,0 +1,558 @@
+package gobot
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// RuleTriggered is published by a Gobot each time one of its rules runs its
+// command. Its data is a RuleResult.
+const RuleTriggered = "rule-triggered"
+
+// Rule runs a command when the events of a device meet a condition. Rules
+// are added to a Gobot, and may link devices of different robots.
+type Rule struct {
+	Name string    `json:"name" yaml:"name"`
+	When RuleEvent `json:"when" yaml:"when"`
+	Then RuleThen  `json:"then" yaml:"then"`
+	// Disabled rules ignore events until enabled
+	Disabled bool `json:"disabled,omitempty" yaml:"disabled,omitempty"`
+}
+
+// RuleEvent describes the events a Rule watches and the condition they must
+// meet.
+//
+// Without Op or Match every event triggers the rule, and Debounce ignores
+// events for a while after it is triggered. With Op or Match the rule is
+// triggered when the condition becomes true, and is not triggered again until
+// the condition has become false, or with Hysteresis until the value has
+// moved Hysteresis back past Value. Debounce then requires the condition to
+// hold for the duration before the rule is triggered.
+type RuleEvent struct {
+	Robot string `json:"robot" yaml:"robot"`
+	// Device publishes the events, or the Robot itself when empty
+	Device string `json:"device,omitempty" yaml:"device,omitempty"`
+	// Event is an event name or pattern, as accepted by Eventer.On
+	Event string `json:"event" yaml:"event"`
+	// Op compares numeric event data with Value: one of >, >=, <, <=, == and !=
+	Op         string   `json:"op,omitempty" yaml:"op,omitempty"`
+	Value      float64  `json:"value,omitempty" yaml:"value,omitempty"`
+	Hysteresis float64  `json:"hysteresis,omitempty" yaml:"hysteresis,omitempty"`
+	Debounce   Duration `json:"debounce,omitempty" yaml:"debounce,omitempty"`
+	// Window, when set, is the time of day the rule may be triggered in
+	Window *TimeWindow `json:"window,omitempty" yaml:"window,omitempty"`
+	// Match, when set, is called with the event data as a further condition
+	Match func(data interface{}) bool `json:"-" yaml:"-"`
+}
+
+// RuleThen describes the command a Rule runs.
+type RuleThen struct {
+	Robot string `json:"robot" yaml:"robot"`
+	// Device has the command, or the Robot itself when empty
+	Device  string                 `json:"device,omitempty" yaml:"device,omitempty"`
+	Command string                 `json:"command" yaml:"command"`
+	Params  map[string]interface{} `json:"params,omitempty" yaml:"params,omitempty"`
+}
+
+// TimeWindow is a daily period of local time, such as from "22:00" to
+// "06:00". A window which ends before it starts spans midnight.
+type TimeWindow struct {
+	From string `json:"from" yaml:"from"`
+	To   string `json:"to" yaml:"to"`
+}
+
+// Contains returns true if the time of day of t is within the window.
+func (w *TimeWindow) Contains(t time.Time) bool {
+	from, err := parseClock(w.From)
+	if err != nil {
+		return false
+	}
+	to, err := parseClock(w.To)
+	if err != nil {
+		return false
+	}
+
+	now := t.Hour()*60 + t.Minute()
+	if from <= to {
+		return now >= from && now < to
+	}
+	return now >= from || now < to
+}
+
+// parseClock returns the minutes since midnight of a "15:04" time.
+func parseClock(s string) (int, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, err
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// RuleResult describes a run of a Rule's command.
+type RuleResult struct {
+	Rule   string      `json:"rule"`
+	Result interface{} `json:"result"`
+	Error  string      `json:"error,omitempty"`
+}
+
+// RuleStatus describes a Rule and how it has fared.
+type RuleStatus struct {
+	Rule
+	Enabled   bool      `json:"enabled"`
+	Triggered int       `json:"triggered"`
+	LastRun   time.Time `json:"last_run,omitempty"`
+	LastError string    `json:"last_error,omitempty"`
+}
+
+var ruleOps = map[string]func(v, value float64) bool{
+	">":  func(v, value float64) bool { return v > value },
+	">=": func(v, value float64) bool { return v >= value },
+	"<":  func(v, value float64) bool { return v < value },
+	"<=": func(v, value float64) bool { return v <= value },
+	"==": func(v, value float64) bool { return v == value },
+	"!=": func(v, value float64) bool { return v != value },
+}
+
+// activeRule is a Rule added to a Gobot, with the state of its condition.
+type activeRule struct {
+	mtx     sync.Mutex
+	status  RuleStatus
+	gobot   *Gobot
+	sub     *Subscription
+	source  Eventer
+	armed   bool
+	pending *Job
+	fired   time.Time
+}
+
+// Validate returns an error describing the first problem with the rule.
+func (r Rule) Validate() error {
+	switch {
+	case r.Name == "":
+		return errors.New("Rule has no name")
+	case r.When.Robot == "" || r.When.Event == "":
+		return fmt.Errorf("Rule %q: when needs a robot and an event", r.Name)
+	case r.Then.Robot == "" || r.Then.Command == "":
+		return fmt.Errorf("Rule %q: then needs a robot and a command", r.Name)
+	}
+	if _, ok := ruleOps[r.When.Op]; r.When.Op != "" && !ok {
+		return fmt.Errorf("Rule %q: unknown op %q", r.Name, r.When.Op)
+	}
+	if _, err := MatchPattern(r.When.Event); err != nil {
+		return fmt.Errorf("Rule %q: %w", r.Name, err)
+	}
+	if w := r.When.Window; w != nil {
+		if _, err := parseClock(w.From); err != nil {
+			return fmt.Errorf("Rule %q: window: %w", r.Name, err)
+		}
+		if _, err := parseClock(w.To); err != nil {
+			return fmt.Errorf("Rule %q: window: %w", r.Name, err)
+		}
+	}
+	return nil
+}
+
+// level returns true if the rule is triggered by its condition becoming
+// true, rather than by every event.
+func (r *activeRule) level() bool {
+	return r.status.When.Op != "" || r.status.When.Match != nil
+}
+
+// condition returns whether data meets the condition of the rule, and
+// whether the rule may be triggered again.
+func (r *activeRule) condition(data interface{}) (met, rearm bool) {
+	when := r.status.When
+	met = when.Match == nil || when.Match(data)
+	if when.Op == "" {
+		return met, !met
+	}
+
+	v, ok := toFloat64(data)
+	if !ok {
+		return false, false
+	}
+	met = met && ruleOps[when.Op](v, when.Value)
+	switch when.Op {
+	case ">", ">=":
+		return met, v < when.Value-when.Hysteresis
+	case "<", "<=":
+		return met, v > when.Value+when.Hysteresis
+	}
+	return met, !met
+}
+
+// handle evaluates an event of the watched device.
+func (r *activeRule) handle(data interface{}) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	if !r.status.Enabled {
+		return
+	}
+	debounce := time.Duration(r.status.When.Debounce)
+
+	if !r.level() {
+		if debounce > 0 && time.Since(r.fired) < debounce {
+			return
+		}
+		r.fired = time.Now()
+		go r.run()
+		return
+	}
+
+	met, rearm := r.condition(data)
+	switch {
+	case met && r.armed:
+		r.armed = false
+		if debounce <= 0 {
+			go r.run()
+			return
+		}
+		var job *Job
+		job = r.gobot.rules.scheduler.After(debounce, func() {
+			r.mtx.Lock()
+			current := r.pending == job
+			if current {
+				r.pending = nil
+			}
+			r.mtx.Unlock()
+			if current {
+				r.run()
+			}
+		})
+		r.pending = job
+	case !met && r.pending != nil:
+		// the condition did not hold for the debounce period
+		r.pending.Cancel()
+		r.pending = nil
+		r.armed = true
+	case rearm:
+		r.armed = true
+	}
+}
+
+// run runs the rule's command, if the time is within its window.
+func (r *activeRule) run() {
+	r.mtx.Lock()
+	status := r.status
+	r.mtx.Unlock()
+	if status.When.Window != nil && !status.When.Window.Contains(time.Now()) {
+		return
+	}
+
+	result := RuleResult{Rule: status.Name}
+	command, err := r.gobot.ruleCommand(status.Then)
+	if err == nil {
+		result.Result = command(status.Then.Params)
+		if e, ok := result.Result.(error); ok {
+			err = e
+		}
+	}
+	if err != nil {
+		result.Error = err.Error()
+		r.gobot.Logger().Warn("Rule failed", "rule", status.Name, "error", err)
+	}
+
+	r.mtx.Lock()
+	r.status.Triggered++
+	r.status.LastRun = time.Now()
+	r.status.LastError = result.Error
+	r.mtx.Unlock()
+	r.gobot.Publish(RuleTriggered, result)
+}
+
+// start subscribes to the events the rule watches. A rule already watching
+// is subscribed again when its subscription was cancelled, such as by its
+// robot stopping, or when the robot or device it watches was replaced.
+func (r *activeRule) start() error {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	when := r.status.When
+	source, err := r.gobot.ruleSource(when)
+	if r.sub != nil {
+		select {
+		case <-r.sub.Done():
+		default:
+			if err == nil && source == r.source {
+				return nil
+			}
+		}
+		r.sub.Cancel()
+		r.sub, r.source = nil, nil
+	}
+	if err == nil {
+		r.sub, err = source.On(when.Event, r.handle)
+	}
+	if err != nil {
+		r.status.LastError = err.Error()
+		return fmt.Errorf("Rule %q: %w", r.status.Name, err)
+	}
+	r.source = source
+	r.armed = true
+	return nil
+}
+
+// stop cancels the rule's subscription and any pending run.
+func (r *activeRule) stop() {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	if r.sub != nil {
+		r.sub.Cancel()
+		r.sub, r.source = nil, nil
+	}
+	if r.pending != nil {
+		r.pending.Cancel()
+		r.pending = nil
+	}
+}
+
+// rules holds the rules of a Gobot in the order they were added, and the
+// subscriptions which restart them when robots and devices are added.
+type rules struct {
+	mtx       sync.RWMutex
+	list      []*activeRule
+	scheduler *Scheduler
+	added     *Subscription
+	started   *Subscription
+	watches   map[*Robot]*Subscription
+}
+
+func (g *Gobot) findRule(name string) *activeRule {
+	g.rules.mtx.RLock()
+	defer g.rules.mtx.RUnlock()
+	for _, r := range g.rules.list {
+		if r.status.Name == name {
+			return r
+		}
+	}
+	return nil
+}
+
+// AddRule validates rule and adds it to the Gobot. If the Gobot is running
+// the rule starts watching events at once, otherwise it starts when the
+// Gobot does.
+func (g *Gobot) AddRule(rule Rule) error {
+	if err := rule.Validate(); err != nil {
+		return err
+	}
+	if g.findRule(rule.Name) != nil {
+		return fmt.Errorf("Rule %q already exists", rule.Name)
+	}
+
+	r := newRule(g, rule)
+	g.rules.mtx.Lock()
+	g.rules.list = append(g.rules.list, r)
+	g.rules.mtx.Unlock()
+
+	if g.Running() {
+		return r.start()
+	}
+	return nil
+}
+
+func newRule(g *Gobot, rule Rule) *activeRule {
+	return &activeRule{gobot: g, status: RuleStatus{Rule: rule, Enabled: !rule.Disabled}}
+}
+
+// RemoveRule stops and removes the rule called name.
+func (g *Gobot) RemoveRule(name string) error {
+	g.rules.mtx.Lock()
+	var removed *activeRule
+	for i, r := range g.rules.list {
+		if r.status.Name == name {
+			removed = r
+			g.rules.list = append(g.rules.list[:i:i], g.rules.list[i+1:]...)
+			break
+		}
+	}
+	g.rules.mtx.Unlock()
+
+	if removed == nil {
+		return errors.New("No Rule found with the name " + name)
+	}
+	removed.stop()
+	return nil
+}
+
+// EnableRule enables or disables the rule called name.
+func (g *Gobot) EnableRule(name string, enabled bool) error {
+	r := g.findRule(name)
+	if r == nil {
+		return errors.New("No Rule found with the name " + name)
+	}
+
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	r.status.Enabled = enabled
+	r.status.Disabled = !enabled
+	r.armed = true
+	if r.pending != nil {
+		r.pending.Cancel()
+		r.pending = nil
+	}
+	return nil
+}
+
+// Rule returns the status of the rule called name. Returns nil if the Rule
+// does not exist.
+func (g *Gobot) Rule(name string) *RuleStatus {
+	r := g.findRule(name)
+	if r == nil {
+		return nil
+	}
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	status := r.status
+	return &status
+}
+
+// Rules returns the status of every rule, in the order they were added.
+func (g *Gobot) Rules() []RuleStatus {
+	g.rules.mtx.RLock()
+	list := append([]*activeRule{}, g.rules.list...)
+	g.rules.mtx.RUnlock()
+
+	statuses := []RuleStatus{}
+	for _, r := range list {
+		r.mtx.Lock()
+		statuses = append(statuses, r.status)
+		r.mtx.Unlock()
+	}
+	return statuses
+}
+
+// startRules starts every rule, returning the errors of rules whose events
+// cannot be watched.
+func (g *Gobot) startRules() (errs []error) {
+	g.rules.mtx.RLock()
+	list := append([]*activeRule{}, g.rules.list...)
+	g.rules.mtx.RUnlock()
+
+	for _, r := range list {
+		if err := r.start(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return
+}
+
+// watchRules restarts the rules whenever a robot is added to the Gobot or a
+// device to one of its robots, so rules watching a robot or device which was
+// missing or replaced pick up the new one, and whenever a robot starts, so
+// rules watching a robot which was stopped subscribe again.
+func (g *Gobot) watchRules() {
+	added, _ := g.On(RobotAdded, func(interface{}) { g.refreshRules() })
+	started, _ := g.On(RobotStarted, func(interface{}) { g.refreshRules() })
+	g.rules.mtx.Lock()
+	g.rules.added, g.rules.started = added, started
+	g.rules.mtx.Unlock()
+	g.watchRobots()
+}
+
+// refreshRules watches any new robots and restarts the rules.
+func (g *Gobot) refreshRules() {
+	if !g.Running() {
+		return
+	}
+	g.watchRobots()
+	for _, err := range g.startRules() {
+		g.Logger().Debug("Starting rule failed", "error", err)
+	}
+}
+
+// watchRobots subscribes to DeviceAdded on each robot of the Gobot which is
+// not already watched, such as robots which were added or whose events
+// were closed by stopping them.
+func (g *Gobot) watchRobots() {
+	robots := g.Robots()
+	g.rules.mtx.Lock()
+	defer g.rules.mtx.Unlock()
+
+	watches := make(map[*Robot]*Subscription)
+	robots.Each(func(robot *Robot) {
+		if sub := g.rules.watches[robot]; sub != nil {
+			select {
+			case <-sub.Done():
+			default:
+				watches[robot] = sub
+				return
+			}
+		}
+		watches[robot], _ = robot.On(DeviceAdded, func(interface{}) { g.refreshRules() })
+	})
+	for robot, sub := range g.rules.watches {
+		if watches[robot] != sub {
+			sub.Cancel()
+		}
+	}
+	g.rules.watches = watches
+}
+
+// stopRules stops every rule.
+func (g *Gobot) stopRules() {
+	g.rules.mtx.Lock()
+	list := append([]*activeRule{}, g.rules.list...)
+	added, started, watches := g.rules.added, g.rules.started, g.rules.watches
+	g.rules.added, g.rules.started, g.rules.watches = nil, nil, nil
+	g.rules.mtx.Unlock()
+
+	for _, sub := range []*Subscription{added, started} {
+		if sub != nil {
+			sub.Cancel()
+		}
+	}
+	for _, sub := range watches {
+		sub.Cancel()
+	}
+	for _, r := range list {
+		r.stop()
+	}
+	g.rules.scheduler.Stop()
+}
+
+// ruleSource returns the Eventer publishing the events watched by when.
+func (g *Gobot) ruleSource(when RuleEvent) (Eventer, error) {
+	robot := g.Robot(when.Robot)
+	if robot == nil {
+		return nil, errors.New("No Robot found with the name " + when.Robot)
+	}
+	if when.Device == "" {
+		return robot.Eventer, nil
+	}
+	device := robot.Device(when.Device)
+	if device == nil {
+		return nil, errors.New("No Device found with the name " + when.Device)
+	}
+	eventer, ok := device.(Eventer)
+	if !ok {
+		return nil, fmt.Errorf("Device %q does not publish events", when.Device)
+	}
+	return eventer, nil
+}
+
+// ruleCommand returns the command run by then.
+func (g *Gobot) ruleCommand(then RuleThen) (func(map[string]interface{}) interface{}, error) {
+	robot := g.Robot(then.Robot)
+	if robot == nil {
+		return nil, errors.New("No Robot found with the name " + then.Robot)
+	}
+	var commander Commander = robot
+	if then.Device != "" {
+		device := robot.Device(then.Device)
+		if device == nil {
+			return nil, errors.New("No Device found with the name " + then.Device)
+		}
+		c, ok := device.(Commander)
+		if !ok {
+			return nil, fmt.Errorf("Device %q has no commands", then.Device)
+		}
+		commander = c
+	}
+	command := commander.Command(then.Command)
+	if command == nil {
+		return nil, errors.New("No Command found with the name " + then.Command)
+	}
+	return command, nil
+}
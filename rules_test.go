@@ -0,0 +1,334 @@
+package gobot
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/hybridgroup/gobot/gobottest"
+)
+
+// initTestRules returns a Gobot whose "sensor" robot publishes "reading"
+// events and whose "actuator" robot records the params of its "Switch"
+// command.
+func initTestRules() (*Gobot, chan map[string]interface{}) {
+	g := NewGobot()
+	sensor := newTestRobot("sensor")
+	sensor.AddEvent("reading")
+	actuator := newTestRobot("actuator")
+	switched := make(chan map[string]interface{}, 10)
+	actuator.AddCommand("Switch", func(params map[string]interface{}) interface{} {
+		switched <- params
+		return "switched"
+	})
+	g.AddRobot(sensor)
+	g.AddRobot(actuator)
+	return g, switched
+}
+
+// waitRule returns the next RuleResult published on results.
+func waitRule(t *testing.T, results eventChannel) RuleResult {
+	select {
+	case evt := <-results:
+		return evt.Data.(RuleResult)
+	case <-time.After(time.Second):
+		t.Fatal("rule was not triggered")
+	}
+	return RuleResult{}
+}
+
+func publishReadings(g *Gobot, readings ...interface{}) {
+	for _, v := range readings {
+		g.Robot("sensor").Publish("reading", v)
+	}
+}
+
+func TestRuleValidate(t *testing.T) {
+	when := RuleEvent{Robot: "sensor", Event: "reading"}
+	then := RuleThen{Robot: "actuator", Command: "Switch"}
+
+	gobottest.Assert(t, Rule{Name: "ok", When: when, Then: then}.Validate(), nil)
+	gobottest.Assert(t, Rule{When: when, Then: then}.Validate().Error(), "Rule has no name")
+	gobottest.Assert(t, Rule{Name: "r", Then: then}.Validate().Error(),
+		`Rule "r": when needs a robot and an event`)
+	gobottest.Assert(t, Rule{Name: "r", When: when}.Validate().Error(),
+		`Rule "r": then needs a robot and a command`)
+
+	bad := when
+	bad.Op = "=>"
+	gobottest.Assert(t, Rule{Name: "r", When: bad, Then: then}.Validate().Error(), `Rule "r": unknown op "=>"`)
+	bad = when
+	bad.Window = &TimeWindow{From: "22:00", To: "25:00"}
+	gobottest.Refute(t, Rule{Name: "r", When: bad, Then: then}.Validate(), nil)
+}
+
+func TestTimeWindowContains(t *testing.T) {
+	at := func(hour, min int) time.Time { return time.Date(2016, 1, 1, hour, min, 0, 0, time.Local) }
+
+	day := &TimeWindow{From: "08:00", To: "18:30"}
+	gobottest.Assert(t, day.Contains(at(8, 0)), true)
+	gobottest.Assert(t, day.Contains(at(18, 29)), true)
+	gobottest.Assert(t, day.Contains(at(18, 30)), false)
+	gobottest.Assert(t, day.Contains(at(3, 0)), false)
+
+	night := &TimeWindow{From: "22:00", To: "06:00"}
+	gobottest.Assert(t, night.Contains(at(23, 0)), true)
+	gobottest.Assert(t, night.Contains(at(5, 59)), true)
+	gobottest.Assert(t, night.Contains(at(12, 0)), false)
+}
+
+func TestRuleThreshold(t *testing.T) {
+	g, switched := initTestRules()
+	results := g.Subscribe()
+	err := g.AddRule(Rule{
+		Name: "hot",
+		When: RuleEvent{Robot: "sensor", Event: "reading", Op: ">", Value: 10, Hysteresis: 2},
+		Then: RuleThen{Robot: "actuator", Command: "Switch", Params: map[string]interface{}{"on": true}},
+	})
+	gobottest.Assert(t, err, nil)
+	gobottest.Assert(t, len(g.startRules()), 0)
+	defer g.stopRules()
+
+	// 11 and 9 are within the hysteresis, so only 12 after 7 triggers again
+	publishReadings(g, 5, 12, 11, 9, 11, 7, 12.5)
+	gobottest.Assert(t, waitRule(t, results), RuleResult{Rule: "hot", Result: "switched"})
+	gobottest.Assert(t, <-switched, map[string]interface{}{"on": true})
+	waitRule(t, results)
+	gobottest.Assert(t, g.Rule("hot").Triggered, 2)
+	gobottest.Assert(t, g.Rule("hot").LastRun.IsZero(), false)
+
+	// readings which are not numbers never meet the condition
+	publishReadings(g, "hot", 5, 20)
+	waitRule(t, results)
+	gobottest.Assert(t, g.Rule("hot").Triggered, 3)
+}
+
+func TestRuleDebounce(t *testing.T) {
+	g, _ := initTestRules()
+	results := g.Subscribe()
+	g.AddRule(Rule{
+		Name: "held",
+		When: RuleEvent{Robot: "sensor", Event: "reading", Op: ">=", Value: 10, Debounce: Duration(20 * time.Millisecond)},
+		Then: RuleThen{Robot: "actuator", Command: "Switch"},
+	})
+	g.startRules()
+	defer g.stopRules()
+
+	// the condition does not hold for long enough
+	publishReadings(g, 10, 3)
+	time.Sleep(40 * time.Millisecond)
+	gobottest.Assert(t, g.Rule("held").Triggered, 0)
+
+	publishReadings(g, 15)
+	waitRule(t, results)
+	gobottest.Assert(t, g.Rule("held").Triggered, 1)
+}
+
+func TestRulePulse(t *testing.T) {
+	g, _ := initTestRules()
+	results := g.Subscribe()
+	g.AddRule(Rule{
+		Name: "any",
+		When: RuleEvent{Robot: "sensor", Event: "reading", Debounce: Duration(time.Hour)},
+		Then: RuleThen{Robot: "actuator", Command: "Switch"},
+	})
+	g.AddRule(Rule{
+		Name: "strings",
+		When: RuleEvent{Robot: "sensor", Event: "reading",
+			Match: func(data interface{}) bool { _, ok := data.(string); return ok }},
+		Then: RuleThen{Robot: "actuator", Command: "Switch"},
+	})
+	g.startRules()
+	defer g.stopRules()
+
+	publishReadings(g, 1, 2, "three")
+	waitRule(t, results)
+	waitRule(t, results)
+	gobottest.Assert(t, g.Rule("any").Triggered, 1)
+	gobottest.Assert(t, g.Rule("strings").Triggered, 1)
+}
+
+func TestRuleWindow(t *testing.T) {
+	g, _ := initTestRules()
+	now := time.Now()
+	g.AddRule(Rule{
+		Name: "closed",
+		When: RuleEvent{Robot: "sensor", Event: "reading", Window: &TimeWindow{
+			From: now.Add(2 * time.Hour).Format("15:04"),
+			To:   now.Add(3 * time.Hour).Format("15:04"),
+		}},
+		Then: RuleThen{Robot: "actuator", Command: "Switch"},
+	})
+	g.startRules()
+	defer g.stopRules()
+
+	publishReadings(g, 1)
+	time.Sleep(20 * time.Millisecond)
+	gobottest.Assert(t, g.Rule("closed").Triggered, 0)
+}
+
+func TestRuleCommandError(t *testing.T) {
+	g, _ := initTestRules()
+	results := g.Subscribe()
+	g.AddRule(Rule{
+		Name: "missing",
+		When: RuleEvent{Robot: "sensor", Event: "reading"},
+		Then: RuleThen{Robot: "actuator", Device: "Device1", Command: "Missing"},
+	})
+	g.startRules()
+	defer g.stopRules()
+
+	publishReadings(g, 1)
+	gobottest.Assert(t, waitRule(t, results).Error, "No Command found with the name Missing")
+	gobottest.Assert(t, g.Rule("missing").LastError, "No Command found with the name Missing")
+}
+
+func TestRuleStartErrors(t *testing.T) {
+	g, _ := initTestRules()
+	g.AddRule(Rule{
+		Name: "nobody",
+		When: RuleEvent{Robot: "ghost", Event: "reading"},
+		Then: RuleThen{Robot: "actuator", Command: "Switch"},
+	})
+	g.AddRule(Rule{
+		Name: "quiet",
+		When: RuleEvent{Robot: "sensor", Device: "Device1", Event: "reading"},
+		Then: RuleThen{Robot: "actuator", Command: "Switch"},
+	})
+	errs := g.startRules()
+	defer g.stopRules()
+	gobottest.Assert(t, len(errs), 2)
+	gobottest.Assert(t, errs[0].Error(), `Rule "nobody": No Robot found with the name ghost`)
+	gobottest.Assert(t, errs[1].Error(), `Rule "quiet": Device "Device1" does not publish events`)
+}
+
+func TestRuleManagement(t *testing.T) {
+	g, _ := initTestRules()
+	results := g.Subscribe()
+	rule := Rule{
+		Name:     "toggle",
+		When:     RuleEvent{Robot: "sensor", Event: "reading"},
+		Then:     RuleThen{Robot: "actuator", Command: "Switch"},
+		Disabled: true,
+	}
+	gobottest.Assert(t, g.AddRule(rule), nil)
+	gobottest.Assert(t, g.AddRule(rule).Error(), `Rule "toggle" already exists`)
+	gobottest.Refute(t, g.AddRule(Rule{Name: "invalid"}), nil)
+	gobottest.Assert(t, len(g.Rules()), 1)
+	gobottest.Assert(t, g.Rules()[0].Enabled, false)
+	gobottest.Assert(t, g.Rule("nothing"), (*RuleStatus)(nil))
+
+	g.startRules()
+	defer g.stopRules()
+	publishReadings(g, 1)
+	time.Sleep(20 * time.Millisecond)
+	gobottest.Assert(t, g.Rule("toggle").Triggered, 0)
+
+	gobottest.Assert(t, g.EnableRule("toggle", true), nil)
+	gobottest.Assert(t, g.Rule("toggle").Disabled, false)
+	publishReadings(g, 1)
+	waitRule(t, results)
+	gobottest.Assert(t, g.Rule("toggle").Triggered, 1)
+
+	gobottest.Assert(t, g.EnableRule("nothing", true).Error(), "No Rule found with the name nothing")
+	gobottest.Assert(t, g.RemoveRule("toggle"), nil)
+	gobottest.Assert(t, g.RemoveRule("toggle").Error(), "No Rule found with the name toggle")
+	gobottest.Assert(t, len(g.Rules()), 0)
+}
+
+func TestGobotStartRules(t *testing.T) {
+	g, switched := initTestRules()
+	g.AutoStop = false
+	g.AddRule(Rule{
+		Name: "started",
+		When: RuleEvent{Robot: "sensor", Event: "reading"},
+		Then: RuleThen{Robot: "actuator", Command: "Switch"},
+	})
+	gobottest.Assert(t, g.Running(), false)
+	gobottest.Assert(t, len(g.Start()), 0)
+	gobottest.Assert(t, g.Running(), true)
+
+	// rules added while running start at once
+	g.AddRule(Rule{
+		Name: "late",
+		When: RuleEvent{Robot: "sensor", Event: "reading"},
+		Then: RuleThen{Robot: "actuator", Command: "Switch"},
+	})
+	publishReadings(g, 1)
+	<-switched
+	<-switched
+
+	g.Stop()
+	gobottest.Assert(t, g.Running(), false)
+}
+
+func TestRuleResubscribe(t *testing.T) {
+	g, switched := initTestRules()
+	g.AutoStop = false
+	g.AddRule(Rule{
+		Name: "robot",
+		When: RuleEvent{Robot: "sensor", Event: "reading"},
+		Then: RuleThen{Robot: "actuator", Command: "Switch", Params: map[string]interface{}{"by": "robot"}},
+	})
+	g.AddRule(Rule{
+		Name: "device",
+		When: RuleEvent{Robot: "sensor", Device: "probe", Event: "reading"},
+		Then: RuleThen{Robot: "actuator", Command: "Switch", Params: map[string]interface{}{"by": "device"}},
+	})
+	gobottest.Assert(t, len(g.Start()), 0)
+	defer g.Stop()
+	waitWatching := func(name string, source Eventer) {
+		r := g.findRule(name)
+		for deadline := time.Now().Add(time.Second); ; time.Sleep(time.Millisecond) {
+			r.mtx.Lock()
+			watching := r.source == source && r.sub != nil
+			if watching {
+				select {
+				case <-r.sub.Done():
+					watching = false
+				default:
+				}
+			}
+			r.mtx.Unlock()
+			if watching {
+				return
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("rule %q did not watch the new source", name)
+			}
+		}
+	}
+	waitSwitch := func(by string) {
+		select {
+		case params := <-switched:
+			gobottest.Assert(t, params["by"], by)
+		case <-time.After(time.Second):
+			t.Fatalf("rule %q was not triggered", by)
+		}
+	}
+
+	// a replaced robot is watched once it is added
+	gobottest.Assert(t, len(g.RemoveRobot("sensor")), 0)
+	sensor := newTestRobot("sensor")
+	gobottest.Assert(t, len(g.AddRobotContext(context.Background(), sensor)), 0)
+	waitWatching("robot", sensor.Eventer)
+	publishReadings(g, 1)
+	waitSwitch("robot")
+
+	// as is a device added later
+	probe := &testEventDriver{
+		testDriver: newTestDriver(newTestAdaptor("Connection1", "/dev/null"), "probe", "1"),
+		Eventer:    NewEventer(),
+	}
+	gobottest.Assert(t, len(sensor.AddDeviceContext(context.Background(), probe)), 0)
+	waitWatching("device", probe)
+	probe.Publish("reading", 1)
+	waitSwitch("device")
+
+	// and a rule whose robot was stopped is subscribed again when started
+	gobottest.Assert(t, len(sensor.Stop()), 0)
+	gobottest.Assert(t, len(sensor.Start()), 0)
+	waitWatching("robot", sensor.Eventer)
+	publishReadings(g, 1)
+	waitSwitch("robot")
+}
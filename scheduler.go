@@ -0,0 +1,357 @@
+package gobot
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Overlap decides what happens when a repeating job is due while its
+// previous run has not finished.
+type Overlap int
+
+const (
+	// SkipIfRunning drops the run which is due.
+	SkipIfRunning Overlap = iota
+	// Queue runs the job again as soon as the previous run finishes, once
+	// for every time it fell due.
+	Queue
+)
+
+// JobOptions describes how a repeating job is run.
+type JobOptions struct {
+	// Overlap is applied when the job is due while it is still running
+	Overlap Overlap
+}
+
+// Job is a handle to a function scheduled by a Scheduler. Runs of a job
+// never overlap one another.
+type Job struct {
+	f       func()
+	overlap Overlap
+	next    func(time.Time) time.Time
+	once    bool
+
+	runs    int64
+	pending int64
+	running int32
+	wake    chan struct{}
+	cancel  chan struct{}
+	done    chan struct{}
+	stop    sync.Once
+}
+
+// Cancel stops the job. A run which is in progress is left to finish, but
+// no further runs are started. It is safe to call Cancel more than once.
+func (j *Job) Cancel() {
+	j.stop.Do(func() { close(j.cancel) })
+}
+
+// Done returns a channel which is closed once the job is cancelled, or has
+// run for the last time, and no run is in progress.
+func (j *Job) Done() <-chan struct{} {
+	return j.done
+}
+
+// Runs returns the number of runs of the job which have finished.
+func (j *Job) Runs() int {
+	return int(atomic.LoadInt64(&j.runs))
+}
+
+// timer signals the runner each time the job falls due, until it is
+// cancelled.
+func (j *Job) timer() {
+	last := time.Now()
+	for {
+		due := j.next(last)
+		if due.IsZero() {
+			j.Cancel()
+			return
+		}
+
+		t := time.NewTimer(due.Sub(time.Now()))
+		select {
+		case <-j.cancel:
+			t.Stop()
+			return
+		case <-t.C:
+		}
+		last = due
+
+		if j.overlap == Queue {
+			atomic.AddInt64(&j.pending, 1)
+		} else if atomic.LoadInt32(&j.running) == 1 {
+			continue
+		}
+		select {
+		case j.wake <- struct{}{}:
+		default:
+		}
+		if j.once {
+			return
+		}
+	}
+}
+
+// run calls f each time the timer signals, until the job is cancelled.
+func (j *Job) run() {
+	defer close(j.done)
+	for {
+		select {
+		case <-j.cancel:
+			return
+		case <-j.wake:
+		}
+
+		for {
+			if j.overlap == Queue {
+				if atomic.LoadInt64(&j.pending) == 0 {
+					break
+				}
+				atomic.AddInt64(&j.pending, -1)
+			}
+			select {
+			case <-j.cancel:
+				return
+			default:
+			}
+			atomic.StoreInt32(&j.running, 1)
+			j.f()
+			atomic.StoreInt32(&j.running, 0)
+			atomic.AddInt64(&j.runs, 1)
+			if j.once {
+				j.Cancel()
+				return
+			}
+			if j.overlap != Queue {
+				break
+			}
+		}
+	}
+}
+
+// Scheduler runs functions at intervals, once after a delay, or on a cron
+// schedule. Every Robot has one, which is stopped when the Robot stops. It is
+// safe for concurrent use.
+type Scheduler struct {
+	mtx  sync.Mutex
+	jobs map[*Job]struct{}
+}
+
+// NewScheduler returns a new Scheduler.
+func NewScheduler() *Scheduler {
+	return &Scheduler{jobs: make(map[*Job]struct{})}
+}
+
+// Every runs f every d until the returned Job is cancelled or the Scheduler
+// is stopped. When f is still running as it falls due again, opts decide
+// whether the run is skipped, which is the default, or queued. It panics if
+// d is not positive.
+func (s *Scheduler) Every(d time.Duration, f func(), opts ...JobOptions) *Job {
+	if d <= 0 {
+		panic("non-positive interval for Every")
+	}
+	return s.schedule(f, func(t time.Time) time.Time { return t.Add(d) }, false, opts)
+}
+
+// After runs f once after d, unless the returned Job is cancelled or the
+// Scheduler is stopped first.
+func (s *Scheduler) After(d time.Duration, f func()) *Job {
+	return s.schedule(f, func(t time.Time) time.Time { return t.Add(d) }, true, nil)
+}
+
+// Cron runs f at the times described by spec, a cron expression as parsed
+// by ParseCron, until the returned Job is cancelled or the Scheduler is
+// stopped.
+func (s *Scheduler) Cron(spec string, f func(), opts ...JobOptions) (*Job, error) {
+	c, err := ParseCron(spec)
+	if err != nil {
+		return nil, err
+	}
+	return s.schedule(f, c.Next, false, opts), nil
+}
+
+// Jobs returns the number of jobs which have not finished.
+func (s *Scheduler) Jobs() int {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	return len(s.jobs)
+}
+
+// Stop cancels every job. Runs in progress are left to finish. The
+// Scheduler remains usable afterwards.
+func (s *Scheduler) Stop() {
+	s.mtx.Lock()
+	jobs := make([]*Job, 0, len(s.jobs))
+	for j := range s.jobs {
+		jobs = append(jobs, j)
+	}
+	s.mtx.Unlock()
+
+	for _, j := range jobs {
+		j.Cancel()
+	}
+}
+
+func (s *Scheduler) schedule(f func(), next func(time.Time) time.Time, once bool, opts []JobOptions) *Job {
+	o := JobOptions{}
+	if len(opts) > 0 {
+		o = opts[0]
+	}
+
+	j := &Job{
+		f:       f,
+		overlap: o.Overlap,
+		next:    next,
+		once:    once,
+		wake:    make(chan struct{}, 1),
+		cancel:  make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	s.mtx.Lock()
+	s.jobs[j] = struct{}{}
+	s.mtx.Unlock()
+
+	go j.timer()
+	go func() {
+		j.run()
+		s.mtx.Lock()
+		delete(s.jobs, j)
+		s.mtx.Unlock()
+	}()
+	return j
+}
+
+// CronSchedule is a parsed cron expression.
+type CronSchedule struct {
+	minute, hour, dom, month, dow uint64
+	// domAny and dowAny record a "*" day field, which cron treats
+	// differently from a list of every day
+	domAny, dowAny bool
+}
+
+var cronDescriptors = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// ParseCron parses a cron expression of five fields: minute, hour, day of
+// month, month and day of week (0 is Sunday). Each field is "*", a number,
+// a range such as "1-5", or a comma separated list of these, and may end in
+// a step such as "*/15". The descriptors @yearly, @monthly, @weekly, @daily
+// and @hourly are also accepted. Times are in the local time zone.
+func ParseCron(spec string) (c *CronSchedule, err error) {
+	if d, ok := cronDescriptors[strings.TrimSpace(spec)]; ok {
+		spec = d
+	}
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("Invalid cron spec %q: expected 5 fields, got %v", spec, len(fields))
+	}
+
+	c = &CronSchedule{domAny: fields[2] == "*", dowAny: fields[4] == "*"}
+	bounds := []struct {
+		bits     *uint64
+		min, max int
+	}{
+		{&c.minute, 0, 59},
+		{&c.hour, 0, 23},
+		{&c.dom, 1, 31},
+		{&c.month, 1, 12},
+		{&c.dow, 0, 7},
+	}
+	for i, b := range bounds {
+		if *b.bits, err = parseCronField(fields[i], b.min, b.max); err != nil {
+			return nil, fmt.Errorf("Invalid cron spec %q: %w", spec, err)
+		}
+	}
+	// 7 is another name for Sunday
+	if c.dow&(1<<7) != 0 {
+		c.dow |= 1
+	}
+	return c, nil
+}
+
+func parseCronField(field string, min, max int) (bits uint64, err error) {
+	for _, part := range strings.Split(field, ",") {
+		step := 1
+		if i := strings.Index(part, "/"); i >= 0 {
+			if step, err = strconv.Atoi(part[i+1:]); err != nil || step < 1 {
+				return 0, fmt.Errorf("bad step in %q", part)
+			}
+			part = part[:i]
+		}
+
+		lo, hi := min, max
+		switch {
+		case part == "*":
+		case strings.Contains(part, "-"):
+			r := strings.SplitN(part, "-", 2)
+			lo, err = strconv.Atoi(r[0])
+			if err == nil {
+				hi, err = strconv.Atoi(r[1])
+			}
+		default:
+			lo, err = strconv.Atoi(part)
+			hi = lo
+			if err == nil && step > 1 {
+				hi = max
+			}
+		}
+		if err != nil || lo < min || hi > max || lo > hi {
+			return 0, fmt.Errorf("%q is out of range %v-%v", part, min, max)
+		}
+
+		for v := lo; v <= hi; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+	return
+}
+
+// Next returns the first time after t matched by the schedule, or the zero
+// Time if there is none within five years.
+func (c *CronSchedule) Next(t time.Time) time.Time {
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(5, 0, 0)
+
+	for t.Before(limit) {
+		if c.month&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if !c.matchDay(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if c.hour&(1<<uint(t.Hour())) == 0 {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+			continue
+		}
+		if c.minute&(1<<uint(t.Minute())) == 0 {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
+
+// matchDay follows cron in matching either day field when both are
+// restricted, and both otherwise.
+func (c *CronSchedule) matchDay(t time.Time) bool {
+	dom := c.dom&(1<<uint(t.Day())) != 0
+	dow := c.dow&(1<<uint(t.Weekday())) != 0
+	if !c.domAny && !c.dowAny {
+		return dom || dow
+	}
+	return dom && dow
+}
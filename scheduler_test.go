@@ -0,0 +1,143 @@
+package gobot
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/hybridgroup/gobot/gobottest"
+)
+
+func TestSchedulerEvery(t *testing.T) {
+	s := NewScheduler()
+	runs := make(chan bool, 10)
+	job := s.Every(time.Millisecond, func() { runs <- true })
+
+	<-runs
+	<-runs
+	job.Cancel()
+	job.Cancel()
+	<-job.Done()
+	gobottest.Assert(t, job.Runs() >= 2, true)
+	gobottest.Assert(t, s.Jobs() <= 1, true)
+}
+
+func TestSchedulerEverySkipIfRunning(t *testing.T) {
+	s := NewScheduler()
+	var running, overlapped int32
+	release := make(chan bool)
+	job := s.Every(time.Millisecond, func() {
+		if !atomic.CompareAndSwapInt32(&running, 0, 1) {
+			atomic.StoreInt32(&overlapped, 1)
+		}
+		<-release
+		atomic.StoreInt32(&running, 0)
+	})
+
+	release <- true
+	time.Sleep(10 * time.Millisecond)
+	release <- true
+	job.Cancel()
+	<-job.Done()
+
+	gobottest.Assert(t, atomic.LoadInt32(&overlapped), int32(0))
+	gobottest.Assert(t, job.Runs(), 2)
+}
+
+func TestSchedulerEveryQueue(t *testing.T) {
+	s := NewScheduler()
+	release := make(chan bool)
+	job := s.Every(time.Millisecond, func() { <-release }, JobOptions{Overlap: Queue})
+
+	// ticks pile up behind the first run, and are each run in turn
+	time.Sleep(10 * time.Millisecond)
+	for i := 0; i < 3; i++ {
+		release <- true
+	}
+	job.Cancel()
+	select {
+	case release <- true:
+	case <-job.Done():
+	}
+	<-job.Done()
+	gobottest.Assert(t, job.Runs() >= 3, true)
+}
+
+func TestSchedulerAfter(t *testing.T) {
+	s := NewScheduler()
+	ran := make(chan bool, 1)
+	job := s.After(time.Millisecond, func() { ran <- true })
+	<-job.Done()
+	gobottest.Assert(t, len(ran), 1)
+	gobottest.Assert(t, job.Runs(), 1)
+
+	job = s.After(time.Hour, func() { ran <- true })
+	job.Cancel()
+	<-job.Done()
+	gobottest.Assert(t, job.Runs(), 0)
+}
+
+func TestSchedulerStop(t *testing.T) {
+	s := NewScheduler()
+	jobs := []*Job{
+		s.Every(time.Hour, func() {}),
+		s.After(time.Hour, func() {}),
+	}
+	job, err := s.Cron("@daily", func() {})
+	gobottest.Assert(t, err, nil)
+	jobs = append(jobs, job)
+	gobottest.Assert(t, s.Jobs(), 3)
+
+	s.Stop()
+	for _, job := range jobs {
+		<-job.Done()
+	}
+
+	_, err = s.Cron("* * *", func() {})
+	gobottest.Assert(t, err.Error(), `Invalid cron spec "* * *": expected 5 fields, got 3`)
+}
+
+func TestRobotStopCancelsJobs(t *testing.T) {
+	r := newTestRobot("Robot99")
+	job := r.Every(time.Hour, func() {})
+	after := r.After(time.Hour, func() {})
+	r.Stop()
+	<-job.Done()
+	<-after.Done()
+	gobottest.Assert(t, r.Scheduler().Jobs() <= 2, true)
+}
+
+func TestParseCron(t *testing.T) {
+	at := func(s string) time.Time {
+		t, _ := time.ParseInLocation("2006-01-02 15:04", s, time.Local)
+		return t
+	}
+	tests := []struct {
+		spec, from, next string
+	}{
+		{"* * * * *", "2016-06-01 10:00", "2016-06-01 10:01"},
+		{"*/15 * * * *", "2016-06-01 10:01", "2016-06-01 10:15"},
+		{"30 9 * * 1-5", "2016-06-03 10:00", "2016-06-06 09:30"},
+		{"0 0 1,15 * *", "2016-06-02 00:00", "2016-06-15 00:00"},
+		{"0 12 * 2 *", "2016-06-02 00:00", "2017-02-01 12:00"},
+		{"0 0 13 * 5", "2016-06-01 00:00", "2016-06-03 00:00"},
+		{"0 0 * * 7", "2016-06-01 00:00", "2016-06-05 00:00"},
+		{"@hourly", "2016-06-01 10:30", "2016-06-01 11:00"},
+		{"0 0 31 2 *", "2016-06-01 00:00", "0001-01-01 00:00"},
+	}
+	for _, test := range tests {
+		c, err := ParseCron(test.spec)
+		gobottest.Assert(t, err, nil)
+		next := c.Next(at(test.from))
+		if test.next == "0001-01-01 00:00" {
+			gobottest.Assert(t, next.IsZero(), true)
+			continue
+		}
+		gobottest.Assert(t, next, at(test.next))
+	}
+
+	for _, spec := range []string{"60 * * * *", "* 24 * * *", "* * 0 * *", "*/0 * * * *", "5-1 * * * *", "a * * * *"} {
+		_, err := ParseCron(spec)
+		gobottest.Refute(t, err, nil)
+	}
+}
@@ -0,0 +1,284 @@
+package gobot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FailurePolicy decides what a Robot does when one of its connections or
+// devices fails to start.
+type FailurePolicy int
+
+const (
+	// Abort stops starting the Robot at the first failure and returns its
+	// errors. It is the default.
+	Abort FailurePolicy = iota
+	// Continue starts every other connection and device, and the Robot's
+	// work, as if nothing had failed.
+	Continue
+	// Skip is like Continue, except that devices whose connection or
+	// dependencies did not start are skipped.
+	Skip
+)
+
+// Dependent is the interface that describes a driver which must be started
+// after other devices of its robot.
+type Dependent interface {
+	// DependsOn returns the names of the devices to start first
+	DependsOn() []string
+}
+
+// StartStatus is the outcome of starting a connection or device.
+type StartStatus string
+
+const (
+	// Started means the connection or device started
+	Started StartStatus = "started"
+	// Failed means the connection or device returned errors
+	Failed StartStatus = "failed"
+	// Skipped means the connection or device was not started because of an
+	// earlier failure
+	Skipped StartStatus = "skipped"
+)
+
+// StartResult describes how a single connection or device started.
+type StartResult struct {
+	Name     string        `json:"name"`
+	Status   StartStatus   `json:"status"`
+	Duration time.Duration `json:"duration"`
+	Errors   []string      `json:"errors,omitempty"`
+}
+
+// StartReport describes what was started the last time a Robot started.
+// Connections are listed in the order they were added, devices in the order
+// they were started.
+type StartReport struct {
+	Robot       string        `json:"robot"`
+	Connections []StartResult `json:"connections"`
+	Devices     []StartResult `json:"devices"`
+	Work        bool          `json:"work"`
+	Duration    time.Duration `json:"duration"`
+}
+
+// DependsOn declares that device must be started after each of
+// dependencies, all of them being device names. It adds to any dependencies
+// the device declares itself by implementing Dependent.
+func (r *Robot) DependsOn(device string, dependencies ...string) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	if r.deps == nil {
+		r.deps = make(map[string][]string)
+	}
+	r.deps[device] = append(r.deps[device], dependencies...)
+}
+
+// StartReport returns the report of the last time the Robot started, or nil
+// if it has not been started.
+func (r *Robot) StartReport() *StartReport {
+	r.mtx.RLock()
+	defer r.mtx.RUnlock()
+	return r.report
+}
+
+// StartConcurrent starts every Robot in the collection at the same time and
+// waits for all of them. Unlike Start, a Robot which fails does not keep the
+// others from starting, and the errors of every Robot are returned.
+func (r *Robots) StartConcurrent(ctx context.Context) (errs []error) {
+	var wg sync.WaitGroup
+	var mtx sync.Mutex
+
+	for _, robot := range *r {
+		wg.Add(1)
+		go func(robot *Robot) {
+			defer wg.Done()
+			rerrs := robot.StartContext(ctx)
+			for i, err := range rerrs {
+				rerrs[i] = fmt.Errorf("Robot %q: %w", robot.Name, err)
+			}
+			mtx.Lock()
+			errs = append(errs, rerrs...)
+			mtx.Unlock()
+		}(robot)
+	}
+	wg.Wait()
+	return
+}
+
+// startAll starts the connections and then the devices of r in dependency
+// order, applying its FailurePolicy and recording each outcome in report.
+// ok is false when starting was aborted.
+func (r *Robot) startAll(ctx context.Context, report *StartReport) (errs []error, ok bool) {
+	aborted := false
+	down := make(map[Connection]bool)
+
+	logger := r.Logger()
+	logger.Info("Starting connections")
+	for _, connection := range *r.Connections() {
+		if aborted {
+			report.Connections = append(report.Connections, StartResult{Name: connection.Name(), Status: Skipped})
+			continue
+		}
+
+		fields := []interface{}{"connection", connection.Name()}
+		if porter, ok := connection.(Porter); ok {
+			fields = append(fields, "port", porter.Port())
+		}
+		logger.Info("Starting connection", fields...)
+
+		result, cerrs := startResult(connection.Name(), func() []error {
+			return connect(ctx, r.Timeouts.Connect, connection)
+		})
+		report.Connections = append(report.Connections, result)
+		if len(cerrs) > 0 {
+			down[connection] = true
+			for i, err := range cerrs {
+				cerrs[i] = fmt.Errorf("Connection %q: %w", connection.Name(), err)
+			}
+			aborted = r.failed(cerrs, &errs)
+		}
+	}
+
+	logger.Info("Starting devices")
+	devices, unresolved := r.startOrder()
+	notStarted := make(map[string]bool)
+	for _, device := range devices {
+		if aborted || (r.FailurePolicy == Skip && r.blocked(device, down, notStarted)) {
+			notStarted[device.Name()] = true
+			report.Devices = append(report.Devices, StartResult{Name: device.Name(), Status: Skipped})
+			continue
+		}
+
+		fields := []interface{}{"device", device.Name()}
+		if pinner, ok := device.(Pinner); ok {
+			fields = append(fields, "pin", pinner.Pin())
+		}
+		logger.Info("Starting device", fields...)
+
+		result, derrs := startResult(device.Name(), func() []error {
+			if err, ok := unresolved[device]; ok {
+				return []error{err}
+			}
+			return start(ctx, r.Timeouts.Start, device)
+		})
+		report.Devices = append(report.Devices, result)
+		if len(derrs) > 0 {
+			notStarted[device.Name()] = true
+			for i, err := range derrs {
+				derrs[i] = fmt.Errorf("Device %q: %w", device.Name(), err)
+			}
+			aborted = r.failed(derrs, &errs)
+		}
+	}
+
+	return errs, !aborted
+}
+
+// failed logs errs and, when the Robot aborts on failure, adds them to the
+// errors returned by Start. It returns true if starting must abort.
+func (r *Robot) failed(errs []error, all *[]error) bool {
+	if r.FailurePolicy == Abort {
+		*all = append(*all, errs...)
+		return true
+	}
+	for _, err := range errs {
+		r.Logger().Error("Start failed", "error", err)
+	}
+	return false
+}
+
+// blocked returns true if the connection of device is down or one of its
+// dependencies did not start.
+func (r *Robot) blocked(device Device, down map[Connection]bool, notStarted map[string]bool) bool {
+	if c := device.Connection(); c != nil && down[c] {
+		return true
+	}
+	for _, name := range r.dependencies(device) {
+		if notStarted[name] {
+			return true
+		}
+	}
+	return false
+}
+
+// dependencies returns the names of the devices which device depends on.
+func (r *Robot) dependencies(device Device) (names []string) {
+	if dependent, ok := device.(Dependent); ok {
+		names = append(names, dependent.DependsOn()...)
+	}
+	r.mtx.RLock()
+	names = append(names, r.deps[device.Name()]...)
+	r.mtx.RUnlock()
+	return
+}
+
+// startOrder returns the devices of r sorted so that each device comes after
+// its dependencies, otherwise keeping the order they were added in. Devices
+// whose dependencies are unknown or circular cannot be started; they are
+// mapped to the error which explains why in unresolved.
+func (r *Robot) startOrder() (ordered []Device, unresolved map[Device]error) {
+	unresolved = make(map[Device]error)
+	placed := make(map[string]bool)
+	remaining := append([]Device{}, *r.Devices()...)
+
+	for _, device := range remaining {
+		for _, name := range r.dependencies(device) {
+			if r.Device(name) == nil {
+				unresolved[device] = fmt.Errorf("depends on unknown device %q", name)
+			}
+		}
+	}
+
+	for len(remaining) > 0 {
+		progress := false
+		for i := 0; i < len(remaining); i++ {
+			device := remaining[i]
+			if _, ok := unresolved[device]; !ok && !r.ready(device, placed) {
+				continue
+			}
+			ordered = append(ordered, device)
+			placed[device.Name()] = true
+			remaining = append(remaining[:i], remaining[i+1:]...)
+			progress = true
+			break
+		}
+		if !progress {
+			names := []string{}
+			for _, device := range remaining {
+				names = append(names, device.Name())
+			}
+			err := errors.New("dependency cycle between " + strings.Join(names, ", "))
+			for _, device := range remaining {
+				unresolved[device] = err
+			}
+			ordered = append(ordered, remaining...)
+			break
+		}
+	}
+	return
+}
+
+func (r *Robot) ready(device Device, placed map[string]bool) bool {
+	for _, name := range r.dependencies(device) {
+		if !placed[name] {
+			return false
+		}
+	}
+	return true
+}
+
+func startResult(name string, f func() []error) (result StartResult, errs []error) {
+	begin := time.Now()
+	errs = f()
+	result = StartResult{Name: name, Status: Started, Duration: time.Since(begin)}
+	if len(errs) > 0 {
+		result.Status = Failed
+		for _, err := range errs {
+			result.Errors = append(result.Errors, err.Error())
+		}
+	}
+	return
+}
@@ -0,0 +1,196 @@
+package gobot
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hybridgroup/gobot/gobottest"
+)
+
+type testStartLog struct {
+	mtx   sync.Mutex
+	names []string
+}
+
+func (l *testStartLog) add(name string) {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	l.names = append(l.names, name)
+}
+
+type testOrderDriver struct {
+	*testDriver
+	log  *testStartLog
+	deps []string
+	err  error
+}
+
+func (t *testOrderDriver) Start() (errs []error) {
+	if t.err != nil {
+		return []error{t.err}
+	}
+	t.log.add(t.name)
+	return
+}
+
+func (t *testOrderDriver) DependsOn() []string { return t.deps }
+
+type testFailingAdaptor struct {
+	*testAdaptor
+}
+
+func (t *testFailingAdaptor) Connect() (errs []error) {
+	return []error{errors.New("connect error")}
+}
+
+func newTestOrderDriver(l *testStartLog, c Connection, name string, deps ...string) *testOrderDriver {
+	return &testOrderDriver{
+		testDriver: &testDriver{name: name, connection: c, Commander: NewCommander()},
+		log:        l,
+		deps:       deps,
+	}
+}
+
+func TestRobotStartDependencyOrder(t *testing.T) {
+	l := &testStartLog{}
+	adaptor := newTestAdaptor("Connection1", "/dev/null")
+	r := NewRobot("Robot1",
+		[]Connection{adaptor},
+		[]Device{
+			newTestOrderDriver(l, adaptor, "arm", "base"),
+			newTestOrderDriver(l, adaptor, "gripper"),
+			newTestOrderDriver(l, adaptor, "base"),
+		},
+	)
+	r.DependsOn("gripper", "arm")
+
+	gobottest.Assert(t, len(r.Start()), 0)
+	gobottest.Assert(t, l.names, []string{"base", "arm", "gripper"})
+
+	report := r.StartReport()
+	gobottest.Assert(t, report.Robot, "Robot1")
+	gobottest.Assert(t, len(report.Devices), 3)
+	gobottest.Assert(t, report.Devices[0].Name, "base")
+	gobottest.Assert(t, report.Devices[2].Status, Started)
+	gobottest.Assert(t, report.Connections[0].Status, Started)
+}
+
+func TestRobotStartDependencyErrors(t *testing.T) {
+	l := &testStartLog{}
+	adaptor := newTestAdaptor("Connection1", "/dev/null")
+	r := NewRobot("Robot1",
+		[]Connection{adaptor},
+		[]Device{
+			newTestOrderDriver(l, adaptor, "a", "b"),
+			newTestOrderDriver(l, adaptor, "b", "a"),
+			newTestOrderDriver(l, adaptor, "c", "missing"),
+			newTestOrderDriver(l, adaptor, "d"),
+		},
+	)
+	r.FailurePolicy = Continue
+
+	gobottest.Assert(t, len(r.Start()), 0)
+	gobottest.Assert(t, l.names, []string{"d"})
+
+	results := map[string]StartResult{}
+	for _, result := range r.StartReport().Devices {
+		results[result.Name] = result
+	}
+	gobottest.Assert(t, results["a"].Status, Failed)
+	gobottest.Assert(t, results["a"].Errors, []string{"dependency cycle between a, b"})
+	gobottest.Assert(t, results["c"].Errors, []string{`depends on unknown device "missing"`})
+	gobottest.Assert(t, results["d"].Status, Started)
+}
+
+func TestRobotStartFailurePolicy(t *testing.T) {
+	newRobot := func(l *testStartLog, policy FailurePolicy) *Robot {
+		good := newTestAdaptor("good", "/dev/null")
+		bad := &testFailingAdaptor{newTestAdaptor("bad", "/dev/null")}
+		broken := newTestOrderDriver(l, good, "broken")
+		broken.err = errors.New("start error")
+		r := NewRobot("Robot1",
+			[]Connection{bad, good},
+			[]Device{
+				newTestOrderDriver(l, bad, "onBad"),
+				broken,
+				newTestOrderDriver(l, good, "afterBroken", "broken"),
+				newTestOrderDriver(l, good, "fine"),
+			},
+			func() { l.add("work") },
+		)
+		r.FailurePolicy = policy
+		return r
+	}
+
+	l := &testStartLog{}
+	r := newRobot(l, Abort)
+	errs := r.Start()
+	gobottest.Assert(t, len(errs), 1)
+	gobottest.Assert(t, errs[0].Error(), `Connection "bad": connect error`)
+	gobottest.Assert(t, len(l.names), 0)
+	report := r.StartReport()
+	gobottest.Assert(t, report.Connections[1].Status, Skipped)
+	gobottest.Assert(t, report.Devices[0].Status, Skipped)
+	gobottest.Assert(t, report.Work, false)
+
+	l = &testStartLog{}
+	r = newRobot(l, Continue)
+	gobottest.Assert(t, len(r.Start()), 0)
+	gobottest.Assert(t, l.names, []string{"onBad", "afterBroken", "fine", "work"})
+	gobottest.Assert(t, r.StartReport().Work, true)
+
+	l = &testStartLog{}
+	r = newRobot(l, Skip)
+	gobottest.Assert(t, len(r.Start()), 0)
+	gobottest.Assert(t, l.names, []string{"fine", "work"})
+	report = r.StartReport()
+	gobottest.Assert(t, report.Connections[0].Status, Failed)
+	gobottest.Assert(t, report.Devices[0].Status, Skipped)
+	gobottest.Assert(t, report.Devices[1].Status, Failed)
+	gobottest.Assert(t, report.Devices[1].Errors, []string{"start error"})
+	gobottest.Assert(t, report.Devices[2].Status, Skipped)
+	gobottest.Assert(t, report.Devices[3].Status, Started)
+}
+
+func TestRobotsStartConcurrent(t *testing.T) {
+	var wg sync.WaitGroup
+	wg.Add(2)
+	started := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(started)
+	}()
+
+	// each robot's work waits for the other robot to start, which only
+	// succeeds if both start at the same time
+	work := func() {
+		wg.Done()
+		select {
+		case <-started:
+		case <-time.After(time.Second):
+		}
+	}
+	bad := NewRobot("bad",
+		[]Connection{&testFailingAdaptor{newTestAdaptor("Connection1", "/dev/null")}},
+	)
+	robots := &Robots{
+		NewRobot("Robot1", work),
+		bad,
+		NewRobot("Robot2", work),
+	}
+
+	errs := robots.StartConcurrent(context.Background())
+	gobottest.Assert(t, len(errs), 1)
+	gobottest.Assert(t, errs[0].Error(), `Robot "bad": Connection "Connection1": connect error`)
+
+	select {
+	case <-started:
+	default:
+		t.Error("robots were not started concurrently")
+	}
+	gobottest.Assert(t, (*robots)[0].StartReport().Work, true)
+	gobottest.Assert(t, (*robots)[2].StartReport().Work, true)
+}
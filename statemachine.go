@@ -0,0 +1,391 @@
+package gobot
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// StateChanged is published by a StateMachine, and by the Robot it was added
+// to, each time the machine enters a state. Its data is a StateChange.
+const StateChanged = "state-changed"
+
+// StateChange describes a transition of a StateMachine.
+type StateChange struct {
+	Machine string `json:"machine"`
+	From    string `json:"from"`
+	To      string `json:"to"`
+	// Event is the event name, or pattern, of the transition taken; empty
+	// when it was triggered by a timer
+	Event string `json:"event,omitempty"`
+}
+
+// State is a state of a StateMachine, with optional actions run as the
+// machine enters and leaves it.
+type State struct {
+	Name    string
+	OnEnter func()
+	OnExit  func()
+}
+
+// AnyState may be used as the From of a Transition which applies in every
+// state.
+const AnyState = "*"
+
+// Transition moves a StateMachine from one state to another. It is triggered
+// either by an event whose name matches Event, or by the machine having been
+// in From for After.
+type Transition struct {
+	From string
+	To   string
+	// Event is an event name or pattern, as accepted by Eventer.On
+	Event string
+	// Source publishes the events which trigger the transition. When nil,
+	// the events of the Robot the machine was added to are used. Events
+	// passed to StateMachine.Fire trigger the transition whatever its
+	// Source.
+	Source Eventer
+	// After triggers the transition once the machine has been in From for
+	// the duration, instead of on an event
+	After time.Duration
+	// Guard, when set, is called with the event data; the transition is
+	// only taken when it returns true
+	Guard func(data interface{}) bool
+}
+
+type trigger struct {
+	event string
+	data  interface{}
+	// source published the event, which matched the pattern event; nil for
+	// fired events, whose event is their name
+	source     Eventer
+	transition *Transition
+	// entered is the number of the state entry which scheduled a timer
+	// trigger, so timers of states which have since been left are ignored
+	entered int
+}
+
+// StateMachine runs a finite state machine whose transitions are triggered
+// by events and timers. Entry and exit actions and transitions all run on a
+// single goroutine, so actions may safely Fire further events, which are
+// queued without limit and handled once the action returns. Actions must not
+// call Stop, which waits for them to return; they may call it on another
+// goroutine.
+type StateMachine struct {
+	Name    string
+	Initial string
+	Eventer
+
+	mtx         sync.RWMutex
+	states      map[string]State
+	transitions []*Transition
+	current     string
+	entered     int
+	robot       *Robot
+	queue       []trigger
+	wake        chan struct{}
+	stop        chan struct{}
+	done        chan struct{}
+	subs        []*Subscription
+	timers      []*Job
+	scheduler   *Scheduler
+}
+
+// NewStateMachine returns a new StateMachine which starts in the initial
+// state.
+func NewStateMachine(name, initial string) *StateMachine {
+	m := &StateMachine{
+		Name:      name,
+		Initial:   initial,
+		Eventer:   NewEventer(),
+		states:    make(map[string]State),
+		scheduler: NewScheduler(),
+	}
+	m.AddEvent(StateChanged)
+	return m
+}
+
+// AddState adds s to the machine. States without actions need not be added.
+func (m *StateMachine) AddState(s State) *StateMachine {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	m.states[s.Name] = s
+	return m
+}
+
+// AddTransition adds t to the machine. When several transitions match, the
+// one added first is taken.
+func (m *StateMachine) AddTransition(t Transition) *StateMachine {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	m.transitions = append(m.transitions, &t)
+	return m
+}
+
+// State returns the name of the current state, empty before the machine
+// starts.
+func (m *StateMachine) State() string {
+	m.mtx.RLock()
+	defer m.mtx.RUnlock()
+	return m.current
+}
+
+// Fire triggers the transitions from the current state whose Event matches
+// name, as if an event called name had been published with data. It returns
+// an error if the machine is not running. It never blocks, even when called
+// from an action.
+func (m *StateMachine) Fire(name string, data interface{}) error {
+	if !m.send(trigger{event: name, data: data}) {
+		return errors.New("State machine " + m.Name + " is not running")
+	}
+	return nil
+}
+
+// Start enters the initial state and starts listening for the events which
+// trigger transitions. Robots start the machines added to them.
+func (m *StateMachine) Start() error {
+	m.mtx.Lock()
+	for _, t := range m.transitions {
+		if _, err := MatchPattern(t.Event); t.Event != "" && err != nil {
+			m.mtx.Unlock()
+			return fmt.Errorf("State machine %q: %w", m.Name, err)
+		}
+	}
+	if m.wake != nil {
+		m.mtx.Unlock()
+		return nil
+	}
+	m.queue = nil
+	m.wake = make(chan struct{}, 1)
+	m.stop = make(chan struct{})
+	m.done = make(chan struct{})
+	m.current = ""
+	transitions := m.transitions
+	robot := m.robot
+	m.mtx.Unlock()
+
+	// one subscription for each source and pattern, so each event is
+	// matched against the transitions once
+	type watch struct {
+		source  Eventer
+		pattern string
+	}
+	watched := make(map[watch]bool)
+	for _, t := range transitions {
+		source := transitionSource(t, robot)
+		if t.Event == "" || source == nil || watched[watch{source, t.Event}] {
+			continue
+		}
+		watched[watch{source, t.Event}] = true
+
+		pattern := t.Event
+		sub, _ := source.On(pattern, func(data interface{}) {
+			m.send(trigger{event: pattern, data: data, source: source})
+		})
+		m.mtx.Lock()
+		m.subs = append(m.subs, sub)
+		m.mtx.Unlock()
+	}
+
+	go m.loop(m.wake, m.stop, m.done)
+	return nil
+}
+
+// Stop stops listening for events, cancels pending timers and drops queued
+// events, and waits for a running action to return, so it must not be called
+// from an action. The current state is left without running its exit action.
+func (m *StateMachine) Stop() {
+	m.mtx.Lock()
+	stop, done, subs, timers := m.stop, m.done, m.subs, m.timers
+	m.queue, m.wake, m.stop, m.subs, m.timers = nil, nil, nil, nil, nil
+	m.mtx.Unlock()
+
+	if stop == nil {
+		return
+	}
+	for _, sub := range subs {
+		sub.Cancel()
+	}
+	for _, t := range timers {
+		t.Cancel()
+	}
+	close(stop)
+	<-done
+}
+
+// send queues tr and wakes the loop, returning false if the machine is not
+// running.
+func (m *StateMachine) send(tr trigger) bool {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	if m.wake == nil {
+		return false
+	}
+	m.queue = append(m.queue, tr)
+	select {
+	case m.wake <- struct{}{}:
+	default:
+	}
+	return true
+}
+
+// next takes the first queued trigger, if any, unless the loop woken by wake
+// has been stopped.
+func (m *StateMachine) next(wake chan struct{}) (tr trigger, ok bool) {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	if m.wake != wake || len(m.queue) == 0 {
+		return tr, false
+	}
+	tr, m.queue = m.queue[0], m.queue[1:]
+	return tr, true
+}
+
+func (m *StateMachine) loop(wake, stop, done chan struct{}) {
+	defer close(done)
+	m.enter(m.Initial, "")
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-wake:
+			for tr, ok := m.next(wake); ok; tr, ok = m.next(wake) {
+				if t := m.match(tr); t != nil {
+					m.enter(t.To, tr.event)
+				}
+			}
+		}
+	}
+}
+
+// transitionSource returns the Eventer publishing the events which trigger
+// t, or nil if there is none.
+func transitionSource(t *Transition, robot *Robot) Eventer {
+	if t.Source == nil && robot != nil {
+		return robot.Eventer
+	}
+	return t.Source
+}
+
+// match returns the transition taken for tr from the current state, if any.
+func (m *StateMachine) match(tr trigger) *Transition {
+	m.mtx.RLock()
+	current, entered, transitions, robot := m.current, m.entered, m.transitions, m.robot
+	m.mtx.RUnlock()
+
+	from := func(t *Transition) bool {
+		return t.From == current || t.From == AnyState
+	}
+	guard := func(t *Transition) bool {
+		return t.Guard == nil || t.Guard(tr.data)
+	}
+
+	if tr.transition != nil {
+		if tr.transition.After > 0 && tr.entered != entered {
+			return nil
+		}
+		if from(tr.transition) && guard(tr.transition) {
+			return tr.transition
+		}
+		return nil
+	}
+
+	for _, t := range transitions {
+		if t.Event == "" || !from(t) {
+			continue
+		}
+		if tr.source != nil {
+			if t.Event != tr.event || transitionSource(t, robot) != tr.source {
+				continue
+			}
+		} else if match, err := MatchPattern(t.Event); err != nil || !match(&Event{Name: tr.event}) {
+			continue
+		}
+		if guard(t) {
+			return t
+		}
+	}
+	return nil
+}
+
+// enter leaves the current state for to, running the exit and entry actions
+// and scheduling the timed transitions of the new state.
+func (m *StateMachine) enter(to, event string) {
+	m.mtx.Lock()
+	from := m.current
+	exit := m.states[from].OnExit
+	entry := m.states[to].OnEnter
+	for _, t := range m.timers {
+		t.Cancel()
+	}
+	m.timers = nil
+	m.current = to
+	m.entered++
+	entered := m.entered
+	robot := m.robot
+
+	for _, t := range m.transitions {
+		if t.After <= 0 || (t.From != to && t.From != AnyState) {
+			continue
+		}
+		tr := trigger{transition: t, entered: entered}
+		m.timers = append(m.timers, m.scheduler.After(t.After, func() { m.send(tr) }))
+	}
+	m.mtx.Unlock()
+
+	if from != "" && exit != nil {
+		exit()
+	}
+	if entry != nil {
+		entry()
+	}
+
+	change := StateChange{Machine: m.Name, From: from, To: to, Event: event}
+	m.Publish(StateChanged, change)
+	if robot != nil {
+		robot.Publish(StateChanged, change)
+		robot.Logger().Debug("State changed", "machine", m.Name, "from", from, "to", to)
+	}
+}
+
+// JSONStateMachine is a JSON representation of a StateMachine.
+type JSONStateMachine struct {
+	Name  string `json:"name"`
+	State string `json:"state"`
+}
+
+// AddStateMachine adds m to the Robot. The machine is started with the
+// Robot's work, or at once if the Robot is running, and stopped when the
+// Robot stops. Transitions without a Source are triggered by the Robot's
+// events, and the machine's StateChanged events are also published by the
+// Robot.
+func (r *Robot) AddStateMachine(m *StateMachine) error {
+	m.mtx.Lock()
+	m.robot = r
+	m.mtx.Unlock()
+
+	r.mtx.Lock()
+	r.machines = append(r.machines, m)
+	running := r.running
+	r.mtx.Unlock()
+
+	if running {
+		return m.Start()
+	}
+	return nil
+}
+
+// StateMachine returns the state machine called name, or nil if there is
+// none.
+func (r *Robot) StateMachine(name string) *StateMachine {
+	r.mtx.RLock()
+	defer r.mtx.RUnlock()
+	for _, m := range r.machines {
+		if m.Name == name {
+			return m
+		}
+	}
+	return nil
+}
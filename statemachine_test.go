@@ -0,0 +1,139 @@
+package gobot
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hybridgroup/gobot/gobottest"
+)
+
+// waitState returns the next StateChange published by m.
+func waitState(t *testing.T, changes eventChannel) StateChange {
+	select {
+	case evt := <-changes:
+		return evt.Data.(StateChange)
+	case <-time.After(time.Second):
+		t.Fatal("state did not change")
+	}
+	return StateChange{}
+}
+
+func TestStateMachineFire(t *testing.T) {
+	var log []string
+	m := NewStateMachine("door", "closed")
+	m.AddState(State{
+		Name:    "closed",
+		OnEnter: func() { log = append(log, "enter closed") },
+		OnExit:  func() { log = append(log, "exit closed") },
+	})
+	m.AddState(State{Name: "open", OnEnter: func() { log = append(log, "enter open") }})
+	m.AddTransition(Transition{From: "closed", To: "open", Event: "push"})
+	m.AddTransition(Transition{From: "open", To: "closed", Event: "pull"})
+	m.AddTransition(Transition{From: AnyState, To: "locked", Event: "lock",
+		Guard: func(data interface{}) bool { return data == "key" }})
+	changes := m.Subscribe()
+
+	gobottest.Assert(t, m.Fire("push", nil).Error(), "State machine door is not running")
+	gobottest.Assert(t, m.Start(), nil)
+	gobottest.Assert(t, waitState(t, changes), StateChange{Machine: "door", To: "closed"})
+
+	m.Fire("pull", nil)
+	m.Fire("push", nil)
+	gobottest.Assert(t, waitState(t, changes), StateChange{Machine: "door", From: "closed", To: "open", Event: "push"})
+	m.Fire("lock", "card")
+	m.Fire("lock", "key")
+	gobottest.Assert(t, waitState(t, changes), StateChange{Machine: "door", From: "open", To: "locked", Event: "lock"})
+	gobottest.Assert(t, m.State(), "locked")
+
+	m.Stop()
+	m.Stop()
+	gobottest.Assert(t, log, []string{"enter closed", "exit closed", "enter open"})
+}
+
+func TestStateMachineAfter(t *testing.T) {
+	m := NewStateMachine("blink", "on")
+	m.AddTransition(Transition{From: "on", To: "off", After: time.Millisecond})
+	m.AddTransition(Transition{From: "off", To: "on", Event: "wake"})
+	m.AddTransition(Transition{From: "off", To: "asleep", After: time.Hour})
+	changes := m.Subscribe()
+
+	m.Start()
+	defer m.Stop()
+	waitState(t, changes)
+	gobottest.Assert(t, waitState(t, changes), StateChange{Machine: "blink", From: "on", To: "off"})
+	m.Fire("wake", nil)
+	gobottest.Assert(t, waitState(t, changes).To, "on")
+	gobottest.Assert(t, waitState(t, changes).To, "off")
+}
+
+func TestStateMachineBadPattern(t *testing.T) {
+	m := NewStateMachine("bad", "idle")
+	m.AddTransition(Transition{From: "idle", To: "busy", Event: "[push"})
+	gobottest.Refute(t, m.Start(), nil)
+}
+
+func TestRobotStateMachine(t *testing.T) {
+	r := newTestRobot("Robot99")
+	r.AddEvent("bump")
+	source := NewEventer()
+	m := NewStateMachine("mode", "roaming")
+	m.AddTransition(Transition{From: "roaming", To: "reversing", Event: "bump"})
+	m.AddTransition(Transition{From: "reversing", To: "roaming", Event: "clear", Source: source})
+	gobottest.Assert(t, r.AddStateMachine(m), nil)
+	gobottest.Assert(t, r.StateMachine("mode"), m)
+	gobottest.Assert(t, r.StateMachine("none"), (*StateMachine)(nil))
+
+	changes := r.Subscribe(SubscriberOptions{Buffer: 8, Filter: func(e *Event) bool { return e.Name == StateChanged }})
+	gobottest.Assert(t, len(r.Start()), 0)
+	gobottest.Assert(t, waitState(t, changes).To, "roaming")
+
+	r.Publish("bump", nil)
+	gobottest.Assert(t, waitState(t, changes).To, "reversing")
+	source.Publish("clear", nil)
+	gobottest.Assert(t, waitState(t, changes).To, "roaming")
+	gobottest.Assert(t, NewJSONRobot(r).StateMachines, []*JSONStateMachine{{Name: "mode", State: "roaming"}})
+
+	r.Stop()
+	gobottest.Refute(t, m.Fire("bump", nil), nil)
+}
+
+func TestStateMachineFireFromAction(t *testing.T) {
+	m := NewStateMachine("counter", "idle")
+	count := 0
+	m.AddState(State{Name: "idle", OnEnter: func() {
+		// more events than any buffer would hold, fired before the action returns
+		for i := 0; i < 4*DefaultBufferSize; i++ {
+			m.Fire("tick", nil)
+		}
+	}})
+	m.AddState(State{Name: "counting", OnEnter: func() { count++ }})
+	m.AddTransition(Transition{From: AnyState, To: "counting", Event: "tick"})
+	changes := m.Subscribe(SubscriberOptions{Buffer: 8 * DefaultBufferSize})
+
+	gobottest.Assert(t, m.Start(), nil)
+	for i := 0; i <= 4*DefaultBufferSize; i++ {
+		waitState(t, changes)
+	}
+	m.Stop()
+	gobottest.Assert(t, count, 4*DefaultBufferSize)
+}
+
+func TestStateMachineOneTransitionPerEvent(t *testing.T) {
+	source := NewEventer()
+	m := NewStateMachine("steps", "a")
+	m.AddTransition(Transition{From: "a", To: "b", Event: "x", Source: source})
+	m.AddTransition(Transition{From: "b", To: "c", Event: "x", Source: source})
+	m.AddTransition(Transition{From: AnyState, To: "a", Event: "reset"})
+	changes := m.Subscribe()
+
+	gobottest.Assert(t, m.Start(), nil)
+	defer m.Stop()
+	waitState(t, changes)
+	for i := 0; i < 100; i++ {
+		// one event takes one step, and the reset is the next change
+		source.Publish("x", nil)
+		gobottest.Assert(t, waitState(t, changes).To, "b")
+		m.Fire("reset", nil)
+		gobottest.Assert(t, waitState(t, changes).To, "a")
+	}
+}
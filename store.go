@@ -0,0 +1,267 @@
+package gobot
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Store is the interface which describes where Gobot, its robots and their
+// drivers and adaptors keep state, such as calibrations, counters and
+// last-known values, which should outlive a restart. Values are stored as
+// JSON, so they are decoded into v as they would be by json.Unmarshal.
+type Store interface {
+	// Get decodes the value stored under key into v, returning false if
+	// there is none
+	Get(key string, v interface{}) (ok bool, err error)
+	// Set stores value under key, replacing any value already there
+	Set(key string, value interface{}) error
+	// Delete removes the value stored under key, if any
+	Delete(key string) error
+	// Keys returns the keys which have values, sorted
+	Keys() []string
+}
+
+// Storable is the interface that describes a driver or adaptor which keeps
+// state in a Store. Robots give each Storable device and connection a Store
+// scoped to the robot's name and the device's or connection's name.
+type Storable interface {
+	SetStore(s Store)
+}
+
+// Storage implements Storable, and can be embedded in drivers and adaptors.
+// Until SetStore is called, Store returns a MemoryStore of its own.
+type Storage struct {
+	mtx   sync.Mutex
+	store Store
+}
+
+// SetStore sets the Store to use.
+func (s *Storage) SetStore(store Store) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	s.store = store
+}
+
+// Store returns the Store to use.
+func (s *Storage) Store() Store {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	if s.store == nil {
+		s.store = NewMemoryStore()
+	}
+	return s.store
+}
+
+// MemoryStore is a Store which keeps values in memory, for tests and for
+// state which need not outlive the process. It is safe for concurrent use.
+type MemoryStore struct {
+	mtx    sync.RWMutex
+	values map[string]json.RawMessage
+}
+
+// NewMemoryStore returns a new, empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{values: make(map[string]json.RawMessage)}
+}
+
+// Get decodes the value stored under key into v, returning false if there is
+// none.
+func (s *MemoryStore) Get(key string, v interface{}) (ok bool, err error) {
+	s.mtx.RLock()
+	value, ok := s.values[key]
+	s.mtx.RUnlock()
+	if !ok {
+		return false, nil
+	}
+	return true, json.Unmarshal(value, v)
+}
+
+// Set stores value under key.
+func (s *MemoryStore) Set(key string, value interface{}) error {
+	return s.update(func(values map[string]json.RawMessage) error {
+		data, err := json.Marshal(value)
+		if err != nil {
+			return err
+		}
+		values[key] = data
+		return nil
+	})
+}
+
+// Delete removes the value stored under key.
+func (s *MemoryStore) Delete(key string) error {
+	return s.update(func(values map[string]json.RawMessage) error {
+		delete(values, key)
+		return nil
+	})
+}
+
+// Keys returns the keys which have values, sorted.
+func (s *MemoryStore) Keys() []string {
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+	keys := make([]string, 0, len(s.values))
+	for key := range s.values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// update calls f with the values locked for writing.
+func (s *MemoryStore) update(f func(map[string]json.RawMessage) error) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	return f(s.values)
+}
+
+// FileStore is a Store which keeps its values in a JSON file, rewriting the
+// file each time a value is set or deleted. It is safe for concurrent use,
+// but not for use by several processes at once.
+type FileStore struct {
+	MemoryStore
+	path string
+}
+
+// NewFileStore returns a FileStore keeping its values in the file at path,
+// loading any values already there. The file is created on the first Set.
+func NewFileStore(path string) (*FileStore, error) {
+	s := &FileStore{path: path}
+	s.values = make(map[string]json.RawMessage)
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err = json.Unmarshal(data, &s.values); err != nil {
+		return nil, err
+	}
+	if s.values == nil {
+		s.values = make(map[string]json.RawMessage)
+	}
+	return s, nil
+}
+
+// Path returns the path of the file the values are kept in.
+func (s *FileStore) Path() string { return s.path }
+
+// Set stores value under key and writes the file.
+func (s *FileStore) Set(key string, value interface{}) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	return s.update(func(values map[string]json.RawMessage) error {
+		previous, ok := values[key]
+		values[key] = data
+		if err := s.save(values); err != nil {
+			if ok {
+				values[key] = previous
+			} else {
+				delete(values, key)
+			}
+			return err
+		}
+		return nil
+	})
+}
+
+// Delete removes the value stored under key and writes the file.
+func (s *FileStore) Delete(key string) error {
+	return s.update(func(values map[string]json.RawMessage) error {
+		previous, ok := values[key]
+		if !ok {
+			return nil
+		}
+		delete(values, key)
+		if err := s.save(values); err != nil {
+			values[key] = previous
+			return err
+		}
+		return nil
+	})
+}
+
+// save writes values to a temporary file which then replaces the file at
+// s.path, so the file is never left half written.
+func (s *FileStore) save(values map[string]json.RawMessage) error {
+	data, err := json.MarshalIndent(values, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := s.path + ".tmp"
+	if err = ioutil.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path)
+}
+
+type scopedStore struct {
+	store  Store
+	prefix string
+}
+
+// ScopeStore returns a Store which keeps its values in s, under keys
+// prefixed with prefix. Its Keys are those of s which have the prefix,
+// without it.
+func ScopeStore(s Store, prefix string) Store {
+	return &scopedStore{store: s, prefix: prefix}
+}
+
+func (s *scopedStore) Get(key string, v interface{}) (bool, error) {
+	return s.store.Get(s.prefix+key, v)
+}
+
+func (s *scopedStore) Set(key string, value interface{}) error {
+	return s.store.Set(s.prefix+key, value)
+}
+
+func (s *scopedStore) Delete(key string) error {
+	return s.store.Delete(s.prefix + key)
+}
+
+func (s *scopedStore) Keys() []string {
+	keys := []string{}
+	for _, key := range s.store.Keys() {
+		if strings.HasPrefix(key, s.prefix) {
+			keys = append(keys, strings.TrimPrefix(key, s.prefix))
+		}
+	}
+	return keys
+}
+
+// DefaultStorePath is the path of the file DefaultStore keeps its values in,
+// unless the GOBOT_STORE environment variable names another. It is read the
+// first time DefaultStore is called.
+var DefaultStorePath = "gobot_store.json"
+
+var (
+	defaultStoreOnce sync.Once
+	defaultStore     Store
+)
+
+// DefaultStore returns the Store used when none is set. It is a FileStore at
+// DefaultStorePath, so values outlive a restart. If the file cannot be read,
+// the error is logged and values are kept in memory instead.
+func DefaultStore() Store {
+	defaultStoreOnce.Do(func() {
+		path := DefaultStorePath
+		if env := os.Getenv("GOBOT_STORE"); env != "" {
+			path = env
+		}
+		store, err := NewFileStore(path)
+		if err != nil {
+			DefaultLogger().Error("Opening store failed, keeping values in memory", "path", path, "error", err)
+			defaultStore = NewMemoryStore()
+			return
+		}
+		defaultStore = store
+	})
+	return defaultStore
+}
@@ -0,0 +1,129 @@
+package gobot
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hybridgroup/gobot/gobottest"
+)
+
+type testCalibration struct {
+	Offset int     `json:"offset"`
+	Scale  float64 `json:"scale"`
+}
+
+func testStore(t *testing.T, s Store) {
+	var c testCalibration
+	ok, err := s.Get("calibration", &c)
+	gobottest.Assert(t, ok, false)
+	gobottest.Assert(t, err, nil)
+
+	gobottest.Assert(t, s.Set("calibration", testCalibration{Offset: 3, Scale: 1.5}), nil)
+	gobottest.Assert(t, s.Set("count", 7), nil)
+	ok, err = s.Get("calibration", &c)
+	gobottest.Assert(t, ok, true)
+	gobottest.Assert(t, c, testCalibration{Offset: 3, Scale: 1.5})
+	gobottest.Assert(t, s.Keys(), []string{"calibration", "count"})
+
+	var count string
+	_, err = s.Get("count", &count)
+	gobottest.Refute(t, err, nil)
+
+	gobottest.Assert(t, s.Delete("count"), nil)
+	gobottest.Assert(t, s.Delete("count"), nil)
+	gobottest.Assert(t, s.Keys(), []string{"calibration"})
+
+	gobottest.Refute(t, s.Set("bad", make(chan int)), nil)
+	gobottest.Assert(t, s.Keys(), []string{"calibration"})
+}
+
+func TestMemoryStore(t *testing.T) {
+	testStore(t, NewMemoryStore())
+}
+
+func TestFileStore(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "gobot")
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "state.json")
+
+	s, err := NewFileStore(path)
+	gobottest.Assert(t, err, nil)
+	gobottest.Assert(t, s.Path(), path)
+	testStore(t, s)
+
+	// values outlive the store
+	s, err = NewFileStore(path)
+	gobottest.Assert(t, err, nil)
+	var c testCalibration
+	ok, _ := s.Get("calibration", &c)
+	gobottest.Assert(t, ok, true)
+	gobottest.Assert(t, c.Offset, 3)
+	_, err = os.Stat(path + ".tmp")
+	gobottest.Assert(t, os.IsNotExist(err), true)
+
+	// values are kept when the file cannot be written
+	s, _ = NewFileStore(filepath.Join(dir, "missing", "state.json"))
+	gobottest.Refute(t, s.Set("count", 1), nil)
+	gobottest.Assert(t, s.Keys(), []string{})
+
+	ioutil.WriteFile(path, []byte("{"), 0644)
+	_, err = NewFileStore(path)
+	gobottest.Refute(t, err, nil)
+}
+
+func TestScopeStore(t *testing.T) {
+	base := NewMemoryStore()
+	s := ScopeStore(base, "bot/")
+	testStore(t, s)
+	gobottest.Assert(t, base.Keys(), []string{"bot/calibration"})
+
+	ScopeStore(s, "led/").Set("level", 1)
+	gobottest.Assert(t, base.Keys(), []string{"bot/calibration", "bot/led/level"})
+	gobottest.Assert(t, s.Keys(), []string{"calibration", "led/level"})
+}
+
+type testStorableDriver struct {
+	*testDriver
+	Storage
+}
+
+func TestRobotStore(t *testing.T) {
+	driver := &testStorableDriver{testDriver: newTestDriver(newTestAdaptor("Connection1", "/dev/null"), "led", "13")}
+	gobottest.Assert(t, driver.Store().Keys(), []string{})
+
+	r := NewRobot("storebot", []Device{driver})
+	r.SetStore(NewMemoryStore())
+	r.Store().Set("count", 1)
+	driver.Store().Set("level", 2)
+	gobottest.Assert(t, r.Store().Keys(), []string{"count", "led/level"})
+
+	g := NewGobot()
+	store := NewMemoryStore()
+	g.SetStore(store)
+	gobottest.Assert(t, g.Store(), Store(store))
+	r = NewRobot("storebot", []Device{driver})
+	g.AddRobot(r)
+	driver.Store().Set("level", 3)
+	gobottest.Assert(t, store.Keys(), []string{"storebot/led/level"})
+
+	// robots without a Store of their own follow their Gobot's
+	other := NewMemoryStore()
+	g.SetStore(other)
+	driver.Store().Set("level", 4)
+	gobottest.Assert(t, other.Keys(), []string{"storebot/led/level"})
+
+	gobottest.Assert(t, NewGobot().Store(), DefaultStore())
+}
+
+func TestDefaultStore(t *testing.T) {
+	path := DefaultStorePath
+	if env := os.Getenv("GOBOT_STORE"); env != "" {
+		path = env
+	}
+	store, ok := DefaultStore().(*FileStore)
+	gobottest.Assert(t, ok, true)
+	gobottest.Assert(t, store.Path(), path)
+	gobottest.Assert(t, DefaultStore(), Store(store))
+}
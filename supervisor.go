@@ -0,0 +1,357 @@
+package gobot
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Events published on a supervised Robot's Eventer. The event data is the
+// name of the Connection.
+const (
+	// ConnectionLost is published when a Connection fails. Adaptors which
+	// implement Eventer may also publish it to report that they have failed.
+	ConnectionLost = "connection-lost"
+	// Reconnected is published once a failed Connection and the devices
+	// which depend on it have been restarted
+	Reconnected = "reconnected"
+	// ReconnectFailed is published when a Connection could not be
+	// reconnected within Supervision.Retries attempts
+	ReconnectFailed = "reconnect-failed"
+)
+
+// ConnectionState describes the health of a supervised Connection.
+type ConnectionState string
+
+const (
+	// Connected means the Connection is up
+	Connected ConnectionState = "connected"
+	// Reconnecting means the Connection failed and is being reconnected
+	Reconnecting ConnectionState = "reconnecting"
+	// ConnectionFailed means reconnecting was given up
+	ConnectionFailed ConnectionState = "failed"
+)
+
+const (
+	// DefaultBackoff is the delay before the first reconnect attempt when
+	// Supervision.Backoff is not set.
+	DefaultBackoff = 1 * time.Second
+	// DefaultMaxBackoff is the longest delay between reconnect attempts when
+	// Supervision.MaxBackoff is not set.
+	DefaultMaxBackoff = 30 * time.Second
+)
+
+// Supervision configures how a Robot watches its connections once started.
+// A Connection has failed when it implements Pinger and Ping returns an
+// error, or when it implements Eventer and publishes ConnectionLost. The
+// devices which depend on a failed Connection are halted, the Connection
+// is reconnected with exponential backoff, and the devices are started
+// again.
+type Supervision struct {
+	// Interval between calls to Ping. Zero disables pinging.
+	Interval time.Duration
+	// Backoff is the delay before the first reconnect attempt. It doubles
+	// after each failed attempt, up to MaxBackoff.
+	Backoff time.Duration
+	// MaxBackoff is the longest delay between reconnect attempts
+	MaxBackoff time.Duration
+	// Retries is the number of reconnect attempts before giving up, zero
+	// meaning retry until the Robot is stopped
+	Retries int
+}
+
+type supervisor struct {
+	robot  *Robot
+	config Supervision
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	mtx    sync.RWMutex
+	states map[Connection]ConnectionState
+	subs   map[Connection]*Subscription
+	// halted and finalized hold the devices and connections the supervisor
+	// has stopped and not yet restarted, so the Robot does not stop them
+	// again
+	halted    map[Device]bool
+	finalized map[Connection]bool
+}
+
+func newSupervisor(r *Robot, config Supervision) *supervisor {
+	if config.Backoff <= 0 {
+		config.Backoff = DefaultBackoff
+	}
+	if config.MaxBackoff <= 0 {
+		config.MaxBackoff = DefaultMaxBackoff
+	}
+	if config.MaxBackoff < config.Backoff {
+		config.MaxBackoff = config.Backoff
+	}
+
+	s := &supervisor{
+		robot:  r,
+		config: config,
+		states: make(map[Connection]ConnectionState),
+		subs:   make(map[Connection]*Subscription),
+
+		halted:    make(map[Device]bool),
+		finalized: make(map[Connection]bool),
+	}
+	s.ctx, s.cancel = context.WithCancel(context.Background())
+	return s
+}
+
+// start begins watching every Connection of the robot.
+func (s *supervisor) start() {
+	s.robot.Connections().Each(s.watch)
+
+	if s.config.Interval > 0 {
+		s.wg.Add(1)
+		go s.ping()
+	}
+}
+
+// stop stops watching and waits for any reconnect in progress to give up.
+func (s *supervisor) stop() {
+	s.mtx.Lock()
+	s.cancel()
+	subs := s.subs
+	s.subs = make(map[Connection]*Subscription)
+	s.mtx.Unlock()
+
+	for _, sub := range subs {
+		sub.Cancel()
+	}
+	s.wg.Wait()
+}
+
+// watch starts supervising c.
+func (s *supervisor) watch(c Connection) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	s.states[c] = Connected
+	if e, ok := c.(Eventer); ok {
+		if sub, err := e.On(ConnectionLost, func(interface{}) { s.lost(c) }); err == nil {
+			s.subs[c] = sub
+		}
+	}
+}
+
+// unwatch stops supervising c, abandoning any reconnect in progress.
+func (s *supervisor) unwatch(c Connection) {
+	s.mtx.Lock()
+	sub := s.subs[c]
+	delete(s.subs, c)
+	delete(s.states, c)
+	s.mtx.Unlock()
+
+	if sub != nil {
+		sub.Cancel()
+	}
+}
+
+func (s *supervisor) state(c Connection) ConnectionState {
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+	return s.states[c]
+}
+
+// setState sets the state of c, unless it is no longer supervised.
+func (s *supervisor) setState(c Connection, state ConnectionState) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	if _, ok := s.states[c]; ok {
+		s.states[c] = state
+	}
+}
+
+func (s *supervisor) ping() {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(s.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-ticker.C:
+			s.robot.Connections().Each(func(c Connection) {
+				p, ok := c.(Pinger)
+				if !ok || s.state(c) != Connected {
+					return
+				}
+				if err := p.Ping(); err != nil {
+					s.robot.Logger().Warn("Ping failed", "connection", c.Name(), "error", err)
+					s.lost(c)
+				}
+			})
+		}
+	}
+}
+
+// lost starts reconnecting c, unless it is already being reconnected or the
+// supervisor has stopped.
+func (s *supervisor) lost(c Connection) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	if s.ctx.Err() != nil || s.states[c] != Connected {
+		return
+	}
+	s.states[c] = Reconnecting
+	s.wg.Add(1)
+	go s.reconnect(c)
+}
+
+func (s *supervisor) reconnect(c Connection) {
+	defer s.wg.Done()
+
+	r := s.robot
+	logger := r.Logger().With("connection", c.Name())
+	logger.Warn("Connection lost, reconnecting")
+	r.Publish(ConnectionLost, c.Name())
+
+	devices := s.dependents(c)
+	s.halt(devices)
+	s.finalize(c)
+
+	backoff := s.config.Backoff
+	for attempt := 1; ; attempt++ {
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		if s.state(c) == "" {
+			return
+		}
+
+		errs := s.restart(c, devices)
+		result := "success"
+		if len(errs) > 0 {
+			result = "failure"
+		}
+		r.Registry().Counter("gobot_reconnects_total", "Attempts to reconnect failed connections.",
+			Labels{"robot": r.Name, "connection": c.Name(), "result": result}).Inc()
+		if len(errs) == 0 {
+			logger.Info("Connection reconnected")
+			s.setState(c, Connected)
+			r.Publish(Reconnected, c.Name())
+			return
+		}
+		logger.Warn("Reconnect failed", "attempt", attempt, "errors", errs)
+
+		if s.config.Retries > 0 && attempt >= s.config.Retries {
+			logger.Error("Connection could not be reconnected")
+			s.setState(c, ConnectionFailed)
+			r.Publish(ReconnectFailed, c.Name())
+			return
+		}
+
+		if backoff *= 2; backoff > s.config.MaxBackoff {
+			backoff = s.config.MaxBackoff
+		}
+	}
+}
+
+// restart connects c and starts devices. When a device fails to start, the
+// devices which did start and c are stopped again so the next attempt starts
+// afresh.
+func (s *supervisor) restart(c Connection, devices []Device) (errs []error) {
+	r := s.robot
+	if errs = connect(s.ctx, r.Timeouts.Connect, c); len(errs) > 0 {
+		return
+	}
+	s.setFinalized(c, false)
+	for i, device := range devices {
+		if errs = start(s.ctx, r.Timeouts.Start, device); len(errs) > 0 {
+			s.halt(devices[:i])
+			s.finalize(c)
+			return
+		}
+		s.setHalted(device, false)
+	}
+	return
+}
+
+// halt halts devices, which are then left to the supervisor until they are
+// restarted. A device which fails to halt is not halted again.
+func (s *supervisor) halt(devices []Device) {
+	for _, device := range devices {
+		if errs := halt(s.ctx, s.robot.Timeouts.Halt, device); len(errs) > 0 {
+			s.robot.Logger().Warn("Halt failed", "device", device.Name(), "errors", errs)
+		}
+		s.setHalted(device, true)
+	}
+}
+
+// finalize finalizes c, which is then left to the supervisor until it is
+// reconnected.
+func (s *supervisor) finalize(c Connection) {
+	if errs := finalize(s.ctx, s.robot.Timeouts.Finalize, c); len(errs) > 0 {
+		s.robot.Logger().Warn("Finalize failed", "connection", c.Name(), "errors", errs)
+	}
+	s.setFinalized(c, true)
+}
+
+// setHalted records whether the supervisor has halted device.
+func (s *supervisor) setHalted(device Device, halted bool) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	if halted {
+		s.halted[device] = true
+	} else {
+		delete(s.halted, device)
+	}
+}
+
+// setFinalized records whether the supervisor has finalized c.
+func (s *supervisor) setFinalized(c Connection, finalized bool) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	if finalized {
+		s.finalized[c] = true
+	} else {
+		delete(s.finalized, c)
+	}
+}
+
+// isHalted reports whether the supervisor has halted device.
+func (s *supervisor) isHalted(device Device) bool {
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+	return s.halted[device]
+}
+
+// running returns devices and connections without those the supervisor has
+// stopped.
+func (s *supervisor) running(devices Devices, connections Connections) (Devices, Connections) {
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+
+	var d Devices
+	for _, device := range devices {
+		if !s.halted[device] {
+			d = append(d, device)
+		}
+	}
+	var c Connections
+	for _, connection := range connections {
+		if !s.finalized[connection] {
+			c = append(c, connection)
+		}
+	}
+	return d, c
+}
+
+// dependents returns the devices of the robot which use c.
+func (s *supervisor) dependents(c Connection) (devices []Device) {
+	s.robot.Devices().Each(func(d Device) {
+		if d.Connection() == c {
+			devices = append(devices, d)
+		}
+	})
+	return
+}
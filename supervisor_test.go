@@ -0,0 +1,282 @@
+package gobot
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/hybridgroup/gobot/gobottest"
+)
+
+type testSupervisedAdaptor struct {
+	*testAdaptor
+	Eventer
+	mtx      sync.Mutex
+	pingErr  error
+	connects int32
+	failures int32
+}
+
+func (t *testSupervisedAdaptor) Connect() (errs []error) {
+	atomic.AddInt32(&t.connects, 1)
+	if atomic.LoadInt32(&t.failures) > 0 {
+		atomic.AddInt32(&t.failures, -1)
+		return []error{errors.New("connect error")}
+	}
+	return
+}
+
+func (t *testSupervisedAdaptor) Ping() error {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+	return t.pingErr
+}
+
+func (t *testSupervisedAdaptor) setPingErr(err error) {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+	t.pingErr = err
+}
+
+type testSupervisedDriver struct {
+	*testDriver
+	starts   int32
+	halts    int32
+	failures int32
+}
+
+func (t *testSupervisedDriver) Start() (errs []error) {
+	atomic.AddInt32(&t.starts, 1)
+	if atomic.LoadInt32(&t.failures) > 0 {
+		atomic.AddInt32(&t.failures, -1)
+		return []error{errors.New("start error")}
+	}
+	return
+}
+
+func (t *testSupervisedDriver) Halt() (errs []error) {
+	atomic.AddInt32(&t.halts, 1)
+	return
+}
+
+func initTestSupervisedRobot(s *Supervision) (*Robot, *testSupervisedAdaptor, *testSupervisedDriver, *testSupervisedDriver) {
+	adaptor := &testSupervisedAdaptor{
+		testAdaptor: newTestAdaptor("Connection1", "/dev/null"),
+		Eventer:     NewEventer(),
+	}
+	other := newTestAdaptor("Connection2", "/dev/null")
+	driver := &testSupervisedDriver{testDriver: &testDriver{name: "Device1", connection: adaptor, Commander: NewCommander()}}
+	otherDriver := &testSupervisedDriver{testDriver: &testDriver{name: "Device2", connection: other, Commander: NewCommander()}}
+
+	r := NewRobot("Robot1",
+		[]Connection{adaptor, other},
+		[]Device{driver, otherDriver},
+	)
+	r.Supervision = s
+	return r, adaptor, driver, otherDriver
+}
+
+func TestRobotSupervisionPing(t *testing.T) {
+	r, adaptor, driver, otherDriver := initTestSupervisedRobot(&Supervision{
+		Interval: 5 * time.Millisecond,
+		Backoff:  time.Millisecond,
+	})
+
+	lost := make(chan interface{}, 1)
+	reconnected := make(chan interface{}, 1)
+	r.Once(ConnectionLost, func(data interface{}) { lost <- data })
+	r.Once(Reconnected, func(data interface{}) { reconnected <- data })
+
+	gobottest.Assert(t, len(r.Start()), 0)
+	gobottest.Assert(t, r.ConnectionState("Connection1"), Connected)
+
+	adaptor.setPingErr(errors.New("device unplugged"))
+	select {
+	case data := <-lost:
+		gobottest.Assert(t, data, "Connection1")
+	case <-time.After(time.Second):
+		t.Fatal("connection-lost was not published")
+	}
+
+	adaptor.setPingErr(nil)
+	select {
+	case data := <-reconnected:
+		gobottest.Assert(t, data, "Connection1")
+	case <-time.After(time.Second):
+		t.Fatal("reconnected was not published")
+	}
+
+	gobottest.Assert(t, r.ConnectionState("Connection1"), Connected)
+	gobottest.Assert(t, atomic.LoadInt32(&adaptor.connects), int32(2))
+	gobottest.Assert(t, atomic.LoadInt32(&driver.halts), int32(1))
+	gobottest.Assert(t, atomic.LoadInt32(&driver.starts), int32(2))
+	gobottest.Assert(t, atomic.LoadInt32(&otherDriver.halts), int32(0))
+	gobottest.Assert(t, atomic.LoadInt32(&otherDriver.starts), int32(1))
+
+	gobottest.Assert(t, len(r.Stop()), 0)
+	gobottest.Assert(t, r.ConnectionState("Connection1"), ConnectionState(""))
+}
+
+func TestRobotSupervisionLostEvent(t *testing.T) {
+	r, adaptor, _, _ := initTestSupervisedRobot(&Supervision{Backoff: time.Millisecond})
+
+	reconnected := make(chan interface{}, 1)
+	r.Once(Reconnected, func(data interface{}) { reconnected <- data })
+
+	gobottest.Assert(t, len(r.Start()), 0)
+	atomic.StoreInt32(&adaptor.failures, 1)
+
+	adaptor.Publish(ConnectionLost, nil)
+	select {
+	case <-reconnected:
+	case <-time.After(time.Second):
+		t.Fatal("reconnected was not published")
+	}
+	gobottest.Assert(t, atomic.LoadInt32(&adaptor.connects), int32(3))
+
+	gobottest.Assert(t, len(r.Stop()), 0)
+}
+
+func TestRobotSupervisionRetries(t *testing.T) {
+	r, adaptor, driver, _ := initTestSupervisedRobot(&Supervision{
+		Backoff:    time.Millisecond,
+		MaxBackoff: 2 * time.Millisecond,
+		Retries:    3,
+	})
+
+	failed := make(chan interface{}, 1)
+	r.Once(ReconnectFailed, func(data interface{}) { failed <- data })
+
+	gobottest.Assert(t, len(r.Start()), 0)
+	atomic.StoreInt32(&adaptor.failures, 100)
+	adaptor.Publish(ConnectionLost, nil)
+
+	select {
+	case data := <-failed:
+		gobottest.Assert(t, data, "Connection1")
+	case <-time.After(time.Second):
+		t.Fatal("reconnect-failed was not published")
+	}
+	gobottest.Assert(t, r.ConnectionState("Connection1"), ConnectionFailed)
+	gobottest.Assert(t, atomic.LoadInt32(&adaptor.connects), int32(4))
+	gobottest.Assert(t, atomic.LoadInt32(&driver.starts), int32(1))
+
+	gobottest.Assert(t, len(r.Stop()), 0)
+}
+
+func TestRobotSupervisionStop(t *testing.T) {
+	r, adaptor, _, _ := initTestSupervisedRobot(&Supervision{Backoff: time.Hour})
+
+	gobottest.Assert(t, len(r.Start()), 0)
+	adaptor.Publish(ConnectionLost, nil)
+
+	deadline := time.Now().Add(time.Second)
+	for r.ConnectionState("Connection1") != Reconnecting {
+		if time.Now().After(deadline) {
+			t.Fatal("connection did not start reconnecting")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		r.Stop()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Stop did not end the reconnect")
+	}
+}
+
+func TestRobotUnsupervised(t *testing.T) {
+	r, _, _, _ := initTestSupervisedRobot(nil)
+	gobottest.Assert(t, len(r.Start()), 0)
+	gobottest.Assert(t, r.ConnectionState("Connection1"), ConnectionState(""))
+	gobottest.Assert(t, len(r.Stop()), 0)
+}
+
+// testPollingDriver halts like the gpio drivers, handing off to the polling
+// goroutine started by Start, so a second Halt blocks forever.
+type testPollingDriver struct {
+	*testDriver
+	halt chan bool
+}
+
+func (t *testPollingDriver) Start() (errs []error) {
+	go func() { <-t.halt }()
+	return
+}
+
+func (t *testPollingDriver) Halt() (errs []error) {
+	t.halt <- true
+	return
+}
+
+func TestRobotSupervisionStopFailed(t *testing.T) {
+	adaptor := &testSupervisedAdaptor{
+		testAdaptor: newTestAdaptor("Connection1", "/dev/null"),
+		Eventer:     NewEventer(),
+	}
+	driver := &testPollingDriver{
+		testDriver: &testDriver{name: "Device1", connection: adaptor, Commander: NewCommander()},
+		halt:       make(chan bool),
+	}
+	r := NewRobot("Robot1", []Connection{adaptor}, []Device{driver})
+	r.Supervision = &Supervision{Backoff: time.Millisecond, Retries: 1}
+
+	failed := make(chan interface{}, 1)
+	r.Once(ReconnectFailed, func(data interface{}) { failed <- data })
+
+	gobottest.Assert(t, len(r.Start()), 0)
+	atomic.StoreInt32(&adaptor.failures, 100)
+	adaptor.Publish(ConnectionLost, nil)
+	select {
+	case <-failed:
+	case <-time.After(time.Second):
+		t.Fatal("reconnect-failed was not published")
+	}
+	gobottest.Assert(t, r.ConnectionState("Connection1"), ConnectionFailed)
+
+	done := make(chan []error)
+	go func() { done <- r.Stop() }()
+	select {
+	case errs := <-done:
+		gobottest.Assert(t, len(errs), 0)
+	case <-time.After(time.Second):
+		t.Fatal("Stop halted the device again")
+	}
+}
+
+func TestRobotSupervisionRestartFailure(t *testing.T) {
+	adaptor := &testSupervisedAdaptor{
+		testAdaptor: newTestAdaptor("Connection1", "/dev/null"),
+		Eventer:     NewEventer(),
+	}
+	first := &testPollingDriver{
+		testDriver: &testDriver{name: "Device1", connection: adaptor, Commander: NewCommander()},
+		halt:       make(chan bool),
+	}
+	second := &testSupervisedDriver{testDriver: &testDriver{name: "Device2", connection: adaptor, Commander: NewCommander()}}
+	r := NewRobot("Robot1", []Connection{adaptor}, []Device{first, second})
+	r.Supervision = &Supervision{Backoff: time.Millisecond, Retries: 1}
+
+	failed := make(chan interface{}, 1)
+	r.Once(ReconnectFailed, func(data interface{}) { failed <- data })
+	gobottest.Assert(t, len(r.Start()), 0)
+
+	// the second device fails to start again, so only the first is halted
+	// after the failed attempt
+	atomic.StoreInt32(&second.failures, 100)
+	adaptor.Publish(ConnectionLost, nil)
+	select {
+	case <-failed:
+	case <-time.After(time.Second):
+		t.Fatal("reconnect-failed was not published")
+	}
+	gobottest.Assert(t, atomic.LoadInt32(&second.halts), int32(1))
+	gobottest.Assert(t, len(r.Stop()), 0)
+}
@@ -61,22 +61,31 @@ var notExportedError = errors.New("pin has not been exported")
 
 func (d *digitalPin) Direction(dir string) error {
 	_, err := writeFile(d.direction, []byte(dir))
+	d.record("direction", err)
 	return err
 }
 
 func (d *digitalPin) Write(b int) error {
 	_, err := writeFile(d.value, []byte(strconv.Itoa(b)))
+	d.record("write", err)
 	return err
 }
 
 func (d *digitalPin) Read() (n int, err error) {
 	buf, err := readFile(d.value)
+	d.record("read", err)
 	if err != nil {
 		return 0, err
 	}
 	return strconv.Atoi(string(buf[0]))
 }
 
+// record counts an operation on the pin, and whether it failed, in the
+// Metrics set by SetMetrics.
+func (d *digitalPin) record(op string, err error) {
+	count("gpio", "GPIO", map[string]string{"pin": d.label, "op": op}, err)
+}
+
 func (d *digitalPin) Export() error {
 	export, err := fs.OpenFile(GPIOPATH+"/export", os.O_WRONLY, 0644)
 	if err != nil {
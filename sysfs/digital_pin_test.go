@@ -4,6 +4,7 @@ import (
 	"errors"
 	"os"
 	"syscall"
+	"fmt"
 	"testing"
 
 	"github.com/hybridgroup/gobot/gobottest"
@@ -84,3 +85,37 @@ func TestDigitalPin(t *testing.T) {
 	err = pin.Export()
 	gobottest.Assert(t, err.(*os.PathError).Err, errors.New("write error"))
 }
+
+// testMetrics counts operations by name and labels.
+type testMetrics map[string]float64
+
+func (m testMetrics) Count(name, help string, labels map[string]string, v float64) {
+	m[fmt.Sprintf("%v %v", name, labels)] += v
+}
+
+func TestDigitalPinMetrics(t *testing.T) {
+	m := testMetrics{}
+	SetMetrics(m)
+	defer SetMetrics(nil)
+	// adaptors created later do not replace the Metrics set
+	SetDefaultMetrics(testMetrics{})
+	pin := NewDigitalPin(7, "metrics7")
+
+	writeFile = func(File, []byte) (int, error) {
+		return 0, &os.PathError{Err: errors.New("write error")}
+	}
+	pin.Write(1)
+
+	writeFile = func(File, []byte) (int, error) {
+		return 1, nil
+	}
+	pin.Write(1)
+	pin.Read()
+
+	gobottest.Assert(t, m, testMetrics{
+		"gobot_gpio_operations_total map[op:write pin:metrics7]": 2,
+		"gobot_gpio_errors_total map[op:write pin:metrics7]":     1,
+		"gobot_gpio_operations_total map[op:read pin:metrics7]":  1,
+		"gobot_gpio_errors_total map[op:read pin:metrics7]":      1,
+	})
+}
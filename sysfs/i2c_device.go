@@ -34,14 +34,16 @@ type I2cDevice interface {
 }
 
 type i2cDevice struct {
-	file  File
-	funcs uint64 // adapter functionality mask
+	file     File
+	funcs    uint64 // adapter functionality mask
+	location string
+	address  int
 }
 
 // NewI2cDevice returns an io.ReadWriteCloser with the proper ioctrl given
 // an i2c bus location and device address
 func NewI2cDevice(location string, address int) (d *i2cDevice, err error) {
-	d = &i2cDevice{}
+	d = &i2cDevice{location: location}
 
 	if d.file, err = OpenFile(location, os.O_RDWR, os.ModeExclusive); err != nil {
 		return
@@ -79,6 +81,8 @@ func (d *i2cDevice) SetAddress(address int) (err error) {
 
 	if errno != 0 {
 		err = fmt.Errorf("Setting address failed with syscall.Errno %v", errno)
+	} else {
+		d.address = address
 	}
 
 	return
@@ -89,6 +93,24 @@ func (d *i2cDevice) Close() (err error) {
 }
 
 func (d *i2cDevice) Read(b []byte) (n int, err error) {
+	n, err = d.read(b)
+	d.record("read", err)
+	return
+}
+
+func (d *i2cDevice) Write(b []byte) (n int, err error) {
+	n, err = d.write(b)
+	d.record("write", err)
+	return
+}
+
+// record counts an operation on the device, and whether it failed, in the
+// Metrics set by SetMetrics.
+func (d *i2cDevice) record(op string, err error) {
+	count("i2c", "I2C", map[string]string{"bus": d.location, "address": fmt.Sprintf("0x%02x", d.address), "op": op}, err)
+}
+
+func (d *i2cDevice) read(b []byte) (n int, err error) {
 	if d.funcs&I2C_FUNC_SMBUS_READ_BLOCK_DATA == 0 {
 		// Adapter doesn't support SMBus block read
 		return d.file.Read(b)
@@ -119,7 +141,7 @@ func (d *i2cDevice) Read(b []byte) (n int, err error) {
 	return int(data[0]), nil
 }
 
-func (d *i2cDevice) Write(b []byte) (n int, err error) {
+func (d *i2cDevice) write(b []byte) (n int, err error) {
 	if d.funcs&I2C_FUNC_SMBUS_WRITE_BLOCK_DATA == 0 {
 		// Adapter doesn't support SMBus block write
 		return d.file.Write(b)
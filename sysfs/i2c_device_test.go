@@ -47,3 +47,22 @@ func TestNewI2cDevice(t *testing.T) {
 	gobottest.Assert(t, err, nil)
 
 }
+
+func TestI2cDeviceMetrics(t *testing.T) {
+	SetFilesystem(NewMockFilesystem([]string{"/dev/i2c-9"}))
+	SetSyscall(&MockSyscall{})
+	m := testMetrics{}
+	SetMetrics(m)
+	defer SetMetrics(nil)
+
+	i, err := NewI2cDevice("/dev/i2c-9", 0x42)
+	gobottest.Assert(t, err, nil)
+	i.Write([]byte{0x01, 0x02})
+	i.Read(make([]byte, 2))
+	i.Read(make([]byte, 2))
+
+	gobottest.Assert(t, m, testMetrics{
+		"gobot_i2c_operations_total map[address:0x42 bus:/dev/i2c-9 op:write]": 1,
+		"gobot_i2c_operations_total map[address:0x42 bus:/dev/i2c-9 op:read]":  2,
+	})
+}
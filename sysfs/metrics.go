@@ -0,0 +1,46 @@
+package sysfs
+
+import "sync/atomic"
+
+// Metrics is the interface which describes where digital pins and I2C
+// devices count their operations. The Registry of the gobot package
+// implements it, and the adaptors built on sysfs pass it
+// gobot.DefaultRegistry() unless SetMetrics was called first.
+type Metrics interface {
+	// Count adds v to the counter with name and labels
+	Count(name, help string, labels map[string]string, v float64)
+}
+
+// metricsSetting wraps the Metrics set, so that nil may be stored.
+type metricsSetting struct {
+	m Metrics
+}
+
+// No operations are counted until SetMetrics or SetDefaultMetrics is
+// called.
+var metrics atomic.Value
+
+// SetMetrics sets where operations are counted, nil counting none.
+func SetMetrics(m Metrics) {
+	metrics.Store(metricsSetting{m})
+}
+
+// SetDefaultMetrics sets where operations are counted unless SetMetrics or
+// SetDefaultMetrics has already been called.
+func SetDefaultMetrics(m Metrics) {
+	metrics.CompareAndSwap(nil, metricsSetting{m})
+}
+
+// count counts an operation in the gobot_<kind>_operations_total counter,
+// and in gobot_<kind>_errors_total when it failed.
+func count(kind, name string, labels map[string]string, err error) {
+	s, _ := metrics.Load().(metricsSetting)
+	m := s.m
+	if m == nil {
+		return
+	}
+	m.Count("gobot_"+kind+"_operations_total", name+" operations.", labels, 1)
+	if err != nil {
+		m.Count("gobot_"+kind+"_errors_total", name+" operations which failed.", labels, 1)
+	}
+}
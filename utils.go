@@ -1,9 +1,10 @@
 package gobot
 
 import (
+	"context"
 	"crypto/rand"
 	"errors"
-	"log"
+	"fmt"
 	"math"
 	"math/big"
 	"time"
@@ -17,38 +18,81 @@ var (
 var eventError = func(e *Event) (err error) {
 	if e == nil {
 		err = ErrUnknownEvent
-		log.Println(err.Error())
+		DefaultLogger().Error(err.Error())
 		return
 	}
 	return
 }
 
+var defaultScheduler = NewScheduler()
+
 // Every triggers f every t time until the end of days, or when a
 // bool value is sent to the channel returned by the Every function.
-// It does not wait for the previous execution of f to finish before
-// it fires the next f.
+// A run of f which falls due while the previous one is still going is
+// skipped. Robot.Every is preferred, as its jobs stop with the Robot.
 func Every(t time.Duration, f func()) chan bool {
 	done := make(chan bool)
-	c := time.Tick(t)
+	job := defaultScheduler.Every(t, f)
 
 	go func() {
-		for {
-			select {
-			case <-done:
-				return
-			default:
-				<-c
-				go f()
-			}
+		select {
+		case <-done:
+			job.Cancel()
+		case <-job.Done():
 		}
 	}()
 
 	return done
 }
 
-// After triggers f after t duration.
-func After(t time.Duration, f func()) {
-	time.AfterFunc(t, f)
+// After triggers f after t duration. The returned Job cancels it. Robot.After
+// is preferred, as its jobs stop with the Robot.
+func After(t time.Duration, f func()) *Job {
+	return defaultScheduler.After(t, f)
+}
+
+// withTimeout returns a copy of ctx which is cancelled after d. A d of zero or
+// less leaves ctx untouched, so no deadline is imposed.
+func withTimeout(ctx context.Context, d time.Duration) (context.Context, context.CancelFunc) {
+	if d <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, d)
+}
+
+// share returns a context for the next steps of the total steps which
+// remain, giving them their part of what is left before ctx's deadline, so a
+// step which hangs leaves the later ones their own time rather than an
+// expired context.
+func share(ctx context.Context, steps, total int) (context.Context, context.CancelFunc) {
+	deadline, ok := ctx.Deadline()
+	if !ok || steps >= total {
+		return ctx, func() {}
+	}
+	left := time.Until(deadline)
+	return context.WithTimeout(ctx, time.Duration(float64(left)*float64(steps)/float64(total)))
+}
+
+// runContext calls f and waits for it to return or for ctx to be done,
+// whichever happens first. When ctx can never be done f is simply called.
+// On cancellation the error names the phase which did not complete and
+// f is left to finish in the background.
+func runContext(ctx context.Context, phase string, f func() []error) []error {
+	if ctx.Done() == nil {
+		return f()
+	}
+
+	done := make(chan []error, 1)
+	go func() {
+		done <- f()
+	}()
+
+	select {
+	case errs := <-done:
+		return errs
+	case <-ctx.Done():
+		return []error{fmt.Errorf("%v did not complete: %w", phase, ctx.Err())}
+	}
 }
 
 // Rand returns a positive random int up to max